@@ -39,6 +39,11 @@ func main() {
 	defer dbConn.Close()
 	log.Println("INFO: Pool de conexiones MySQL listo.")
 
+	// --- Tablas auxiliares (dispositivos, etiquetas, ...) ---
+	if err := core.EnsureSchema(dbConn); err != nil {
+		log.Fatalf("CRÍTICO: No se pudo preparar el esquema de la BD: %v", err)
+	}
+
 	// --- Instanciar Repositorio de Usuarios ---
 	var userRepo userDomain.UserRepository
 	userRepo = userAdapters.NewMySQLUserRepository(dbConn)
@@ -77,12 +82,17 @@ func main() {
 
 
 	// --- Instanciar Componentes de Autenticación, Registro y Asignación MAC ---
+	ownershipRepo := userAdapters.NewMySQLOwnershipRepository(dbConn) // Historial de dueños por MAC
+	orgRepo := userAdapters.NewMySQLOrganizationRepository(dbConn)     // Organización personal al registrarse
+	shareRepo := userAdapters.NewMySQLShareRepository(dbConn)         // Accesos que se revocan si la MAC cambia de organización
 	loginUseCase := authApp.NewLoginUseCase(userRepo)
 	loginController := authInfra.NewLoginController(*loginUseCase)
-	createUserUseCase := authApp.NewCreateUserUseCase(userRepo)
+	createUserUseCase := authApp.NewCreateUserUseCase(userRepo, ownershipRepo, orgRepo, shareRepo, wsManager)
 	createUserController := authInfra.NewCreateUserController(*createUserUseCase)
-	assignMacUseCase := authApp.NewAssignMacToUserUseCase(userRepo)
+	assignMacUseCase := authApp.NewAssignMacToUserUseCase(userRepo, ownershipRepo, orgRepo, shareRepo, wsManager)
 	assignMacController := authInfra.NewAssignMacController(*assignMacUseCase)
+	deviceOwnershipUseCase := authApp.NewDeviceOwnership(ownershipRepo, userRepo)
+	deviceOwnershipController := authInfra.NewDeviceOwnershipController(*deviceOwnershipUseCase)
 	authMiddleware := authMW.JWTMiddleware()
 	log.Println("INFO: Componentes de Autenticación, Registro y Admin listos.")
 
@@ -101,13 +111,14 @@ func main() {
 	sensoresInfra.SetupRoutesDatos(r, wsManager, dbConn, userRepo, authMiddleware)
 
 
-	// --- Configurar Rutas de Administración ---
+	// --- Configurar Rutas de Administración (operadores de la plataforma) ---
 	adminGroup := r.Group("/admin")
-	adminGroup.Use(authMiddleware) // Protegido por JWT
+	adminGroup.Use(authMiddleware, authMW.RequirePlatformOperator()) // JWT + rol domain.RolePlatformOperator
 	{
 		adminGroup.PUT("/users/:userId/assign-mac", assignMacController.Execute)
+		adminGroup.GET("/devices/:mac/ownership", deviceOwnershipController.ExecuteAdmin)
 	}
-	log.Println("INFO: Rutas de administración /admin configuradas y protegidas por JWT.")
+	log.Println("INFO: Rutas de administración /admin configuradas (JWT + operador de la plataforma).")
 
 
 	// --- Ruta WebSocket ---
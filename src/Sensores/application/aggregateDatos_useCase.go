@@ -0,0 +1,521 @@
+// File: aggregateDatos_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Zonas IANA aunque el contenedor no traiga /usr/share/zoneinfo
+)
+
+// Cubetas admitidas por GET /datos/aggregate
+var aggregateBuckets = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"6h":  6 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+var aggregateMetrics = []string{"temperatura", "distancia", "peso", "movimiento"}
+
+// aggregateDerivedMetrics se piden explícitamente con ?metrics=; dependen de la geometría del
+// contenedor de cada dispositivo (vigente al consultar), por eso no tienen resúmenes. Lo mismo
+// vale para las métricas derivadas que definen los usuarios (se piden por su nombre).
+var aggregateDerivedMetrics = []string{"llenado", "volumen"}
+
+const (
+	aggregateDefaultBuckets = 100  // Sin ?from, se agregan las últimas 100 cubetas
+	aggregateMaxBuckets     = 5000 // Límite de cubetas por dispositivo y consulta
+
+	// Rangos más largos con cubetas de 1h o más se leen de los resúmenes precalculados
+	aggregateRollupMinRange = 3 * 24 * time.Hour
+)
+
+// AggregateInput DTO con los parámetros de agregación pedidos por el cliente
+type AggregateInput struct {
+	Bucket      string // 1m | 5m | 15m | 1h | 6h | 1d
+	Metricas    []string
+	Percentiles []string // "50", "95", ... (1..99)
+	Zona        string   // Nombre IANA (America/Mexico_City); "" = zona del servidor
+	Resolucion  string   // auto (por defecto) | raw: forzar lecturas sin resumir
+	SinHuecos   bool     // No calcular los huecos de cada serie
+}
+
+type AggregateDatos struct {
+	db          domain.DatosRepository
+	rollupRepo  domain.RollupRepository
+	deviceRepo  domain.DeviceRepository
+	derivedRepo domain.DerivedMetricRepository
+	gaps        gapDetector
+}
+
+func NewAggregateDatos(db domain.DatosRepository, rollupRepo domain.RollupRepository, deviceRepo domain.DeviceRepository, derivedRepo domain.DerivedMetricRepository) *AggregateDatos {
+	if db == nil || rollupRepo == nil || deviceRepo == nil || derivedRepo == nil {
+		log.Fatal("Error: AggregateDatos recibió dependencias nulas (db, rollupRepo, deviceRepo o derivedRepo).")
+	}
+	return &AggregateDatos{db: db, rollupRepo: rollupRepo, deviceRepo: deviceRepo, derivedRepo: derivedRepo, gaps: newGapDetector()}
+}
+
+// Execute valida los parámetros y agrega en la BD las lecturas visibles para el usuario
+func (uc *AggregateDatos) Execute(userID int, filter domain.DatosFilter, input AggregateInput) (*entities.DatosAggregate, error) {
+	width, ok := aggregateBuckets[input.Bucket]
+	if !ok {
+		return nil, fmt.Errorf("bucket_invalido")
+	}
+	query := domain.DatosAggregateQuery{Bucket: width, Zona: time.Local}
+	if input.Zona != "" {
+		zona, err := time.LoadLocation(input.Zona)
+		if err != nil {
+			return nil, fmt.Errorf("zona_invalida")
+		}
+		query.Zona = zona
+	}
+
+	metricas, err := parseAggregateMetrics(input.Metricas)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkUserMetrics(userID, metricas); err != nil {
+		return nil, err
+	}
+	query.Metricas = metricas
+	if query.Percentiles, err = parsePercentiles(input.Percentiles); err != nil {
+		return nil, err
+	}
+
+	if filter.Hasta.IsZero() {
+		filter.Hasta = time.Now()
+	}
+	if filter.Desde.IsZero() {
+		filter.Desde = filter.Hasta.Add(-aggregateDefaultBuckets * width)
+	}
+	if !filter.Desde.Before(filter.Hasta) {
+		return nil, fmt.Errorf("rango_invalido")
+	}
+	if filter.Hasta.Sub(filter.Desde)/width > aggregateMaxBuckets {
+		return nil, fmt.Errorf("demasiados_buckets")
+	}
+	filter.Etiquetas = NormalizeTags(filter.Etiquetas)
+	if input.Resolucion != "" && input.Resolucion != "auto" && input.Resolucion != "raw" {
+		return nil, fmt.Errorf("resolucion_invalida")
+	}
+
+	resolucion := ""
+	if input.Resolucion != "raw" {
+		resolucion = uc.rollupResolution(filter, query)
+	}
+	var series []entities.DeviceSeries
+	if resolucion == "" {
+		series, err = uc.db.Aggregate(userID, filter, query)
+	} else {
+		series, err = uc.aggregateWithRollups(userID, filter, query, resolucion)
+	}
+	if err != nil {
+		if err.Error() != "percentiles_no_soportados" {
+			log.Printf("ERROR: [AggregateDatos] Falló la agregación para UserID %d: %v", userID, err)
+		}
+		return nil, err
+	}
+	if resolucion == "" {
+		resolucion = "raw"
+	}
+	if len(series) > 0 {
+		macs := make([]string, len(series))
+		for i, s := range series {
+			macs[i] = s.Mac
+		}
+		devices, err := uc.deviceRepo.FindByMacs(macs)
+		if err != nil {
+			// Los metadatos son informativos: se devuelve la agregación igualmente
+			log.Printf("ADVERTENCIA: [AggregateDatos] No se pudieron adjuntar metadatos de dispositivos: %v", err)
+		}
+		for i := range series {
+			series[i].Dispositivo = devices[series[i].Mac]
+		}
+		if !input.SinHuecos {
+			if err := uc.attachGaps(userID, filter, width, query.Zona, series, resolucion); err != nil {
+				log.Printf("ADVERTENCIA: [AggregateDatos] No se pudieron calcular los huecos de las series: %v", err)
+			}
+		}
+	}
+	return &entities.DatosAggregate{
+		Bucket:      input.Bucket,
+		Zona:        query.Zona.String(),
+		Desde:       filter.Desde.In(query.Zona),
+		Hasta:       filter.Hasta.In(query.Zona),
+		Metricas:    query.Metricas,
+		Percentiles: query.Percentiles,
+		Resolucion:  resolucion,
+		Series:      series,
+	}, nil
+}
+
+// attachGaps añade a cada serie los huecos (según el intervalo de reporte de su dispositivo) que duran
+// al menos una cubeta: los más cortos no dejan cubetas vacías, así que no cortan la línea del gráfico.
+// Si la consulta se respondió con resúmenes, los huecos salen de las lecturas por hora de esos
+// resúmenes; sobre 'rutas' solo se buscan en rangos de hasta gapsMaxRange (si no, se omiten).
+func (uc *AggregateDatos) attachGaps(userID int, filter domain.DatosFilter, width time.Duration, zona *time.Location, series []entities.DeviceSeries, resolucion string) error {
+	// Un hueco es que el dispositivo no reportó, no que sus valores no cumplan la expresión
+	filter.Expresion = nil
+	if now := time.Now(); now.Before(filter.Hasta) {
+		filter.Hasta = now
+	}
+	if !filter.Desde.Before(filter.Hasta) {
+		return nil
+	}
+	// [desde, hasta) es el tramo que se lee de los resúmenes por hora; vacío si no se usan
+	desde, hasta := filter.Hasta, filter.Hasta
+	if resolucion != "raw" {
+		coverage, err := uc.rollupRepo.Coverage()
+		if err != nil {
+			return err
+		}
+		desde = ceilLocal(filter.Desde, domain.RollupHora)
+		hasta = floorLocal(filter.Hasta, domain.RollupHora)
+		if limite := floorLocal(coverage, domain.RollupHora); limite.Before(hasta) {
+			hasta = limite
+		}
+		if !desde.Before(hasta) {
+			desde, hasta = filter.Hasta, filter.Hasta
+		}
+	}
+	if desde.Sub(filter.Desde)+filter.Hasta.Sub(hasta) > gapsMaxRange {
+		return nil
+	}
+
+	positions := make(map[string]int, len(series))
+	for i := range series {
+		positions[series[i].Mac] = i
+	}
+	// Lecturas sueltas de los extremos que no cubren los resúmenes (todo el rango si no se usan)
+	sueltas := make(map[string][]gapSpan)
+	for _, tramo := range [][2]time.Time{{filter.Desde, desde}, {hasta, filter.Hasta}} {
+		if !tramo[0].Before(tramo[1]) {
+			continue
+		}
+		rawFilter := filter
+		rawFilter.Desde, rawFilter.Hasta = tramo[0], tramo[1]
+		err := streamTimelines(uc.db, userID, rawFilter, func(mac string, fechas []time.Time) {
+			if _, ok := positions[mac]; !ok {
+				return
+			}
+			for _, fecha := range fechas {
+				sueltas[mac] = append(sueltas[mac], gapSpan{inicio: fecha, fin: fecha})
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	done := make(map[string]bool, len(series))
+	finish := func(mac string, horas []time.Time, lecturas []int64) {
+		i, ok := positions[mac]
+		if !ok {
+			return
+		}
+		done[mac] = true
+		spans := append([]gapSpan(nil), sueltas[mac]...)
+		var interval time.Duration
+		if len(horas) == 0 {
+			fechas := make([]time.Time, len(spans))
+			for j, span := range spans {
+				fechas[j] = span.inicio
+			}
+			interval, _ = uc.gaps.interval(series[i].Dispositivo, fechas)
+		} else {
+			interval = uc.gaps.hourlyInterval(series[i].Dispositivo, horas, lecturas)
+			for _, hora := range horas {
+				spans = append(spans, gapSpan{inicio: hora, fin: hora.Add(time.Hour)})
+			}
+			sort.Slice(spans, func(a, b int) bool { return spans[a].inicio.Before(spans[b].inicio) })
+		}
+		series[i].Huecos = []entities.DataGap{}
+		for _, gap := range uc.gaps.findSpans(spans, filter.Desde, filter.Hasta, uc.gaps.threshold(interval)) {
+			if gap.Fin.Sub(gap.Inicio) >= width {
+				gap.Inicio, gap.Fin = gap.Inicio.In(zona), gap.Fin.In(zona)
+				series[i].Huecos = append(series[i].Huecos, gap)
+			}
+		}
+	}
+
+	if desde.Before(hasta) {
+		rollupFilter := filter
+		rollupFilter.Desde, rollupFilter.Hasta = desde, hasta
+		mac := ""
+		horas, lecturas := []time.Time{}, []int64{}
+		err := uc.rollupRepo.StreamHourly(userID, rollupFilter, func(m string, hora time.Time, total int64) error {
+			if m != mac {
+				if len(horas) > 0 {
+					finish(mac, horas, lecturas)
+				}
+				mac, horas, lecturas = m, horas[:0], lecturas[:0]
+			}
+			horas = append(horas, hora)
+			lecturas = append(lecturas, total)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(horas) > 0 {
+			finish(mac, horas, lecturas)
+		}
+	}
+	for _, s := range series {
+		if !done[s.Mac] {
+			finish(s.Mac, nil, nil)
+		}
+	}
+	return nil
+}
+
+// rollupResolution elige los resúmenes que sirven para la consulta ("" = leer 'rutas'): solo sin
+// percentiles, métricas derivadas ni expresión sobre las métricas, con rangos largos y cubetas que sean horas (o días) enteros del reloj del servidor.
+func (uc *AggregateDatos) rollupResolution(filter domain.DatosFilter, query domain.DatosAggregateQuery) string {
+	if len(query.Percentiles) > 0 || filter.Expresion != nil || query.Bucket < time.Hour || filter.Hasta.Sub(filter.Desde) < aggregateRollupMinRange {
+		return ""
+	}
+	for _, m := range query.Metricas {
+		if !isBaseMetric(m) {
+			return ""
+		}
+	}
+	// Diferencias entre la zona pedida y la del servidor a lo largo del rango
+	sameZone, wholeHours := true, true
+	for t := filter.Desde; t.Before(filter.Hasta); t = t.Add(time.Hour) {
+		_, target := t.In(query.Zona).Zone()
+		_, local := t.In(time.Local).Zone()
+		sameZone = sameZone && target == local
+		wholeHours = wholeHours && (target-local)%3600 == 0
+	}
+	switch {
+	case query.Bucket == 24*time.Hour && sameZone:
+		return domain.RollupDia
+	case wholeHours:
+		return domain.RollupHora
+	}
+	return ""
+}
+
+// aggregateWithRollups lee de los resúmenes el tramo alineado y ya calculado del rango, y de
+// 'rutas' los extremos sueltos y lo más reciente; las cubetas compartidas se combinan.
+func (uc *AggregateDatos) aggregateWithRollups(userID int, filter domain.DatosFilter, query domain.DatosAggregateQuery, resolucion string) ([]entities.DeviceSeries, error) {
+	coverage, err := uc.rollupRepo.Coverage()
+	if err != nil {
+		return nil, err
+	}
+	desde := ceilLocal(filter.Desde, resolucion)
+	hasta := floorLocal(filter.Hasta, resolucion)
+	if limite := floorLocal(coverage, resolucion); limite.Before(hasta) {
+		hasta = limite
+	}
+	if !desde.Before(hasta) {
+		return uc.db.Aggregate(userID, filter, query)
+	}
+
+	rollupFilter := filter
+	rollupFilter.Desde, rollupFilter.Hasta = desde, hasta
+	series, err := uc.rollupRepo.Aggregate(userID, rollupFilter, query, resolucion)
+	if err != nil {
+		return nil, err
+	}
+	for _, tramo := range [][2]time.Time{{filter.Desde, desde}, {hasta, filter.Hasta}} {
+		if !tramo[0].Before(tramo[1]) {
+			continue
+		}
+		rawFilter := filter
+		rawFilter.Desde, rawFilter.Hasta = tramo[0], tramo[1]
+		raw, err := uc.db.Aggregate(userID, rawFilter, query)
+		if err != nil {
+			return nil, err
+		}
+		series = mergeSeries(series, raw)
+	}
+	return series, nil
+}
+
+// floorLocal y ceilLocal redondean al inicio de la hora o del día en el reloj del servidor
+func floorLocal(t time.Time, resolucion string) time.Time {
+	t = t.In(time.Local)
+	if resolucion == domain.RollupDia {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.Local)
+}
+
+func ceilLocal(t time.Time, resolucion string) time.Time {
+	floor := floorLocal(t, resolucion)
+	if floor.Equal(t) {
+		return floor
+	}
+	if resolucion == domain.RollupDia {
+		return floor.AddDate(0, 0, 1)
+	}
+	return floorLocal(floor.Add(90*time.Minute), resolucion) // Siguiente hora del reloj, también en cambios de horario
+}
+
+// mergeSeries añade a dst las cubetas de src, combinando las que empiezan en el mismo instante
+func mergeSeries(dst, src []entities.DeviceSeries) []entities.DeviceSeries {
+	positions := make(map[string]int, len(dst))
+	for i := range dst {
+		positions[dst[i].Mac] = i
+	}
+	for _, s := range src {
+		pos, ok := positions[s.Mac]
+		if !ok {
+			positions[s.Mac] = len(dst)
+			dst = append(dst, s)
+			continue
+		}
+		target := &dst[pos]
+		index := make(map[int64]int, len(target.Buckets))
+		for i, b := range target.Buckets {
+			index[b.Inicio.Unix()] = i
+		}
+		for _, b := range s.Buckets {
+			if i, ok := index[b.Inicio.Unix()]; ok {
+				target.Buckets[i] = mergeBucket(target.Buckets[i], b)
+			} else {
+				target.Buckets = append(target.Buckets, b)
+			}
+		}
+		sort.Slice(target.Buckets, func(i, j int) bool { return target.Buckets[i].Inicio.Before(target.Buckets[j].Inicio) })
+	}
+	sort.Slice(dst, func(i, j int) bool { return dst[i].Mac < dst[j].Mac })
+	return dst
+}
+
+func mergeBucket(a, b entities.AggregateBucket) entities.AggregateBucket {
+	merged := entities.AggregateBucket{Inicio: a.Inicio, Lecturas: a.Lecturas + b.Lecturas, Metricas: make(map[string]entities.MetricAggregate, len(a.Metricas))}
+	for name, ma := range a.Metricas {
+		mb := b.Metricas[name]
+		m := entities.MetricAggregate{Lecturas: ma.Lecturas + mb.Lecturas}
+		if m.Lecturas > 0 {
+			promedio := (valueOr0(ma.Promedio)*float64(ma.Lecturas) + valueOr0(mb.Promedio)*float64(mb.Lecturas)) / float64(m.Lecturas)
+			m.Promedio = &promedio
+		}
+		m.Min = pickFloat(ma.Min, mb.Min, func(x, y float64) bool { return x < y })
+		m.Max = pickFloat(ma.Max, mb.Max, func(x, y float64) bool { return x > y })
+		merged.Metricas[name] = m
+	}
+	return merged
+}
+
+func valueOr0(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// pickFloat devuelve el que gana según better, ignorando los nil
+func pickFloat(a, b *float64, better func(x, y float64) bool) *float64 {
+	if a == nil {
+		return b
+	}
+	if b == nil || better(*a, *b) {
+		return a
+	}
+	return b
+}
+
+func isBaseMetric(name string) bool {
+	for _, m := range aggregateMetrics {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUserMetrics comprueba que las métricas que no son propias de las lecturas sean métricas
+// derivadas de alguno de los dispositivos del usuario
+func (uc *AggregateDatos) checkUserMetrics(userID int, metricas []string) error {
+	pending := map[string]bool{}
+	for _, m := range metricas {
+		if domain.ValidDerivedName(m) {
+			pending[m] = true
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	devices, err := uc.deviceRepo.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+	macs := make([]string, 0, len(devices))
+	for _, device := range devices {
+		macs = append(macs, device.Mac)
+	}
+	names, err := uc.derivedRepo.FindNames(macs)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		delete(pending, name)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("metrica_invalida")
+	}
+	return nil
+}
+
+// parseAggregateMetrics valida las métricas pedidas (todas si no se indica ninguna), sin duplicados.
+// Los nombres que no son métricas conocidas pero sirven para una métrica derivada se validan aparte.
+func parseAggregateMetrics(values []string) ([]string, error) {
+	if len(values) == 0 {
+		return aggregateMetrics, nil
+	}
+	seen := make(map[string]bool)
+	metricas := []string{}
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		valid := domain.ValidDerivedName(v)
+		for _, m := range append(aggregateMetrics, aggregateDerivedMetrics...) {
+			valid = valid || m == v
+		}
+		if !valid {
+			return nil, fmt.Errorf("metrica_invalida")
+		}
+		seen[v] = true
+		metricas = append(metricas, v)
+	}
+	if len(metricas) == 0 {
+		return aggregateMetrics, nil
+	}
+	return metricas, nil
+}
+
+func parsePercentiles(values []string) ([]int, error) {
+	seen := make(map[int]bool)
+	percentiles := []int{}
+	for _, v := range values {
+		v = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(v)), "p")
+		if v == "" {
+			continue
+		}
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 || p > 99 {
+			return nil, fmt.Errorf("percentil_invalido")
+		}
+		if !seen[p] {
+			seen[p] = true
+			percentiles = append(percentiles, p)
+		}
+	}
+	sort.Ints(percentiles)
+	return percentiles, nil
+}
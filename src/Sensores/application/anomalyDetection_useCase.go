@@ -0,0 +1,307 @@
+// File: anomalyDetection_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	anomalyDefaultZ     = 3.5  // Desviaciones típicas a partir de las cuales una lectura es anómala
+	anomalyDefaultAlpha = 0.05 // Peso de cada lectura nueva en los promedios móviles
+	anomalyWarmup       = 30   // Muestras del modelo global antes de empezar a marcar lecturas
+	anomalyHourWarmup   = 10   // Muestras del modelo de una hora para preferirlo al global
+	anomalyMinStd       = 0.01 // Desviación mínima (relativa a la media si es mayor que 1) para no dividir por casi cero
+	anomalyMinZ         = 1.0
+	anomalyMaxZ         = 10.0
+)
+
+// AnomalySettingsInput DTO con la configuración pedida por el cliente
+type AnomalySettingsInput struct {
+	UmbralZ *float64 // nil = el predeterminado
+	Activa  *bool    // nil = activa
+}
+
+// AnomalyDetection marca lecturas anómalas comparando cada métrica con un modelo en línea del
+// dispositivo: media y varianza con promedio móvil exponencial, una por hora del día (para
+// respetar el ciclo diario) y otra global que se usa mientras la de la hora tiene pocas muestras.
+// Los modelos se guardan en la base, así que sobreviven a los reinicios.
+type AnomalyDetection struct {
+	repo      domain.AnomalyRepository
+	userRepo  domain.UserRepository
+	orgRepo   domain.OrganizationRepository
+	shareRepo domain.ShareRepository
+	notifier  domain.DatosNotifier
+	umbralZ   float64
+	alpha     float64
+	locks     *macLocks // Serializa el aprendizaje por dispositivo; los distintos avanzan en paralelo
+}
+
+// macLocks es un mutex por MAC; cada uno existe solo mientras alguien lo usa o lo espera
+type macLocks struct {
+	mu    sync.Mutex
+	locks map[string]*macLock
+}
+
+type macLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newMacLocks() *macLocks {
+	return &macLocks{locks: make(map[string]*macLock)}
+}
+
+// lock bloquea la MAC y devuelve la función que la libera
+func (l *macLocks) lock(mac string) func() {
+	l.mu.Lock()
+	entry := l.locks[mac]
+	if entry == nil {
+		entry = &macLock{}
+		l.locks[mac] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.mu.Lock()
+	return func() {
+		entry.mu.Unlock()
+		l.mu.Lock()
+		if entry.refs--; entry.refs == 0 {
+			delete(l.locks, mac)
+		}
+		l.mu.Unlock()
+	}
+}
+
+func NewAnomalyDetection(repo domain.AnomalyRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository, notifier domain.DatosNotifier) *AnomalyDetection {
+	if repo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil || notifier == nil {
+		log.Fatal("Error: AnomalyDetection recibió dependencias nulas (repo, userRepo, orgRepo, shareRepo o notifier).")
+	}
+	uc := &AnomalyDetection{
+		repo: repo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo, notifier: notifier,
+		umbralZ: floatFromEnv("ANOMALY_UMBRAL_Z", anomalyDefaultZ),
+		alpha:   floatFromEnv("ANOMALY_ALPHA", anomalyDefaultAlpha),
+		locks:   newMacLocks(),
+	}
+	if uc.umbralZ < anomalyMinZ || uc.umbralZ > anomalyMaxZ {
+		log.Printf("ADVERTENCIA: ANOMALY_UMBRAL_Z (%g) fuera de [%g, %g]; se usa %g.", uc.umbralZ, anomalyMinZ, anomalyMaxZ, anomalyDefaultZ)
+		uc.umbralZ = anomalyDefaultZ
+	}
+	if uc.alpha <= 0 || uc.alpha >= 1 {
+		log.Printf("ADVERTENCIA: ANOMALY_ALPHA (%g) debe estar entre 0 y 1; se usa %g.", uc.alpha, anomalyDefaultAlpha)
+		uc.alpha = anomalyDefaultAlpha
+	}
+	return uc
+}
+
+// Process evalúa y aprende las métricas de una lectura recién guardada. Las lecturas anteriores a
+// la última procesada del dispositivo se ignoran. Solo se espera a otras lecturas del mismo
+// dispositivo: la configuración y los modelos se leen en una consulta y se guardan, con las
+// anomalías, en una transacción.
+func (uc *AnomalyDetection) Process(data entities.Datos) {
+	valores := map[string]*float64{
+		"temperatura": domain.ParseNumber(data.Temperatura),
+		"distancia":   domain.ParseNumber(data.Distancia),
+		"peso":        domain.ParseNumber(data.Peso),
+	}
+	if valores["temperatura"] == nil && valores["distancia"] == nil && valores["peso"] == nil {
+		return
+	}
+	defer uc.locks.lock(data.Mac)()
+	hora := data.Fecha.In(time.Local).Hour()
+	settings, states, err := uc.repo.GetModel(data.Mac, hora)
+	if err != nil {
+		log.Printf("ERROR: [AnomalyDetection] %v", err)
+		return
+	}
+	if settings == nil {
+		settings = uc.defaultSettings(data.Mac)
+	}
+	models := make(map[string]*entities.AnomalyState, len(states))
+	for i := range states {
+		models[anomalyStateKey(states[i].Metrica, states[i].Hora)] = &states[i]
+	}
+	model := func(metrica string, h int) *entities.AnomalyState {
+		if state := models[anomalyStateKey(metrica, h)]; state != nil {
+			return state
+		}
+		return &entities.AnomalyState{Mac: data.Mac, Metrica: metrica, Hora: h}
+	}
+
+	changed := []entities.AnomalyState{}
+	events := []entities.AnomalyEvent{}
+	for _, metrica := range entities.AnomalyMetricas {
+		valor := valores[metrica]
+		if valor == nil {
+			continue
+		}
+		global, hourly := model(metrica, entities.AnomalyHoraGlobal), model(metrica, hora)
+		if global.Muestras > 0 && data.Fecha.Before(global.Actualizado) {
+			continue
+		}
+		reference, estacional := global, false
+		if hourly.Muestras >= anomalyHourWarmup {
+			reference, estacional = hourly, true
+		}
+		learn := *valor
+		if global.Muestras >= anomalyWarmup {
+			desviacion := anomalyStd(reference)
+			score := (*valor - reference.Media) / desviacion
+			if math.Abs(score) >= settings.UmbralZ {
+				// La lectura anómala se aprende recortada al umbral: un valor aislado no arrastra el
+				// modelo, pero un cambio sostenido termina siendo el nuevo normal
+				learn = reference.Media + math.Copysign(settings.UmbralZ*desviacion, score)
+				if settings.Activa {
+					event := entities.AnomalyEvent{DatoID: int64(data.ID), Mac: data.Mac, UserID: data.UserID, OrgID: data.OrgID, Metrica: metrica,
+						Tipo: entities.AnomalyAlta, Fecha: data.Fecha, Valor: *valor, Esperado: reference.Media, Desviacion: desviacion,
+						Score: score, Estacional: estacional}
+					if score < 0 {
+						event.Tipo = entities.AnomalyBaja
+					}
+					events = append(events, event)
+				}
+			}
+		}
+		uc.learn(global, learn, data.Fecha)
+		uc.learn(hourly, learn, data.Fecha)
+		changed = append(changed, *global, *hourly)
+	}
+
+	if err := uc.repo.SaveResults(changed, events); err != nil {
+		log.Printf("ERROR: [AnomalyDetection] %v", err)
+		return
+	}
+	for _, event := range events {
+		log.Printf("INFO: [AnomalyDetection] Anomalía de %s en MAC %s: %g (esperado %g, score %.2f).", event.Metrica, event.Mac, event.Valor, event.Esperado, event.Score)
+		notification := entities.AnomalyNotification{Tipo: entities.AnomalyNotifyDetectada, Evento: event, Dispositivo: data.Dispositivo}
+		if err := uc.notifier.NotifyAnomaly(notification); err != nil {
+			log.Printf("ADVERTENCIA: [AnomalyDetection] Falló la notificación de la anomalía %d: %v", event.ID, err)
+		}
+	}
+}
+
+func anomalyStateKey(metrica string, hora int) string {
+	return fmt.Sprintf("%s:%d", metrica, hora)
+}
+
+// anomalyStd es la desviación típica del modelo, con un mínimo para métricas casi constantes
+func anomalyStd(state *entities.AnomalyState) float64 {
+	return math.Max(math.Sqrt(state.Varianza), anomalyMinStd*math.Max(1, math.Abs(state.Media)))
+}
+
+// learn incorpora un valor al modelo (media y varianza móviles exponenciales)
+func (uc *AnomalyDetection) learn(state *entities.AnomalyState, valor float64, fecha time.Time) {
+	if state.Muestras == 0 {
+		state.Media, state.Varianza = valor, 0
+	} else {
+		diff := valor - state.Media
+		incr := uc.alpha * diff
+		state.Media += incr
+		state.Varianza = (1 - uc.alpha) * (state.Varianza + diff*incr)
+	}
+	state.Muestras++
+	state.Actualizado = fecha
+}
+
+// settingsFor devuelve la configuración del dispositivo o la predeterminada
+func (uc *AnomalyDetection) settingsFor(mac string) (*entities.AnomalySettings, error) {
+	settings, err := uc.repo.FindSettings(mac)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = uc.defaultSettings(mac)
+	}
+	return settings, nil
+}
+
+func (uc *AnomalyDetection) defaultSettings(mac string) *entities.AnomalySettings {
+	return &entities.AnomalySettings{Mac: mac, UmbralZ: uc.umbralZ, Activa: true}
+}
+
+// GetSettings devuelve la configuración del detector para un dispositivo al que el usuario tiene
+// acceso (la predeterminada si no se configuró)
+func (uc *AnomalyDetection) GetSettings(userID int, mac string) (*entities.AnomalySettings, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	return uc.settingsFor(mac)
+}
+
+// SaveSettings fija la sensibilidad del detector; requiere poder editar el dispositivo
+func (uc *AnomalyDetection) SaveSettings(userID int, mac string, input AnomalySettingsInput) (*entities.AnomalySettings, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return nil, err
+	}
+	settings := &entities.AnomalySettings{Mac: mac, UmbralZ: uc.umbralZ, Activa: input.Activa == nil || *input.Activa}
+	if input.UmbralZ != nil {
+		if math.IsNaN(*input.UmbralZ) || *input.UmbralZ < anomalyMinZ || *input.UmbralZ > anomalyMaxZ {
+			return nil, fmt.Errorf("umbral_invalido")
+		}
+		settings.UmbralZ = *input.UmbralZ
+	}
+	if err := uc.repo.SaveSettings(settings); err != nil {
+		return nil, err
+	}
+	log.Printf("INFO: [AnomalyDetection] Sensibilidad de MAC %s fijada en %g por UserID %d (activa: %t).", mac, settings.UmbralZ, userID, settings.Activa)
+	return uc.settingsFor(mac)
+}
+
+// DeleteSettings vuelve a la configuración predeterminada
+func (uc *AnomalyDetection) DeleteSettings(userID int, mac string) error {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return err
+	}
+	return uc.repo.DeleteSettings(mac)
+}
+
+// ResetModel descarta lo aprendido del dispositivo (p. ej. tras moverlo de lugar); vuelve a marcar
+// lecturas cuando junta de nuevo las muestras mínimas
+func (uc *AnomalyDetection) ResetModel(userID int, mac string) error {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return err
+	}
+	defer uc.locks.lock(mac)()
+	return uc.repo.DeleteStates(mac)
+}
+
+// List devuelve las anomalías de un dispositivo al que el usuario tiene acceso detectadas en
+// [desde, hasta); sin rango, las de las últimas 24 horas
+func (uc *AnomalyDetection) List(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.AnomalyEvent, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	desde, hasta, limit, err := deviceEventsRange(desde, hasta, limit)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.FindByMac(userID, mac, desde, hasta, limit)
+}
+
+// attachAnomalies rellena Datos.Anomalias en las lecturas que el detector marcó
+func attachAnomalies(anomalyRepo domain.AnomalyRepository, datos []entities.Datos) error {
+	ids := make([]int32, 0, len(datos))
+	for _, d := range datos {
+		if d.ID > 0 {
+			ids = append(ids, d.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	marks, err := anomalyRepo.FindMarks(ids)
+	if err != nil {
+		return err
+	}
+	for i := range datos {
+		datos[i].Anomalias = marks[datos[i].ID]
+	}
+	return nil
+}
@@ -2,29 +2,37 @@ package application // O el paquete que uses para casos de uso
 
 import (
 	userDomain "API/src/Sensores/domain" // Ruta a tu paquete domain
+	"API/src/Sensores/domain/entities"
 	"database/sql"
 	"fmt"
 	"log"
 	"net" // Para validar formato MAC (opcional)
+	"strings"
 )
 
 // AssignMacInput DTO para la entrada
 type AssignMacInput struct {
 	TargetUserID int    // ID del usuario a modificar
 	MacAddress   string // Nueva MAC address (puede ser vacía para desasignar)
+	AdminUserID  int    // Admin que hace el cambio (queda en el historial)
+	Motivo       string // Obligatorio si la MAC pertenece a otro usuario
 }
 
 // AssignMacToUserUseCase maneja la lógica de asignar MAC
 type AssignMacToUserUseCase struct {
-	userRepo userDomain.UserRepository
+	userRepo      userDomain.UserRepository
+	ownershipRepo userDomain.OwnershipRepository    // Historial de dueños por dispositivo
+	orgRepo       userDomain.OrganizationRepository // La MAC pasa a la organización personal del nuevo dueño
+	shareRepo     userDomain.ShareRepository        // Los accesos de la organización anterior se revocan
+	revoker       userDomain.SubscriptionRevoker    // Corta el feed en vivo de quienes pierden el acceso
 }
 
 // NewAssignMacToUserUseCase crea la instancia
-func NewAssignMacToUserUseCase(userRepo userDomain.UserRepository) *AssignMacToUserUseCase {
-	if userRepo == nil {
-		log.Fatal("CRITICO: AssignMacToUserUseCase recibió userRepo nulo.")
+func NewAssignMacToUserUseCase(userRepo userDomain.UserRepository, ownershipRepo userDomain.OwnershipRepository, orgRepo userDomain.OrganizationRepository, shareRepo userDomain.ShareRepository, revoker userDomain.SubscriptionRevoker) *AssignMacToUserUseCase {
+	if userRepo == nil || ownershipRepo == nil || orgRepo == nil || shareRepo == nil || revoker == nil {
+		log.Fatal("CRITICO: AssignMacToUserUseCase recibió dependencias nulas (userRepo, ownershipRepo, orgRepo, shareRepo o revoker).")
 	}
-	return &AssignMacToUserUseCase{userRepo: userRepo}
+	return &AssignMacToUserUseCase{userRepo: userRepo, ownershipRepo: ownershipRepo, orgRepo: orgRepo, shareRepo: shareRepo, revoker: revoker}
 }
 
 // Función de ayuda para validar formato MAC (opcional)
@@ -36,28 +44,61 @@ func isValidMacAddress(mac string) bool {
 	return err == nil
 }
 
-// Execute realiza la asignación
-func (uc *AssignMacToUserUseCase) Execute(input AssignMacInput) error {
+// Execute realiza la asignación y devuelve el nuevo periodo de propiedad (nil al desasignar)
+func (uc *AssignMacToUserUseCase) Execute(input AssignMacInput) (*entities.DeviceOwnership, error) {
 	log.Printf("INFO: [AssignMacUC] Intentando asignar MAC '%s' a UserID %d", input.MacAddress, input.TargetUserID)
 
 	// 1. Validar formato de MAC (Opcional pero recomendado)
 	if !isValidMacAddress(input.MacAddress) {
 		log.Printf("WARN: [AssignMacUC] Formato de MAC inválido: '%s'", input.MacAddress)
-		return fmt.Errorf("formato_mac_invalido")
+		return nil, fmt.Errorf("formato_mac_invalido")
 	}
+	input.Motivo = strings.TrimSpace(input.Motivo)
 
-	// 2. Preparar sql.NullString
-	// Si la MAC está vacía, Valid será false, guardando NULL.
-	macSQLString := sql.NullString{String: input.MacAddress, Valid: input.MacAddress != ""}
+	// 2. Mover una MAC que ya tiene dueño exige dejar constancia del motivo
+	if input.MacAddress != "" && input.Motivo == "" {
+		ownerID, err := uc.userRepo.FindAssignedUserID(input.MacAddress)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == nil && ownerID != input.TargetUserID {
+			log.Printf("WARN: [AssignMacUC] Reasignación de MAC '%s' (UserID %d -> %d) sin motivo", input.MacAddress, ownerID, input.TargetUserID)
+			return nil, fmt.Errorf("motivo_requerido")
+		}
+	}
+
+	// 3. Si la MAC cambia de organización, revocar los accesos compartidos de la anterior
+	formerOrg := 0
+	if input.MacAddress != "" {
+		var err error
+		formerOrg, err = leavingOrg(uc.orgRepo, uc.shareRepo, uc.revoker, input.MacAddress, input.TargetUserID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// 3. Llamar al repositorio para actualizar
-	err := uc.userRepo.UpdateMacAddress(input.TargetUserID, macSQLString)
+	// 4. Transferir en el repositorio (quita la MAC al dueño anterior y registra el cambio)
+	period, err := uc.ownershipRepo.Transfer(userDomain.OwnershipChange{
+		TargetUserID: input.TargetUserID,
+		Mac:          input.MacAddress,
+		AssignedBy:   input.AdminUserID,
+		Motivo:       input.Motivo,
+	})
 	if err != nil {
-		// Propagar errores específicos del repo (not found, duplicado) u otros
+		// Propagar errores específicos del repo (not found) u otros
 		log.Printf("ERROR: [AssignMacUC] Error del repositorio al actualizar MAC para UserID %d: %v", input.TargetUserID, err)
-		return err // Devolver el error original del repo
+		return nil, err // Devolver el error original del repo
+	}
+
+	// 5. Cortar el feed en vivo de la organización anterior y del dueño anterior
+	if period != nil {
+		previous := []int{}
+		if period.PreviousUserID != nil {
+			previous = append(previous, *period.PreviousUserID)
+		}
+		revokeFormerAccess(uc.orgRepo, uc.userRepo, uc.shareRepo, uc.revoker, input.MacAddress, formerOrg, previous...)
 	}
 
 	log.Printf("INFO: [AssignMacUC] Operación de asignación de MAC completada para UserID %d.", input.TargetUserID)
-	return nil // Éxito
-}
\ No newline at end of file
+	return period, nil // Éxito
+}
@@ -0,0 +1,498 @@
+// File: complianceReports_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	complianceDefaultGap    = 900       // Segundos sin lecturas que cuentan como falta de datos
+	complianceCheckInterval = time.Hour // Cada cuánto se buscan reportes automáticos pendientes
+	complianceListDays      = 90        // Días listados por defecto
+)
+
+// ComplianceProfileInput DTO con el perfil pedido por el cliente
+type ComplianceProfileInput struct {
+	TempMin        float64
+	TempMax        float64
+	ToleranciaSeg  int
+	HuecoMaximoSeg int // 0 = complianceDefaultGap
+}
+
+// ComplianceReports mide cuánto tiempo la temperatura de un dispositivo se mantuvo en el rango de
+// su perfil. Genera cada hora los reportes diario y semanal del periodo anterior y, a pedido,
+// los de cualquier periodo terminado; todos quedan guardados para auditoría.
+type ComplianceReports struct {
+	repo      domain.ComplianceRepository
+	db        domain.DatosRepository
+	userRepo  domain.UserRepository
+	orgRepo   domain.OrganizationRepository
+	shareRepo domain.ShareRepository
+}
+
+func NewComplianceReports(repo domain.ComplianceRepository, db domain.DatosRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *ComplianceReports {
+	if repo == nil || db == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: ComplianceReports recibió dependencias nulas (repo, db, userRepo, orgRepo o shareRepo).")
+	}
+	return &ComplianceReports{repo: repo, db: db, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo}
+}
+
+// Run genera los reportes automáticos pendientes. Se lanza con 'go' al arrancar.
+func (uc *ComplianceReports) Run() {
+	ticker := time.NewTicker(complianceCheckInterval)
+	defer ticker.Stop()
+	for {
+		uc.generatePending(time.Now())
+		<-ticker.C
+	}
+}
+
+// generatePending crea, para cada perfil, los reportes del día y la semana anteriores si faltan.
+// Los periodos que terminaron antes de configurar el perfil no se generan solos.
+func (uc *ComplianceReports) generatePending(now time.Time) {
+	profiles, err := uc.repo.FindProfiles()
+	if err != nil {
+		log.Printf("ERROR: [ComplianceReports] %v", err)
+		return
+	}
+	generated := 0
+	for _, profile := range profiles {
+		for _, periodo := range []string{entities.ComplianceDiario, entities.ComplianceSemanal} {
+			desde, hasta := compliancePeriod(periodo, now)
+			desde, hasta = previousCompliancePeriod(periodo, desde), desde
+			if !hasta.After(profile.Creado) {
+				continue
+			}
+			exists, err := uc.repo.ReportExists(profile.Mac, periodo, desde)
+			if err != nil {
+				log.Printf("ERROR: [ComplianceReports] %v", err)
+				return
+			}
+			if exists {
+				continue
+			}
+			if _, err := uc.generate(profile, periodo, desde, hasta, 0); err != nil {
+				if err.Error() == "reporte_duplicado" {
+					continue
+				}
+				log.Printf("ERROR: [ComplianceReports] Falló el reporte %s de MAC %s desde %s: %v", periodo, profile.Mac, desde.Format("2006-01-02"), err)
+				continue
+			}
+			generated++
+		}
+	}
+	if generated > 0 {
+		log.Printf("INFO: [ComplianceReports] %d reportes de cumplimiento generados.", generated)
+	}
+}
+
+// compliancePeriod devuelve el día o la semana (de lunes a lunes) que contiene t
+func compliancePeriod(periodo string, t time.Time) (time.Time, time.Time) {
+	desde := startOfDay(t)
+	if periodo == entities.ComplianceSemanal {
+		desde = desde.AddDate(0, 0, -((int(desde.Weekday()) + 6) % 7))
+		return desde, desde.AddDate(0, 0, 7)
+	}
+	return desde, desde.AddDate(0, 0, 1)
+}
+
+func previousCompliancePeriod(periodo string, desde time.Time) time.Time {
+	if periodo == entities.ComplianceSemanal {
+		return desde.AddDate(0, 0, -7)
+	}
+	return desde.AddDate(0, 0, -1)
+}
+
+// GetProfile devuelve el perfil de un dispositivo al que el usuario tiene acceso
+func (uc *ComplianceReports) GetProfile(userID int, mac string) (*entities.ComplianceProfile, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	profile, err := uc.repo.FindProfile(mac)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("perfil_no_configurado")
+	}
+	return profile, nil
+}
+
+// SaveProfile crea o reemplaza el perfil; requiere poder editar el dispositivo. Los reportes ya
+// generados conservan el perfil con el que se calcularon.
+func (uc *ComplianceReports) SaveProfile(userID int, mac string, input ComplianceProfileInput) (*entities.ComplianceProfile, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return nil, err
+	}
+	if math.IsNaN(input.TempMin) || math.IsInf(input.TempMin, 0) || math.IsNaN(input.TempMax) || math.IsInf(input.TempMax, 0) || input.TempMin >= input.TempMax {
+		return nil, fmt.Errorf("rango_temperatura_invalido")
+	}
+	if input.ToleranciaSeg < 0 || input.HuecoMaximoSeg < 0 {
+		return nil, fmt.Errorf("duracion_invalida")
+	}
+	if input.HuecoMaximoSeg == 0 {
+		input.HuecoMaximoSeg = complianceDefaultGap
+	}
+	profile := &entities.ComplianceProfile{Mac: mac, TempMin: input.TempMin, TempMax: input.TempMax, ToleranciaSeg: input.ToleranciaSeg, HuecoMaximoSeg: input.HuecoMaximoSeg}
+	if err := uc.repo.SaveProfile(profile); err != nil {
+		return nil, err
+	}
+	log.Printf("INFO: [ComplianceReports] Perfil de MAC %s configurado por UserID %d (%g a %g).", mac, userID, input.TempMin, input.TempMax)
+	return uc.repo.FindProfile(mac)
+}
+
+// DeleteProfile deja de generar reportes para el dispositivo; los ya generados se conservan
+func (uc *ComplianceReports) DeleteProfile(userID int, mac string) error {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return err
+	}
+	return uc.repo.DeleteProfile(mac)
+}
+
+// Generate crea a pedido el reporte del día o la semana que contiene fecha (cero = el periodo
+// anterior al actual); requiere poder editar el dispositivo. El periodo debe haber terminado. Cada
+// periodo tiene un solo reporte: si ya existe se devuelve ese (nuevo = false) sin recalcularlo.
+func (uc *ComplianceReports) Generate(userID int, mac string, periodo string, fecha time.Time) (report *entities.ComplianceReport, nuevo bool, err error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return nil, false, err
+	}
+	if periodo != entities.ComplianceDiario && periodo != entities.ComplianceSemanal {
+		return nil, false, fmt.Errorf("periodo_invalido")
+	}
+	now := time.Now()
+	var desde, hasta time.Time
+	if fecha.IsZero() {
+		desde, _ = compliancePeriod(periodo, now)
+		desde, hasta = previousCompliancePeriod(periodo, desde), desde
+	} else {
+		desde, hasta = compliancePeriod(periodo, fecha)
+	}
+	if hasta.After(now) {
+		return nil, false, fmt.Errorf("periodo_incompleto")
+	}
+	if existing, err := uc.repo.FindPeriodReport(mac, periodo, desde); err != nil || existing != nil {
+		return existing, false, err
+	}
+	profile, err := uc.repo.FindProfile(mac)
+	if err != nil {
+		return nil, false, err
+	}
+	if profile == nil {
+		return nil, false, fmt.Errorf("perfil_no_configurado")
+	}
+	report, err = uc.generate(*profile, periodo, desde, hasta, userID)
+	if err != nil && err.Error() == "reporte_duplicado" {
+		// Otro pedido (o la generación automática) lo guardó mientras se calculaba
+		report, err = uc.repo.FindPeriodReport(mac, periodo, desde)
+		return report, false, err
+	}
+	return report, err == nil, err
+}
+
+func (uc *ComplianceReports) generate(profile entities.ComplianceProfile, periodo string, desde time.Time, hasta time.Time, userID int) (*entities.ComplianceReport, error) {
+	builder := newComplianceBuilder(profile, periodo, desde, hasta)
+	// Se lee desde un hueco antes del periodo para saber la temperatura al empezar
+	filter := domain.DatosFilter{Mac: profile.Mac, Desde: desde.Add(-builder.gap), Hasta: hasta}
+	err := uc.db.StreamAll(filter, domain.OrdenFechaAsc, func(d entities.Datos) error {
+		if temperatura := domain.ParseNumber(d.Temperatura); temperatura != nil {
+			builder.add(d.Fecha, *temperatura)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report := builder.finish()
+	report.Generado, report.GeneradoPor = time.Now(), userID
+	if err := uc.repo.CreateReport(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// List devuelve los reportes del dispositivo que empiezan en [desde, hasta); sin rango, los de
+// los últimos 90 días
+func (uc *ComplianceReports) List(userID int, mac string, periodo string, desde time.Time, hasta time.Time, limit int) ([]entities.ComplianceSummary, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	if periodo != "" && periodo != entities.ComplianceDiario && periodo != entities.ComplianceSemanal {
+		return nil, fmt.Errorf("periodo_invalido")
+	}
+	if desde.IsZero() {
+		if hasta.IsZero() {
+			hasta = time.Now()
+		}
+		desde = hasta.AddDate(0, 0, -complianceListDays)
+	}
+	desde, hasta, limit, err := deviceEventsRange(desde, hasta, limit)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.FindReports(mac, periodo, desde, hasta, limit)
+}
+
+// Get devuelve un reporte completo de un dispositivo al que el usuario tiene acceso
+func (uc *ComplianceReports) Get(userID int, id int) (*entities.ComplianceReport, error) {
+	report, err := uc.repo.FindReport(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("reporte_no_encontrado")
+		}
+		return nil, err
+	}
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, report.Mac); err != nil {
+		if err.Error() == "dispositivo_no_encontrado" {
+			return nil, fmt.Errorf("reporte_no_encontrado")
+		}
+		return nil, err
+	}
+	return report, nil
+}
+
+// complianceBuilder recorre las temperaturas en orden. Cada lectura vale desde su fecha hasta la
+// siguiente; si entre dos lecturas (o los bordes del periodo) pasa más del hueco máximo, ese
+// intervalo es falta de datos.
+type complianceBuilder struct {
+	report  *entities.ComplianceReport
+	gap     time.Duration
+	cursor  time.Time
+	last    *complianceSample
+	open    *entities.Excursion
+	covered time.Duration
+	inRange time.Duration
+}
+
+type complianceSample struct {
+	fecha time.Time
+	valor float64
+}
+
+func newComplianceBuilder(profile entities.ComplianceProfile, periodo string, desde time.Time, hasta time.Time) *complianceBuilder {
+	report := &entities.ComplianceReport{
+		ComplianceSummary: entities.ComplianceSummary{Mac: profile.Mac, Periodo: periodo, Desde: desde, Hasta: hasta, Cumple: true},
+		Perfil:            profile, Excursiones: []entities.Excursion{}, Huecos: []entities.DataGap{},
+	}
+	return &complianceBuilder{report: report, gap: time.Duration(profile.HuecoMaximoSeg) * time.Second, cursor: desde}
+}
+
+func (b *complianceBuilder) add(fecha time.Time, valor float64) {
+	if fecha.Before(b.report.Desde) {
+		b.last = &complianceSample{fecha: fecha, valor: valor}
+		return
+	}
+	if fecha.After(b.report.Hasta) || fecha.Equal(b.report.Hasta) {
+		return
+	}
+	b.report.Lecturas++
+	if b.report.TempMinima == nil || valor < *b.report.TempMinima {
+		b.report.TempMinima = &valor
+	}
+	if b.report.TempMaxima == nil || valor > *b.report.TempMaxima {
+		b.report.TempMaxima = &valor
+	}
+	b.advance(fecha)
+	b.last = &complianceSample{fecha: fecha, valor: valor}
+}
+
+// advance atribuye el tiempo entre el cursor y 'hasta' a la última lectura (o a un hueco)
+func (b *complianceBuilder) advance(hasta time.Time) {
+	if !hasta.After(b.cursor) {
+		return
+	}
+	if b.last == nil || hasta.Sub(b.last.fecha) > b.gap {
+		b.closeExcursion(b.cursor)
+		b.addGap(b.cursor, hasta)
+		b.cursor = hasta
+		return
+	}
+	span := hasta.Sub(b.cursor)
+	b.covered += span
+	tipo := ""
+	if b.last.valor > b.report.Perfil.TempMax {
+		tipo = entities.ExcursionAlta
+	} else if b.last.valor < b.report.Perfil.TempMin {
+		tipo = entities.ExcursionBaja
+	}
+	if tipo == "" {
+		b.inRange += span
+		b.closeExcursion(b.cursor)
+	} else {
+		if b.open != nil && b.open.Tipo != tipo {
+			b.closeExcursion(b.cursor)
+		}
+		if b.open == nil {
+			b.open = &entities.Excursion{Tipo: tipo, Inicio: b.cursor, Pico: b.last.valor}
+		}
+		if (tipo == entities.ExcursionAlta && b.last.valor > b.open.Pico) || (tipo == entities.ExcursionBaja && b.last.valor < b.open.Pico) {
+			b.open.Pico = b.last.valor
+		}
+	}
+	b.cursor = hasta
+}
+
+func (b *complianceBuilder) closeExcursion(fin time.Time) {
+	if b.open == nil {
+		return
+	}
+	b.open.Fin = fin
+	b.open.DuracionSeg = int64(fin.Sub(b.open.Inicio) / time.Second)
+	b.open.Tolerada = b.open.DuracionSeg <= int64(b.report.Perfil.ToleranciaSeg)
+	if !b.open.Tolerada {
+		b.report.Cumple = false
+	}
+	b.report.Excursiones = append(b.report.Excursiones, *b.open)
+	b.open = nil
+}
+
+func (b *complianceBuilder) addGap(inicio time.Time, fin time.Time) {
+	if n := len(b.report.Huecos); n > 0 && b.report.Huecos[n-1].Fin.Equal(inicio) {
+		inicio = b.report.Huecos[n-1].Inicio
+		b.report.Huecos = b.report.Huecos[:n-1]
+	}
+	b.report.Huecos = append(b.report.Huecos, entities.DataGap{Inicio: inicio, Fin: fin, DuracionSeg: int64(fin.Sub(inicio) / time.Second)})
+}
+
+func (b *complianceBuilder) finish() *entities.ComplianceReport {
+	b.advance(b.report.Hasta)
+	b.closeExcursion(b.report.Hasta)
+	report := b.report
+	report.SegundosCubiertos = int64(b.covered / time.Second)
+	report.SegundosEnRango = int64(b.inRange / time.Second)
+	report.SegundosFueraRango = int64((b.covered - b.inRange) / time.Second)
+	report.PorcentajeCobertura = float64(b.covered) / float64(report.Hasta.Sub(report.Desde)) * 100
+	if b.covered > 0 {
+		enRango := float64(b.inRange) / float64(b.covered) * 100
+		report.PorcentajeEnRango = &enRango
+	}
+	return report
+}
+
+// complianceTime es el formato de fechas de los reportes descargables (hora local del servidor)
+func complianceTime(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02 15:04:05")
+}
+
+func compliancePercent(p *float64) string {
+	if p == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*p, 'f', 2, 64)
+}
+
+// WriteComplianceCSV escribe el resumen (campo, valor), una línea en blanco y las excursiones y
+// huecos en orden cronológico
+func WriteComplianceCSV(report *entities.ComplianceReport, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	cobertura := report.PorcentajeCobertura
+	rows := [][]string{
+		{"campo", "valor"},
+		{"reporte", strconv.Itoa(report.ID)},
+		{"mac", csvSafe(report.Mac)},
+		{"periodo", report.Periodo},
+		{"desde", complianceTime(report.Desde)},
+		{"hasta", complianceTime(report.Hasta)},
+		{"temp_min", strconv.FormatFloat(report.Perfil.TempMin, 'f', -1, 64)},
+		{"temp_max", strconv.FormatFloat(report.Perfil.TempMax, 'f', -1, 64)},
+		{"tolerancia_seg", strconv.Itoa(report.Perfil.ToleranciaSeg)},
+		{"hueco_maximo_seg", strconv.Itoa(report.Perfil.HuecoMaximoSeg)},
+		{"lecturas", strconv.Itoa(report.Lecturas)},
+		{"porcentaje_en_rango", compliancePercent(report.PorcentajeEnRango)},
+		{"porcentaje_cobertura", compliancePercent(&cobertura)},
+		{"segundos_fuera_rango", strconv.FormatInt(report.SegundosFueraRango, 10)},
+		{"cumple", strconv.FormatBool(report.Cumple)},
+		{"generado", complianceTime(report.Generado)},
+		{},
+		{"evento", "tipo", "inicio", "fin", "duracion_seg", "pico", "tolerada"},
+	}
+	events := make([][]string, 0, len(report.Excursiones)+len(report.Huecos))
+	e, g := 0, 0
+	for e < len(report.Excursiones) || g < len(report.Huecos) {
+		if g == len(report.Huecos) || (e < len(report.Excursiones) && report.Excursiones[e].Inicio.Before(report.Huecos[g].Inicio)) {
+			x := report.Excursiones[e]
+			events = append(events, []string{"excursion", x.Tipo, complianceTime(x.Inicio), complianceTime(x.Fin), strconv.FormatInt(x.DuracionSeg, 10),
+				strconv.FormatFloat(x.Pico, 'f', -1, 64), strconv.FormatBool(x.Tolerada)})
+			e++
+		} else {
+			h := report.Huecos[g]
+			events = append(events, []string{"hueco", "", complianceTime(h.Inicio), complianceTime(h.Fin), strconv.FormatInt(h.DuracionSeg, 10), "", ""})
+			g++
+		}
+	}
+	if err := writer.WriteAll(append(rows, events...)); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// complianceDuration muestra una duración en segundos como 1h2m3s
+func complianceDuration(seg int64) string {
+	return (time.Duration(seg) * time.Second).String()
+}
+
+var complianceHTML = template.Must(template.New("reporte").Funcs(template.FuncMap{
+	"fecha":      complianceTime,
+	"porcentaje": compliancePercent,
+	"duracion":   complianceDuration,
+}).Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>Reporte de cumplimiento {{.ID}} - {{.Mac}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.cumple { color: #1a7f37; }
+.incumple { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Reporte de cumplimiento de temperatura</h1>
+<p>Dispositivo <strong>{{.Mac}}</strong> &middot; periodo {{.Periodo}} del {{fecha .Desde}} al {{fecha .Hasta}} (reporte {{.ID}}, generado el {{fecha .Generado}})</p>
+<p class="{{if .Cumple}}cumple{{else}}incumple{{end}}"><strong>{{if .Cumple}}Cumple{{else}}No cumple{{end}}</strong></p>
+<table>
+<tr><th>Rango permitido</th><td>{{.Perfil.TempMin}} a {{.Perfil.TempMax}}</td></tr>
+<tr><th>Excursión tolerada</th><td>{{duracion .Tolerancia}}</td></tr>
+<tr><th>Hueco máximo entre lecturas</th><td>{{duracion .HuecoMaximo}}</td></tr>
+<tr><th>Lecturas</th><td>{{.Lecturas}}</td></tr>
+<tr><th>Tiempo en rango</th><td>{{with porcentaje .PorcentajeEnRango}}{{.}} %{{else}}sin lecturas{{end}}</td></tr>
+<tr><th>Cobertura de datos</th><td>{{porcentaje .Cobertura}} %</td></tr>
+<tr><th>Tiempo fuera de rango</th><td>{{duracion .SegundosFueraRango}}</td></tr>
+{{with .TempMinima}}<tr><th>Temperatura mínima</th><td>{{.}}</td></tr>{{end}}
+{{with .TempMaxima}}<tr><th>Temperatura máxima</th><td>{{.}}</td></tr>{{end}}
+</table>
+<h2>Excursiones ({{len .Excursiones}})</h2>
+{{if .Excursiones}}<table>
+<tr><th>Tipo</th><th>Inicio</th><th>Fin</th><th>Duración</th><th>Pico</th><th>Tolerada</th></tr>
+{{range .Excursiones}}<tr><td>{{.Tipo}}</td><td>{{fecha .Inicio}}</td><td>{{fecha .Fin}}</td><td>{{duracion .DuracionSeg}}</td><td>{{.Pico}}</td><td>{{if .Tolerada}}sí{{else}}no{{end}}</td></tr>
+{{end}}</table>{{else}}<p>Sin excursiones.</p>{{end}}
+<h2>Falta de datos ({{len .Huecos}})</h2>
+{{if .Huecos}}<table>
+<tr><th>Inicio</th><th>Fin</th><th>Duración</th></tr>
+{{range .Huecos}}<tr><td>{{fecha .Inicio}}</td><td>{{fecha .Fin}}</td><td>{{duracion .DuracionSeg}}</td></tr>
+{{end}}</table>{{else}}<p>Sin huecos.</p>{{end}}
+</body>
+</html>
+`))
+
+// WriteComplianceHTML escribe el reporte como página HTML autocontenida
+func WriteComplianceHTML(report *entities.ComplianceReport, w io.Writer) error {
+	return complianceHTML.Execute(w, struct {
+		*entities.ComplianceReport
+		Tolerancia  int64
+		HuecoMaximo int64
+		Cobertura   *float64
+	}{report, int64(report.Perfil.ToleranciaSeg), int64(report.Perfil.HuecoMaximoSeg), &report.PorcentajeCobertura})
+}
@@ -9,23 +9,38 @@ import (
 	"database/sql"                    // Para sql.ErrNoRows
 	"fmt"
 	"log"
+	"time"
 )
 
+// ReadingProcessor deriva información de cada lectura recién guardada (p. ej. intervalos de
+// movimiento). Se ejecuta después de guardar y notificar; sus errores no afectan la ingesta.
+type ReadingProcessor interface {
+	Process(data entities.Datos)
+}
+
 type CreateDatos struct {
 	datosRepo sensorDomain.DatosRepository // Puerto hacia persistencia de sensores
 	userRepo  userDomain.UserRepository   // NUEVO: Puerto hacia persistencia de usuarios
+	deviceRepo sensorDomain.DeviceRepository // Metadatos del dispositivo para la notificación
+	orgRepo   sensorDomain.OrganizationRepository // Organización dueña de la lectura
 	notifier  sensorDomain.DatosNotifier  // Puerto hacia la notificación
+	containerRepo sensorDomain.ContainerRepository // Geometría para informar el llenado en la notificación
+	processors []ReadingProcessor         // Derivados de cada lectura (intervalos de movimiento, ...)
 }
 
-// Ahora recibe UserRepository también
-func NewCreateDatos(datosRepo sensorDomain.DatosRepository, userRepo userDomain.UserRepository, notifier sensorDomain.DatosNotifier) *CreateDatos {
-	if datosRepo == nil || notifier == nil || userRepo == nil {
-		log.Fatal("Error: CreateDatos recibió dependencias nulas (datosRepo, userRepo o notifier).")
+// Ahora recibe UserRepository, DeviceRepository y OrganizationRepository también
+func NewCreateDatos(datosRepo sensorDomain.DatosRepository, userRepo userDomain.UserRepository, deviceRepo sensorDomain.DeviceRepository, orgRepo sensorDomain.OrganizationRepository, notifier sensorDomain.DatosNotifier, containerRepo sensorDomain.ContainerRepository, processors ...ReadingProcessor) *CreateDatos {
+	if datosRepo == nil || notifier == nil || userRepo == nil || deviceRepo == nil || orgRepo == nil || containerRepo == nil {
+		log.Fatal("Error: CreateDatos recibió dependencias nulas (datosRepo, userRepo, deviceRepo, orgRepo, notifier o containerRepo).")
 	}
 	return &CreateDatos{
 		datosRepo: datosRepo,
 		userRepo:  userRepo,
+		deviceRepo: deviceRepo,
+		orgRepo:   orgRepo,
 		notifier:  notifier,
+		containerRepo: containerRepo,
+		processors: processors,
 	}
 }
 
@@ -40,7 +55,7 @@ func (cr *CreateDatos) Execute(temperatura string, movimiento string, distancia
 	}
 
 	// 2. Buscar el UserID asociado a la MAC
-	userID, err := cr.userRepo.FindUserIDByMAC(mac)
+	userID, err := cr.userRepo.FindAssignedUserID(mac)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// MAC no asignada a ningún usuario
@@ -56,8 +71,26 @@ func (cr *CreateDatos) Execute(temperatura string, movimiento string, distancia
 		return fmt.Errorf("error interno al buscar usuario: %w", err) // Error genérico
 	}
 
-	// 3. Guardar en la base de datos usando el repositorio, AHORA con UserID
-	err = cr.datosRepo.Save(userID, temperatura, movimiento, distancia, peso, mac)
+	// 3. La lectura pertenece a la organización del dispositivo (o a la personal del usuario).
+	// Solo se atribuye al usuario asignado (y se publica en su tema) si es miembro de esa organización.
+	orgID, err := cr.orgRepo.DeviceOrg(mac)
+	if err == nil && orgID == 0 {
+		orgID, err = cr.orgRepo.PersonalOrgID(userID)
+	} else if err == nil {
+		if _, errMember := cr.userRepo.FindUserIDByMAC(orgID, mac); errMember == sql.ErrNoRows {
+			log.Printf("ADVERTENCIA: [CreateDatos] UserID %d tiene asignada la MAC '%s' pero no es miembro de la organización %d; la lectura queda sin usuario.", userID, mac, orgID)
+			userID = 0
+		} else if errMember != nil {
+			err = errMember
+		}
+	}
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("ERROR: [CreateDatos] Falló la búsqueda de organización para MAC '%s': %v", mac, err)
+		return fmt.Errorf("error interno al buscar organización: %w", err)
+	}
+
+	// 4. Guardar en la base de datos usando el repositorio, con UserID y organización
+	datoID, err := cr.datosRepo.Save(userID, orgID, temperatura, movimiento, distancia, peso, mac)
 	if err != nil {
 		log.Printf("ERROR: [CreateDatos] Falló al guardar datos para UserID %d (MAC %s): %v", userID, mac, err)
 		return err // Retornar el error de guardado
@@ -65,15 +98,28 @@ func (cr *CreateDatos) Execute(temperatura string, movimiento string, distancia
 
 	log.Printf("INFO: [CreateDatos] Datos guardados exitosamente para UserID %d (MAC %s).", userID, mac)
 
-	// 4. Notificar (si usas WebSockets dirigidos, necesitarás el userID)
+	// 5. Notificar (si usas WebSockets dirigidos, necesitarás el userID)
 	newData := entities.Datos{
-		// ID se genera en la BD, no lo tenemos aquí a menos que Save lo devuelva
+		ID:          int32(datoID), // Generado por la BD al insertar
 		Temperatura: temperatura,
 		Movimiento:  movimiento,
 		Distancia:   distancia,
 		Peso:        peso,
 		Mac:         mac,
-		// UserID:     int32(userID), // Añade UserID a tu entidad si lo necesitas en el frontend
+		Fecha:       time.Now(), // Aproximada: la BD fija created_at al insertar
+		UserID:      userID, // Permite dirigir la notificación a los suscriptores del dueño
+		OrgID:       orgID,  // ... y a los miembros de su organización
+	}
+	if device, errDevice := cr.deviceRepo.FindByMac(mac); errDevice != nil {
+		log.Printf("ADVERTENCIA: [CreateDatos] No se pudieron cargar metadatos de MAC %s para la notificación: %v", mac, errDevice)
+	} else {
+		newData.Dispositivo = device
+	}
+	if config, errFill := cr.containerRepo.FindByMac(mac); errFill != nil {
+		log.Printf("ADVERTENCIA: [CreateDatos] No se pudo leer el contenedor de MAC %s para la notificación: %v", mac, errFill)
+	} else if medida := sensorDomain.ParseNumber(distancia); config != nil && medida != nil {
+		fill := config.Fill(*medida)
+		newData.Llenado = &fill
 	}
 
 
@@ -87,5 +133,9 @@ func (cr *CreateDatos) Execute(temperatura string, movimiento string, distancia
 		log.Printf("INFO: [CreateDatos] Notificación de nuevos datos iniciada exitosamente para UserID %d.", userID)
 	}
 
+	for _, processor := range cr.processors {
+		processor.Process(newData)
+	}
+
 	return nil
 }
\ No newline at end of file
@@ -26,15 +26,19 @@ type CreateUserInput struct {
 
 // CreateUserUseCase maneja la lógica de crear un usuario
 type CreateUserUseCase struct {
-	userRepo userDomain.UserRepository // Dependencia del repositorio
+	userRepo      userDomain.UserRepository         // Dependencia del repositorio
+	ownershipRepo userDomain.OwnershipRepository    // Abre el primer periodo si se registra con MAC
+	orgRepo       userDomain.OrganizationRepository // Crea la organización personal del usuario
+	shareRepo     userDomain.ShareRepository        // Revoca los accesos de la organización anterior de la MAC
+	revoker       userDomain.SubscriptionRevoker    // Corta el feed en vivo de quienes pierden la MAC
 }
 
 // NewCreateUserUseCase crea una instancia del caso de uso
-func NewCreateUserUseCase(userRepo userDomain.UserRepository) *CreateUserUseCase {
-	if userRepo == nil {
-		log.Fatal("CRITICO: CreateUserUseCase recibió un userRepo nulo.")
+func NewCreateUserUseCase(userRepo userDomain.UserRepository, ownershipRepo userDomain.OwnershipRepository, orgRepo userDomain.OrganizationRepository, shareRepo userDomain.ShareRepository, revoker userDomain.SubscriptionRevoker) *CreateUserUseCase {
+	if userRepo == nil || ownershipRepo == nil || orgRepo == nil || shareRepo == nil || revoker == nil {
+		log.Fatal("CRITICO: CreateUserUseCase recibió dependencias nulas (userRepo, ownershipRepo, orgRepo, shareRepo o revoker).")
 	}
-	return &CreateUserUseCase{userRepo: userRepo}
+	return &CreateUserUseCase{userRepo: userRepo, ownershipRepo: ownershipRepo, orgRepo: orgRepo, shareRepo: shareRepo, revoker: revoker}
 }
 
 // Execute procesa la creación del usuario
@@ -48,7 +52,12 @@ func (uc *CreateUserUseCase) Execute(input CreateUserInput) error { // Devuelve
 		return fmt.Errorf("el nombre de usuario es requerido")
 	}
 	if input.Role == "" {
-		input.Role = "user" // Rol por defecto si no se especifica
+		input.Role = userDomain.RoleUser // Rol por defecto si no se especifica
+	}
+	if input.Role != userDomain.RoleUser {
+		// El rol de operador de la plataforma no se puede obtener registrándose
+		log.Printf("WARN: [CreateUser] Intento de registro de '%s' con rol '%s'", input.Username, input.Role)
+		return fmt.Errorf("rol_no_permitido")
 	}
 
 	// 2. Hashear la contraseña
@@ -81,6 +90,26 @@ func (uc *CreateUserUseCase) Execute(input CreateUserInput) error { // Devuelve
 		return fmt.Errorf("error interno al guardar el usuario") // Error genérico 500
 	}
 
+	// 5. Crear su organización personal y registrar el inicio del periodo de propiedad de la
+	// MAC, que la asigna a esa organización (no bloquean el registro)
+	created, errFind := uc.userRepo.FindByUsername(input.Username)
+	if errFind == nil {
+		_, errFind = uc.orgRepo.CreatePersonal(created.ID, input.Username)
+	}
+	if errFind != nil {
+		log.Printf("ADVERTENCIA: [CreateUser] No se pudo crear la organización personal de %s: %v", input.Username, errFind)
+	} else if newUser.MacAddress.Valid {
+		formerOrg, errOwnership := leavingOrg(uc.orgRepo, uc.shareRepo, uc.revoker, input.MacAddress, created.ID)
+		if errOwnership == nil {
+			errOwnership = uc.ownershipRepo.OpenRegistration(created.ID, input.MacAddress)
+		}
+		if errOwnership != nil {
+			log.Printf("ADVERTENCIA: [CreateUser] No se pudo registrar el historial de la MAC '%s' para %s: %v", input.MacAddress, input.Username, errOwnership)
+		} else {
+			revokeFormerAccess(uc.orgRepo, uc.userRepo, uc.shareRepo, uc.revoker, input.MacAddress, formerOrg)
+		}
+	}
+
 	log.Printf("INFO: [CreateUser] Usuario '%s' registrado exitosamente.", input.Username)
 	return nil // Éxito
 }
\ No newline at end of file
@@ -0,0 +1,288 @@
+// File: dataGaps_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	gapsDefaultInterval = 60  // Segundos entre lecturas si no hay configuración ni lecturas para estimarlo
+	gapsDefaultFactor   = 2.5 // Una separación de más de 2.5 intervalos cuenta como hueco
+	gapsMinSamples      = 5   // Separaciones entre lecturas necesarias para estimar el intervalo
+	gapsDefaultDays     = 7   // Sin ?from, los últimos 7 días (contando el actual)
+	gapsMaxRange        = 93 * 24 * time.Hour
+)
+
+// gapDetector decide el intervalo de reporte esperado de un dispositivo y los huecos entre sus lecturas
+type gapDetector struct {
+	factor     float64
+	porDefecto time.Duration
+}
+
+func newGapDetector() gapDetector {
+	factor := floatFromEnv("GAP_FACTOR", gapsDefaultFactor)
+	if factor < 1 {
+		log.Printf("ADVERTENCIA: GAP_FACTOR debe ser al menos 1; se usa %g.", float64(gapsDefaultFactor))
+		factor = gapsDefaultFactor
+	}
+	porDefecto := secondsFromEnv("GAP_INTERVALO_SEG", gapsDefaultInterval)
+	if porDefecto <= 0 {
+		porDefecto = gapsDefaultInterval * time.Second
+	}
+	return gapDetector{factor: factor, porDefecto: porDefecto}
+}
+
+// interval devuelve el intervalo configurado en el dispositivo o, si no lo tiene, la mediana de las
+// separaciones entre sus lecturas (en orden cronológico); la mediana no se altera por los huecos
+func (g gapDetector) interval(device *entities.Device, fechas []time.Time) (time.Duration, string) {
+	if device != nil && device.IntervaloSeg != nil {
+		return time.Duration(*device.IntervaloSeg) * time.Second, entities.IntervaloConfigurado
+	}
+	deltas := make([]time.Duration, 0, len(fechas))
+	for i := 1; i < len(fechas); i++ {
+		if d := fechas[i].Sub(fechas[i-1]); d > 0 {
+			deltas = append(deltas, d)
+		}
+	}
+	if len(deltas) < gapsMinSamples {
+		return g.porDefecto, entities.IntervaloPorDefecto
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i] < deltas[j] })
+	median := deltas[len(deltas)/2].Round(time.Second)
+	if median < time.Second {
+		median = time.Second
+	}
+	return median, entities.IntervaloEstimado
+}
+
+// threshold es la separación entre lecturas a partir de la cual se cuenta un hueco
+func (g gapDetector) threshold(interval time.Duration) time.Duration {
+	return time.Duration(float64(interval) * g.factor)
+}
+
+// find devuelve los huecos de [desde, hasta) dadas las lecturas del rango en orden cronológico. Los
+// extremos del rango hacen de lecturas: los huecos del principio y del final se miden desde ellos.
+func (g gapDetector) find(fechas []time.Time, desde time.Time, hasta time.Time, umbral time.Duration) []entities.DataGap {
+	gaps := []entities.DataGap{}
+	prev := desde
+	for i := 0; i <= len(fechas); i++ {
+		next := hasta
+		if i < len(fechas) {
+			next = fechas[i]
+		}
+		if next.Sub(prev) > umbral {
+			gaps = append(gaps, entities.DataGap{Inicio: prev, Fin: next, DuracionSeg: int64(next.Sub(prev) / time.Second)})
+		}
+		prev = next
+	}
+	return gaps
+}
+
+// gapSpan es un tramo en que el dispositivo reportó: una lectura suelta (inicio == fin) o una hora
+// de los resúmenes con lecturas, sin saber en qué minutos
+type gapSpan struct {
+	inicio time.Time
+	fin    time.Time
+}
+
+// findSpans es como find pero sobre tramos ordenados por inicio: solo cuenta como hueco el tiempo
+// entre tramos, así que con horas de los resúmenes los huecos se miden con precisión de una hora
+func (g gapDetector) findSpans(spans []gapSpan, desde time.Time, hasta time.Time, umbral time.Duration) []entities.DataGap {
+	gaps := []entities.DataGap{}
+	prev := desde
+	for i := 0; i <= len(spans); i++ {
+		next := gapSpan{inicio: hasta, fin: hasta}
+		if i < len(spans) {
+			next = spans[i]
+		}
+		if next.inicio.Sub(prev) > umbral {
+			gaps = append(gaps, entities.DataGap{Inicio: prev, Fin: next.inicio, DuracionSeg: int64(next.inicio.Sub(prev) / time.Second)})
+		}
+		if next.fin.After(prev) {
+			prev = next.fin
+		}
+	}
+	return gaps
+}
+
+// hourlyInterval estima el intervalo de reporte a partir de las horas con lecturas (en orden) y de
+// cuántas tuvo cada una: con varias lecturas por hora, la hora entre la mediana; con una sola, la
+// mediana de la separación entre esas horas
+func (g gapDetector) hourlyInterval(device *entities.Device, horas []time.Time, lecturas []int64) time.Duration {
+	if device != nil && device.IntervaloSeg != nil {
+		return time.Duration(*device.IntervaloSeg) * time.Second
+	}
+	if len(lecturas) >= gapsMinSamples {
+		sorted := append([]int64(nil), lecturas...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		if median := sorted[len(sorted)/2]; median >= 2 {
+			return (time.Hour / time.Duration(median)).Round(time.Second)
+		}
+	}
+	interval, _ := g.interval(nil, horas)
+	return interval
+}
+
+// streamTimelines recorre las lecturas visibles del filtro y entrega, dispositivo por dispositivo, las
+// fechas de sus lecturas en orden cronológico. fn no debe conservar el slice: se reutiliza.
+func streamTimelines(db domain.DatosRepository, userID int, filter domain.DatosFilter, fn func(mac string, fechas []time.Time)) error {
+	mac := ""
+	fechas := []time.Time{}
+	err := db.StreamByUserID(userID, filter, domain.OrdenDispositivoFecha, func(d entities.Datos) error {
+		if d.Mac != mac {
+			if len(fechas) > 0 {
+				fn(mac, fechas)
+			}
+			mac, fechas = d.Mac, fechas[:0]
+		}
+		fechas = append(fechas, d.Fecha)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(fechas) > 0 {
+		fn(mac, fechas)
+	}
+	return nil
+}
+
+// DataGaps detecta los periodos en que un dispositivo dejó de reportar, según su intervalo de
+// reporte esperado, y calcula qué fracción de las lecturas esperadas llegó cada día.
+type DataGaps struct {
+	db         domain.DatosRepository
+	deviceRepo domain.DeviceRepository
+	userRepo   domain.UserRepository
+	orgRepo    domain.OrganizationRepository
+	shareRepo  domain.ShareRepository
+	detector   gapDetector
+}
+
+func NewDataGaps(db domain.DatosRepository, deviceRepo domain.DeviceRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *DataGaps {
+	if db == nil || deviceRepo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: DataGaps recibió dependencias nulas (db, deviceRepo, userRepo, orgRepo o shareRepo).")
+	}
+	return &DataGaps{db: db, deviceRepo: deviceRepo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo, detector: newGapDetector()}
+}
+
+// Execute devuelve los huecos y la completitud diaria (días de la zona pedida; "" = la del servidor)
+// de las lecturas visibles de un dispositivo. El rango se recorta al momento actual.
+func (uc *DataGaps) Execute(userID int, mac string, desde time.Time, hasta time.Time, zonaNombre string) (*entities.DeviceGaps, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	zona := time.Local
+	if zonaNombre != "" {
+		loaded, err := time.LoadLocation(zonaNombre)
+		if err != nil {
+			return nil, fmt.Errorf("zona_invalida")
+		}
+		zona = loaded
+	}
+	now := time.Now()
+	if hasta.IsZero() || hasta.After(now) {
+		hasta = now
+	}
+	if desde.IsZero() {
+		desde = dayStartIn(hasta, zona).AddDate(0, 0, -(gapsDefaultDays - 1))
+	}
+	if !desde.Before(hasta) {
+		return nil, fmt.Errorf("rango_invalido")
+	}
+	if hasta.Sub(desde) > gapsMaxRange {
+		return nil, fmt.Errorf("rango_demasiado_largo")
+	}
+
+	device, err := uc.deviceRepo.FindByMac(mac)
+	if err != nil {
+		return nil, err
+	}
+	fechas := []time.Time{}
+	filter := domain.DatosFilter{Mac: mac, Desde: desde, Hasta: hasta}
+	err = uc.db.StreamByUserID(userID, filter, domain.OrdenFechaAsc, func(d entities.Datos) error {
+		fechas = append(fechas, d.Fecha)
+		return nil
+	})
+	if err != nil {
+		log.Printf("ERROR: [DataGaps] Falló la lectura de MAC %s para UserID %d: %v", mac, userID, err)
+		return nil, err
+	}
+
+	interval, origen := uc.detector.interval(device, fechas)
+	umbral := uc.detector.threshold(interval)
+	result := &entities.DeviceGaps{
+		Mac: mac, Desde: desde.In(zona), Hasta: hasta.In(zona), Zona: zona.String(),
+		IntervaloSeg: int64(interval / time.Second), OrigenIntervalo: origen, UmbralSeg: int64(umbral / time.Second),
+		Lecturas: int64(len(fechas)), Huecos: []entities.DataGap{}, Dias: []entities.DayCompleteness{},
+	}
+	for _, gap := range uc.detector.find(fechas, desde, hasta, umbral) {
+		gap.Inicio, gap.Fin = gap.Inicio.In(zona), gap.Fin.In(zona)
+		result.Huecos = append(result.Huecos, gap)
+	}
+
+	next := 0
+	for inicio := desde; inicio.Before(hasta); {
+		dia := dayStartIn(inicio, zona)
+		fin := dia.AddDate(0, 0, 1)
+		if fin.After(hasta) {
+			fin = hasta
+		}
+		day := entities.DayCompleteness{Dia: dia, Esperadas: expectedReadings(fin.Sub(inicio), interval), SinDatosSeg: gapSeconds(result.Huecos, inicio, fin)}
+		for next < len(fechas) && fechas[next].Before(fin) {
+			day.Lecturas++
+			next++
+		}
+		day.Porcentaje = completeness(day.Lecturas, day.Esperadas)
+		result.Dias = append(result.Dias, day)
+		result.Esperadas += day.Esperadas
+		result.SinDatosSeg += day.SinDatosSeg
+		inicio = fin
+	}
+	result.Porcentaje = completeness(result.Lecturas, result.Esperadas)
+	return result, nil
+}
+
+// dayStartIn es la medianoche en la zona del día que contiene t
+func dayStartIn(t time.Time, zona *time.Location) time.Time {
+	t = t.In(zona)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, zona)
+}
+
+// expectedReadings son las lecturas que caben en un tramo al intervalo de reporte
+func expectedReadings(span time.Duration, interval time.Duration) int64 {
+	return int64(math.Round(float64(span) / float64(interval)))
+}
+
+// completeness es el porcentaje de lecturas esperadas que llegaron; un dispositivo que reporta más
+// seguido de lo esperado no pasa del 100
+func completeness(lecturas int64, esperadas int64) float64 {
+	if esperadas == 0 {
+		return 100
+	}
+	return math.Min(100, float64(lecturas)/float64(esperadas)*100)
+}
+
+// gapSeconds suma la parte de los huecos que cae en [inicio, fin)
+func gapSeconds(gaps []entities.DataGap, inicio time.Time, fin time.Time) int64 {
+	var total time.Duration
+	for _, gap := range gaps {
+		desde, hasta := gap.Inicio, gap.Fin
+		if desde.Before(inicio) {
+			desde = inicio
+		}
+		if hasta.After(fin) {
+			hasta = fin
+		}
+		if desde.Before(hasta) {
+			total += hasta.Sub(desde)
+		}
+	}
+	return int64(total / time.Second)
+}
@@ -22,8 +22,9 @@ func NewDeleteDatos(db domain.DatosRepository /*, notifier DatosNotifier */) *De
 }
 
 // Execute ahora devuelve error para indicar si la eliminación falló.
-func (dp *DeleteDatos) Execute(id int) error {
-	err := dp.db.Delete(id, 0)
+// Solo borra lecturas que el usuario puede editar en su organización.
+func (dp *DeleteDatos) Execute(id int, userID int) error {
+	err := dp.db.Delete(id, userID)
 	if err != nil {
 		log.Printf("ERROR: [DeleteDatos] Falló al eliminar datos con ID %d: %v", id, err)
 		return err // Devuelve el error
@@ -0,0 +1,233 @@
+// File: derivedMetrics_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxMetricasDerivadas  = 20             // Métricas derivadas por dispositivo
+	derivedPreviousMaxAge = 24 * time.Hour // delta y rate solo comparan con lecturas de las últimas 24 h
+)
+
+// DerivedMetricInput DTO con la definición pedida por el cliente
+type DerivedMetricInput struct {
+	Nombre    string
+	Expresion string
+	Unidad    string
+}
+
+// DerivedMetrics administra las métricas derivadas de cada dispositivo y las evalúa con cada
+// lectura recién guardada. La lectura anterior (para delta y rate) se recuerda en memoria y, tras
+// un reinicio, se busca en la base.
+type DerivedMetrics struct {
+	repo      domain.DerivedMetricRepository
+	db        domain.DatosRepository
+	userRepo  domain.UserRepository
+	orgRepo   domain.OrganizationRepository
+	shareRepo domain.ShareRepository
+	mu        *sync.Mutex
+	previas   map[string]domain.DerivedReading // Última lectura procesada de cada MAC
+}
+
+func NewDerivedMetrics(repo domain.DerivedMetricRepository, db domain.DatosRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *DerivedMetrics {
+	if repo == nil || db == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: DerivedMetrics recibió dependencias nulas (repo, db, userRepo, orgRepo o shareRepo).")
+	}
+	return &DerivedMetrics{repo: repo, db: db, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo,
+		mu: &sync.Mutex{}, previas: make(map[string]domain.DerivedReading)}
+}
+
+// Process calcula y guarda las métricas derivadas del dispositivo para una lectura recién guardada
+func (uc *DerivedMetrics) Process(data entities.Datos) {
+	if data.ID == 0 {
+		return
+	}
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	actual := domain.NewDerivedReading(data.Fecha, data.Temperatura, data.Movimiento, data.Distancia, data.Peso)
+	previa, conocida := uc.previas[data.Mac]
+	uc.previas[data.Mac] = actual
+
+	metrics, err := uc.repo.FindByMac(data.Mac)
+	if err != nil {
+		log.Printf("ERROR: [DerivedMetrics] %v", err)
+		return
+	}
+	exprs := make([]*domain.DerivedExpr, 0, len(metrics))
+	temporal := false
+	for _, metric := range metrics {
+		expr, err := domain.ParseDerivedExpr(metric.Expresion)
+		if err != nil {
+			log.Printf("ERROR: [DerivedMetrics] Expresión inválida en la métrica %d de MAC %s: %v", metric.ID, data.Mac, err)
+		}
+		exprs = append(exprs, expr)
+		temporal = temporal || (expr != nil && expr.Temporal)
+	}
+	if temporal && !conocida {
+		found, err := uc.previousReading(data)
+		if err != nil {
+			log.Printf("ADVERTENCIA: [DerivedMetrics] No se pudo leer la lectura anterior de MAC %s: %v", data.Mac, err)
+		}
+		if found != nil {
+			previa, conocida = *found, true
+		}
+	}
+	var anterior *domain.DerivedReading
+	if conocida && previa.Fecha.Before(actual.Fecha) && actual.Fecha.Sub(previa.Fecha) <= derivedPreviousMaxAge {
+		anterior = &previa
+	}
+
+	values := []entities.DerivedValue{}
+	for i, metric := range metrics {
+		if exprs[i] == nil {
+			continue
+		}
+		if v := exprs[i].Eval(actual, anterior); v != nil {
+			values = append(values, entities.DerivedValue{MetricID: metric.ID, DatoID: int64(data.ID), Mac: data.Mac, Fecha: data.Fecha, Valor: *v})
+		}
+	}
+	if err := uc.repo.SaveValues(values); err != nil {
+		log.Printf("ERROR: [DerivedMetrics] %v", err)
+	}
+}
+
+// previousReading busca en la base la lectura del dispositivo anterior a data (nil si no hay una
+// reciente). Fecha es la del servidor al guardar, así que se buscan lecturas de segundos anteriores.
+func (uc *DerivedMetrics) previousReading(data entities.Datos) (*domain.DerivedReading, error) {
+	filter := domain.DatosFilter{Mac: data.Mac, Desde: data.Fecha.Add(-derivedPreviousMaxAge), Hasta: data.Fecha.Truncate(time.Second)}
+	latest, err := uc.db.GetLatestByUserID(data.UserID, filter)
+	if err != nil || len(latest) == 0 || latest[0].ID >= data.ID {
+		return nil, err
+	}
+	reading := domain.NewDerivedReading(latest[0].Fecha, latest[0].Temperatura, latest[0].Movimiento, latest[0].Distancia, latest[0].Peso)
+	return &reading, nil
+}
+
+// List devuelve las métricas derivadas de un dispositivo al que el usuario tiene acceso
+func (uc *DerivedMetrics) List(userID int, mac string) ([]entities.DerivedMetric, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	return uc.repo.FindByMac(mac)
+}
+
+// Create define una métrica derivada; requiere poder editar el dispositivo. Solo las lecturas
+// recibidas desde ahora tendrán valor.
+func (uc *DerivedMetrics) Create(userID int, mac string, input DerivedMetricInput) (*entities.DerivedMetric, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return nil, err
+	}
+	metric := &entities.DerivedMetric{Mac: mac}
+	if err := validateDerivedMetric(metric, input); err != nil {
+		return nil, err
+	}
+	existing, err := uc.repo.FindByMac(mac)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxMetricasDerivadas {
+		return nil, fmt.Errorf("demasiadas_metricas")
+	}
+	if err := uc.repo.Create(metric); err != nil {
+		if err.Error() != "nombre_duplicado" {
+			log.Printf("ERROR: [DerivedMetrics] Falló al crear la métrica '%s' de MAC %s: %v", metric.Nombre, mac, err)
+		}
+		return nil, err
+	}
+	metric.Creado, metric.Actualizado = time.Now(), time.Now()
+	log.Printf("INFO: [DerivedMetrics] Métrica '%s' (%d) definida en MAC %s por UserID %d.", metric.Nombre, metric.ID, mac, userID)
+	return metric, nil
+}
+
+// Update reemplaza la definición. Si cambia la expresión, los valores ya calculados se borran: no
+// corresponderían a la nueva.
+func (uc *DerivedMetrics) Update(userID int, mac string, id int, input DerivedMetricInput) (*entities.DerivedMetric, error) {
+	metric, err := uc.find(userID, mac, id)
+	if err != nil {
+		return nil, err
+	}
+	anterior := metric.Expresion
+	if err := validateDerivedMetric(metric, input); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.Update(metric, metric.Expresion != anterior); err != nil {
+		if err.Error() != "nombre_duplicado" {
+			log.Printf("ERROR: [DerivedMetrics] Falló al actualizar la métrica %d de MAC %s: %v", id, mac, err)
+		}
+		return nil, err
+	}
+	metric.Actualizado = time.Now()
+	return metric, nil
+}
+
+// Delete borra la métrica y sus valores
+func (uc *DerivedMetrics) Delete(userID int, mac string, id int) error {
+	if _, err := uc.find(userID, mac, id); err != nil {
+		return err
+	}
+	return uc.repo.Delete(id)
+}
+
+// find devuelve la métrica del dispositivo si el usuario puede editarlo
+func (uc *DerivedMetrics) find(userID int, mac string, id int) (*entities.DerivedMetric, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return nil, err
+	}
+	metric, err := uc.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if metric == nil || metric.Mac != mac {
+		return nil, fmt.Errorf("metrica_no_encontrada")
+	}
+	return metric, nil
+}
+
+// validateDerivedMetric normaliza y valida la definición; los errores de la expresión son
+// *domain.MetricFilterError con la posición
+func validateDerivedMetric(metric *entities.DerivedMetric, input DerivedMetricInput) error {
+	metric.Nombre = strings.ToLower(strings.TrimSpace(input.Nombre))
+	if !domain.ValidDerivedName(metric.Nombre) {
+		return fmt.Errorf("nombre_invalido")
+	}
+	metric.Unidad = strings.TrimSpace(input.Unidad)
+	if utf8.RuneCountInString(metric.Unidad) > 20 {
+		return fmt.Errorf("unidad_invalida")
+	}
+	expr, err := domain.ParseDerivedExpr(strings.TrimSpace(input.Expresion))
+	if err != nil {
+		return err
+	}
+	metric.Expresion = expr.Texto
+	return nil
+}
+
+// attachDerived rellena Datos.Derivadas con los valores guardados de las métricas derivadas
+func attachDerived(repo domain.DerivedMetricRepository, datos []entities.Datos) error {
+	ids := make([]int32, 0, len(datos))
+	for _, d := range datos {
+		if d.ID > 0 {
+			ids = append(ids, d.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	values, err := repo.FindValues(ids)
+	if err != nil {
+		return err
+	}
+	for i := range datos {
+		datos[i].Derivadas = values[datos[i].ID]
+	}
+	return nil
+}
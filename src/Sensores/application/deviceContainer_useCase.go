@@ -0,0 +1,148 @@
+// File: deviceContainer_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// ContainerInput DTO con la geometría del contenedor pedida por el cliente
+type ContainerInput struct {
+	Forma        string
+	AlturaSensor float64
+	AlturaMaxima float64 // 0 = AlturaSensor (o el diámetro en cilindros horizontales)
+	Diametro     *float64
+	Largo        *float64
+	Ancho        *float64
+	UmbralBajo   *float64
+	UmbralAlto   *float64
+}
+
+// DeviceContainer administra la geometría del contenedor de cada dispositivo, con la que se
+// deriva el llenado de sus lecturas de distancia
+type DeviceContainer struct {
+	containerRepo domain.ContainerRepository
+	userRepo      domain.UserRepository
+	orgRepo       domain.OrganizationRepository
+	shareRepo     domain.ShareRepository
+}
+
+func NewDeviceContainer(containerRepo domain.ContainerRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *DeviceContainer {
+	if containerRepo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: DeviceContainer recibió dependencias nulas (containerRepo, userRepo, orgRepo o shareRepo).")
+	}
+	return &DeviceContainer{containerRepo: containerRepo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo}
+}
+
+// Get devuelve la geometría de un dispositivo al que el usuario tiene acceso (propio o compartido)
+func (uc *DeviceContainer) Get(userID int, mac string) (*entities.ContainerConfig, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	config, err := uc.containerRepo.FindByMac(mac)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("contenedor_no_configurado")
+	}
+	return config, nil
+}
+
+// Save crea o reemplaza la geometría; requiere poder editar el dispositivo en su organización
+func (uc *DeviceContainer) Save(userID int, mac string, input ContainerInput) (*entities.ContainerConfig, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return nil, err
+	}
+	config, err := validateContainer(mac, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.containerRepo.Save(config); err != nil {
+		log.Printf("ERROR: [DeviceContainer] Falló al guardar el contenedor de MAC %s: %v", mac, err)
+		return nil, err
+	}
+	config.Actualizado = time.Now()
+	log.Printf("INFO: [DeviceContainer] Contenedor de MAC %s configurado por UserID %d (%s).", mac, userID, config.Forma)
+	return config, nil
+}
+
+// Delete quita la geometría: las lecturas dejan de traer llenado
+func (uc *DeviceContainer) Delete(userID int, mac string) error {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return err
+	}
+	return uc.containerRepo.Delete(mac)
+}
+
+func validateContainer(mac string, input ContainerInput) (*entities.ContainerConfig, error) {
+	config := &entities.ContainerConfig{
+		Mac: mac, Forma: strings.ToLower(strings.TrimSpace(input.Forma)), AlturaSensor: input.AlturaSensor, AlturaMaxima: input.AlturaMaxima,
+		Diametro: input.Diametro, Largo: input.Largo, Ancho: input.Ancho, UmbralBajo: input.UmbralBajo, UmbralAlto: input.UmbralAlto,
+	}
+	positive := func(v *float64) bool { return v == nil || (*v > 0 && !math.IsInf(*v, 0)) }
+	if !positive(&config.AlturaSensor) || config.AlturaMaxima < 0 || !positive(config.Diametro) || !positive(config.Largo) || !positive(config.Ancho) {
+		return nil, fmt.Errorf("dimensiones_invalidas")
+	}
+	switch config.Forma {
+	case entities.ContainerCilindro, entities.ContainerRectangular:
+	case entities.ContainerCilindroHorizontal:
+		if config.Diametro == nil || config.Largo == nil {
+			return nil, fmt.Errorf("dimensiones_invalidas")
+		}
+		if config.AlturaMaxima == 0 {
+			config.AlturaMaxima = *config.Diametro
+		}
+	default:
+		return nil, fmt.Errorf("forma_invalida")
+	}
+	if config.AlturaMaxima == 0 {
+		config.AlturaMaxima = config.AlturaSensor
+	}
+	if config.AlturaMaxima > config.AlturaSensor || (config.Forma == entities.ContainerCilindroHorizontal && config.AlturaMaxima > *config.Diametro) {
+		return nil, fmt.Errorf("dimensiones_invalidas")
+	}
+	inRange := func(v *float64) bool { return v == nil || (*v >= 0 && *v <= 100) }
+	if !inRange(config.UmbralBajo) || !inRange(config.UmbralAlto) ||
+		(config.UmbralBajo != nil && config.UmbralAlto != nil && *config.UmbralBajo >= *config.UmbralAlto) {
+		return nil, fmt.Errorf("umbrales_invalidos")
+	}
+	return config, nil
+}
+
+// attachFillLevels rellena Datos.Llenado en las lecturas con distancia de dispositivos que tienen
+// contenedor configurado, consultando una sola vez cada MAC distinta
+func attachFillLevels(containerRepo domain.ContainerRepository, datos []entities.Datos) error {
+	if len(datos) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	macs := []string{}
+	for _, d := range datos {
+		if d.Mac != "" && !seen[d.Mac] {
+			seen[d.Mac] = true
+			macs = append(macs, d.Mac)
+		}
+	}
+	configs, err := containerRepo.FindByMacs(macs)
+	if err != nil {
+		return err
+	}
+	for i := range datos {
+		config := configs[datos[i].Mac]
+		if config == nil {
+			continue
+		}
+		if distancia := domain.ParseNumber(datos[i].Distancia); distancia != nil {
+			fill := config.Fill(*distancia)
+			datos[i].Llenado = &fill
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// File: deviceOwnership_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"log"
+)
+
+type DeviceOwnership struct {
+	ownershipRepo domain.OwnershipRepository
+	userRepo      domain.UserRepository
+}
+
+func NewDeviceOwnership(ownershipRepo domain.OwnershipRepository, userRepo domain.UserRepository) *DeviceOwnership {
+	if ownershipRepo == nil || userRepo == nil {
+		log.Fatal("Error: DeviceOwnership recibió dependencias nulas (ownershipRepo o userRepo).")
+	}
+	return &DeviceOwnership{ownershipRepo: ownershipRepo, userRepo: userRepo}
+}
+
+// ExecuteForOwner devuelve los periodos del propio usuario sobre la MAC.
+// Los periodos de otros dueños no se exponen; solo se informa previous_user_id en el periodo actual.
+func (uc *DeviceOwnership) ExecuteForOwner(userID int, mac string) ([]entities.DeviceOwnership, error) {
+	if err := checkDeviceOwner(uc.userRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	periods, err := uc.ownershipRepo.FindByMac(mac)
+	if err != nil {
+		return nil, err
+	}
+	own := []entities.DeviceOwnership{}
+	for _, p := range periods {
+		if p.UserID == userID {
+			own = append(own, p)
+		}
+	}
+	return own, nil
+}
+
+// ExecuteAdmin devuelve el historial completo de la MAC (solo admin)
+func (uc *DeviceOwnership) ExecuteAdmin(mac string) ([]entities.DeviceOwnership, error) {
+	return uc.ownershipRepo.FindByMac(mac)
+}
+
+// DecideHistory registra si el nuevo dueño recibe (transferir=true) las lecturas
+// del dueño anterior o si quedan privadas. Solo se puede decidir una vez por periodo.
+func (uc *DeviceOwnership) DecideHistory(userID int, mac string, transferir bool) (*entities.DeviceOwnership, error) {
+	decision := entities.HistorialPrivado
+	if transferir {
+		decision = entities.HistorialTransferido
+	}
+	period, err := uc.ownershipRepo.DecideHistory(userID, mac, decision)
+	if err != nil {
+		log.Printf("WARN: [DeviceOwnership] UserID %d no pudo decidir historial de MAC %s: %v", userID, mac, err)
+		return nil, err
+	}
+	return period, nil
+}
@@ -0,0 +1,162 @@
+// File: deviceStats_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	deviceStatsDefaultWindow = "24h"
+	deviceStatsMaxWindow     = 90 * 24 * time.Hour
+	deviceStatsCacheTTL      = 30 * time.Second // Los tableros consultan seguido; se toleran 30 s de retraso
+	deviceStatsCacheMax      = 1000             // Entradas antes de purgar las vencidas
+)
+
+type deviceStatsEntry struct {
+	stats  []entities.DeviceStats
+	expira time.Time
+}
+
+// deviceStatsCache va por puntero: los controladores guardan una copia del caso de uso
+type deviceStatsCache struct {
+	mu      sync.Mutex
+	entries map[string]deviceStatsEntry
+}
+
+// DeviceStats resume por dispositivo las lecturas visibles para el usuario (GET /devices/stats).
+// Los resultados se guardan unos segundos en memoria por usuario y parámetros.
+type DeviceStats struct {
+	db         domain.DatosRepository
+	deviceRepo domain.DeviceRepository
+	userRepo   domain.UserRepository
+	orgRepo    domain.OrganizationRepository
+	shareRepo  domain.ShareRepository
+	cache      *deviceStatsCache
+}
+
+func NewDeviceStats(db domain.DatosRepository, deviceRepo domain.DeviceRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *DeviceStats {
+	if db == nil || deviceRepo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: DeviceStats recibió dependencias nulas (db, deviceRepo, userRepo, orgRepo o shareRepo).")
+	}
+	return &DeviceStats{db: db, deviceRepo: deviceRepo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo, cache: &deviceStatsCache{entries: make(map[string]deviceStatsEntry)}}
+}
+
+// Execute devuelve las estadísticas de todos los dispositivos con lecturas visibles que cumplen
+// el filtro (mac, etiquetas, sitio, área); ventana es una duración como 30m, 24h o 7d
+func (uc *DeviceStats) Execute(userID int, filter domain.DatosFilter, ventana string) ([]entities.DeviceStats, error) {
+	filter.Etiquetas = NormalizeTags(filter.Etiquetas)
+	return uc.compute(userID, filter, ventana)
+}
+
+// ExecuteOne devuelve las estadísticas de un dispositivo al que el usuario tiene acceso (propio o
+// compartido); si no tiene lecturas visibles se devuelven en cero
+func (uc *DeviceStats) ExecuteOne(userID int, mac string, ventana string) (*entities.DeviceStats, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	stats, err := uc.compute(userID, domain.DatosFilter{Mac: mac}, ventana)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) > 0 {
+		return &stats[0], nil
+	}
+	window, _ := parseStatsWindow(ventana)
+	now := time.Now()
+	empty := entities.DeviceStats{Mac: mac, Ventana: ventanaOrDefault(ventana), Desde: now.Add(-window), Hasta: now}
+	if device, err := uc.deviceRepo.FindByMac(mac); err == nil {
+		empty.Dispositivo = device
+	}
+	return &empty, nil
+}
+
+func (uc *DeviceStats) compute(userID int, filter domain.DatosFilter, ventana string) ([]entities.DeviceStats, error) {
+	window, err := parseStatsWindow(ventana)
+	if err != nil {
+		return nil, err
+	}
+	ventana = ventanaOrDefault(ventana)
+	key := fmt.Sprintf("%d|%s|%s|%d|%d|%s", userID, ventana, filter.Mac, filter.SiteID, filter.AreaID, strings.Join(filter.Etiquetas, ","))
+	if filter.Expresion != nil {
+		key += "|" + filter.Expresion.Texto
+	}
+	if stats, ok := uc.cache.get(key); ok {
+		return stats, nil
+	}
+
+	stats, err := uc.db.DeviceStats(userID, filter, time.Now().Add(-window))
+	if err != nil {
+		log.Printf("ERROR: [DeviceStats] Falló el cálculo para UserID %d: %v", userID, err)
+		return nil, err
+	}
+	macs := make([]string, 0, len(stats))
+	for i := range stats {
+		stats[i].Ventana = ventana
+		macs = append(macs, stats[i].Mac)
+	}
+	devices, errDevices := uc.deviceRepo.FindByMacs(macs)
+	if errDevices != nil {
+		log.Printf("ADVERTENCIA: [DeviceStats] No se pudieron adjuntar metadatos de dispositivos: %v", errDevices)
+	}
+	for i := range stats {
+		stats[i].Dispositivo = devices[stats[i].Mac]
+	}
+	uc.cache.put(key, stats)
+	return stats, nil
+}
+
+func (c *deviceStatsCache) get(key string) ([]entities.DeviceStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expira) {
+		return nil, false
+	}
+	return entry.stats, true
+}
+
+func (c *deviceStatsCache) put(key string, stats []entities.DeviceStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= deviceStatsCacheMax {
+		for k, entry := range c.entries {
+			if now.After(entry.expira) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) < deviceStatsCacheMax {
+		c.entries[key] = deviceStatsEntry{stats: stats, expira: now.Add(deviceStatsCacheTTL)}
+	}
+}
+
+func ventanaOrDefault(ventana string) string {
+	if ventana == "" {
+		return deviceStatsDefaultWindow
+	}
+	return ventana
+}
+
+// parseStatsWindow acepta un entero positivo seguido de m, h o d (30m, 24h, 7d), hasta 90 días
+func parseStatsWindow(ventana string) (time.Duration, error) {
+	ventana = ventanaOrDefault(ventana)
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour}
+	unit, ok := units[ventana[len(ventana)-1]]
+	if !ok {
+		return 0, fmt.Errorf("ventana_invalida")
+	}
+	n, err := strconv.Atoi(ventana[:len(ventana)-1])
+	if err != nil || n <= 0 || time.Duration(n) > deviceStatsMaxWindow/unit {
+		return 0, fmt.Errorf("ventana_invalida")
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,190 @@
+// File: exportDatos_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportColumns son las columnas exportables y cómo se obtiene cada una de una lectura.
+// Los valores de las métricas se exportan tal como se guardaron.
+var exportColumns = map[string]func(d entities.Datos, zona *time.Location, csv bool) interface{}{
+	"id":          func(d entities.Datos, _ *time.Location, _ bool) interface{} { return d.ID },
+	"fecha":       exportFecha,
+	"mac":         func(d entities.Datos, _ *time.Location, _ bool) interface{} { return d.Mac },
+	"temperatura": func(d entities.Datos, _ *time.Location, _ bool) interface{} { return d.Temperatura },
+	"movimiento":  func(d entities.Datos, _ *time.Location, _ bool) interface{} { return d.Movimiento },
+	"distancia":   func(d entities.Datos, _ *time.Location, _ bool) interface{} { return d.Distancia },
+	"peso":        func(d entities.Datos, _ *time.Location, _ bool) interface{} { return d.Peso },
+	"user_id":     func(d entities.Datos, _ *time.Location, _ bool) interface{} { return d.UserID },
+	"org_id":      func(d entities.Datos, _ *time.Location, _ bool) interface{} { return d.OrgID },
+}
+
+var exportDefaultColumns = []string{"fecha", "mac", "temperatura", "movimiento", "distancia", "peso"}
+
+// exportFecha usa en CSV un formato que Excel reconoce como fecha; en NDJSON, RFC3339 con desfase
+func exportFecha(d entities.Datos, zona *time.Location, csv bool) interface{} {
+	if csv {
+		return d.Fecha.In(zona).Format("2006-01-02 15:04:05")
+	}
+	return d.Fecha.In(zona).Format(time.RFC3339)
+}
+
+// ExportInput DTO con el formato pedido por el cliente
+type ExportInput struct {
+	Formato  string   // csv | ndjson
+	Columnas []string // vacío = exportDefaultColumns
+	Zona     string   // Nombre IANA para 'fecha'; "" = zona del servidor
+	Orden    string   // "asc" (por defecto) | "desc"
+}
+
+// ExportSpec es una exportación ya validada, lista para escribirse
+type ExportSpec struct {
+	Formato  string
+	Columnas []string
+	Zona     *time.Location
+	Orden    domain.DatosOrden
+}
+
+// ContentType y Extension describen el archivo resultante
+func (spec *ExportSpec) ContentType() string {
+	if spec.Formato == "csv" {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+func (spec *ExportSpec) Extension() string {
+	return spec.Formato
+}
+
+type ExportDatos struct {
+	db domain.DatosRepository
+}
+
+func NewExportDatos(db domain.DatosRepository) *ExportDatos {
+	if db == nil {
+		log.Fatal("Error: ExportDatos recibió dependencias nulas (db).")
+	}
+	return &ExportDatos{db: db}
+}
+
+// Prepare valida el pedido antes de empezar a escribir la respuesta
+func (uc *ExportDatos) Prepare(input ExportInput) (*ExportSpec, error) {
+	spec := &ExportSpec{Formato: strings.ToLower(input.Formato), Zona: time.Local}
+	if spec.Formato == "" {
+		spec.Formato = "csv"
+	}
+	if spec.Formato != "csv" && spec.Formato != "ndjson" {
+		return nil, fmt.Errorf("formato_invalido")
+	}
+	switch input.Orden {
+	case "", "asc":
+		spec.Orden = domain.OrdenFechaAsc
+	case "desc":
+		spec.Orden = domain.OrdenFechaDesc
+	default:
+		return nil, fmt.Errorf("orden_invalido")
+	}
+	if input.Zona != "" {
+		zona, err := time.LoadLocation(input.Zona)
+		if err != nil {
+			return nil, fmt.Errorf("zona_invalida")
+		}
+		spec.Zona = zona
+	}
+	seen := make(map[string]bool)
+	for _, col := range input.Columnas {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if col == "" || seen[col] {
+			continue
+		}
+		if _, ok := exportColumns[col]; !ok {
+			return nil, fmt.Errorf("columna_invalida")
+		}
+		seen[col] = true
+		spec.Columnas = append(spec.Columnas, col)
+	}
+	if len(spec.Columnas) == 0 {
+		spec.Columnas = exportDefaultColumns
+	}
+	return spec, nil
+}
+
+// Stream escribe las lecturas visibles para el usuario a medida que llegan de la BD.
+// Devuelve cuántas filas se escribieron; un error a mitad deja el archivo truncado.
+func (uc *ExportDatos) Stream(userID int, filter domain.DatosFilter, spec *ExportSpec, w io.Writer) (int, error) {
+	filter.Etiquetas = NormalizeTags(filter.Etiquetas)
+	count := 0
+	var err error
+	if spec.Formato == "csv" {
+		writer := csv.NewWriter(w)
+		if err = writer.Write(spec.Columnas); err != nil {
+			return 0, err
+		}
+		record := make([]string, len(spec.Columnas))
+		err = uc.db.StreamByUserID(userID, filter, spec.Orden, func(d entities.Datos) error {
+			for i, col := range spec.Columnas {
+				record[i] = csvSafe(fmt.Sprint(exportColumns[col](d, spec.Zona, true)))
+			}
+			count++
+			return writer.Write(record)
+		})
+		writer.Flush()
+		if err == nil {
+			err = writer.Error()
+		}
+	} else {
+		buffered := bufio.NewWriter(w)
+		err = uc.db.StreamByUserID(userID, filter, spec.Orden, func(d entities.Datos) error {
+			// Una línea JSON por lectura, con las claves en el orden de las columnas pedidas
+			buffered.WriteByte('{')
+			for i, col := range spec.Columnas {
+				if i > 0 {
+					buffered.WriteByte(',')
+				}
+				value, err := json.Marshal(exportColumns[col](d, spec.Zona, false))
+				if err != nil {
+					return err
+				}
+				buffered.WriteString(strconv.Quote(col))
+				buffered.WriteByte(':')
+				buffered.Write(value)
+			}
+			count++
+			_, err := buffered.WriteString("}\n")
+			return err
+		})
+		if flushErr := buffered.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	if err != nil {
+		log.Printf("ERROR: [ExportDatos] Exportación %s de UserID %d interrumpida tras %d filas: %v", spec.Formato, userID, count, err)
+		return count, err
+	}
+	log.Printf("INFO: [ExportDatos] Exportadas %d filas en %s para UserID %d.", count, spec.Formato, userID)
+	return count, nil
+}
+
+// csvSafe evita que una hoja de cálculo interprete como fórmula un texto que empieza por
+// =, +, -, @ (los números negativos se dejan tal cual)
+func csvSafe(value string) string {
+	if value == "" || !strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return value
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return "'" + value
+}
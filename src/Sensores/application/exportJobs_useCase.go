@@ -0,0 +1,236 @@
+// File: exportJobs_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	exportJobsMaxActive  = 3   // Trabajos pendientes o en proceso por usuario
+	exportJobsQueueSize  = 100 // Trabajos en espera antes de rechazar nuevos
+	exportJobsDefaultTTL = 24  // Horas que se conserva un archivo terminado
+)
+
+// ExportJobs ejecuta en segundo plano exportaciones de lecturas a archivos (Parquet) que luego
+// se descargan por HTTP. Los trabajos se procesan de a uno, en orden de llegada.
+type ExportJobs struct {
+	jobRepo domain.ExportJobRepository
+	db      domain.DatosRepository
+	store   domain.ArchiveStore
+	queue   chan int
+	ttl     time.Duration
+}
+
+func NewExportJobs(jobRepo domain.ExportJobRepository, db domain.DatosRepository, store domain.ArchiveStore) *ExportJobs {
+	if jobRepo == nil || db == nil || store == nil {
+		log.Fatal("Error: ExportJobs recibió dependencias nulas (jobRepo, db o store).")
+	}
+	ttl := exportJobsDefaultTTL
+	if raw := os.Getenv("EXPORT_TTL_HORAS"); raw != "" {
+		if hours, err := strconv.Atoi(raw); err == nil && hours > 0 {
+			ttl = hours
+		} else {
+			log.Printf("ADVERTENCIA: EXPORT_TTL_HORAS inválido ('%s'); se usan %d horas.", raw, exportJobsDefaultTTL)
+		}
+	}
+	return &ExportJobs{jobRepo: jobRepo, db: db, store: store, queue: make(chan int, exportJobsQueueSize), ttl: time.Duration(ttl) * time.Hour}
+}
+
+// Run procesa la cola y borra cada hora los archivos vencidos. Se lanza con 'go' al arrancar.
+func (uc *ExportJobs) Run() {
+	// La cola vive en memoria: lo que quedó a medias en un arranque anterior ya no se va a procesar
+	if n, err := uc.jobRepo.FailInterrupted(); err != nil {
+		log.Printf("ERROR: [ExportJobs] %v", err)
+	} else if n > 0 {
+		log.Printf("INFO: [ExportJobs] %d exportaciones interrumpidas marcadas como fallidas.", n)
+	}
+	uc.removeExpired()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+	for {
+		select {
+		case id := <-uc.queue:
+			uc.process(id)
+		case <-cleanup.C:
+			uc.removeExpired()
+		}
+	}
+}
+
+// Create registra un trabajo sobre las lecturas visibles para el usuario o, con todos=true
+// (solo operadores de la plataforma, verificado por el controlador), sobre todas las lecturas
+func (uc *ExportJobs) Create(userID int, filter entities.ExportFilter, todos bool) (*entities.ExportJob, error) {
+	if filter.Desde != nil && filter.Hasta != nil && !filter.Desde.Before(*filter.Hasta) {
+		return nil, fmt.Errorf("rango_invalido")
+	}
+	filter.Etiquetas = NormalizeTags(filter.Etiquetas)
+	active, err := uc.jobRepo.CountActive(userID)
+	if err != nil {
+		return nil, err
+	}
+	if active >= exportJobsMaxActive {
+		return nil, fmt.Errorf("demasiados_trabajos")
+	}
+	job := &entities.ExportJob{UserID: userID, Todos: todos, Formato: "parquet", Filtro: filter, Estado: entities.ExportPendiente, Creado: time.Now()}
+	if err := uc.jobRepo.Create(job); err != nil {
+		return nil, err
+	}
+	select {
+	case uc.queue <- job.ID:
+	default:
+		uc.jobRepo.Fail(job.ID, "cola de exportaciones llena")
+		return nil, fmt.Errorf("cola_llena")
+	}
+	return job, nil
+}
+
+// List devuelve los trabajos pedidos por el usuario
+func (uc *ExportJobs) List(userID int) ([]entities.ExportJob, error) {
+	return uc.jobRepo.FindForUser(userID)
+}
+
+// Get devuelve un trabajo del usuario; los ajenos se reportan como inexistentes
+func (uc *ExportJobs) Get(userID int, jobID int) (*entities.ExportJob, error) {
+	job, err := uc.jobRepo.FindByID(jobID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("exportacion_no_encontrada")
+		}
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, fmt.Errorf("exportacion_no_encontrada")
+	}
+	return job, nil
+}
+
+// Download abre el archivo de un trabajo completado y vigente
+func (uc *ExportJobs) Download(userID int, jobID int) (*entities.ExportJob, io.ReadSeekCloser, error) {
+	job, err := uc.Get(userID, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch job.Estado {
+	case entities.ExportCompletado:
+	case entities.ExportExpirado:
+		return nil, nil, fmt.Errorf("exportacion_expirada")
+	default:
+		return nil, nil, fmt.Errorf("exportacion_no_lista")
+	}
+	if job.Expira != nil && !time.Now().Before(*job.Expira) {
+		return nil, nil, fmt.Errorf("exportacion_expirada")
+	}
+	file, err := uc.store.Open(job.ID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("exportacion_expirada")
+		}
+		return nil, nil, err
+	}
+	return job, file, nil
+}
+
+// FileName y ContentType describen el archivo que se descarga
+func (uc *ExportJobs) FileName(job *entities.ExportJob) string {
+	return fmt.Sprintf("datos-%d-%s.%s", job.ID, job.Creado.Format("20060102-150405"), uc.store.Extension())
+}
+
+func (uc *ExportJobs) ContentType() string {
+	return uc.store.ContentType()
+}
+
+func toDatosFilter(f entities.ExportFilter) (domain.DatosFilter, error) {
+	filter := domain.DatosFilter{Mac: f.Mac, Etiquetas: f.Etiquetas, SiteID: f.SiteID, AreaID: f.AreaID, UserID: f.UserID}
+	if f.Desde != nil {
+		filter.Desde = *f.Desde
+	}
+	if f.Hasta != nil {
+		filter.Hasta = *f.Hasta
+	}
+	if f.Where != "" {
+		expr, err := domain.ParseMetricFilter(f.Where)
+		if err != nil {
+			return filter, err
+		}
+		filter.Expresion = expr
+	}
+	return filter, nil
+}
+
+func (uc *ExportJobs) process(id int) {
+	job, err := uc.jobRepo.FindByID(id)
+	if err != nil {
+		log.Printf("ERROR: [ExportJobs] No se pudo leer la exportación %d: %v", id, err)
+		return
+	}
+	if err := uc.jobRepo.MarkRunning(id); err != nil {
+		return
+	}
+	filter, err := toDatosFilter(job.Filtro)
+	if err != nil {
+		uc.jobRepo.Fail(id, "filtro inválido: "+err.Error())
+		return
+	}
+	start := time.Now()
+	writer, err := uc.store.Create(id)
+	if err != nil {
+		log.Printf("ERROR: [ExportJobs] Exportación %d: %v", id, err)
+		uc.jobRepo.Fail(id, "no se pudo crear el archivo")
+		return
+	}
+	var filas int64
+	write := func(d entities.Datos) error {
+		filas++
+		return writer.Write(d)
+	}
+	// Ordenadas por dispositivo y fecha para que cada partición se escriba de una vez
+	if job.Todos {
+		err = uc.db.StreamAll(filter, domain.OrdenDispositivoFecha, write)
+	} else {
+		err = uc.db.StreamByUserID(job.UserID, filter, domain.OrdenDispositivoFecha, write)
+	}
+	if err != nil {
+		writer.Abort()
+		log.Printf("ERROR: [ExportJobs] Exportación %d falló tras %d filas: %v", id, filas, err)
+		uc.jobRepo.Fail(id, "error al leer o escribir las lecturas")
+		return
+	}
+	bytes, err := writer.Close()
+	if err != nil {
+		log.Printf("ERROR: [ExportJobs] Exportación %d no se pudo cerrar: %v", id, err)
+		uc.jobRepo.Fail(id, "error al terminar el archivo")
+		return
+	}
+	if err := uc.jobRepo.Finish(id, filas, bytes, time.Now().Add(uc.ttl)); err != nil {
+		uc.store.Remove(id)
+		return
+	}
+	log.Printf("INFO: [ExportJobs] Exportación %d completada: %d filas, %d bytes en %s.", id, filas, bytes, time.Since(start).Round(time.Millisecond))
+}
+
+func (uc *ExportJobs) removeExpired() {
+	jobs, err := uc.jobRepo.FindExpired(time.Now())
+	if err != nil {
+		log.Printf("ERROR: [ExportJobs] %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if err := uc.store.Remove(job.ID); err != nil {
+			log.Printf("ERROR: [ExportJobs] No se pudo borrar el archivo de la exportación %d: %v", job.ID, err)
+			continue
+		}
+		uc.jobRepo.MarkExpired(job.ID)
+	}
+	if len(jobs) > 0 {
+		log.Printf("INFO: [ExportJobs] %d exportaciones vencidas eliminadas.", len(jobs))
+	}
+}
@@ -0,0 +1,371 @@
+// File: forecast_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	forecastDefaultHorizon = 72   // Horas proyectadas por defecto
+	forecastMaxHorizon     = 720  // 30 días
+	forecastDefaultHistory = 168  // Horas de historia por defecto (7 días)
+	forecastMaxHistory     = 1440 // 60 días
+	forecastMinHours       = 6    // Horas con lecturas necesarias para ajustar una tendencia
+	forecastSeasonalSpan   = 48   // Horas de historia necesarias para estimar el patrón diario
+	forecastSeasonalHours  = 18   // Horas del día distintas con lecturas para estimar el patrón diario
+	forecastMaxThresholds  = 5    // Umbrales pedidos por consulta
+)
+
+// forecastZ son los cuantiles normales de las bandas de confianza admitidas
+var forecastZ = map[int]float64{80: 1.2816, 90: 1.6449, 95: 1.96, 99: 2.5758}
+
+// ForecastInput DTO con el pronóstico pedido por el cliente
+type ForecastInput struct {
+	Metrica   string    // peso | distancia | temperatura
+	Horizonte int       // Horas; 0 = forecastDefaultHorizon
+	Historia  int       // Horas; 0 = forecastDefaultHistory
+	Umbrales  []float64 // Además de los que salen de la configuración del dispositivo
+	Confianza int       // 80 | 90 | 95 | 99; 0 = 95
+}
+
+// Forecasting proyecta peso, distancia o temperatura de un dispositivo ajustando una tendencia
+// lineal (y, con historia suficiente, el patrón de cada hora del día) a los promedios por hora de
+// sus lecturas recientes. Estima además cuándo se alcanzan los umbrales pedidos y los que salen
+// del producto, el contenedor o el perfil de cumplimiento del dispositivo.
+type Forecasting struct {
+	db             domain.DatosRepository
+	productRepo    domain.ProductRepository
+	containerRepo  domain.ContainerRepository
+	complianceRepo domain.ComplianceRepository
+	userRepo       domain.UserRepository
+	orgRepo        domain.OrganizationRepository
+	shareRepo      domain.ShareRepository
+}
+
+func NewForecasting(db domain.DatosRepository, productRepo domain.ProductRepository, containerRepo domain.ContainerRepository, complianceRepo domain.ComplianceRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *Forecasting {
+	if db == nil || productRepo == nil || containerRepo == nil || complianceRepo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: Forecasting recibió dependencias nulas (db, productRepo, containerRepo, complianceRepo, userRepo, orgRepo o shareRepo).")
+	}
+	return &Forecasting{db: db, productRepo: productRepo, containerRepo: containerRepo, complianceRepo: complianceRepo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo}
+}
+
+// forecastSample es el promedio de una hora con lecturas; t son horas relativas a la generación
+type forecastSample struct {
+	t    float64
+	hora int
+	y    float64
+}
+
+// forecastModel es el ajuste: a + b·t más el desvío de la hora del día
+type forecastModel struct {
+	a, b     float64
+	offsets  [24]float64
+	seasonal bool
+	n        int
+	tMean    float64
+	sxx      float64
+	sigma    float64
+}
+
+type forecastThreshold struct {
+	valor  float64
+	origen string
+}
+
+// Execute arma el pronóstico de una métrica de un dispositivo al que el usuario tiene acceso
+func (uc *Forecasting) Execute(userID int, mac string, input ForecastInput) (*entities.Forecast, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	if input.Metrica != "peso" && input.Metrica != "distancia" && input.Metrica != "temperatura" {
+		return nil, fmt.Errorf("metrica_invalida")
+	}
+	if input.Horizonte == 0 {
+		input.Horizonte = forecastDefaultHorizon
+	}
+	if input.Horizonte < 1 || input.Horizonte > forecastMaxHorizon {
+		return nil, fmt.Errorf("horizonte_invalido")
+	}
+	if input.Historia == 0 {
+		input.Historia = forecastDefaultHistory
+	}
+	if input.Historia < forecastMinHours || input.Historia > forecastMaxHistory {
+		return nil, fmt.Errorf("historia_invalida")
+	}
+	if input.Confianza == 0 {
+		input.Confianza = 95
+	}
+	z, ok := forecastZ[input.Confianza]
+	if !ok {
+		return nil, fmt.Errorf("confianza_invalida")
+	}
+	if len(input.Umbrales) > forecastMaxThresholds {
+		return nil, fmt.Errorf("umbral_invalido")
+	}
+	thresholds := make([]forecastThreshold, 0, len(input.Umbrales)+2)
+	for _, u := range input.Umbrales {
+		if math.IsNaN(u) || math.IsInf(u, 0) {
+			return nil, fmt.Errorf("umbral_invalido")
+		}
+		thresholds = append(thresholds, forecastThreshold{valor: u, origen: entities.UmbralPedido})
+	}
+	configured, err := uc.configuredThresholds(mac, input.Metrica)
+	if err != nil {
+		return nil, err
+	}
+	thresholds = append(thresholds, configured...)
+
+	now := time.Now()
+	forecast := &entities.Forecast{Mac: mac, Metrica: input.Metrica, Generado: now, HistoriaDesde: now.Add(-time.Duration(input.Historia) * time.Hour),
+		Confianza: input.Confianza, Puntos: []entities.ForecastPoint{}, Cruces: []entities.ThresholdCrossing{}}
+	samples, err := uc.hourlySamples(userID, forecast)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) < forecastMinHours {
+		return nil, fmt.Errorf("historia_insuficiente")
+	}
+	model := fitForecast(samples)
+	forecast.Modelo = entities.ForecastTendencia
+	if model.seasonal {
+		forecast.Modelo = entities.ForecastEstacional
+	}
+	forecast.HorasConDatos, forecast.PendientePorHora, forecast.Desviacion = model.n, model.b, model.sigma
+
+	for k := 1; k <= input.Horizonte; k++ {
+		fecha := now.Add(time.Duration(k) * time.Hour)
+		valor, banda := model.predict(float64(k), fecha.In(time.Local).Hour(), z)
+		forecast.Puntos = append(forecast.Puntos, entities.ForecastPoint{Fecha: fecha, Valor: valor, Inferior: valor - banda, Superior: valor + banda})
+	}
+	for _, th := range thresholds {
+		forecast.Cruces = append(forecast.Cruces, forecastCrossing(forecast, th))
+	}
+	return forecast, nil
+}
+
+// configuredThresholds devuelve los umbrales que salen de la configuración del dispositivo
+func (uc *Forecasting) configuredThresholds(mac string, metrica string) ([]forecastThreshold, error) {
+	thresholds := []forecastThreshold{}
+	switch metrica {
+	case "peso":
+		product, err := uc.productRepo.FindByMac(mac)
+		if err != nil || product == nil {
+			return thresholds, err
+		}
+		thresholds = append(thresholds, forecastThreshold{valor: product.Tara, origen: entities.UmbralProductoVacio})
+		if product.UmbralMinimo != nil && *product.UmbralMinimo > 0 {
+			minimo := product.Tara + float64(*product.UmbralMinimo)*product.PesoUnitario
+			thresholds = append(thresholds, forecastThreshold{valor: minimo, origen: entities.UmbralStockMinimo})
+		}
+	case "distancia":
+		config, err := uc.containerRepo.FindByMac(mac)
+		if err != nil || config == nil {
+			return thresholds, err
+		}
+		// La distancia crece a medida que el contenedor se vacía
+		thresholds = append(thresholds, forecastThreshold{valor: config.AlturaSensor, origen: entities.UmbralContenedorVacio})
+		if config.UmbralBajo != nil {
+			thresholds = append(thresholds, forecastThreshold{valor: config.AlturaSensor - *config.UmbralBajo/100*config.AlturaMaxima, origen: entities.UmbralContenedorBajo})
+		}
+		if config.UmbralAlto != nil {
+			thresholds = append(thresholds, forecastThreshold{valor: config.AlturaSensor - *config.UmbralAlto/100*config.AlturaMaxima, origen: entities.UmbralContenedorAlto})
+		}
+	case "temperatura":
+		profile, err := uc.complianceRepo.FindProfile(mac)
+		if err != nil || profile == nil {
+			return thresholds, err
+		}
+		thresholds = append(thresholds, forecastThreshold{valor: profile.TempMax, origen: entities.UmbralCumplimientoMax},
+			forecastThreshold{valor: profile.TempMin, origen: entities.UmbralCumplimientoMin})
+	}
+	return thresholds, nil
+}
+
+// hourlySamples promedia por hora (local del servidor) las lecturas visibles de la historia y
+// registra la última lectura en el pronóstico
+func (uc *Forecasting) hourlySamples(userID int, forecast *entities.Forecast) ([]forecastSample, error) {
+	type bucket struct {
+		inicio time.Time
+		suma   float64
+		n      int
+	}
+	buckets := []*bucket{}
+	filter := domain.DatosFilter{Mac: forecast.Mac, Desde: forecast.HistoriaDesde, Hasta: forecast.Generado}
+	err := uc.db.StreamByUserID(userID, filter, domain.OrdenFechaAsc, func(d entities.Datos) error {
+		var valor *float64
+		switch forecast.Metrica {
+		case "peso":
+			valor = domain.ParseNumber(d.Peso)
+		case "distancia":
+			valor = domain.ParseNumber(d.Distancia)
+		default:
+			valor = domain.ParseNumber(d.Temperatura)
+		}
+		if valor == nil {
+			return nil
+		}
+		local := d.Fecha.In(time.Local)
+		inicio := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, time.Local)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].inicio.Equal(inicio) {
+			buckets = append(buckets, &bucket{inicio: inicio})
+		}
+		current := buckets[len(buckets)-1]
+		current.suma += *valor
+		current.n++
+		forecast.UltimaLectura, forecast.UltimoValor = d.Fecha, *valor
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]forecastSample, 0, len(buckets))
+	for _, b := range buckets {
+		medio := b.inicio.Add(30 * time.Minute)
+		samples = append(samples, forecastSample{t: medio.Sub(forecast.Generado).Hours(), hora: b.inicio.Hour(), y: b.suma / float64(b.n)})
+	}
+	return samples, nil
+}
+
+// fitForecast ajusta la tendencia por mínimos cuadrados. Con historia suficiente estima también el
+// desvío promedio de cada hora del día respecto de la tendencia y vuelve a ajustar sin él.
+func fitForecast(samples []forecastSample) *forecastModel {
+	m := &forecastModel{n: len(samples)}
+	horas := make(map[int]bool)
+	for _, s := range samples {
+		horas[s.hora] = true
+	}
+	span := samples[len(samples)-1].t - samples[0].t
+	m.seasonal = span >= forecastSeasonalSpan && len(horas) >= forecastSeasonalHours
+	rounds := 1
+	if m.seasonal {
+		rounds = 3
+	}
+	for round := 0; round < rounds; round++ {
+		m.fitTrend(samples)
+		if !m.seasonal || round == rounds-1 {
+			break
+		}
+		var suma [24]float64
+		var cuenta [24]int
+		for _, s := range samples {
+			suma[s.hora] += s.y - (m.a + m.b*s.t)
+			cuenta[s.hora]++
+		}
+		total, conDatos := 0.0, 0
+		for h := 0; h < 24; h++ {
+			m.offsets[h] = 0
+			if cuenta[h] > 0 {
+				m.offsets[h] = suma[h] / float64(cuenta[h])
+				total += m.offsets[h]
+				conDatos++
+			}
+		}
+		// El patrón se centra en cero para que el nivel quede en la tendencia
+		for h := 0; h < 24; h++ {
+			if cuenta[h] > 0 {
+				m.offsets[h] -= total / float64(conDatos)
+			}
+		}
+	}
+
+	params := 2
+	if m.seasonal {
+		params += len(horas) - 1
+	}
+	sse := 0.0
+	for _, s := range samples {
+		r := s.y - (m.a + m.b*s.t + m.offsets[s.hora])
+		sse += r * r
+	}
+	if dof := m.n - params; dof > 0 {
+		m.sigma = math.Sqrt(sse / float64(dof))
+	} else {
+		m.sigma = math.Sqrt(sse / float64(m.n))
+	}
+	return m
+}
+
+// fitTrend ajusta a + b·t a los valores sin el patrón diario
+func (m *forecastModel) fitTrend(samples []forecastSample) {
+	var st, sy float64
+	for _, s := range samples {
+		st += s.t
+		sy += s.y - m.offsets[s.hora]
+	}
+	n := float64(len(samples))
+	m.tMean = st / n
+	yMean := sy / n
+	var sxy float64
+	m.sxx = 0
+	for _, s := range samples {
+		dt := s.t - m.tMean
+		m.sxx += dt * dt
+		sxy += dt * (s.y - m.offsets[s.hora] - yMean)
+	}
+	m.b = 0
+	if m.sxx > 0 {
+		m.b = sxy / m.sxx
+	}
+	m.a = yMean - m.b*m.tMean
+}
+
+// predict devuelve el valor esperado en t y la mitad del ancho de su banda de predicción
+func (m *forecastModel) predict(t float64, hora int, z float64) (float64, float64) {
+	valor := m.a + m.b*t + m.offsets[hora]
+	extra := 0.0
+	if m.sxx > 0 {
+		extra = (t - m.tMean) * (t - m.tMean) / m.sxx
+	}
+	return valor, z * m.sigma * math.Sqrt(1+1/float64(m.n)+extra)
+}
+
+// forecastCrossing busca en los puntos proyectados cuándo se alcanza el umbral desde el lado de la
+// última lectura, interpolando entre horas
+func forecastCrossing(forecast *entities.Forecast, th forecastThreshold) entities.ThresholdCrossing {
+	crossing := entities.ThresholdCrossing{Umbral: th.valor, Origen: th.origen, Sentido: "sube"}
+	baja := forecast.UltimoValor > th.valor
+	if baja {
+		crossing.Sentido = "baja"
+	}
+	reached := func(v float64) bool {
+		if baja {
+			return v <= th.valor
+		}
+		return v >= th.valor
+	}
+	find := func(value func(p entities.ForecastPoint) float64) *time.Time {
+		prevT, prevV := forecast.Generado, forecast.UltimoValor
+		if reached(prevV) {
+			return &prevT
+		}
+		for _, p := range forecast.Puntos {
+			v := value(p)
+			if reached(v) {
+				frac := (th.valor - prevV) / (v - prevV)
+				at := prevT.Add(time.Duration(frac * float64(p.Fecha.Sub(prevT))))
+				return &at
+			}
+			prevT, prevV = p.Fecha, v
+		}
+		return nil
+	}
+	crossing.Estimado = find(func(p entities.ForecastPoint) float64 { return p.Valor })
+	inferior := find(func(p entities.ForecastPoint) float64 { return p.Inferior })
+	superior := find(func(p entities.ForecastPoint) float64 { return p.Superior })
+	if baja {
+		crossing.Temprano, crossing.Tardio = inferior, superior
+	} else {
+		crossing.Temprano, crossing.Tardio = superior, inferior
+	}
+	if crossing.Estimado != nil {
+		horas := crossing.Estimado.Sub(forecast.Generado).Hours()
+		crossing.EnHoras = &horas
+	}
+	return crossing
+}
@@ -5,39 +5,207 @@ package application
 import (
 	"API/src/Sensores/domain"
 	"API/src/Sensores/domain/entities"
+	"encoding/base64"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Límites de página de GET /datos
+const (
+	DatosPageDefault = 100
+	DatosPageMax     = 1000
+)
+
+// DatosPageInput DTO con la paginación pedida por el cliente
+type DatosPageInput struct {
+	Limit  int    // 0 = DatosPageDefault
+	Cursor string // next_cursor de la página anterior ("" = primera página)
+	Orden  string // "desc" (por defecto, más recientes primero) | "asc"
+}
+
 type GetDatos struct {
-	db domain.DatosRepository
+	db            domain.DatosRepository
+	deviceRepo    domain.DeviceRepository        // Para adjuntar nombre, ubicación y etiquetas a cada lectura
+	containerRepo domain.ContainerRepository     // Para derivar el llenado de las lecturas de distancia
+	anomalyRepo   domain.AnomalyRepository       // Para marcar las lecturas anómalas
+	derivedRepo   domain.DerivedMetricRepository // Valores de las métricas derivadas de cada lectura
 }
 
-func NewGetDatos(db domain.DatosRepository) *GetDatos {
-	if db == nil {
-		log.Fatal("Error: GetDatos recibió dependencia db nula.")
+func NewGetDatos(db domain.DatosRepository, deviceRepo domain.DeviceRepository, containerRepo domain.ContainerRepository, anomalyRepo domain.AnomalyRepository, derivedRepo domain.DerivedMetricRepository) *GetDatos {
+	if db == nil || deviceRepo == nil || containerRepo == nil || anomalyRepo == nil || derivedRepo == nil {
+		log.Fatal("Error: GetDatos recibió dependencias nulas (db, deviceRepo, containerRepo, anomalyRepo o derivedRepo).")
 	}
-	return &GetDatos{db: db}
+	return &GetDatos{db: db, deviceRepo: deviceRepo, containerRepo: containerRepo, anomalyRepo: anomalyRepo, derivedRepo: derivedRepo}
 }
 
-// Execute AHORA recibe el userID del usuario que hace la petición
-func (gp *GetDatos) Execute(userID int) ([]entities.Datos, error) {
-	// Llamar al nuevo método del repositorio
-	datos, err := gp.db.GetByUserID(userID)
+// Execute recibe el userID del usuario que hace la petición, los filtros opcionales y la página pedida
+func (gp *GetDatos) Execute(userID int, filter domain.DatosFilter, input DatosPageInput) (*entities.DatosPage, error) {
+	filter.Etiquetas = NormalizeTags(filter.Etiquetas)
+	page, err := parseDatosPage(filter, input)
+	if err != nil {
+		return nil, err
+	}
+
+	datos, hasMore, err := gp.db.GetByUserID(userID, filter, page)
 	if err != nil {
 		log.Printf("ERROR: [GetDatos] Falló al obtener datos para UserID %d: %v", userID, err)
-		return []entities.Datos{}, err // Devuelve slice vacío y error
+		return nil, err
 	}
+	gp.enrich(datos)
 	log.Printf("INFO: [GetDatos] Se recuperaron %d registros para UserID %d.", len(datos), userID)
+	return newDatosPage(datos, hasMore, page), nil
+}
+
+// enrich adjunta a las lecturas el dispositivo, el llenado, las marcas de anomalías y las métricas
+// derivadas. Son informativos: si algo falla se registra y las lecturas se devuelven igualmente.
+func (gp *GetDatos) enrich(datos []entities.Datos) {
+	if err := attachDevices(gp.deviceRepo, datos); err != nil {
+		log.Printf("ADVERTENCIA: [GetDatos] No se pudieron adjuntar metadatos de dispositivos: %v", err)
+	}
+	if err := attachFillLevels(gp.containerRepo, datos); err != nil {
+		log.Printf("ADVERTENCIA: [GetDatos] No se pudo calcular el llenado de los contenedores: %v", err)
+	}
+	if err := attachAnomalies(gp.anomalyRepo, datos); err != nil {
+		log.Printf("ADVERTENCIA: [GetDatos] No se pudieron adjuntar las marcas de anomalías: %v", err)
+	}
+	if err := attachDerived(gp.derivedRepo, datos); err != nil {
+		log.Printf("ADVERTENCIA: [GetDatos] No se pudieron adjuntar las métricas derivadas: %v", err)
+	}
+}
+
+// parseDatosPage valida el rango y la paginación pedidos
+func parseDatosPage(filter domain.DatosFilter, input DatosPageInput) (domain.DatosPageQuery, error) {
+	page := domain.DatosPageQuery{Limit: input.Limit}
+	if !filter.Desde.IsZero() && !filter.Hasta.IsZero() && !filter.Desde.Before(filter.Hasta) {
+		return page, fmt.Errorf("rango_invalido")
+	}
+	if page.Limit == 0 {
+		page.Limit = DatosPageDefault
+	}
+	if page.Limit < 0 || page.Limit > DatosPageMax {
+		return page, fmt.Errorf("limite_invalido")
+	}
+	switch input.Orden {
+	case "", "desc":
+	case "asc":
+		page.Ascendente = true
+	default:
+		return page, fmt.Errorf("orden_invalido")
+	}
+	if input.Cursor != "" {
+		cursor, ascendente, err := decodeDatosCursor(input.Cursor)
+		if err != nil || ascendente != page.Ascendente {
+			return page, fmt.Errorf("cursor_invalido")
+		}
+		page.Despues = cursor
+	}
+	return page, nil
+}
+
+func newDatosPage(datos []entities.Datos, hasMore bool, page domain.DatosPageQuery) *entities.DatosPage {
+	result := &entities.DatosPage{Datos: datos, HasMore: hasMore}
+	if hasMore {
+		last := datos[len(datos)-1]
+		result.NextCursor = encodeDatosCursor(domain.DatosCursor{Fecha: last.Fecha, ID: last.ID}, page.Ascendente)
+	}
+	return result
+}
+
+// ExecuteLatest devuelve la lectura más reciente de cada dispositivo al que el usuario tiene acceso
+func (gp *GetDatos) ExecuteLatest(userID int, filter domain.DatosFilter) ([]entities.Datos, error) {
+	filter.Etiquetas = NormalizeTags(filter.Etiquetas)
+	datos, err := gp.db.GetLatestByUserID(userID, filter)
+	if err != nil {
+		log.Printf("ERROR: [GetDatos] Falló al obtener últimas lecturas para UserID %d: %v", userID, err)
+		return nil, err
+	}
+	gp.enrich(datos)
 	return datos, nil
 }
 
-// Si necesitas una función para obtener TODOS (admin)
-func (gp *GetDatos) ExecuteAll() ([]entities.Datos, error) {
-    datos, err := gp.db.GetAll()
-    if err != nil {
-        log.Printf("ERROR: [GetDatos] Falló al obtener todos los datos (admin): %v", err)
-        return []entities.Datos{}, err
-    }
-    log.Printf("INFO: [GetDatos] Se recuperaron %d registros en total (admin).", len(datos))
-    return datos, nil
-}
\ No newline at end of file
+// ExecuteOne devuelve una lectura a la que el usuario tiene acceso; "dato_no_encontrado" si no
+// existe o es de un dispositivo ajeno (no se revela cuál de los dos)
+func (gp *GetDatos) ExecuteOne(userID int, id int) (*entities.Datos, error) {
+	dato, err := gp.db.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	datos := []entities.Datos{*dato}
+	gp.enrich(datos)
+	return &datos[0], nil
+}
+
+// encodeDatosCursor serializa la posición y el orden de la página. El cliente lo trata como opaco;
+// el orden va incluido para rechazar un cursor reutilizado con otro ?sort.
+func encodeDatosCursor(cursor domain.DatosCursor, ascendente bool) string {
+	orden := "d"
+	if ascendente {
+		orden = "a"
+	}
+	raw := fmt.Sprintf("%s|%d|%d", orden, cursor.Fecha.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeDatosCursor(value string) (*domain.DatosCursor, bool, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, false, err
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || (parts[0] != "a" && parts[0] != "d") {
+		return nil, false, fmt.Errorf("formato de cursor desconocido")
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, false, err
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 32)
+	if err != nil || id <= 0 {
+		return nil, false, fmt.Errorf("id de cursor inválido")
+	}
+	return &domain.DatosCursor{Fecha: time.Unix(0, nanos), ID: int32(id)}, parts[0] == "a", nil
+}
+
+// ExecuteAll pagina todas las lecturas de la plataforma (GET /admin/datos); filter.UserID
+// restringe a las atribuidas a un usuario
+func (gp *GetDatos) ExecuteAll(filter domain.DatosFilter, input DatosPageInput) (*entities.DatosPage, error) {
+	filter.Etiquetas = NormalizeTags(filter.Etiquetas)
+	page, err := parseDatosPage(filter, input)
+	if err != nil {
+		return nil, err
+	}
+	datos, hasMore, err := gp.db.GetAll(filter, page)
+	if err != nil {
+		log.Printf("ERROR: [GetDatos] Falló al obtener todos los datos (admin): %v", err)
+		return nil, err
+	}
+	gp.enrich(datos)
+	log.Printf("INFO: [GetDatos] Se recuperaron %d registros en total (admin).", len(datos))
+	return newDatosPage(datos, hasMore, page), nil
+}
+
+// attachDevices rellena Datos.Dispositivo consultando una sola vez cada MAC distinta.
+func attachDevices(deviceRepo domain.DeviceRepository, datos []entities.Datos) error {
+	if len(datos) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	macs := []string{}
+	for _, d := range datos {
+		if d.Mac != "" && !seen[d.Mac] {
+			seen[d.Mac] = true
+			macs = append(macs, d.Mac)
+		}
+	}
+	devices, err := deviceRepo.FindByMacs(macs)
+	if err != nil {
+		return err
+	}
+	for i := range datos {
+		datos[i].Dispositivo = devices[datos[i].Mac]
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// File: getDevices_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type GetDevices struct {
+	deviceRepo domain.DeviceRepository
+	userRepo   domain.UserRepository
+	orgRepo    domain.OrganizationRepository
+	shareRepo  domain.ShareRepository
+}
+
+func NewGetDevices(deviceRepo domain.DeviceRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *GetDevices {
+	if deviceRepo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: GetDevices recibió dependencias nulas (deviceRepo, userRepo, orgRepo o shareRepo).")
+	}
+	return &GetDevices{deviceRepo: deviceRepo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo}
+}
+
+// Execute lista los dispositivos de las organizaciones del usuario y los compartidos con él
+func (uc *GetDevices) Execute(userID int) ([]entities.Device, error) {
+	devices, err := uc.deviceRepo.FindByUserID(userID)
+	if err != nil {
+		log.Printf("ERROR: [GetDevices] Falló al listar dispositivos para UserID %d: %v", userID, err)
+		return []entities.Device{}, err
+	}
+	return devices, nil
+}
+
+// ExecuteOne devuelve un dispositivo concreto si el usuario tiene acceso a él (propio o compartido)
+func (uc *GetDevices) ExecuteOne(userID int, mac string) (*entities.Device, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	return uc.deviceRepo.FindByMac(mac)
+}
+
+// checkDeviceOwner verifica que la MAC de un dispositivo sin organización esté asignada al usuario.
+// Devuelve "dispositivo_no_encontrado" tanto si no existe como si es de otro usuario,
+// para no revelar qué MACs están registradas.
+func checkDeviceOwner(userRepo domain.UserRepository, userID int, mac string) error {
+	ownerID, err := userRepo.FindUserIDByMAC(0, mac)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("dispositivo_no_encontrado")
+		}
+		return fmt.Errorf("error interno al verificar dispositivo: %w", err)
+	}
+	if ownerID != userID {
+		log.Printf("WARN: [DeviceAccess] UserID %d intentó acceder a MAC %s de UserID %d", userID, mac, ownerID)
+		return fmt.Errorf("dispositivo_no_encontrado")
+	}
+	return nil
+}
+
+// checkDeviceAccess verifica el rol del usuario en la organización dueña del dispositivo.
+// Un dispositivo sin organización solo es accesible para el usuario que lo tiene asignado.
+// Devuelve "dispositivo_no_encontrado" si no es miembro y "permiso_insuficiente" si su rol no alcanza.
+func checkDeviceAccess(orgRepo domain.OrganizationRepository, userRepo domain.UserRepository, userID int, mac string, nivel int) error {
+	orgID, err := orgRepo.DeviceOrg(mac)
+	if err != nil {
+		return fmt.Errorf("error interno al verificar dispositivo: %w", err)
+	}
+	if orgID == 0 {
+		return checkDeviceOwner(userRepo, userID, mac)
+	}
+	if _, err := checkOrgAccess(orgRepo, userID, orgID, nivel); err != nil {
+		if err.Error() == "organizacion_no_encontrada" {
+			log.Printf("WARN: [DeviceAccess] UserID %d intentó acceder a MAC %s de la organización %d", userID, mac, orgID)
+			return fmt.Errorf("dispositivo_no_encontrado")
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// File: getSiteSummary_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+)
+
+// SiteSummary agrega las lecturas de todos los dispositivos bajo un sitio (o un área)
+type SiteSummary struct {
+	Site         *entities.Site           `json:"sitio"`
+	AreaID       int                      `json:"area_id,omitempty"`
+	Dispositivos []entities.DeviceSummary `json:"dispositivos"`
+}
+
+type GetSiteSummary struct {
+	db         domain.DatosRepository
+	siteRepo   domain.SiteRepository
+	deviceRepo domain.DeviceRepository
+	orgRepo    domain.OrganizationRepository
+}
+
+func NewGetSiteSummary(db domain.DatosRepository, siteRepo domain.SiteRepository, deviceRepo domain.DeviceRepository, orgRepo domain.OrganizationRepository) *GetSiteSummary {
+	if db == nil || siteRepo == nil || deviceRepo == nil || orgRepo == nil {
+		log.Fatal("Error: GetSiteSummary recibió dependencias nulas (db, siteRepo, deviceRepo u orgRepo).")
+	}
+	return &GetSiteSummary{db: db, siteRepo: siteRepo, deviceRepo: deviceRepo, orgRepo: orgRepo}
+}
+
+// Execute resume por dispositivo las lecturas visibles del usuario bajo el sitio;
+// si areaID > 0 se limita a esa área (que debe pertenecer al sitio).
+func (uc *GetSiteSummary) Execute(userID int, siteID int, areaID int) (*SiteSummary, error) {
+	site, err := findAccessibleSite(uc.siteRepo, uc.orgRepo, userID, siteID, accesoLectura)
+	if err != nil {
+		return nil, err
+	}
+	filter := domain.DatosFilter{SiteID: siteID}
+	if areaID > 0 {
+		area, _, err := findAccessibleArea(uc.siteRepo, uc.orgRepo, userID, areaID, accesoLectura)
+		if err != nil {
+			return nil, err
+		}
+		if area.SiteID != siteID {
+			return nil, fmt.Errorf("area_no_encontrada")
+		}
+		filter.AreaID = areaID
+	}
+
+	summaries, err := uc.db.Summarize(userID, filter)
+	if err != nil {
+		log.Printf("ERROR: [GetSiteSummary] Falló el resumen del sitio %d para UserID %d: %v", siteID, userID, err)
+		return nil, err
+	}
+	macs := make([]string, 0, len(summaries))
+	for _, s := range summaries {
+		macs = append(macs, s.Mac)
+	}
+	devices, errDevices := uc.deviceRepo.FindByMacs(macs)
+	if errDevices != nil {
+		// Los metadatos son informativos: se devuelve el resumen igualmente
+		log.Printf("ADVERTENCIA: [GetSiteSummary] No se pudieron adjuntar metadatos de dispositivos: %v", errDevices)
+	}
+	for i := range summaries {
+		summaries[i].Dispositivo = devices[summaries[i].Mac]
+	}
+	return &SiteSummary{Site: site, AreaID: areaID, Dispositivos: summaries}, nil
+}
@@ -0,0 +1,244 @@
+// File: inventory_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	consumptionDefaultDays = 30  // Días del reporte de consumo si no se indica rango
+	consumptionMaxDays     = 366 // Días máximos del reporte de consumo
+)
+
+// ProductInput DTO con los datos editables de un producto
+type ProductInput struct {
+	Nombre       string
+	PesoUnitario float64
+	Tara         float64
+	UmbralMinimo *int
+}
+
+// Inventory cuenta las unidades de un producto a partir del peso de su balanza y registra cada
+// reposición o consumo a partir de los cambios de peso confirmados por WeightEvents
+type Inventory struct {
+	productRepo domain.ProductRepository
+	deviceRepo  domain.DeviceRepository
+	userRepo    domain.UserRepository
+	orgRepo     domain.OrganizationRepository
+	shareRepo   domain.ShareRepository
+}
+
+func NewInventory(productRepo domain.ProductRepository, deviceRepo domain.DeviceRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *Inventory {
+	if productRepo == nil || deviceRepo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: Inventory recibió dependencias nulas (productRepo, deviceRepo, userRepo, orgRepo o shareRepo).")
+	}
+	return &Inventory{productRepo: productRepo, deviceRepo: deviceRepo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo}
+}
+
+// OnWeightEvent convierte un cambio de peso en un movimiento de inventario del producto de la
+// balanza. Los cambios menores que media unidad no generan movimiento.
+func (uc *Inventory) OnWeightEvent(event entities.WeightEvent) {
+	product, err := uc.productRepo.FindByMac(event.Mac)
+	if err != nil {
+		log.Printf("ERROR: [Inventory] %v", err)
+		return
+	}
+	if product == nil {
+		return
+	}
+	antes, despues := product.Units(event.PesoAnterior), product.Units(event.PesoResultante)
+	if antes == despues {
+		return
+	}
+	movement := &entities.InventoryMovement{ProductID: product.ID, WeightEventID: event.ID, Tipo: entities.InventoryReposicion,
+		Unidades: despues - antes, UnidadesResultantes: despues, Fecha: event.Confirmado}
+	if movement.Unidades < 0 {
+		movement.Tipo, movement.Unidades = entities.InventoryConsumo, -movement.Unidades
+	}
+	if err := uc.productRepo.CreateMovement(movement); err != nil {
+		log.Printf("ERROR: [Inventory] %v", err)
+		return
+	}
+	if product.UmbralMinimo != nil && despues <= *product.UmbralMinimo && antes > *product.UmbralMinimo {
+		log.Printf("INFO: [Inventory] Stock bajo de '%s' (producto %d, MAC %s): %d unidades.", product.Nombre, product.ID, product.Mac, despues)
+	}
+}
+
+// List devuelve los productos de los dispositivos a los que el usuario tiene acceso; con soloBajo,
+// solo los que están en o por debajo de su umbral mínimo
+func (uc *Inventory) List(userID int, soloBajo bool) ([]entities.Product, error) {
+	devices, err := uc.deviceRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	macs := make([]string, 0, len(devices))
+	for _, device := range devices {
+		macs = append(macs, device.Mac)
+	}
+	products, err := uc.productRepo.FindByMacs(macs)
+	if err != nil || !soloBajo {
+		return products, err
+	}
+	bajos := []entities.Product{}
+	for _, product := range products {
+		if product.Stock != nil && product.Stock.Estado == entities.StockBajo {
+			bajos = append(bajos, product)
+		}
+	}
+	return bajos, nil
+}
+
+// find devuelve el producto si el usuario puede ver su dispositivo (o editarlo, con escritura)
+func (uc *Inventory) find(userID int, id int, escritura bool) (*entities.Product, error) {
+	product, err := uc.productRepo.FindByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("producto_no_encontrado")
+		}
+		return nil, err
+	}
+	if escritura {
+		err = checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, product.Mac, accesoEscritura)
+	} else {
+		_, err = checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, product.Mac)
+	}
+	if err != nil {
+		if err.Error() == "dispositivo_no_encontrado" {
+			return nil, fmt.Errorf("producto_no_encontrado")
+		}
+		return nil, err
+	}
+	return product, nil
+}
+
+// Get devuelve un producto con su stock actual
+func (uc *Inventory) Get(userID int, id int) (*entities.Product, error) {
+	return uc.find(userID, id, false)
+}
+
+func validateProduct(product *entities.Product, input ProductInput) error {
+	product.Nombre = strings.TrimSpace(input.Nombre)
+	if product.Nombre == "" || len(product.Nombre) > 100 {
+		return fmt.Errorf("nombre_invalido")
+	}
+	if !(input.PesoUnitario > 0) || math.IsInf(input.PesoUnitario, 0) || !(input.Tara >= 0) || math.IsInf(input.Tara, 0) {
+		return fmt.Errorf("peso_invalido")
+	}
+	if input.UmbralMinimo != nil && *input.UmbralMinimo < 0 {
+		return fmt.Errorf("umbral_invalido")
+	}
+	product.PesoUnitario, product.Tara, product.UmbralMinimo = input.PesoUnitario, input.Tara, input.UmbralMinimo
+	return nil
+}
+
+// Create registra el producto de la balanza de un dispositivo que el usuario puede editar
+func (uc *Inventory) Create(userID int, mac string, input ProductInput) (*entities.Product, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return nil, err
+	}
+	product := &entities.Product{Mac: mac}
+	if err := validateProduct(product, input); err != nil {
+		return nil, err
+	}
+	if err := uc.productRepo.Create(product); err != nil {
+		return nil, err
+	}
+	log.Printf("INFO: [Inventory] Producto %d ('%s') creado en MAC %s por UserID %d.", product.ID, product.Nombre, mac, userID)
+	return uc.productRepo.FindByID(product.ID)
+}
+
+// Update cambia nombre, pesos o umbral; el historial de movimientos se conserva tal como se registró
+func (uc *Inventory) Update(userID int, id int, input ProductInput) (*entities.Product, error) {
+	product, err := uc.find(userID, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateProduct(product, input); err != nil {
+		return nil, err
+	}
+	if err := uc.productRepo.Update(product); err != nil {
+		return nil, err
+	}
+	return uc.productRepo.FindByID(id)
+}
+
+// Delete borra el producto y su historial de movimientos
+func (uc *Inventory) Delete(userID int, id int) error {
+	if _, err := uc.find(userID, id, true); err != nil {
+		return err
+	}
+	return uc.productRepo.Delete(id)
+}
+
+// Movements lista las reposiciones y consumos del producto en [desde, hasta); sin rango, los de
+// las últimas 24 horas
+func (uc *Inventory) Movements(userID int, id int, desde time.Time, hasta time.Time, limit int) ([]entities.InventoryMovement, error) {
+	if _, err := uc.find(userID, id, false); err != nil {
+		return nil, err
+	}
+	desde, hasta, limit, err := deviceEventsRange(desde, hasta, limit)
+	if err != nil {
+		return nil, err
+	}
+	return uc.productRepo.FindMovements(id, desde, hasta, limit)
+}
+
+// Consumption arma el consumo diario del producto entre los días desde y hasta (ambos incluidos,
+// hora local del servidor); sin rango, los últimos 30 días hasta hoy. Los días sin movimientos
+// se informan en cero.
+func (uc *Inventory) Consumption(userID int, id int, desde time.Time, hasta time.Time) (*entities.ConsumptionReport, error) {
+	product, err := uc.find(userID, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if hasta.IsZero() {
+		hasta = time.Now()
+	}
+	hasta = startOfDay(hasta)
+	if desde.IsZero() {
+		desde = hasta.AddDate(0, 0, -(consumptionDefaultDays - 1))
+	}
+	desde = startOfDay(desde)
+	if hasta.Before(desde) || desde.AddDate(0, 0, consumptionMaxDays).Before(hasta) {
+		return nil, fmt.Errorf("rango_invalido")
+	}
+	fin := hasta.AddDate(0, 0, 1)
+	found, err := uc.productRepo.DailyConsumption(id, desde, fin)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]entities.DailyConsumption, len(found))
+	for _, day := range found {
+		byDay[day.Dia] = day
+	}
+	report := &entities.ConsumptionReport{Producto: *product, Desde: desde.Format("2006-01-02"), Hasta: hasta.Format("2006-01-02"), Dias: []entities.DailyConsumption{}}
+	for d := desde; d.Before(fin); d = d.AddDate(0, 0, 1) {
+		dia := d.Format("2006-01-02")
+		day, ok := byDay[dia]
+		if !ok {
+			day = entities.DailyConsumption{Dia: dia}
+		}
+		report.TotalConsumido += day.Consumido
+		report.TotalRepuesto += day.Repuesto
+		report.Dias = append(report.Dias, day)
+	}
+	report.PromedioDiario = float64(report.TotalConsumido) / float64(len(report.Dias))
+	if product.Stock != nil && report.PromedioDiario > 0 {
+		restantes := float64(product.Stock.Unidades) / report.PromedioDiario
+		report.DiasRestantes = &restantes
+	}
+	return report, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,273 @@
+// File: manageOrganizations_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// Niveles de acceso que exige cada operación dentro de una organización
+const (
+	accesoLectura   = iota // Cualquier rol (viewer incluido)
+	accesoEscritura        // owner, admin, member
+	accesoGestion          // owner, admin: miembros, mover dispositivos, borrar sitios
+)
+
+func roleAllows(role string, nivel int) bool {
+	switch role {
+	case entities.RolOrgOwner, entities.RolOrgAdmin:
+		return true
+	case entities.RolOrgMember:
+		return nivel <= accesoEscritura
+	case entities.RolOrgViewer:
+		return nivel == accesoLectura
+	}
+	return false
+}
+
+// roleLevel devuelve el mayor nivel de acceso que alcanza el rol (-1 si no es un rol)
+func roleLevel(role string) int {
+	for nivel := accesoGestion; nivel >= accesoLectura; nivel-- {
+		if roleAllows(role, nivel) {
+			return nivel
+		}
+	}
+	return -1
+}
+
+// checkOrgAccess devuelve el rol del usuario en la organización si alcanza el nivel pedido.
+// "organizacion_no_encontrada" si no es miembro (no se revela si existe); "permiso_insuficiente"
+// si es miembro pero su rol no alcanza.
+func checkOrgAccess(orgRepo domain.OrganizationRepository, userID int, orgID int, nivel int) (string, error) {
+	role, err := orgRepo.MemberRole(orgID, userID)
+	if err != nil {
+		return "", fmt.Errorf("error interno al verificar organización: %w", err)
+	}
+	if role == "" {
+		return "", fmt.Errorf("organizacion_no_encontrada")
+	}
+	if !roleAllows(role, nivel) {
+		log.Printf("WARN: [OrgAccess] UserID %d (rol '%s') sin permiso suficiente en organización %d", userID, role, orgID)
+		return role, fmt.Errorf("permiso_insuficiente")
+	}
+	return role, nil
+}
+
+// ManageOrganizations administra organizaciones, sus miembros y qué dispositivos les pertenecen
+type ManageOrganizations struct {
+	orgRepo   domain.OrganizationRepository
+	userRepo  domain.UserRepository
+	siteRepo  domain.SiteRepository
+	shareRepo domain.ShareRepository
+	revoker   domain.SubscriptionRevoker
+}
+
+func NewManageOrganizations(orgRepo domain.OrganizationRepository, userRepo domain.UserRepository, siteRepo domain.SiteRepository, shareRepo domain.ShareRepository, revoker domain.SubscriptionRevoker) *ManageOrganizations {
+	if orgRepo == nil || userRepo == nil || siteRepo == nil || shareRepo == nil || revoker == nil {
+		log.Fatal("Error: ManageOrganizations recibió dependencias nulas (orgRepo, userRepo, siteRepo, shareRepo o revoker).")
+	}
+	return &ManageOrganizations{orgRepo: orgRepo, userRepo: userRepo, siteRepo: siteRepo, shareRepo: shareRepo, revoker: revoker}
+}
+
+func validateOrgName(nombre string) (string, error) {
+	nombre = strings.TrimSpace(nombre)
+	if nombre == "" {
+		return "", fmt.Errorf("nombre_requerido")
+	}
+	if utf8.RuneCountInString(nombre) > 100 {
+		return "", fmt.Errorf("metadatos_demasiado_largos")
+	}
+	return nombre, nil
+}
+
+// List devuelve las organizaciones del usuario con su rol en cada una
+func (uc *ManageOrganizations) List(userID int) ([]entities.Organization, error) {
+	return uc.orgRepo.FindForUser(userID)
+}
+
+// Get devuelve la organización con sus miembros (para cualquier miembro)
+func (uc *ManageOrganizations) Get(userID int, orgID int) (*entities.Organization, error) {
+	role, err := checkOrgAccess(uc.orgRepo, userID, orgID, accesoLectura)
+	if err != nil {
+		return nil, err
+	}
+	org, err := uc.orgRepo.FindByID(orgID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("organizacion_no_encontrada")
+		}
+		return nil, err
+	}
+	org.Rol = role
+	return org, nil
+}
+
+// Create crea una organización con el usuario como owner
+func (uc *ManageOrganizations) Create(userID int, nombre string) (*entities.Organization, error) {
+	nombre, err := validateOrgName(nombre)
+	if err != nil {
+		return nil, err
+	}
+	org := &entities.Organization{Nombre: nombre, Rol: entities.RolOrgOwner}
+	if err := uc.orgRepo.Create(org, userID); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (uc *ManageOrganizations) Update(userID int, orgID int, nombre string) (*entities.Organization, error) {
+	nombre, err := validateOrgName(nombre)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkOrgAccess(uc.orgRepo, userID, orgID, accesoGestion); err != nil {
+		return nil, err
+	}
+	org := &entities.Organization{ID: orgID, Nombre: nombre}
+	if err := uc.orgRepo.Update(org); err != nil {
+		return nil, err
+	}
+	return uc.Get(userID, orgID)
+}
+
+// SetMember añade a un usuario (por username) o cambia su rol. Solo un owner puede
+// nombrar owners o modificar a otro owner; nunca se deja la organización sin owner.
+func (uc *ManageOrganizations) SetMember(userID int, orgID int, username string, role string) (*entities.Organization, error) {
+	if !entities.IsValidOrgRole(role) {
+		return nil, fmt.Errorf("rol_invalido")
+	}
+	callerRole, err := checkOrgAccess(uc.orgRepo, userID, orgID, accesoGestion)
+	if err != nil {
+		return nil, err
+	}
+	target, err := uc.userRepo.FindByUsername(strings.TrimSpace(username))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("usuario_no_encontrado")
+		}
+		return nil, err
+	}
+	currentRole, err := uc.orgRepo.MemberRole(orgID, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	if (role == entities.RolOrgOwner || currentRole == entities.RolOrgOwner) && callerRole != entities.RolOrgOwner {
+		return nil, fmt.Errorf("permiso_insuficiente")
+	}
+	if currentRole == entities.RolOrgOwner && role != entities.RolOrgOwner {
+		if err := uc.checkNotLastOwner(orgID); err != nil {
+			return nil, err
+		}
+	}
+	if err := uc.orgRepo.SetMember(orgID, target.ID, role); err != nil {
+		return nil, err
+	}
+	if currentRole != "" && roleLevel(role) < roleLevel(currentRole) {
+		uc.revoker.RecheckTopics(target.ID)
+	}
+	log.Printf("INFO: [ManageOrgs] UserID %d puso a '%s' como '%s' en organización %d.", userID, target.Username, role, orgID)
+	return uc.Get(userID, orgID)
+}
+
+// RemoveMember quita a un miembro. Cualquier miembro puede salir por sí mismo.
+func (uc *ManageOrganizations) RemoveMember(userID int, orgID int, targetUserID int) error {
+	nivel := accesoGestion
+	if targetUserID == userID {
+		nivel = accesoLectura
+	}
+	callerRole, err := checkOrgAccess(uc.orgRepo, userID, orgID, nivel)
+	if err != nil {
+		return err
+	}
+	targetRole, err := uc.orgRepo.MemberRole(orgID, targetUserID)
+	if err != nil {
+		return err
+	}
+	if targetRole == "" {
+		return fmt.Errorf("miembro_no_encontrado")
+	}
+	if targetRole == entities.RolOrgOwner {
+		if callerRole != entities.RolOrgOwner {
+			return fmt.Errorf("permiso_insuficiente")
+		}
+		if err := uc.checkNotLastOwner(orgID); err != nil {
+			return err
+		}
+	}
+	// Los sitios se leen antes de quitarlo: después ya no aparecen entre los suyos
+	sites, err := uc.siteRepo.FindByUserID(targetUserID)
+	if err != nil {
+		return err
+	}
+	if err := uc.orgRepo.RemoveMember(orgID, targetUserID); err != nil {
+		return err
+	}
+	uc.revokeOrgTopics(targetUserID, orgID, sites)
+	log.Printf("INFO: [ManageOrgs] UserID %d quitó a UserID %d de la organización %d.", userID, targetUserID, orgID)
+	return nil
+}
+
+// revokeOrgTopics retira de las conexiones en vivo del ex miembro los temas de la organización,
+// sus sitios y áreas, y los de sus dispositivos a los que ya no tiene acceso
+func (uc *ManageOrganizations) revokeOrgTopics(targetUserID int, orgID int, sites []entities.Site) {
+	uc.revoker.RevokeTopic(targetUserID, domain.TopicOrg(orgID))
+	for _, site := range sites {
+		if site.OrgID != orgID {
+			continue
+		}
+		uc.revoker.RevokeTopic(targetUserID, domain.TopicSite(site.ID))
+		for _, area := range site.Areas {
+			uc.revoker.RevokeTopic(targetUserID, domain.TopicArea(area.ID))
+		}
+	}
+	uc.revoker.RecheckTopics(targetUserID)
+}
+
+func (uc *ManageOrganizations) checkNotLastOwner(orgID int) error {
+	owners, err := uc.orgRepo.CountOwners(orgID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return fmt.Errorf("ultimo_owner")
+	}
+	return nil
+}
+
+// MoveDevice pasa un dispositivo a otra organización. Requiere gestión en ambas; las lecturas
+// ya guardadas siguen en la organización original, el dispositivo queda sin área y se revocan
+// los accesos compartidos que concedió la organización anterior y las suscripciones en vivo de
+// los miembros que no tienen acceso en la nueva.
+func (uc *ManageOrganizations) MoveDevice(userID int, mac string, orgID int) error {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoGestion); err != nil {
+		return err
+	}
+	if _, err := checkOrgAccess(uc.orgRepo, userID, orgID, accesoGestion); err != nil {
+		return err
+	}
+	currentOrg, err := uc.orgRepo.DeviceOrg(mac)
+	if err != nil {
+		return err
+	}
+	if currentOrg == orgID {
+		return nil
+	}
+	if err := uc.siteRepo.SetDeviceArea(mac, nil); err != nil {
+		return err
+	}
+	if err := revokeDeviceShares(uc.shareRepo, uc.revoker, mac); err != nil {
+		return err
+	}
+	if err := uc.orgRepo.SetDeviceOrg(mac, orgID); err != nil {
+		return err
+	}
+	revokeFormerAccess(uc.orgRepo, uc.userRepo, uc.shareRepo, uc.revoker, mac, currentOrg)
+	log.Printf("INFO: [ManageOrgs] UserID %d movió MAC %s de la organización %d a la %d.", userID, mac, currentOrg, orgID)
+	return nil
+}
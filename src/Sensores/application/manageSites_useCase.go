@@ -0,0 +1,227 @@
+// File: manageSites_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// SiteInput DTO para crear/editar sitios y áreas
+type SiteInput struct {
+	Nombre      string
+	Descripcion string
+}
+
+// ManageSites administra la jerarquía sitio > área > dispositivo de las organizaciones del usuario
+type ManageSites struct {
+	siteRepo   domain.SiteRepository
+	deviceRepo domain.DeviceRepository
+	userRepo   domain.UserRepository
+	orgRepo    domain.OrganizationRepository
+}
+
+func NewManageSites(siteRepo domain.SiteRepository, deviceRepo domain.DeviceRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository) *ManageSites {
+	if siteRepo == nil || deviceRepo == nil || userRepo == nil || orgRepo == nil {
+		log.Fatal("Error: ManageSites recibió dependencias nulas (siteRepo, deviceRepo, userRepo u orgRepo).")
+	}
+	return &ManageSites{siteRepo: siteRepo, deviceRepo: deviceRepo, userRepo: userRepo, orgRepo: orgRepo}
+}
+
+func validateSiteInput(input SiteInput) (SiteInput, error) {
+	input.Nombre = strings.TrimSpace(input.Nombre)
+	input.Descripcion = strings.TrimSpace(input.Descripcion)
+	if input.Nombre == "" {
+		return input, fmt.Errorf("nombre_requerido")
+	}
+	if utf8.RuneCountInString(input.Nombre) > 100 || utf8.RuneCountInString(input.Descripcion) > 500 {
+		return input, fmt.Errorf("metadatos_demasiado_largos")
+	}
+	return input, nil
+}
+
+// accessibleSite carga el sitio y comprueba el rol del usuario en su organización
+func (uc *ManageSites) accessibleSite(userID int, siteID int, nivel int) (*entities.Site, error) {
+	return findAccessibleSite(uc.siteRepo, uc.orgRepo, userID, siteID, nivel)
+}
+
+// findAccessibleSite devuelve "sitio_no_encontrado" si el usuario no es miembro de la
+// organización del sitio y "permiso_insuficiente" si su rol no alcanza el nivel pedido.
+func findAccessibleSite(siteRepo domain.SiteRepository, orgRepo domain.OrganizationRepository, userID int, siteID int, nivel int) (*entities.Site, error) {
+	site, err := siteRepo.FindByID(siteID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("sitio_no_encontrado")
+		}
+		return nil, err
+	}
+	if _, err := checkOrgAccess(orgRepo, userID, site.OrgID, nivel); err != nil {
+		if err.Error() == "organizacion_no_encontrada" {
+			log.Printf("WARN: [ManageSites] UserID %d intentó acceder al sitio %d de la organización %d", userID, siteID, site.OrgID)
+			return nil, fmt.Errorf("sitio_no_encontrado")
+		}
+		return nil, err
+	}
+	return site, nil
+}
+
+// findAccessibleArea carga el área y comprueba el acceso a su sitio ("area_no_encontrada" si no)
+func findAccessibleArea(siteRepo domain.SiteRepository, orgRepo domain.OrganizationRepository, userID int, areaID int, nivel int) (*entities.Area, *entities.Site, error) {
+	area, err := siteRepo.FindArea(areaID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("area_no_encontrada")
+		}
+		return nil, nil, err
+	}
+	site, err := findAccessibleSite(siteRepo, orgRepo, userID, area.SiteID, nivel)
+	if err != nil {
+		if err.Error() == "sitio_no_encontrado" {
+			return nil, nil, fmt.Errorf("area_no_encontrada")
+		}
+		return nil, nil, err
+	}
+	return area, site, nil
+}
+
+// List devuelve los sitios de las organizaciones del usuario con sus áreas
+func (uc *ManageSites) List(userID int) ([]entities.Site, error) {
+	return uc.siteRepo.FindByUserID(userID)
+}
+
+// Get devuelve el árbol completo del sitio, con metadatos de cada dispositivo
+func (uc *ManageSites) Get(userID int, siteID int) (*entities.Site, error) {
+	site, err := uc.accessibleSite(userID, siteID, accesoLectura)
+	if err != nil {
+		return nil, err
+	}
+	macs := []string{}
+	for _, area := range site.Areas {
+		for _, d := range area.Dispositivos {
+			macs = append(macs, d.Mac)
+		}
+	}
+	devices, err := uc.deviceRepo.FindByMacs(macs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range site.Areas {
+		for j, d := range site.Areas[i].Dispositivos {
+			if full, ok := devices[d.Mac]; ok {
+				site.Areas[i].Dispositivos[j] = *full
+			}
+		}
+	}
+	return site, nil
+}
+
+// Create crea un sitio en la organización indicada (orgID 0 = organización personal)
+func (uc *ManageSites) Create(userID int, orgID int, input SiteInput) (*entities.Site, error) {
+	input, err := validateSiteInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if orgID <= 0 {
+		if orgID, err = uc.orgRepo.PersonalOrgID(userID); err != nil {
+			return nil, fmt.Errorf("error al buscar organización personal: %w", err)
+		}
+	}
+	if _, err := checkOrgAccess(uc.orgRepo, userID, orgID, accesoEscritura); err != nil {
+		return nil, err
+	}
+	site := &entities.Site{UserID: userID, OrgID: orgID, Nombre: input.Nombre, Descripcion: input.Descripcion, Areas: []entities.Area{}}
+	if err := uc.siteRepo.Create(site); err != nil {
+		return nil, err
+	}
+	return site, nil
+}
+
+func (uc *ManageSites) Update(userID int, siteID int, input SiteInput) (*entities.Site, error) {
+	input, err := validateSiteInput(input)
+	if err != nil {
+		return nil, err
+	}
+	site, err := uc.accessibleSite(userID, siteID, accesoEscritura)
+	if err != nil {
+		return nil, err
+	}
+	site.Nombre, site.Descripcion = input.Nombre, input.Descripcion
+	if err := uc.siteRepo.Update(site); err != nil {
+		return nil, err
+	}
+	return site, nil
+}
+
+func (uc *ManageSites) Delete(userID int, siteID int) error {
+	if _, err := uc.accessibleSite(userID, siteID, accesoGestion); err != nil {
+		return err
+	}
+	return uc.siteRepo.Delete(siteID)
+}
+
+func (uc *ManageSites) CreateArea(userID int, siteID int, input SiteInput) (*entities.Area, error) {
+	input, err := validateSiteInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.accessibleSite(userID, siteID, accesoEscritura); err != nil {
+		return nil, err
+	}
+	area := &entities.Area{SiteID: siteID, Nombre: input.Nombre, Descripcion: input.Descripcion, Dispositivos: []entities.Device{}}
+	if err := uc.siteRepo.CreateArea(area); err != nil {
+		return nil, err
+	}
+	return area, nil
+}
+
+func (uc *ManageSites) UpdateArea(userID int, areaID int, input SiteInput) (*entities.Area, error) {
+	input, err := validateSiteInput(input)
+	if err != nil {
+		return nil, err
+	}
+	area, _, err := findAccessibleArea(uc.siteRepo, uc.orgRepo, userID, areaID, accesoEscritura)
+	if err != nil {
+		return nil, err
+	}
+	area.Nombre, area.Descripcion = input.Nombre, input.Descripcion
+	if err := uc.siteRepo.UpdateArea(area); err != nil {
+		return nil, err
+	}
+	return area, nil
+}
+
+func (uc *ManageSites) DeleteArea(userID int, areaID int) error {
+	if _, _, err := findAccessibleArea(uc.siteRepo, uc.orgRepo, userID, areaID, accesoEscritura); err != nil {
+		return err
+	}
+	return uc.siteRepo.DeleteArea(areaID)
+}
+
+// AssignDevice ubica un dispositivo en un área de su misma organización (areaID nil = quitarlo)
+func (uc *ManageSites) AssignDevice(userID int, mac string, areaID *int) (*entities.Device, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoEscritura); err != nil {
+		return nil, err
+	}
+	if areaID != nil {
+		_, site, err := findAccessibleArea(uc.siteRepo, uc.orgRepo, userID, *areaID, accesoEscritura)
+		if err != nil {
+			return nil, err
+		}
+		deviceOrg, err := uc.orgRepo.DeviceOrg(mac)
+		if err != nil {
+			return nil, err
+		}
+		if deviceOrg != 0 && deviceOrg != site.OrgID {
+			return nil, fmt.Errorf("organizacion_distinta")
+		}
+	}
+	if err := uc.siteRepo.SetDeviceArea(mac, areaID); err != nil {
+		return nil, err
+	}
+	return uc.deviceRepo.FindByMac(mac)
+}
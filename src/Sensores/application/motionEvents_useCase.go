@@ -0,0 +1,226 @@
+// File: motionEvents_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	motionDefaultGap      = 60 // Segundos sin movimiento que toleran un intervalo antes de cerrarlo
+	motionDefaultDebounce = 0  // Segundos que debe durar un intervalo para reportarse
+	motionSweepInterval   = 30 * time.Second
+	deviceEventsDefault   = 100
+	deviceEventsMax       = 1000
+)
+
+// MotionEvents convierte las muestras de movimiento de cada lectura en intervalos con inicio, fin
+// y duración. Un intervalo sigue abierto mientras las muestras con movimiento lleguen a menos de
+// 'gap' unas de otras, y solo se reporta (y notifica) cuando dura al menos 'debounce'.
+type MotionEvents struct {
+	repo       domain.MotionEventRepository
+	deviceRepo domain.DeviceRepository
+	userRepo   domain.UserRepository
+	orgRepo    domain.OrganizationRepository
+	shareRepo  domain.ShareRepository
+	notifier   domain.DatosNotifier
+	gap        time.Duration
+	debounce   time.Duration
+	locks      *macLocks // Serializa la ingesta y el barrido de cada dispositivo
+}
+
+func NewMotionEvents(repo domain.MotionEventRepository, deviceRepo domain.DeviceRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository, notifier domain.DatosNotifier) *MotionEvents {
+	if repo == nil || deviceRepo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil || notifier == nil {
+		log.Fatal("Error: MotionEvents recibió dependencias nulas (repo, deviceRepo, userRepo, orgRepo, shareRepo o notifier).")
+	}
+	return &MotionEvents{
+		repo: repo, deviceRepo: deviceRepo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo, notifier: notifier,
+		gap:      secondsFromEnv("MOTION_GAP_SEG", motionDefaultGap),
+		debounce: secondsFromEnv("MOTION_DEBOUNCE_SEG", motionDefaultDebounce),
+		locks:    newMacLocks(),
+	}
+}
+
+// secondsFromEnv lee una duración en segundos (>= 0) de una variable de entorno
+func secondsFromEnv(name string, def int) time.Duration {
+	seconds := def
+	if raw := os.Getenv(name); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			seconds = n
+		} else {
+			log.Printf("ADVERTENCIA: %s inválido ('%s'); se usan %d segundos.", name, raw, def)
+		}
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Process incorpora la muestra de movimiento de una lectura recién guardada. Las muestras vacías
+// o anteriores a la última procesada del dispositivo se ignoran.
+func (uc *MotionEvents) Process(data entities.Datos) {
+	moving := domain.ParseMotion(data.Movimiento)
+	if moving == nil {
+		return
+	}
+	defer uc.locks.lock(data.Mac)()
+	open, err := uc.repo.FindOpen(data.Mac)
+	if err != nil {
+		log.Printf("ERROR: [MotionEvents] %v", err)
+		return
+	}
+	if open != nil && data.Fecha.Before(open.UltimoMovimiento) {
+		return
+	}
+
+	if !*moving {
+		// Una muestra sin movimiento solo cierra el intervalo si ya se superó la tolerancia
+		if open != nil && data.Fecha.Sub(open.UltimoMovimiento) >= uc.gap {
+			uc.close(open, data.Dispositivo)
+		}
+		return
+	}
+	if open != nil && data.Fecha.Sub(open.UltimoMovimiento) > uc.gap {
+		uc.close(open, data.Dispositivo)
+		open = nil
+	}
+	if open == nil {
+		event := &entities.MotionEvent{Mac: data.Mac, UserID: data.UserID, OrgID: data.OrgID, Estado: entities.MotionCandidato,
+			Inicio: data.Fecha, UltimoMovimiento: data.Fecha, Muestras: 1}
+		if uc.debounce == 0 {
+			event.Estado = entities.MotionAbierto
+		}
+		if err := uc.repo.Create(event); err != nil {
+			log.Printf("ERROR: [MotionEvents] %v", err)
+			return
+		}
+		if event.Estado == entities.MotionAbierto {
+			uc.notify(entities.MotionNotifyInicio, event, data.Dispositivo)
+		}
+		return
+	}
+
+	open.UltimoMovimiento = data.Fecha
+	open.Muestras++
+	opened := open.Estado == entities.MotionCandidato && open.UltimoMovimiento.Sub(open.Inicio) >= uc.debounce
+	if opened {
+		open.Estado = entities.MotionAbierto
+	}
+	if err := uc.repo.Update(open); err != nil {
+		log.Printf("ERROR: [MotionEvents] %v", err)
+		return
+	}
+	if opened {
+		uc.notify(entities.MotionNotifyInicio, open, data.Dispositivo)
+	}
+}
+
+// close termina un intervalo en su última muestra con movimiento; los candidatos se descartan
+func (uc *MotionEvents) close(event *entities.MotionEvent, device *entities.Device) {
+	if event.Estado == entities.MotionCandidato {
+		if err := uc.repo.Delete(event.ID); err != nil {
+			log.Printf("ERROR: [MotionEvents] %v", err)
+		}
+		return
+	}
+	fin := event.UltimoMovimiento
+	event.Estado = entities.MotionCerrado
+	event.Fin = &fin
+	if err := uc.repo.Update(event); err != nil {
+		log.Printf("ERROR: [MotionEvents] %v", err)
+		return
+	}
+	uc.notify(entities.MotionNotifyFin, event, device)
+}
+
+func (uc *MotionEvents) notify(tipo string, event *entities.MotionEvent, device *entities.Device) {
+	end := event.UltimoMovimiento
+	if event.Fin != nil {
+		end = *event.Fin
+	}
+	event.DuracionSeg = end.Sub(event.Inicio).Seconds()
+	notification := entities.MotionNotification{Tipo: tipo, Evento: *event, Dispositivo: device}
+	if err := uc.notifier.NotifyMotionEvent(notification); err != nil {
+		log.Printf("ADVERTENCIA: [MotionEvents] Falló la notificación %s del intervalo %d: %v", tipo, event.ID, err)
+	}
+}
+
+// Run cierra periódicamente los intervalos de dispositivos que dejaron de enviar lecturas con
+// movimiento (p. ej. porque se apagaron). Se lanza con 'go' al arrancar.
+func (uc *MotionEvents) Run() {
+	ticker := time.NewTicker(motionSweepInterval)
+	defer ticker.Stop()
+	for {
+		uc.sweep()
+		<-ticker.C
+	}
+}
+
+func (uc *MotionEvents) sweep() {
+	limite := time.Now().Add(-uc.gap)
+	stale, err := uc.repo.FindStale(limite)
+	if err != nil {
+		log.Printf("ERROR: [MotionEvents] %v", err)
+		return
+	}
+	for _, event := range stale {
+		uc.closeStale(event.Mac, event.ID, limite)
+	}
+}
+
+// closeStale cierra el intervalo si, con el dispositivo bloqueado, sigue abierto y sin movimiento
+// desde antes del límite (una lectura pudo extenderlo después de FindStale)
+func (uc *MotionEvents) closeStale(mac string, id int, limite time.Time) {
+	defer uc.locks.lock(mac)()
+	open, err := uc.repo.FindOpen(mac)
+	if err != nil {
+		log.Printf("ERROR: [MotionEvents] %v", err)
+		return
+	}
+	if open == nil || open.ID != id || !open.UltimoMovimiento.Before(limite) {
+		return
+	}
+	var device *entities.Device
+	if open.Estado == entities.MotionAbierto {
+		device, _ = uc.deviceRepo.FindByMac(mac)
+	}
+	uc.close(open, device)
+}
+
+// List devuelve los intervalos de un dispositivo al que el usuario tiene acceso que se solapan con
+// [desde, hasta); sin rango, los de las últimas 24 horas
+func (uc *MotionEvents) List(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.MotionEvent, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	desde, hasta, limit, err := deviceEventsRange(desde, hasta, limit)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.FindByMac(userID, mac, desde, hasta, limit)
+}
+
+// deviceEventsRange completa el rango y el límite de las consultas de eventos de un dispositivo:
+// por defecto las últimas 24 horas y 100 eventos (máximo 1000)
+func deviceEventsRange(desde time.Time, hasta time.Time, limit int) (time.Time, time.Time, int, error) {
+	if hasta.IsZero() {
+		hasta = time.Now()
+	}
+	if desde.IsZero() {
+		desde = hasta.Add(-24 * time.Hour)
+	}
+	if !desde.Before(hasta) {
+		return desde, hasta, 0, fmt.Errorf("rango_invalido")
+	}
+	if limit <= 0 {
+		limit = deviceEventsDefault
+	}
+	if limit > deviceEventsMax {
+		limit = deviceEventsMax
+	}
+	return desde, hasta, limit, nil
+}
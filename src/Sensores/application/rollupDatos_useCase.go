@@ -0,0 +1,75 @@
+// File: rollupDatos_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"log"
+	"time"
+)
+
+const (
+	rollupInterval  = time.Minute
+	rollupBatchSize = 5000 // Lecturas nuevas revisadas por consulta
+	rollupHourBatch = 200  // Horas recalculadas por lote
+)
+
+// RollupDatos mantiene en segundo plano los resúmenes por hora y por día de las lecturas
+type RollupDatos struct {
+	repo domain.RollupRepository
+}
+
+func NewRollupDatos(repo domain.RollupRepository) *RollupDatos {
+	if repo == nil {
+		log.Fatal("Error: RollupDatos recibió dependencias nulas (repo).")
+	}
+	return &RollupDatos{repo: repo}
+}
+
+// Run recalcula cada minuto las horas con lecturas nuevas, editadas o borradas. Se lanza con 'go'
+// al arrancar; la primera vez recorre todo el historial por lotes.
+func (uc *RollupDatos) Run() {
+	ticker := time.NewTicker(rollupInterval)
+	defer ticker.Stop()
+	for {
+		uc.catchUp()
+		<-ticker.C
+	}
+}
+
+// catchUp procesa todo lo pendiente; si lo consigue, los resúmenes quedan completos hasta el
+// inicio de la hora en que empezó
+func (uc *RollupDatos) catchUp() {
+	start := time.Now()
+	marked, processed := 0, 0
+	for {
+		n, err := uc.repo.MarkPending(rollupBatchSize)
+		if err != nil {
+			log.Printf("ERROR: [RollupDatos] %v", err)
+			return
+		}
+		marked += n
+		if n < rollupBatchSize {
+			break
+		}
+	}
+	for {
+		n, err := uc.repo.ProcessPending(rollupHourBatch)
+		if err != nil {
+			log.Printf("ERROR: [RollupDatos] %v", err)
+			return
+		}
+		processed += n
+		if n < rollupHourBatch {
+			break
+		}
+	}
+	hasta := time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, time.Local)
+	if err := uc.repo.SetCoverage(hasta); err != nil {
+		log.Printf("ERROR: [RollupDatos] No se pudo guardar la cobertura de los resúmenes: %v", err)
+		return
+	}
+	if processed > 0 {
+		log.Printf("INFO: [RollupDatos] %d lecturas nuevas, %d horas recalculadas en %s.", marked, processed, time.Since(start).Round(time.Millisecond))
+	}
+}
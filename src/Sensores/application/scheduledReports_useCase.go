@@ -0,0 +1,629 @@
+// File: scheduledReports_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"bytes"
+	"database/sql"
+	"fmt"
+	"html/template"
+	"log"
+	"net/mail"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	reportCheckInterval    = time.Minute
+	reportDefaultHour      = 7    // Hora local de envío si no se indica
+	reportDefaultGap       = 3600 // Segundos sin lecturas que se informan como hueco
+	reportMaxSubscriptions = 10   // Suscripciones por usuario
+	reportMaxGapRows       = 200  // Huecos detallados en el HTML (el total se cuenta igual)
+	reportListDefault      = 50
+	reportListMax          = 200
+
+	// Límites de envío: una dirección ajena no debe recibir correos sin haberlos confirmado ni
+	// en ráfagas
+	reportConfirmCooldown = 10 * time.Minute // Entre emails de confirmación de una suscripción
+	reportSendNowWindow   = time.Hour
+	reportSendNowMax      = 3 // Reportes por suscripción en reportSendNowWindow (incluye los programados)
+)
+
+// ReportSubscriptionInput DTO con la suscripción pedida por el cliente
+type ReportSubscriptionInput struct {
+	Frecuencia string
+	Zona       string // "" = UTC
+	Hora       *int   // nil = reportDefaultHour
+	Email      string
+	Activa     *bool // nil = activa
+}
+
+// ScheduledReports genera y envía por email los reportes periódicos de las suscripciones: un
+// resumen HTML con las estadísticas, alertas y huecos de datos de los dispositivos del usuario en
+// el periodo anterior. Cada reporte queda guardado para consultarlo por la API.
+type ScheduledReports struct {
+	repo           domain.ScheduledReportRepository
+	db             domain.DatosRepository
+	deviceRepo     domain.DeviceRepository
+	productRepo    domain.ProductRepository
+	containerRepo  domain.ContainerRepository
+	complianceRepo domain.ComplianceRepository
+	mailer         domain.Mailer
+	gap            time.Duration
+}
+
+func NewScheduledReports(repo domain.ScheduledReportRepository, db domain.DatosRepository, deviceRepo domain.DeviceRepository, productRepo domain.ProductRepository, containerRepo domain.ContainerRepository, complianceRepo domain.ComplianceRepository, mailer domain.Mailer) *ScheduledReports {
+	if repo == nil || db == nil || deviceRepo == nil || productRepo == nil || containerRepo == nil || complianceRepo == nil || mailer == nil {
+		log.Fatal("Error: ScheduledReports recibió dependencias nulas (repo, db, deviceRepo, productRepo, containerRepo, complianceRepo o mailer).")
+	}
+	return &ScheduledReports{
+		repo: repo, db: db, deviceRepo: deviceRepo, productRepo: productRepo, containerRepo: containerRepo,
+		complianceRepo: complianceRepo, mailer: mailer, gap: secondsFromEnv("REPORT_GAP_SEG", reportDefaultGap),
+	}
+}
+
+// Run envía cada minuto los reportes de las suscripciones que llegaron a su próximo envío. Se
+// lanza con 'go' al arrancar.
+func (uc *ScheduledReports) Run() {
+	ticker := time.NewTicker(reportCheckInterval)
+	defer ticker.Stop()
+	for {
+		uc.sendDue(time.Now())
+		<-ticker.C
+	}
+}
+
+// sendDue genera el reporte del último periodo terminado de cada suscripción vencida. Si el
+// servidor estuvo detenido varios periodos, los intermedios no se generan.
+func (uc *ScheduledReports) sendDue(now time.Time) {
+	subs, err := uc.repo.FindDue(now)
+	if err != nil {
+		log.Printf("ERROR: [ScheduledReports] %v", err)
+		return
+	}
+	for _, sub := range subs {
+		loc, err := time.LoadLocation(sub.Zona)
+		if err != nil {
+			log.Printf("ERROR: [ScheduledReports] Zona '%s' de la suscripción %d inválida: %v", sub.Zona, sub.ID, err)
+			loc = time.UTC
+		}
+		inicio := reportPeriodStart(sub.Frecuencia, now.In(loc))
+		if reportRunTime(inicio, sub.Hora).After(now) {
+			inicio = reportShift(sub.Frecuencia, inicio, -1)
+		}
+		if _, err := uc.deliver(sub, loc, reportShift(sub.Frecuencia, inicio, -1), inicio); err != nil {
+			log.Printf("ERROR: [ScheduledReports] Falló el reporte de la suscripción %d: %v", sub.ID, err)
+		}
+		if err := uc.repo.MarkRun(sub.ID, reportNextRun(sub.Frecuencia, sub.Hora, loc, now), now); err != nil {
+			log.Printf("ERROR: [ScheduledReports] %v", err)
+		}
+	}
+}
+
+// reportPeriodStart devuelve el comienzo (medianoche en la zona de t) del día, la semana (lunes)
+// o el mes que contiene t
+func reportPeriodStart(frecuencia string, t time.Time) time.Time {
+	switch frecuencia {
+	case entities.ReportSemanal:
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case entities.ReportMensual:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// reportShift mueve n periodos el comienzo de un periodo. Se usa time.Date para que los cambios de
+// horario no corran la medianoche.
+func reportShift(frecuencia string, inicio time.Time, n int) time.Time {
+	switch frecuencia {
+	case entities.ReportSemanal:
+		return time.Date(inicio.Year(), inicio.Month(), inicio.Day()+7*n, 0, 0, 0, 0, inicio.Location())
+	case entities.ReportMensual:
+		return time.Date(inicio.Year(), inicio.Month()+time.Month(n), 1, 0, 0, 0, 0, inicio.Location())
+	}
+	return time.Date(inicio.Year(), inicio.Month(), inicio.Day()+n, 0, 0, 0, 0, inicio.Location())
+}
+
+// reportRunTime es el envío correspondiente al periodo que empieza en inicio (resume el anterior)
+func reportRunTime(inicio time.Time, hora int) time.Time {
+	return time.Date(inicio.Year(), inicio.Month(), inicio.Day(), hora, 0, 0, 0, inicio.Location())
+}
+
+// reportNextRun devuelve el primer envío posterior a after
+func reportNextRun(frecuencia string, hora int, loc *time.Location, after time.Time) time.Time {
+	inicio := reportPeriodStart(frecuencia, after.In(loc))
+	run := reportRunTime(inicio, hora)
+	if !run.After(after) {
+		run = reportRunTime(reportShift(frecuencia, inicio, 1), hora)
+	}
+	return run
+}
+
+func (uc *ScheduledReports) validate(input ReportSubscriptionInput) (*entities.ReportSubscription, *time.Location, error) {
+	if input.Frecuencia != entities.ReportDiario && input.Frecuencia != entities.ReportSemanal && input.Frecuencia != entities.ReportMensual {
+		return nil, nil, fmt.Errorf("frecuencia_invalida")
+	}
+	if input.Zona == "" {
+		input.Zona = "UTC"
+	}
+	loc, err := time.LoadLocation(input.Zona)
+	if err != nil || input.Zona == "Local" {
+		return nil, nil, fmt.Errorf("zona_invalida")
+	}
+	hora := reportDefaultHour
+	if input.Hora != nil {
+		hora = *input.Hora
+	}
+	if hora < 0 || hora > 23 {
+		return nil, nil, fmt.Errorf("hora_invalida")
+	}
+	addr, err := mail.ParseAddress(input.Email)
+	if err != nil {
+		return nil, nil, fmt.Errorf("email_invalido")
+	}
+	activa := input.Activa == nil || *input.Activa
+	return &entities.ReportSubscription{Frecuencia: input.Frecuencia, Zona: input.Zona, Hora: hora, Email: addr.Address, Activa: activa}, loc, nil
+}
+
+// requestConfirmation prepara un enlace de confirmación nuevo para el email de la suscripción;
+// hasta que se use, la suscripción no envía reportes
+func requestConfirmation(sub *entities.ReportSubscription, now time.Time) error {
+	if sub.ConfirmacionEnviada != nil && now.Sub(*sub.ConfirmacionEnviada) < reportConfirmCooldown {
+		return fmt.Errorf("demasiados_envios")
+	}
+	token, err := newInviteToken()
+	if err != nil {
+		return fmt.Errorf("error al generar confirmación: %w", err)
+	}
+	sub.EmailConfirmado, sub.Token, sub.ConfirmacionEnviada = false, token, &now
+	return nil
+}
+
+// sendConfirmation envía el enlace de confirmación; un fallo se registra y se puede reenviar
+func (uc *ScheduledReports) sendConfirmation(sub *entities.ReportSubscription) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Se pidió enviar a esta dirección un reporte %s de dispositivos.\n\n", sub.Frecuencia)
+	if base := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/"); base != "" {
+		fmt.Fprintf(&b, "Para recibirlo, confirma la dirección: %s/report-confirmations/%s\n", base, sub.Token)
+	} else {
+		fmt.Fprintf(&b, "Para recibirlo, confirma la dirección con este código: %s\n", sub.Token)
+	}
+	b.WriteString("Si no lo pediste, ignora este mensaje: no recibirás ningún reporte.\n")
+	if err := uc.mailer.Send(sub.Email, "Confirma la suscripción a reportes", b.String()); err != nil {
+		log.Printf("ADVERTENCIA: [ScheduledReports] No se pudo enviar la confirmación de la suscripción %d a %s: %v", sub.ID, sub.Email, err)
+	}
+}
+
+// CreateSubscription registra una suscripción; el primer envío es el próximo comienzo de periodo
+// después de confirmar el email
+func (uc *ScheduledReports) CreateSubscription(userID int, input ReportSubscriptionInput) (*entities.ReportSubscription, error) {
+	sub, loc, err := uc.validate(input)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := uc.repo.FindSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= reportMaxSubscriptions {
+		return nil, fmt.Errorf("demasiadas_suscripciones")
+	}
+	now := time.Now()
+	sub.UserID, sub.Creado = userID, now
+	sub.ProximoEnvio = reportNextRun(sub.Frecuencia, sub.Hora, loc, now)
+	if err := requestConfirmation(sub, now); err != nil {
+		return nil, err
+	}
+	if err := uc.repo.CreateSubscription(sub); err != nil {
+		return nil, err
+	}
+	uc.sendConfirmation(sub)
+	log.Printf("INFO: [ScheduledReports] Suscripción %d (%s) creada por UserID %d.", sub.ID, sub.Frecuencia, userID)
+	return sub, nil
+}
+
+// ListSubscriptions devuelve las suscripciones del usuario
+func (uc *ScheduledReports) ListSubscriptions(userID int) ([]entities.ReportSubscription, error) {
+	return uc.repo.FindSubscriptions(userID)
+}
+
+// getSubscription devuelve una suscripción del usuario; las ajenas se reportan como inexistentes
+func (uc *ScheduledReports) getSubscription(userID int, id int) (*entities.ReportSubscription, error) {
+	sub, err := uc.repo.FindSubscription(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("suscripcion_no_encontrada")
+		}
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, fmt.Errorf("suscripcion_no_encontrada")
+	}
+	return sub, nil
+}
+
+// UpdateSubscription reemplaza la configuración y reprograma el próximo envío. Cambiar el email
+// exige confirmarlo de nuevo.
+func (uc *ScheduledReports) UpdateSubscription(userID int, id int, input ReportSubscriptionInput) (*entities.ReportSubscription, error) {
+	current, err := uc.getSubscription(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	sub, loc, err := uc.validate(input)
+	if err != nil {
+		return nil, err
+	}
+	sub.ID, sub.UserID, sub.Creado, sub.UltimoEnvio = current.ID, current.UserID, current.Creado, current.UltimoEnvio
+	sub.EmailConfirmado, sub.Token, sub.ConfirmacionEnviada = current.EmailConfirmado, current.Token, current.ConfirmacionEnviada
+	now := time.Now()
+	sub.ProximoEnvio = reportNextRun(sub.Frecuencia, sub.Hora, loc, now)
+	emailNuevo := !strings.EqualFold(sub.Email, current.Email)
+	if emailNuevo {
+		if err := requestConfirmation(sub, now); err != nil {
+			return nil, err
+		}
+	}
+	if err := uc.repo.UpdateSubscription(sub); err != nil {
+		return nil, err
+	}
+	if emailNuevo {
+		uc.sendConfirmation(sub)
+	}
+	return sub, nil
+}
+
+// ResendConfirmation vuelve a enviar el enlace de confirmación de un email aún no confirmado
+func (uc *ScheduledReports) ResendConfirmation(userID int, id int) error {
+	sub, err := uc.getSubscription(userID, id)
+	if err != nil {
+		return err
+	}
+	if sub.EmailConfirmado {
+		return fmt.Errorf("email_ya_confirmado")
+	}
+	if err := requestConfirmation(sub, time.Now()); err != nil {
+		return err
+	}
+	if err := uc.repo.UpdateSubscription(sub); err != nil {
+		return err
+	}
+	uc.sendConfirmation(sub)
+	return nil
+}
+
+// ConfirmEmail activa los envíos de la suscripción cuyo enlace de confirmación se abrió. No
+// requiere sesión: lo usa quien recibe los correos, que puede no tener cuenta.
+func (uc *ScheduledReports) ConfirmEmail(token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return fmt.Errorf("confirmacion_no_encontrada")
+	}
+	ok, err := uc.repo.ConfirmEmail(token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("confirmacion_no_encontrada")
+	}
+	return nil
+}
+
+// DeleteSubscription deja de enviar reportes; los ya generados se conservan
+func (uc *ScheduledReports) DeleteSubscription(userID int, id int) error {
+	if _, err := uc.getSubscription(userID, id); err != nil {
+		return err
+	}
+	return uc.repo.DeleteSubscription(id)
+}
+
+// SendNow genera y envía en el momento el reporte del último periodo terminado, sin cambiar la
+// programación de la suscripción. Solo con el email confirmado y hasta reportSendNowMax reportes
+// por hora.
+func (uc *ScheduledReports) SendNow(userID int, id int) (*entities.ScheduledReport, error) {
+	sub, err := uc.getSubscription(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.EmailConfirmado {
+		return nil, fmt.Errorf("email_no_confirmado")
+	}
+	recientes, err := uc.repo.CountReportsSince(sub.ID, time.Now().Add(-reportSendNowWindow))
+	if err != nil {
+		return nil, err
+	}
+	if recientes >= reportSendNowMax {
+		return nil, fmt.Errorf("demasiados_envios")
+	}
+	loc, err := time.LoadLocation(sub.Zona)
+	if err != nil {
+		return nil, fmt.Errorf("zona_invalida")
+	}
+	inicio := reportPeriodStart(sub.Frecuencia, time.Now().In(loc))
+	return uc.deliver(*sub, loc, reportShift(sub.Frecuencia, inicio, -1), inicio)
+}
+
+// ListReports devuelve, sin el HTML, los reportes generados para el usuario
+func (uc *ScheduledReports) ListReports(userID int, limit int) ([]entities.ScheduledReport, error) {
+	if limit <= 0 {
+		limit = reportListDefault
+	}
+	if limit > reportListMax {
+		limit = reportListMax
+	}
+	return uc.repo.FindReports(userID, limit)
+}
+
+// GetReport devuelve un reporte del usuario con su HTML
+func (uc *ScheduledReports) GetReport(userID int, id int) (*entities.ScheduledReport, error) {
+	report, err := uc.repo.FindReport(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("reporte_no_encontrado")
+		}
+		return nil, err
+	}
+	if report.UserID != userID {
+		return nil, fmt.Errorf("reporte_no_encontrado")
+	}
+	return report, nil
+}
+
+// reportDevice es una fila de la tabla de dispositivos del reporte
+type reportDevice struct {
+	entities.DeviceSummary
+	Nombre string
+}
+
+// reportContent son los datos con que se arma el HTML
+type reportContent struct {
+	Titulo       string
+	Desde        time.Time
+	Hasta        time.Time
+	Zona         *time.Location
+	Dispositivos []reportDevice
+	Alertas      []entities.ReportAlert
+	Huecos       []entities.ReportGap
+	HuecosTotal  int
+	HuecoMinimo  time.Duration
+}
+
+// deliver arma el reporte de [desde, hasta), lo guarda y lo envía. Un fallo del envío queda
+// registrado en el reporte, que se puede volver a pedir con SendNow.
+func (uc *ScheduledReports) deliver(sub entities.ReportSubscription, loc *time.Location, desde time.Time, hasta time.Time) (*entities.ScheduledReport, error) {
+	content, err := uc.build(sub.UserID, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	content.Zona = loc
+	content.Titulo = reportTitle(sub.Frecuencia, desde.In(loc), hasta.In(loc))
+	var body bytes.Buffer
+	if err := scheduledReportHTML.Execute(&body, content); err != nil {
+		return nil, fmt.Errorf("error al generar el HTML del reporte: %w", err)
+	}
+	report := &entities.ScheduledReport{
+		SubscriptionID: sub.ID, UserID: sub.UserID, Frecuencia: sub.Frecuencia, Zona: sub.Zona, Desde: desde, Hasta: hasta,
+		Email: sub.Email, Asunto: content.Titulo, Dispositivos: len(content.Dispositivos), Alertas: len(content.Alertas),
+		Huecos: content.HuecosTotal, Generado: time.Now(), HTML: body.String(),
+	}
+	if err := uc.repo.CreateReport(report); err != nil {
+		return nil, err
+	}
+	if err := uc.mailer.SendHTML(sub.Email, report.Asunto, report.HTML); err != nil {
+		report.ErrorEnvio = err.Error()
+	} else {
+		enviado := time.Now()
+		report.Enviado = &enviado
+	}
+	if err := uc.repo.MarkSent(report.ID, report.Enviado, report.ErrorEnvio); err != nil {
+		log.Printf("ERROR: [ScheduledReports] %v", err)
+	}
+	log.Printf("INFO: [ScheduledReports] Reporte %d (suscripción %d) generado: %d dispositivos, %d alertas, %d huecos.",
+		report.ID, sub.ID, report.Dispositivos, report.Alertas, report.Huecos)
+	return report, nil
+}
+
+func reportTitle(frecuencia string, desde time.Time, hasta time.Time) string {
+	switch frecuencia {
+	case entities.ReportSemanal:
+		return fmt.Sprintf("Reporte semanal de sensores: %s al %s", desde.Format("2006-01-02"), hasta.AddDate(0, 0, -1).Format("2006-01-02"))
+	case entities.ReportMensual:
+		return fmt.Sprintf("Reporte mensual de sensores: %s", desde.Format("2006-01"))
+	}
+	return fmt.Sprintf("Reporte diario de sensores: %s", desde.Format("2006-01-02"))
+}
+
+// build reúne las estadísticas, alertas y huecos de los dispositivos visibles para el usuario
+func (uc *ScheduledReports) build(userID int, desde time.Time, hasta time.Time) (*reportContent, error) {
+	devices, err := uc.deviceRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	filter := domain.DatosFilter{Desde: desde, Hasta: hasta}
+	summaries, err := uc.db.Summarize(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	content := &reportContent{Desde: desde, Hasta: hasta, Dispositivos: []reportDevice{}, Alertas: []entities.ReportAlert{}, Huecos: []entities.ReportGap{}, HuecoMinimo: uc.gap}
+	names := make(map[string]string)
+	macs := []string{}
+	for _, d := range devices {
+		names[d.Mac] = d.Nombre
+		macs = append(macs, d.Mac)
+	}
+	bySummary := make(map[string]entities.DeviceSummary)
+	for _, s := range summaries {
+		bySummary[s.Mac] = s
+		if _, ok := names[s.Mac]; !ok {
+			names[s.Mac] = ""
+			macs = append(macs, s.Mac)
+		}
+	}
+	sort.Strings(macs)
+	for _, mac := range macs {
+		summary, ok := bySummary[mac]
+		if !ok {
+			summary = entities.DeviceSummary{Mac: mac}
+		}
+		content.Dispositivos = append(content.Dispositivos, reportDevice{DeviceSummary: summary, Nombre: names[mac]})
+	}
+
+	// Un recorrido ordenado por dispositivo y fecha da los huecos y la última lectura del periodo
+	latest := []entities.Datos{}
+	var last time.Time
+	lastMac := ""
+	closeDevice := func() {
+		if lastMac != "" && hasta.Sub(last) > uc.gap {
+			content.addGap(lastMac, last, hasta)
+		}
+	}
+	err = uc.db.StreamByUserID(userID, filter, domain.OrdenDispositivoFecha, func(d entities.Datos) error {
+		if d.Mac != lastMac {
+			closeDevice()
+			lastMac, last = d.Mac, desde
+			latest = append(latest, d)
+		}
+		if d.Fecha.Sub(last) > uc.gap {
+			content.addGap(d.Mac, last, d.Fecha)
+		}
+		last = d.Fecha
+		latest[len(latest)-1] = d
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	closeDevice()
+	for _, dev := range content.Dispositivos {
+		if dev.Lecturas == 0 {
+			content.addGap(dev.Mac, desde, hasta)
+		}
+	}
+
+	if err := uc.addAlerts(content, macs, latest); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (c *reportContent) addGap(mac string, desde time.Time, hasta time.Time) {
+	c.HuecosTotal++
+	if len(c.Huecos) < reportMaxGapRows {
+		c.Huecos = append(c.Huecos, entities.ReportGap{Mac: mac, Desde: desde, Hasta: hasta})
+	}
+}
+
+// addAlerts agrega los reportes de cumplimiento fallidos del periodo, los productos con stock
+// bajo y los contenedores que terminaron el periodo en nivel bajo o alto
+func (uc *ScheduledReports) addAlerts(content *reportContent, macs []string, latest []entities.Datos) error {
+	for _, mac := range macs {
+		reports, err := uc.complianceRepo.FindReports(mac, "", content.Desde, content.Hasta, 100)
+		if err != nil {
+			return err
+		}
+		for i := len(reports) - 1; i >= 0; i-- {
+			r := reports[i]
+			if r.Cumple {
+				continue
+			}
+			desde := r.Desde
+			detalle := fmt.Sprintf("Reporte de cumplimiento %s %d fuera de rango", r.Periodo, r.ID)
+			if r.PorcentajeEnRango != nil {
+				detalle = fmt.Sprintf("%s (%.1f %% del tiempo en rango)", detalle, *r.PorcentajeEnRango)
+			}
+			content.Alertas = append(content.Alertas, entities.ReportAlert{Tipo: entities.ReportAlertaCumplimiento, Mac: mac, Detalle: detalle, Fecha: &desde})
+		}
+	}
+	if len(macs) > 0 {
+		products, err := uc.productRepo.FindByMacs(macs)
+		if err != nil {
+			return err
+		}
+		for _, p := range products {
+			if p.Stock == nil || p.Stock.Estado != entities.StockBajo {
+				continue
+			}
+			actualizado := p.Stock.Actualizado
+			detalle := fmt.Sprintf("%s: %d unidades (mínimo %d)", p.Nombre, p.Stock.Unidades, *p.UmbralMinimo)
+			content.Alertas = append(content.Alertas, entities.ReportAlert{Tipo: entities.ReportAlertaStock, Mac: p.Mac, Detalle: detalle, Fecha: &actualizado})
+		}
+	}
+	if err := attachFillLevels(uc.containerRepo, latest); err != nil {
+		return err
+	}
+	for _, d := range latest {
+		if d.Llenado == nil || d.Llenado.Estado == entities.FillNormal {
+			continue
+		}
+		fecha := d.Fecha
+		detalle := fmt.Sprintf("Contenedor en nivel %s (%.1f %%)", d.Llenado.Estado, d.Llenado.Porcentaje)
+		content.Alertas = append(content.Alertas, entities.ReportAlert{Tipo: entities.ReportAlertaNivel, Mac: d.Mac, Detalle: detalle, Fecha: &fecha})
+	}
+	return nil
+}
+
+func reportNumber(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", *v), "0"), ".")
+}
+
+var scheduledReportHTML = template.Must(template.New("reporte").Funcs(template.FuncMap{
+	"fecha": func(t time.Time, loc *time.Location) string {
+		return t.In(loc).Format("2006-01-02 15:04")
+	},
+	"fechaPtr": func(t *time.Time, loc *time.Location) string {
+		if t == nil {
+			return "-"
+		}
+		return t.In(loc).Format("2006-01-02 15:04")
+	},
+	"numero": reportNumber,
+	"porcentaje": func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		p := *v * 100
+		return reportNumber(&p) + " %"
+	},
+	"duracion": func(desde time.Time, hasta time.Time) string {
+		return hasta.Sub(desde).Round(time.Minute).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>{{.Titulo}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.alerta { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{.Titulo}}</h1>
+<p>Periodo del {{fecha .Desde .Zona}} al {{fecha .Hasta .Zona}} ({{.Zona}})</p>
+<h2>Dispositivos ({{len .Dispositivos}})</h2>
+{{if .Dispositivos}}<table>
+<tr><th>Dispositivo</th><th>MAC</th><th>Lecturas</th><th>Última lectura</th><th>Temperatura prom. (mín / máx)</th><th>Distancia prom.</th><th>Peso prom.</th><th>Movimiento</th></tr>
+{{range .Dispositivos}}<tr><td>{{.Nombre}}</td><td>{{.Mac}}</td><td>{{.Lecturas}}</td><td>{{fechaPtr .Ultima $.Zona}}</td><td>{{numero .Temperatura.Promedio}} ({{numero .Temperatura.Min}} / {{numero .Temperatura.Max}})</td><td>{{numero .Distancia.Promedio}}</td><td>{{numero .Peso.Promedio}}</td><td>{{porcentaje .Movimiento}}</td></tr>
+{{end}}</table>{{else}}<p>No hay dispositivos.</p>{{end}}
+<h2>Alertas ({{len .Alertas}})</h2>
+{{if .Alertas}}<table>
+<tr><th>Tipo</th><th>MAC</th><th>Detalle</th><th>Fecha</th></tr>
+{{range .Alertas}}<tr class="alerta"><td>{{.Tipo}}</td><td>{{.Mac}}</td><td>{{.Detalle}}</td><td>{{fechaPtr .Fecha $.Zona}}</td></tr>
+{{end}}</table>{{else}}<p>Sin alertas.</p>{{end}}
+<h2>Huecos de datos ({{.HuecosTotal}})</h2>
+<p>Intervalos de más de {{.HuecoMinimo}} sin lecturas.</p>
+{{if .Huecos}}<table>
+<tr><th>MAC</th><th>Desde</th><th>Hasta</th><th>Duración</th></tr>
+{{range .Huecos}}<tr><td>{{.Mac}}</td><td>{{fecha .Desde $.Zona}}</td><td>{{fecha .Hasta $.Zona}}</td><td>{{duracion .Desde .Hasta}}</td></tr>
+{{end}}</table>{{if lt (len .Huecos) .HuecosTotal}}<p>Se muestran los primeros {{len .Huecos}}.</p>{{end}}{{else}}<p>Sin huecos.</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,278 @@
+// File: shareDevices_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+)
+
+// ShareInput DTO para compartir un dispositivo con un usuario (Username) o por email (Email)
+type ShareInput struct {
+	Username string
+	Email    string
+	Permiso  string     // entities.PermisoLectura | entities.PermisoEscritura
+	Expira   *time.Time // nil = sin vencimiento
+}
+
+// ShareDevices permite a quien gestiona un dispositivo compartirlo con otros usuarios
+type ShareDevices struct {
+	shareRepo domain.ShareRepository
+	orgRepo   domain.OrganizationRepository
+	userRepo  domain.UserRepository
+	mailer    domain.Mailer
+	revoker   domain.SubscriptionRevoker
+}
+
+func NewShareDevices(shareRepo domain.ShareRepository, orgRepo domain.OrganizationRepository, userRepo domain.UserRepository, mailer domain.Mailer, revoker domain.SubscriptionRevoker) *ShareDevices {
+	if shareRepo == nil || orgRepo == nil || userRepo == nil || mailer == nil || revoker == nil {
+		log.Fatal("Error: ShareDevices recibió dependencias nulas (shareRepo, orgRepo, userRepo, mailer o revoker).")
+	}
+	return &ShareDevices{shareRepo: shareRepo, orgRepo: orgRepo, userRepo: userRepo, mailer: mailer, revoker: revoker}
+}
+
+// checkDeviceRead permite leer un dispositivo por rol en su organización o por acceso compartido.
+// Devuelve el vencimiento del acceso compartido (nil si el acceso no vence).
+func checkDeviceRead(orgRepo domain.OrganizationRepository, userRepo domain.UserRepository, shareRepo domain.ShareRepository, userID int, mac string) (*time.Time, error) {
+	err := checkDeviceAccess(orgRepo, userRepo, userID, mac, accesoLectura)
+	if err == nil || err.Error() != "dispositivo_no_encontrado" {
+		return nil, err
+	}
+	permiso, hasta, errShare := shareRepo.ActivePermission(userID, mac)
+	if errShare != nil {
+		return nil, errShare
+	}
+	if permiso == "" {
+		return nil, err
+	}
+	return hasta, nil
+}
+
+func newInviteToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Share concede acceso al dispositivo. Con Username el acceso es inmediato; con Email se envía
+// una invitación cuyo token debe aceptar un usuario autenticado.
+func (uc *ShareDevices) Share(userID int, mac string, input ShareInput) (*entities.DeviceShare, error) {
+	if input.Permiso != entities.PermisoLectura && input.Permiso != entities.PermisoEscritura {
+		return nil, fmt.Errorf("permiso_invalido")
+	}
+	input.Username = strings.TrimSpace(input.Username)
+	input.Email = strings.TrimSpace(input.Email)
+	if (input.Username == "") == (input.Email == "") {
+		return nil, fmt.Errorf("destinatario_requerido")
+	}
+	now := time.Now()
+	if input.Expira != nil && !input.Expira.After(now) {
+		return nil, fmt.Errorf("expiracion_invalida")
+	}
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoGestion); err != nil {
+		return nil, err
+	}
+	orgID, err := uc.orgRepo.DeviceOrg(mac)
+	if err != nil {
+		return nil, err
+	}
+	if orgID == 0 {
+		if orgID, err = uc.orgRepo.PersonalOrgID(userID); err != nil {
+			return nil, fmt.Errorf("error al buscar organización personal: %w", err)
+		}
+	}
+
+	share := &entities.DeviceShare{Mac: mac, OrgID: orgID, OtorgadoPor: userID, Permiso: input.Permiso, Creado: now, Expira: input.Expira}
+	if input.Username != "" {
+		grantee, err := uc.userRepo.FindByUsername(input.Username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("usuario_no_encontrado")
+			}
+			return nil, err
+		}
+		if grantee.ID == userID {
+			return nil, fmt.Errorf("destinatario_invalido")
+		}
+		share.UserID, share.Username = &grantee.ID, grantee.Username
+	} else {
+		addr, err := mail.ParseAddress(input.Email)
+		if err != nil {
+			return nil, fmt.Errorf("email_invalido")
+		}
+		share.Email = addr.Address
+		if share.Token, err = newInviteToken(); err != nil {
+			return nil, fmt.Errorf("error al generar invitación: %w", err)
+		}
+	}
+
+	if err := uc.shareRepo.Create(share); err != nil {
+		return nil, err
+	}
+	if share.UserID != nil {
+		uc.grantFeed(*share.UserID, share)
+	}
+	if share.Token != "" {
+		if err := uc.mailer.Send(share.Email, "Te invitaron a ver un dispositivo", inviteBody(mac, share)); err != nil {
+			// La invitación queda creada: se puede revocar y volver a enviar
+			log.Printf("ADVERTENCIA: [ShareDevices] Invitación %d creada pero no se pudo enviar a %s: %v", share.ID, share.Email, err)
+		}
+	}
+	return share, nil
+}
+
+func inviteBody(mac string, share *entities.DeviceShare) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Se te concedió acceso de %s al dispositivo %s.\n\n", share.Permiso, mac)
+	if base := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/"); base != "" {
+		fmt.Fprintf(&b, "Acepta la invitación (inicia sesión o regístrate primero): %s/shares/invitaciones/%s\n", base, share.Token)
+	} else {
+		fmt.Fprintf(&b, "Código de invitación (úsalo tras iniciar sesión o registrarte): %s\n", share.Token)
+	}
+	if share.Expira != nil {
+		fmt.Fprintf(&b, "El acceso vence el %s.\n", share.Expira.Format(time.RFC1123))
+	}
+	return b.String()
+}
+
+// List devuelve los accesos vigentes (y las invitaciones pendientes) del dispositivo
+func (uc *ShareDevices) List(userID int, mac string) ([]entities.DeviceShare, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, mac, accesoGestion); err != nil {
+		return nil, err
+	}
+	return uc.shareRepo.FindByMac(mac)
+}
+
+// Received devuelve los dispositivos compartidos con el usuario
+func (uc *ShareDevices) Received(userID int) ([]entities.DeviceShare, error) {
+	return uc.shareRepo.FindForGrantee(userID)
+}
+
+// Accept vincula una invitación por email al usuario autenticado
+func (uc *ShareDevices) Accept(userID int, token string) (*entities.DeviceShare, error) {
+	share, err := uc.shareRepo.FindByToken(strings.TrimSpace(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invitacion_no_encontrada")
+		}
+		return nil, err
+	}
+	if share.Revocado != nil || share.UserID != nil || (share.Expira != nil && !time.Now().Before(*share.Expira)) {
+		return nil, fmt.Errorf("invitacion_no_encontrada")
+	}
+	if err := uc.shareRepo.Accept(share.ID, userID); err != nil {
+		return nil, err
+	}
+	uc.grantFeed(userID, share)
+	return uc.shareRepo.FindByID(share.ID)
+}
+
+// grantFeed suscribe las conexiones WebSocket abiertas del destinatario al tema del dispositivo,
+// para que reciba sus lecturas, anomalías, cambios de peso y movimiento sin reconectarse
+func (uc *ShareDevices) grantFeed(granteeID int, share *entities.DeviceShare) {
+	var hasta time.Time
+	if share.Expira != nil {
+		hasta = *share.Expira
+	}
+	uc.revoker.GrantTopic(granteeID, domain.TopicDevice(share.Mac), hasta)
+}
+
+// Revoke retira un acceso. Puede hacerlo quien gestiona el dispositivo o el propio destinatario.
+func (uc *ShareDevices) Revoke(userID int, shareID int) error {
+	share, err := uc.shareRepo.FindByID(shareID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("acceso_no_encontrado")
+		}
+		return err
+	}
+	isGrantee := share.UserID != nil && *share.UserID == userID
+	if !isGrantee {
+		if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, userID, share.Mac, accesoGestion); err != nil {
+			if err.Error() == "dispositivo_no_encontrado" {
+				return fmt.Errorf("acceso_no_encontrado")
+			}
+			return err
+		}
+	}
+	if share.Revocado != nil {
+		return nil
+	}
+	if err := uc.shareRepo.Revoke(shareID); err != nil {
+		return err
+	}
+	if share.UserID != nil {
+		uc.revoker.RevokeTopic(*share.UserID, domain.TopicDevice(share.Mac))
+	}
+	log.Printf("INFO: [ShareDevices] UserID %d revocó el acceso %d a MAC %s.", userID, shareID, share.Mac)
+	return nil
+}
+
+// revokeDeviceShares revoca todos los accesos del dispositivo (p. ej. al cambiar de organización)
+func revokeDeviceShares(shareRepo domain.ShareRepository, revoker domain.SubscriptionRevoker, mac string) error {
+	shares, err := shareRepo.FindByMac(mac)
+	if err != nil {
+		return err
+	}
+	for _, share := range shares {
+		if err := shareRepo.Revoke(share.ID); err != nil {
+			return err
+		}
+		if share.UserID != nil {
+			revoker.RevokeTopic(*share.UserID, domain.TopicDevice(mac))
+		}
+	}
+	return nil
+}
+
+// revokeFormerAccess retira el tema del dispositivo a los miembros de su organización anterior
+// (0 = ninguna) y a los usuarios dados que ya no pueden leerlo, p. ej. después de moverlo
+func revokeFormerAccess(orgRepo domain.OrganizationRepository, userRepo domain.UserRepository, shareRepo domain.ShareRepository, revoker domain.SubscriptionRevoker, mac string, formerOrg int, userIDs ...int) {
+	if formerOrg > 0 {
+		org, err := orgRepo.FindByID(formerOrg)
+		if err != nil {
+			log.Printf("ADVERTENCIA: [DeviceAccess] No se pudieron cargar los miembros de la organización %d para retirar MAC %s: %v", formerOrg, mac, err)
+		} else {
+			for _, member := range org.Miembros {
+				userIDs = append(userIDs, member.UserID)
+			}
+		}
+	}
+	for _, userID := range userIDs {
+		if _, err := checkDeviceRead(orgRepo, userRepo, shareRepo, userID, mac); err != nil {
+			revoker.RevokeTopic(userID, domain.TopicDevice(mac))
+		}
+	}
+}
+
+// leavingOrg prepara el paso del dispositivo a la organización personal de newOwnerID: si cambia
+// de organización, revoca los accesos compartidos que concedió la anterior. Devuelve la
+// organización anterior (0 si no cambia o no tenía).
+func leavingOrg(orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository, revoker domain.SubscriptionRevoker, mac string, newOwnerID int) (int, error) {
+	formerOrg, err := orgRepo.DeviceOrg(mac)
+	if err != nil {
+		return 0, err
+	}
+	newOrg, err := orgRepo.PersonalOrgID(newOwnerID)
+	if err == sql.ErrNoRows || (err == nil && newOrg == formerOrg) {
+		return 0, nil // Sin organización personal el dispositivo no se mueve
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := revokeDeviceShares(shareRepo, revoker, mac); err != nil {
+		return 0, err
+	}
+	return formerOrg, nil
+}
@@ -0,0 +1,48 @@
+// File: storageUsage_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"log"
+)
+
+// StorageUsage resume para los operadores de la plataforma cuánto almacenamiento ocupa cada usuario
+type StorageUsage struct {
+	db      domain.DatosRepository
+	jobRepo domain.ExportJobRepository
+}
+
+func NewStorageUsage(db domain.DatosRepository, jobRepo domain.ExportJobRepository) *StorageUsage {
+	if db == nil || jobRepo == nil {
+		log.Fatal("Error: StorageUsage recibió dependencias nulas (db o jobRepo).")
+	}
+	return &StorageUsage{db: db, jobRepo: jobRepo}
+}
+
+// Execute reparte el tamaño de la tabla de lecturas en proporción a las lecturas de cada usuario
+// y añade lo que ocupan sus archivos de exportación
+func (uc *StorageUsage) Execute() (*entities.StorageUsage, error) {
+	lecturas, sinUsuario, bytes, err := uc.db.StorageTotals()
+	if err != nil {
+		log.Printf("ERROR: [StorageUsage] %v", err)
+		return nil, err
+	}
+	usuarios, err := uc.db.StorageByUser()
+	if err != nil {
+		return nil, err
+	}
+	exports, err := uc.jobRepo.BytesByUser()
+	if err != nil {
+		log.Printf("ERROR: [StorageUsage] %v", err)
+		return nil, err
+	}
+	for i := range usuarios {
+		if lecturas > 0 {
+			usuarios[i].BytesEstimados = int64(float64(bytes) * float64(usuarios[i].Lecturas) / float64(lecturas))
+		}
+		usuarios[i].ExportacionesBytes = exports[usuarios[i].UserID]
+	}
+	return &entities.StorageUsage{Lecturas: lecturas, LecturasSinUsuario: sinUsuario, BytesTabla: bytes, Usuarios: usuarios}, nil
+}
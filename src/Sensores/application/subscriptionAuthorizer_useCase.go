@@ -0,0 +1,90 @@
+// File: subscriptionAuthorizer_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SubscriptionAuthorizer decide si un usuario autenticado puede suscribirse a un tema WebSocket
+type SubscriptionAuthorizer struct {
+	siteRepo  domain.SiteRepository
+	userRepo  domain.UserRepository
+	orgRepo   domain.OrganizationRepository
+	shareRepo domain.ShareRepository
+}
+
+func NewSubscriptionAuthorizer(siteRepo domain.SiteRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository) *SubscriptionAuthorizer {
+	if siteRepo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil {
+		log.Fatal("Error: SubscriptionAuthorizer recibió dependencias nulas (siteRepo, userRepo, orgRepo o shareRepo).")
+	}
+	return &SubscriptionAuthorizer{siteRepo: siteRepo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo}
+}
+
+// DefaultTopics son los temas de una conexión recién autenticada con su vencimiento (cero = no
+// vence): el del usuario, el de cada organización de la que es miembro y el de cada dispositivo
+// compartido con él
+func (uc *SubscriptionAuthorizer) DefaultTopics(userID int) map[string]time.Time {
+	topics := map[string]time.Time{domain.TopicUser(userID): {}}
+	orgs, err := uc.orgRepo.FindForUser(userID)
+	if err != nil {
+		log.Printf("ADVERTENCIA: [SubscriptionAuth] No se pudieron cargar las organizaciones de UserID %d: %v", userID, err)
+	}
+	for _, org := range orgs {
+		topics[domain.TopicOrg(org.ID)] = time.Time{}
+	}
+	shares, err := uc.shareRepo.FindForGrantee(userID)
+	if err != nil {
+		log.Printf("ADVERTENCIA: [SubscriptionAuth] No se pudieron cargar los dispositivos compartidos con UserID %d: %v", userID, err)
+	}
+	for _, share := range shares {
+		topic := domain.TopicDevice(share.Mac)
+		hasta, exists := topics[topic]
+		if share.Expira == nil {
+			topics[topic] = time.Time{}
+		} else if !exists || (!hasta.IsZero() && share.Expira.After(hasta)) {
+			topics[topic] = *share.Expira
+		}
+	}
+	return topics
+}
+
+// Authorize acepta temas con el formato de domain.TopicUser/TopicOrg/TopicDevice/TopicSite/TopicArea
+// y devuelve hasta cuándo vale la suscripción (cero = no vence)
+func (uc *SubscriptionAuthorizer) Authorize(userID int, topic string) (time.Time, bool) {
+	kind, value, found := strings.Cut(topic, ":")
+	if !found || value == "" {
+		return time.Time{}, false
+	}
+	if kind == "mac" {
+		hasta, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, value)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if hasta != nil {
+			return *hasta, true
+		}
+		return time.Time{}, true
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return time.Time{}, false
+	}
+	switch kind {
+	case "usuario":
+		return time.Time{}, id == userID
+	case "org":
+		_, err = checkOrgAccess(uc.orgRepo, userID, id, accesoLectura)
+	case "sitio":
+		_, err = findAccessibleSite(uc.siteRepo, uc.orgRepo, userID, id, accesoLectura)
+	case "area":
+		_, _, err = findAccessibleArea(uc.siteRepo, uc.orgRepo, userID, id, accesoLectura)
+	default:
+		return time.Time{}, false
+	}
+	return time.Time{}, err == nil
+}
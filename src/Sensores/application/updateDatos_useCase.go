@@ -5,26 +5,58 @@ package application
 import (
 	"API/src/Sensores/domain"
 	// "API/src/Sensores/domain/entities" // Importar si necesitas notificar datos actualizados
+	"fmt"
 	"log"
 )
 
 type UpdateDatos struct {
-	db domain.DatosRepository
+	db       domain.DatosRepository
+	orgRepo  domain.OrganizationRepository // Para validar el dispositivo destino si cambia la MAC
+	userRepo domain.UserRepository
 	// notifier DatosNotifier // Añadir si notificas actualizaciones
 }
 
-func NewUpdateDatos(db domain.DatosRepository /*, notifier DatosNotifier */) *UpdateDatos {
-	if db == nil {
-		log.Fatal("Error: UpdateDatos recibió dependencia db nula.")
+func NewUpdateDatos(db domain.DatosRepository, orgRepo domain.OrganizationRepository, userRepo domain.UserRepository /*, notifier DatosNotifier */) *UpdateDatos {
+	if db == nil || orgRepo == nil || userRepo == nil {
+		log.Fatal("Error: UpdateDatos recibió dependencias nulas (db, orgRepo o userRepo).")
 	}
 	return &UpdateDatos{
-		db: db,
+		db:       db,
+		orgRepo:  orgRepo,
+		userRepo: userRepo,
 		// notifier: notifier,
 	}
 }
 
-func (up *UpdateDatos) Execute(id int, temperatura string, movimiento string, distancia string, peso string, mac string) error {
-	err := up.db.Update(id, 0, temperatura, movimiento, distancia, peso, mac) // Replace '0' with the appropriate int value
+// Execute actualiza la lectura solo si el usuario puede editarla en su organización. Si cambia
+// la MAC, también debe poder editar el dispositivo destino, que tiene que ser de la misma
+// organización que la lectura (su visibilidad depende de ella).
+func (up *UpdateDatos) Execute(id int, userID int, temperatura string, movimiento string, distancia string, peso string, mac string) error {
+	current, err := up.db.GetByID(id, userID)
+	if err != nil {
+		return err
+	}
+	if mac != current.Mac {
+		if err := checkDeviceAccess(up.orgRepo, up.userRepo, userID, mac, accesoEscritura); err != nil {
+			if err.Error() == "dispositivo_no_encontrado" {
+				return fmt.Errorf("mac_destino_no_encontrada")
+			}
+			return err
+		}
+		orgID, err := up.orgRepo.DeviceOrg(mac)
+		if err == nil && orgID == 0 {
+			// Sin organización, checkDeviceAccess exige que sea del propio usuario
+			orgID, err = up.orgRepo.PersonalOrgID(userID)
+		}
+		if err != nil {
+			return fmt.Errorf("error interno al buscar organización del dispositivo destino: %w", err)
+		}
+		if orgID != current.OrgID {
+			log.Printf("WARN: [UpdateDatos] UserID %d intentó mover la lectura %d (org %d) a MAC %s (org %d)", userID, id, current.OrgID, mac, orgID)
+			return fmt.Errorf("organizacion_distinta")
+		}
+	}
+	err = up.db.Update(id, userID, temperatura, movimiento, distancia, peso, mac)
 	if err != nil {
 		log.Printf("ERROR: [UpdateDatos] Falló al actualizar datos (ID: %d): %v", id, err)
 		return err
@@ -0,0 +1,94 @@
+// File: updateDevice_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxEtiquetasPorDispositivo = 20
+	maxLongitudEtiqueta        = 50
+	maxIntervaloReporte        = 86400 // Un día
+)
+
+// UpdateDeviceInput DTO con los metadatos editables
+type UpdateDeviceInput struct {
+	UserID       int
+	Mac          string
+	Nombre       string
+	Descripcion  string
+	Ubicacion    string
+	Etiquetas    []string
+	IntervaloSeg *int // Intervalo de reporte esperado; nil = estimarlo de las lecturas
+}
+
+type UpdateDevice struct {
+	deviceRepo domain.DeviceRepository
+	userRepo   domain.UserRepository
+	orgRepo    domain.OrganizationRepository
+}
+
+func NewUpdateDevice(deviceRepo domain.DeviceRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository) *UpdateDevice {
+	if deviceRepo == nil || userRepo == nil || orgRepo == nil {
+		log.Fatal("Error: UpdateDevice recibió dependencias nulas (deviceRepo, userRepo u orgRepo).")
+	}
+	return &UpdateDevice{deviceRepo: deviceRepo, userRepo: userRepo, orgRepo: orgRepo}
+}
+
+// NormalizeTags pasa las etiquetas a minúsculas, quita espacios y duplicados.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func (uc *UpdateDevice) Execute(input UpdateDeviceInput) (*entities.Device, error) {
+	if err := checkDeviceAccess(uc.orgRepo, uc.userRepo, input.UserID, input.Mac, accesoEscritura); err != nil {
+		return nil, err
+	}
+
+	device := entities.NewDevice(input.Mac)
+	device.Nombre = strings.TrimSpace(input.Nombre)
+	device.Descripcion = strings.TrimSpace(input.Descripcion)
+	device.Ubicacion = strings.TrimSpace(input.Ubicacion)
+	device.Etiquetas = NormalizeTags(input.Etiquetas)
+	device.IntervaloSeg = input.IntervaloSeg
+
+	// Validaciones de longitud (coinciden con las columnas de 'devices' y 'device_tags')
+	if utf8.RuneCountInString(device.Nombre) > 100 || utf8.RuneCountInString(device.Descripcion) > 500 || utf8.RuneCountInString(device.Ubicacion) > 200 {
+		return nil, fmt.Errorf("metadatos_demasiado_largos")
+	}
+	if len(device.Etiquetas) > maxEtiquetasPorDispositivo {
+		return nil, fmt.Errorf("demasiadas_etiquetas")
+	}
+	for _, tag := range device.Etiquetas {
+		if utf8.RuneCountInString(tag) > maxLongitudEtiqueta {
+			return nil, fmt.Errorf("etiqueta_invalida")
+		}
+	}
+
+	if device.IntervaloSeg != nil && (*device.IntervaloSeg < 1 || *device.IntervaloSeg > maxIntervaloReporte) {
+		return nil, fmt.Errorf("intervalo_invalido")
+	}
+
+	if err := uc.deviceRepo.Save(device); err != nil {
+		log.Printf("ERROR: [UpdateDevice] Falló al guardar metadatos de MAC %s: %v", input.Mac, err)
+		return nil, err
+	}
+	log.Printf("INFO: [UpdateDevice] Metadatos de MAC %s actualizados por UserID %d.", input.Mac, input.UserID)
+	return device, nil
+}
@@ -0,0 +1,154 @@
+// File: weightEvents_useCase.go
+
+package application
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	weightDefaultSettle   = 30   // Segundos que el peso debe sostenerse en el nuevo nivel
+	weightDefaultMinDelta = 0.1  // Diferencia mínima con el nivel estable para considerar un cambio
+	weightDefaultNoise    = 0.05 // Variación tolerada entre lecturas del mismo nivel
+)
+
+// WeightEventListener recibe cada cambio de peso confirmado (inventario, ...)
+type WeightEventListener interface {
+	OnWeightEvent(event entities.WeightEvent)
+}
+
+// WeightEvents detecta cambios escalonados de peso (algo se añadió o se retiró de la balanza).
+// Una lectura que se aleja más de minDelta del nivel estable abre un nivel candidato; si las
+// lecturas siguientes se mantienen a menos de 'noise' de él durante 'settle', el cambio se
+// registra con el promedio del nuevo nivel. Volver dentro de minDelta descarta el candidato.
+type WeightEvents struct {
+	repo      domain.WeightEventRepository
+	userRepo  domain.UserRepository
+	orgRepo   domain.OrganizationRepository
+	shareRepo domain.ShareRepository
+	notifier  domain.DatosNotifier
+	listeners []WeightEventListener
+	settle    time.Duration
+	minDelta  float64
+	noise     float64
+	locks     *macLocks // Serializa el seguimiento de cada dispositivo
+}
+
+func NewWeightEvents(repo domain.WeightEventRepository, userRepo domain.UserRepository, orgRepo domain.OrganizationRepository, shareRepo domain.ShareRepository, notifier domain.DatosNotifier, listeners ...WeightEventListener) *WeightEvents {
+	if repo == nil || userRepo == nil || orgRepo == nil || shareRepo == nil || notifier == nil {
+		log.Fatal("Error: WeightEvents recibió dependencias nulas (repo, userRepo, orgRepo, shareRepo o notifier).")
+	}
+	uc := &WeightEvents{
+		repo: repo, userRepo: userRepo, orgRepo: orgRepo, shareRepo: shareRepo, notifier: notifier, listeners: listeners,
+		settle:   secondsFromEnv("WEIGHT_SETTLE_SEG", weightDefaultSettle),
+		minDelta: floatFromEnv("WEIGHT_MIN_DELTA", weightDefaultMinDelta),
+		noise:    floatFromEnv("WEIGHT_NOISE", weightDefaultNoise),
+		locks:    newMacLocks(),
+	}
+	if uc.noise > uc.minDelta {
+		log.Printf("ADVERTENCIA: WEIGHT_NOISE (%g) es mayor que WEIGHT_MIN_DELTA (%g); se usa %g.", uc.noise, uc.minDelta, uc.minDelta)
+		uc.noise = uc.minDelta
+	}
+	return uc
+}
+
+// floatFromEnv lee un número (>= 0) de una variable de entorno
+func floatFromEnv(name string, def float64) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		log.Printf("ADVERTENCIA: %s inválido ('%s'); se usa %g.", name, raw, def)
+		return def
+	}
+	return f
+}
+
+// Process incorpora el peso de una lectura recién guardada. Las lecturas sin peso o anteriores a
+// la última procesada del dispositivo se ignoran.
+func (uc *WeightEvents) Process(data entities.Datos) {
+	peso := domain.ParseNumber(data.Peso)
+	if peso == nil {
+		return
+	}
+	defer uc.locks.lock(data.Mac)()
+	state, err := uc.repo.GetState(data.Mac)
+	if err != nil {
+		log.Printf("ERROR: [WeightEvents] %v", err)
+		return
+	}
+	if state == nil {
+		// La primera lectura fija el nivel de partida
+		state = &entities.WeightState{Mac: data.Mac, PesoEstable: *peso, UltimaMuestra: data.Fecha}
+		if err := uc.repo.SaveResult(state, nil); err != nil {
+			log.Printf("ERROR: [WeightEvents] %v", err)
+		}
+		return
+	}
+	if data.Fecha.Before(state.UltimaMuestra) {
+		return
+	}
+	state.UltimaMuestra = data.Fecha
+
+	var event *entities.WeightEvent
+	switch {
+	case math.Abs(*peso-state.PesoEstable) <= uc.minDelta:
+		clearWeightCandidate(state)
+	case state.CandidatoDesde == nil || math.Abs(*peso-state.CandidatoPromedio()) > uc.noise:
+		desde := data.Fecha
+		state.CandidatoDesde, state.CandidatoSuma, state.CandidatoLecturas = &desde, *peso, 1
+	default:
+		state.CandidatoSuma += *peso
+		state.CandidatoLecturas++
+	}
+	if state.CandidatoDesde != nil && data.Fecha.Sub(*state.CandidatoDesde) >= uc.settle {
+		nivel := state.CandidatoPromedio()
+		event = &entities.WeightEvent{Mac: data.Mac, UserID: data.UserID, OrgID: data.OrgID, Tipo: entities.WeightAgregado,
+			Inicio: *state.CandidatoDesde, Confirmado: data.Fecha, PesoAnterior: state.PesoEstable, PesoResultante: nivel,
+			Delta: nivel - state.PesoEstable, Muestras: state.CandidatoLecturas}
+		if event.Delta < 0 {
+			event.Tipo = entities.WeightRetirado
+		}
+		state.PesoEstable = nivel
+		clearWeightCandidate(state)
+	}
+
+	if err := uc.repo.SaveResult(state, event); err != nil {
+		log.Printf("ERROR: [WeightEvents] %v", err)
+		return
+	}
+	if event != nil {
+		notification := entities.WeightNotification{Tipo: entities.WeightNotifyCambio, Evento: *event, Dispositivo: data.Dispositivo}
+		if err := uc.notifier.NotifyWeightEvent(notification); err != nil {
+			log.Printf("ADVERTENCIA: [WeightEvents] Falló la notificación del cambio de peso %d: %v", event.ID, err)
+		}
+		for _, listener := range uc.listeners {
+			listener.OnWeightEvent(*event)
+		}
+	}
+}
+
+func clearWeightCandidate(state *entities.WeightState) {
+	state.CandidatoDesde, state.CandidatoSuma, state.CandidatoLecturas = nil, 0, 0
+}
+
+// List devuelve los cambios de peso de un dispositivo al que el usuario tiene acceso confirmados
+// en [desde, hasta); sin rango, los de las últimas 24 horas
+func (uc *WeightEvents) List(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.WeightEvent, error) {
+	if _, err := checkDeviceRead(uc.orgRepo, uc.userRepo, uc.shareRepo, userID, mac); err != nil {
+		return nil, err
+	}
+	desde, hasta, limit, err := deviceEventsRange(desde, hasta, limit)
+	if err != nil {
+		return nil, err
+	}
+	return uc.repo.FindByMac(userID, mac, desde, hasta, limit)
+}
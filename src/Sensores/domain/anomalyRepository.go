@@ -0,0 +1,29 @@
+//File: anomalyRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"time"
+)
+
+type AnomalyRepository interface {
+	// FindSettings devuelve la configuración del dispositivo (nil si usa la predeterminada)
+	FindSettings(mac string) (*entities.AnomalySettings, error)
+	// SaveSettings crea o reemplaza la configuración
+	SaveSettings(settings *entities.AnomalySettings) error
+	DeleteSettings(mac string) error
+	// GetModel devuelve en una sola consulta la configuración del dispositivo (nil si usa la
+	// predeterminada) y sus modelos para una hora del día y los globales
+	GetModel(mac string, hora int) (*entities.AnomalySettings, []entities.AnomalyState, error)
+	// SaveResults crea o reemplaza los modelos dados y registra las anomalías (les asigna ID) en
+	// una transacción
+	SaveResults(states []entities.AnomalyState, events []entities.AnomalyEvent) error
+	// DeleteStates borra los modelos del dispositivo (vuelven a aprender desde cero)
+	DeleteStates(mac string) error
+	// FindByMac lista, del más reciente al más antiguo, las anomalías del dispositivo visibles
+	// para el usuario en [desde, hasta)
+	FindByMac(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.AnomalyEvent, error)
+	// FindMarks devuelve las marcas de anomalía de las lecturas dadas, por ID de lectura
+	FindMarks(datoIDs []int32) (map[int32][]entities.AnomalyMark, error)
+}
@@ -0,0 +1,30 @@
+//File: complianceRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"time"
+)
+
+// ComplianceRepository guarda los perfiles de cumplimiento y los reportes generados. Los reportes
+// no se modifican ni se borran (auditoría), tampoco al quitar el perfil.
+type ComplianceRepository interface {
+	// FindProfile devuelve el perfil del dispositivo (nil si no tiene)
+	FindProfile(mac string) (*entities.ComplianceProfile, error)
+	FindProfiles() ([]entities.ComplianceProfile, error)
+	// SaveProfile crea o reemplaza el perfil
+	SaveProfile(profile *entities.ComplianceProfile) error
+	DeleteProfile(mac string) error
+	// CreateReport guarda el reporte; "reporte_duplicado" si el periodo del dispositivo ya tiene uno
+	CreateReport(report *entities.ComplianceReport) error
+	// FindReport devuelve el reporte completo; sql.ErrNoRows si no existe
+	FindReport(id int) (*entities.ComplianceReport, error)
+	// FindReports lista, del más reciente al más antiguo, los reportes del dispositivo cuyo periodo
+	// empieza en [desde, hasta); periodo "" = todos
+	FindReports(mac string, periodo string, desde time.Time, hasta time.Time, limit int) ([]entities.ComplianceSummary, error)
+	// ReportExists indica si ya hay un reporte del dispositivo para ese periodo
+	ReportExists(mac string, periodo string, desde time.Time) (bool, error)
+	// FindPeriodReport devuelve el reporte completo del dispositivo para ese periodo (nil si no hay)
+	FindPeriodReport(mac string, periodo string, desde time.Time) (*entities.ComplianceReport, error)
+}
@@ -0,0 +1,15 @@
+//File: containerRepository.go
+
+package domain
+
+import "API/src/Sensores/domain/entities"
+
+type ContainerRepository interface {
+	// FindByMac devuelve la geometría del contenedor del dispositivo (nil si no tiene)
+	FindByMac(mac string) (*entities.ContainerConfig, error)
+	// FindByMacs obtiene en bloque las geometrías de varias MACs (solo las configuradas)
+	FindByMacs(macs []string) (map[string]*entities.ContainerConfig, error)
+	// Save crea o reemplaza la geometría del contenedor
+	Save(config *entities.ContainerConfig) error
+	Delete(mac string) error
+}
@@ -2,19 +2,104 @@
 
 package domain
 
-import "API/src/Sensores/domain/entities"
+import (
+    "API/src/Sensores/domain/entities"
+    "time"
+)
+
+// DatosFilter agrupa los filtros opcionales de las consultas de lecturas.
+// Los campos vacíos no filtran.
+type DatosFilter struct {
+    Mac       string        // Solo lecturas de esta MAC
+    Etiquetas []string      // Solo dispositivos que tengan TODAS estas etiquetas
+    SiteID    int           // Solo dispositivos ubicados (actualmente) en áreas de este sitio
+    AreaID    int           // Solo dispositivos ubicados (actualmente) en esta área
+    Desde     time.Time     // Solo lecturas creadas en o después de este instante
+    Hasta     time.Time     // Solo lecturas creadas antes de este instante
+    UserID    int           // Solo lecturas atribuidas a este usuario al insertarlas
+    Expresion *MetricFilter // Solo lecturas cuyas métricas cumplen la expresión (nil = sin filtro)
+}
+
+// DatosOrden es el orden en que StreamByUserID / StreamAll recorren las lecturas
+type DatosOrden int
+
+const (
+    OrdenFechaAsc         DatosOrden = iota // Cronológico
+    OrdenFechaDesc                          // Más recientes primero
+    OrdenDispositivoFecha                   // Por MAC y, dentro de cada una, cronológico
+)
+
+// DatosCursor identifica la última lectura de una página (orden por fecha y luego id)
+type DatosCursor struct {
+    Fecha time.Time
+    ID    int32
+}
+
+// DatosPageQuery pide una página de lecturas. Despues nil = primera página.
+type DatosPageQuery struct {
+    Limit      int
+    Ascendente bool
+    Despues    *DatosCursor
+}
+
+// DatosAggregateQuery pide estadísticas por cubetas de tiempo. Las cubetas se alinean al reloj
+// de Zona (un día empieza a medianoche local, también en cambios de horario).
+type DatosAggregateQuery struct {
+    Bucket      time.Duration
+    Zona        *time.Location
+    Metricas    []string // temperatura | distancia | peso | movimiento
+    Percentiles []int    // 1..99; vacío = sin percentiles
+}
 
 type DatosRepository interface {
-    // Save ahora requiere el user_id asociado
-    Save(userID int, temperatura string, movimiento string, distancia string, peso string, mac string) error
+    // Save requiere el user_id asociado y la organización dueña del dispositivo (0 = ninguna);
+    // devuelve el ID de la lectura
+    Save(userID int, orgID int, temperatura string, movimiento string, distancia string, peso string, mac string) (int64, error)
+
+    // GetAll es como GetByUserID pero sobre todas las lecturas (solo para operadores de la plataforma)
+    GetAll(filter DatosFilter, page DatosPageQuery) ([]entities.Datos, bool, error)
+
+    // StorageByUser cuenta las lecturas atribuidas a cada usuario (rutas.user_id), incluidos los que
+    // no tienen ninguna
+    StorageByUser() ([]entities.UserStorage, error)
+
+    // StorageTotals devuelve el total de lecturas, las que no tienen usuario y el tamaño en disco
+    // de la tabla (datos + índices) según el servidor
+    StorageTotals() (lecturas int64, sinUsuario int64, bytes int64, err error)
+
+    // Para obtener una página de las lecturas visibles para un usuario, aplicando filtros opcionales.
+    // Devuelve hasta page.Limit lecturas e indica si hay más después de la última.
+    GetByUserID(userID int, filter DatosFilter, page DatosPageQuery) ([]entities.Datos, bool, error)
+
+    // StreamByUserID recorre en el orden pedido las lecturas visibles que cumplen el filtro sin
+    // cargarlas en memoria; si fn devuelve error se detiene y lo devuelve
+    StreamByUserID(userID int, filter DatosFilter, orden DatosOrden, fn func(entities.Datos) error) error
+
+    // StreamAll es como StreamByUserID pero sobre todas las lecturas (solo para operadores de la plataforma)
+    StreamAll(filter DatosFilter, orden DatosOrden, fn func(entities.Datos) error) error
+
+    // GetLatestByUserID devuelve la lectura más reciente de cada dispositivo visible para el usuario
+    GetLatestByUserID(userID int, filter DatosFilter) ([]entities.Datos, error)
+
+    // Summarize agrega por dispositivo (conteo, primera/última lectura, promedio/mín/máx por métrica)
+    Summarize(userID int, filter DatosFilter) ([]entities.DeviceSummary, error)
+
+    // DeviceStats calcula por dispositivo los totales de las lecturas visibles que cumplen el
+    // filtro y las estadísticas de las creadas desde 'desde' (sin usar filter.Desde ni filter.Hasta)
+    DeviceStats(userID int, filter DatosFilter, desde time.Time) ([]entities.DeviceStats, error)
 
-    // Para obtener TODOS los datos (quizás para un admin)
-    GetAll() ([]entities.Datos, error)
+    // Aggregate agrega en cubetas de tiempo por dispositivo las lecturas visibles que cumplen el filtro
+    // (filter.Desde y filter.Hasta son obligatorios). Si se piden percentiles y el motor no los
+    // soporta devuelve "percentiles_no_soportados".
+    Aggregate(userID int, filter DatosFilter, query DatosAggregateQuery) ([]entities.DeviceSeries, error)
 
-    // NUEVO: Para obtener datos solo de un usuario específico
-    GetByUserID(userID int) ([]entities.Datos, error)
+    // GetByID devuelve una lectura visible para el usuario; "dato_no_encontrado" si no existe o
+    // no la puede ver
+    GetByID(id int, userID int) (*entities.Datos, error)
 
-    // Update y Delete probablemente también deberían verificar el user_id si la lógica lo requiere
-    Update(id int, userID int, temperatura string, movimiento string, distancia string, peso string, mac string) error // userID añadido para posible validación
-    Delete(id int, userID int) error // userID añadido para posible validación
-}
\ No newline at end of file
+    // Update y Delete solo afectan lecturas que el usuario puede editar (rol owner/admin/member en
+    // la organización de la lectura o dispositivo compartido con escritura). Devuelven
+    // "dato_no_encontrado" si no la puede ver y "permiso_insuficiente" si solo la puede leer.
+    Update(id int, userID int, temperatura string, movimiento string, distancia string, peso string, mac string) error
+    Delete(id int, userID int) error
+}
@@ -0,0 +1,455 @@
+// File: derivedExpr.go
+
+package domain
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lenguaje de las métricas derivadas: una expresión aritmética que se evalúa con cada lectura.
+//
+//	(peso - 1.2) * 1000
+//	round(100 - distancia / 120 * 100, 1)
+//	rate(peso)
+//
+// Campos: temperatura, distancia, peso y movimiento (1 = sí, 0 = no). Operadores: + - * / % ^ y
+// paréntesis. Funciones: abs, sqrt, exp, ln, round(x[, decimales]), min(a, b, ...), max(a, b, ...),
+// delta(x) (x menos su valor en la lectura anterior del dispositivo) y rate(x) (delta por hora).
+// Si falta una métrica, se divide por cero o el resultado no es finito, la lectura no tiene valor.
+
+const (
+	derivedExprMaxLength = 500
+	derivedExprMaxNodes  = 100
+)
+
+// derivedNamePattern son los nombres admitidos para una métrica derivada
+var derivedNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// derivedFunctions indica cuántos argumentos admite cada función (mínimo y máximo; -1 = sin límite)
+var derivedFunctions = map[string][2]int{
+	"abs": {1, 1}, "sqrt": {1, 1}, "exp": {1, 1}, "ln": {1, 1}, "round": {1, 2},
+	"min": {2, -1}, "max": {2, -1}, "delta": {1, 1}, "rate": {1, 1},
+}
+
+// ValidDerivedName indica si un nombre sirve para una métrica derivada: minúsculas, dígitos y '_',
+// sin coincidir con una métrica propia de las lecturas (ver MetricFilterFields) ni con una palabra
+// de los filtros (and, or, not, ...), que 'where' nunca leería como campo
+func ValidDerivedName(nombre string) bool {
+	_, reservado := MetricFilterFields[nombre]
+	return derivedNamePattern.MatchString(nombre) && !reservado && !metricFilterKeywords[nombre]
+}
+
+// DerivedReading son las métricas de una lectura con las que se evalúa una expresión
+type DerivedReading struct {
+	Fecha   time.Time
+	Valores map[string]*float64 // temperatura, distancia, peso y movimiento; nil = vacía
+}
+
+// NewDerivedReading toma las métricas de una lectura con las mismas reglas que las consultas
+func NewDerivedReading(fecha time.Time, temperatura string, movimiento string, distancia string, peso string) DerivedReading {
+	valores := map[string]*float64{
+		"temperatura": ParseNumber(temperatura),
+		"distancia":   ParseNumber(distancia),
+		"peso":        ParseNumber(peso),
+		"movimiento":  nil,
+	}
+	if moving := ParseMotion(movimiento); moving != nil {
+		v := 0.0
+		if *moving {
+			v = 1
+		}
+		valores["movimiento"] = &v
+	}
+	return DerivedReading{Fecha: fecha, Valores: valores}
+}
+
+// DerivedExpr es una expresión ya validada; Texto es la original. Temporal indica si usa delta o
+// rate (y por lo tanto necesita la lectura anterior).
+type DerivedExpr struct {
+	Texto    string
+	Temporal bool
+	raiz     derivedNode
+}
+
+// derivedContext es la lectura con la que se evalúa un nodo y la anterior del dispositivo (nil si no hay)
+type derivedContext struct {
+	actual DerivedReading
+	previa *DerivedReading
+}
+
+type derivedNode interface {
+	eval(ctx derivedContext) (float64, bool)
+}
+
+type derivedNumber float64
+
+type derivedField string
+
+type derivedNeg struct {
+	expr derivedNode
+}
+
+type derivedBinary struct {
+	op          byte
+	left, right derivedNode
+}
+
+type derivedCall struct {
+	fn   string
+	args []derivedNode
+}
+
+// Eval calcula el valor de la expresión para una lectura; nil si no tiene valor
+func (e *DerivedExpr) Eval(actual DerivedReading, previa *DerivedReading) *float64 {
+	v, ok := e.raiz.eval(derivedContext{actual: actual, previa: previa})
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+func (n derivedNumber) eval(derivedContext) (float64, bool) {
+	return float64(n), true
+}
+
+func (n derivedField) eval(ctx derivedContext) (float64, bool) {
+	v := ctx.actual.Valores[string(n)]
+	if v == nil {
+		return 0, false
+	}
+	return *v, true
+}
+
+func (n derivedNeg) eval(ctx derivedContext) (float64, bool) {
+	v, ok := n.expr.eval(ctx)
+	return -v, ok
+}
+
+func (n derivedBinary) eval(ctx derivedContext) (float64, bool) {
+	a, ok := n.left.eval(ctx)
+	if !ok {
+		return 0, false
+	}
+	b, ok := n.right.eval(ctx)
+	if !ok {
+		return 0, false
+	}
+	switch n.op {
+	case '+':
+		return a + b, true
+	case '-':
+		return a - b, true
+	case '*':
+		return a * b, true
+	case '/':
+		return a / b, b != 0
+	case '%':
+		return math.Mod(a, b), b != 0
+	}
+	return math.Pow(a, b), true
+}
+
+func (n derivedCall) eval(ctx derivedContext) (float64, bool) {
+	if n.fn == "delta" || n.fn == "rate" {
+		if ctx.previa == nil {
+			return 0, false
+		}
+		actual, ok := n.args[0].eval(ctx)
+		if !ok {
+			return 0, false
+		}
+		previa, ok := n.args[0].eval(derivedContext{actual: *ctx.previa})
+		if !ok {
+			return 0, false
+		}
+		if n.fn == "delta" {
+			return actual - previa, true
+		}
+		horas := ctx.actual.Fecha.Sub(ctx.previa.Fecha).Hours()
+		return (actual - previa) / horas, horas > 0
+	}
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, ok := arg.eval(ctx)
+		if !ok {
+			return 0, false
+		}
+		args[i] = v
+	}
+	switch n.fn {
+	case "abs":
+		return math.Abs(args[0]), true
+	case "sqrt":
+		return math.Sqrt(args[0]), true
+	case "exp":
+		return math.Exp(args[0]), true
+	case "ln":
+		return math.Log(args[0]), true
+	case "round":
+		escala := 1.0
+		if len(args) == 2 {
+			escala = math.Pow(10, math.Round(args[1]))
+		}
+		return math.Round(args[0]*escala) / escala, true
+	case "min", "max":
+		result := args[0]
+		for _, v := range args[1:] {
+			if (n.fn == "min" && v < result) || (n.fn == "max" && v > result) {
+				result = v
+			}
+		}
+		return result, true
+	}
+	return 0, false
+}
+
+type derivedParser struct {
+	text      string
+	tokens    []filterToken
+	next      int
+	nodes     int
+	temporal  bool // Dentro de delta/rate: no se admiten otras
+	usaPrevia bool // La expresión usa delta o rate
+}
+
+// ParseDerivedExpr valida y analiza una expresión. Los errores son *MetricFilterError con la posición.
+func ParseDerivedExpr(text string) (*DerivedExpr, error) {
+	if utf8.RuneCountInString(text) > derivedExprMaxLength {
+		return nil, &MetricFilterError{Posicion: derivedExprMaxLength + 1, Mensaje: fmt.Sprintf("la expresión supera los %d caracteres", derivedExprMaxLength)}
+	}
+	p := &derivedParser{text: text}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	if p.peek().kind == "end" {
+		return nil, p.errorAt(p.peek(), "la expresión está vacía")
+	}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "end" {
+		return nil, p.errorAt(tok, fmt.Sprintf("se esperaba un operador o el final y se encontró '%s'", tok.text))
+	}
+	return &DerivedExpr{Texto: text, Temporal: p.usaPrevia, raiz: root}, nil
+}
+
+func (p *derivedParser) errorAt(tok filterToken, mensaje string) *MetricFilterError {
+	return &MetricFilterError{Posicion: utf8.RuneCountInString(p.text[:tok.pos]) + 1, Mensaje: mensaje}
+}
+
+// tokenize separa números, palabras y operadores. A diferencia de los filtros, el signo nunca es
+// parte del número: "peso-1" es una resta.
+func (p *derivedParser) tokenize() error {
+	text := p.text
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case strings.ContainsRune("+-*/%^(),", r):
+			kind := "op"
+			if r == '(' || r == ')' || r == ',' {
+				kind = string(r)
+			}
+			p.tokens = append(p.tokens, filterToken{kind: kind, text: string(r), pos: start})
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			i++
+			for i < len(text) && (unicode.IsDigit(rune(text[i])) || text[i] == '.' || text[i] == 'e' || text[i] == 'E' ||
+				((text[i] == '-' || text[i] == '+') && (text[i-1] == 'e' || text[i-1] == 'E'))) {
+				i++
+			}
+			p.tokens = append(p.tokens, filterToken{kind: "number", text: text[start:i], pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				i += size
+			}
+			p.tokens = append(p.tokens, filterToken{kind: "word", text: text[start:i], pos: start})
+		default:
+			return p.errorAt(filterToken{pos: start}, fmt.Sprintf("carácter inesperado '%c'", r))
+		}
+	}
+	p.tokens = append(p.tokens, filterToken{kind: "end", text: "final de la expresión", pos: len(text)})
+	return nil
+}
+
+func (p *derivedParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *derivedParser) advance() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != "end" {
+		p.next++
+	}
+	return tok
+}
+
+func (p *derivedParser) isOp(ops string) bool {
+	tok := p.peek()
+	return tok.kind == "op" && strings.Contains(ops, tok.text)
+}
+
+func (p *derivedParser) countNode(tok filterToken) error {
+	p.nodes++
+	if p.nodes > derivedExprMaxNodes {
+		return p.errorAt(tok, fmt.Sprintf("la expresión es demasiado compleja (más de %d operaciones)", derivedExprMaxNodes))
+	}
+	return nil
+}
+
+func (p *derivedParser) parseSum() (derivedNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		opTok := p.advance()
+		if err := p.countNode(opTok); err != nil {
+			return nil, err
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = derivedBinary{op: opTok.text[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *derivedParser) parseProduct() (derivedNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/%") {
+		opTok := p.advance()
+		if err := p.countNode(opTok); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = derivedBinary{op: opTok.text[0], left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *derivedParser) parseUnary() (derivedNode, error) {
+	if p.isOp("+-") {
+		opTok := p.advance()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if opTok.text == "-" {
+			return derivedNeg{expr: expr}, nil
+		}
+		return expr, nil
+	}
+	return p.parsePower()
+}
+
+// parsePower asocia a la derecha y liga más que el signo: -2^2 = -4, 2^3^2 = 2^9
+func (p *derivedParser) parsePower() (derivedNode, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("^") {
+		opTok := p.advance()
+		if err := p.countNode(opTok); err != nil {
+			return nil, err
+		}
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return derivedBinary{op: '^', left: base, right: exponent}, nil
+	}
+	return base, nil
+}
+
+func (p *derivedParser) parsePrimary() (derivedNode, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case "number":
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, p.errorAt(tok, fmt.Sprintf("número inválido '%s'", tok.text))
+		}
+		return derivedNumber(v), nil
+	case "(":
+		expr, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if next := p.advance(); next.kind != ")" {
+			return nil, p.errorAt(next, fmt.Sprintf("se esperaba ')' y se encontró '%s'", next.text))
+		}
+		return expr, nil
+	case "word":
+		name := strings.ToLower(tok.text)
+		if err := p.countNode(tok); err != nil {
+			return nil, err
+		}
+		if p.peek().kind == "(" {
+			return p.parseCall(tok, name)
+		}
+		switch name {
+		case "temperatura", "distancia", "peso", "movimiento":
+			return derivedField(name), nil
+		}
+		return nil, p.errorAt(tok, fmt.Sprintf("campo desconocido '%s': use temperatura, distancia, peso o movimiento", tok.text))
+	}
+	return nil, p.errorAt(tok, fmt.Sprintf("se esperaba un número, un campo o '(' y se encontró '%s'", tok.text))
+}
+
+func (p *derivedParser) parseCall(nameTok filterToken, name string) (derivedNode, error) {
+	arity, ok := derivedFunctions[name]
+	if !ok {
+		return nil, p.errorAt(nameTok, fmt.Sprintf("función desconocida '%s'", nameTok.text))
+	}
+	temporal := name == "delta" || name == "rate"
+	if temporal && p.temporal {
+		return nil, p.errorAt(nameTok, "delta y rate no se pueden anidar")
+	}
+	p.advance() // '('
+	if temporal {
+		p.temporal, p.usaPrevia = true, true
+		defer func() { p.temporal = false }()
+	}
+	call := derivedCall{fn: name}
+	for {
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if p.peek().kind != "," {
+			break
+		}
+		p.advance()
+	}
+	if next := p.advance(); next.kind != ")" {
+		return nil, p.errorAt(next, fmt.Sprintf("se esperaba ',' o ')' y se encontró '%s'", next.text))
+	}
+	if len(call.args) < arity[0] || (arity[1] >= 0 && len(call.args) > arity[1]) {
+		return nil, p.errorAt(nameTok, fmt.Sprintf("número de argumentos inválido para %s", name))
+	}
+	return call, nil
+}
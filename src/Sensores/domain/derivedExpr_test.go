@@ -0,0 +1,266 @@
+// File: derivedExpr_test.go
+
+package domain
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var derivedTestNow = time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+// derivedTestReading es una lectura completa: temperatura 20, distancia 50, peso 10, con movimiento
+func derivedTestReading() DerivedReading {
+	return NewDerivedReading(derivedTestNow, "20", "si", "50", "10")
+}
+
+func evalDerived(t *testing.T, texto string, actual DerivedReading, previa *DerivedReading) *float64 {
+	t.Helper()
+	expr, err := ParseDerivedExpr(texto)
+	if err != nil {
+		t.Fatalf("ParseDerivedExpr(%q): %v", texto, err)
+	}
+	return expr.Eval(actual, previa)
+}
+
+func checkDerivedValue(t *testing.T, got *float64, want *float64) {
+	t.Helper()
+	switch {
+	case want == nil && got != nil:
+		t.Errorf("valor = %v, se esperaba nil", *got)
+	case want != nil && got == nil:
+		t.Errorf("valor = nil, se esperaba %v", *want)
+	case want != nil && math.Abs(*got-*want) > 1e-9:
+		t.Errorf("valor = %v, se esperaba %v", *got, *want)
+	}
+}
+
+func derivedValue(v float64) *float64 {
+	return &v
+}
+
+func TestDerivedExprPrecedence(t *testing.T) {
+	tests := []struct {
+		texto string
+		want  float64
+	}{
+		{"2 + 3 * 4", 14},
+		{"(2 + 3) * 4", 20},
+		{"10 - 4 - 3", 3},
+		{"100 / 10 / 5", 2},
+		{"7 % 4 * 2", 6},
+		{"-2^2", -4},
+		{"(-2)^2", 4},
+		{"2^3^2", 512},
+		{"2^-1", 0.5},
+		{"- -3", 3},
+		{"+3 - -2", 5},
+		{"peso-1", 9},
+		{"temperatura * 9 / 5 + 32", 68},
+		{"PESO + Distancia", 60},
+		{"movimiento * 5", 5},
+		{"round(100 - distancia / 120 * 100, 1)", 58.3},
+		{"round(2.5)", 3},
+		{"round(1234.5678, -2)", 1200},
+		{"min(3, peso, 5)", 3},
+		{"max(3, peso, 5) - min(temperatura, 100)", -10},
+		{"abs(-3) + sqrt(16)", 7},
+		{"ln(exp(2))", 2},
+		{".5 + 1e1", 10.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.texto, func(t *testing.T) {
+			checkDerivedValue(t, evalDerived(t, tt.texto, derivedTestReading(), nil), derivedValue(tt.want))
+		})
+	}
+}
+
+func TestDerivedExprMissingValues(t *testing.T) {
+	// Sin peso ni movimiento, y una temperatura que no es un número
+	reading := NewDerivedReading(derivedTestNow, "abc", "", "50", "")
+	tests := []struct {
+		texto string
+		want  *float64
+	}{
+		{"peso", nil},
+		{"peso + 1", nil},
+		{"1 + peso", nil},
+		{"-peso", nil},
+		{"peso ^ 0", nil},
+		{"max(distancia, peso)", nil},
+		{"round(peso, 1)", nil},
+		{"round(distancia, peso)", nil},
+		{"movimiento", nil},
+		{"temperatura * 0", nil},
+		{"distancia / 2", derivedValue(25)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.texto, func(t *testing.T) {
+			checkDerivedValue(t, evalDerived(t, tt.texto, reading, nil), tt.want)
+		})
+	}
+}
+
+func TestDerivedExprInvalidResults(t *testing.T) {
+	tests := []string{
+		"peso / 0",
+		"peso % 0",
+		"1 / (peso - 10)",
+		"(peso - 10) / (peso - 10)",
+		"0 / 0",
+		"sqrt(-1)",
+		"ln(0)",
+		"10 ^ 400",
+		"exp(1000)",
+		"0 ^ -1",
+	}
+	for _, texto := range tests {
+		t.Run(texto, func(t *testing.T) {
+			checkDerivedValue(t, evalDerived(t, texto, derivedTestReading(), nil), nil)
+		})
+	}
+}
+
+func TestDerivedExprPrevious(t *testing.T) {
+	haceUnaHora := NewDerivedReading(derivedTestNow.Add(-time.Hour), "18", "no", "50", "8")
+	haceMedia := NewDerivedReading(derivedTestNow.Add(-30*time.Minute), "18", "no", "50", "8")
+	mismoInstante := NewDerivedReading(derivedTestNow, "18", "no", "50", "8")
+	sinPeso := NewDerivedReading(derivedTestNow.Add(-time.Hour), "18", "no", "50", "")
+
+	tests := []struct {
+		name   string
+		texto  string
+		previa *DerivedReading
+		want   *float64
+	}{
+		{"delta", "delta(peso)", &haceUnaHora, derivedValue(2)},
+		{"delta sin lectura anterior", "delta(peso)", nil, nil},
+		{"delta sin valor anterior", "delta(peso)", &sinPeso, nil},
+		{"delta de una expresión", "delta(peso * 2 + temperatura)", &haceUnaHora, derivedValue(6)},
+		{"delta de movimiento", "delta(movimiento)", &haceUnaHora, derivedValue(1)},
+		{"delta combinado", "peso + delta(peso)", &haceUnaHora, derivedValue(12)},
+		{"delta en el mismo instante", "delta(peso)", &mismoInstante, derivedValue(2)},
+		{"rate por hora", "rate(peso)", &haceUnaHora, derivedValue(2)},
+		{"rate en media hora", "rate(peso)", &haceMedia, derivedValue(4)},
+		{"rate sin lectura anterior", "rate(peso)", nil, nil},
+		{"rate sin valor anterior", "rate(peso)", &sinPeso, nil},
+		{"rate en el mismo instante", "rate(peso)", &mismoInstante, nil},
+		{"sin delta ignora la anterior", "peso", &haceUnaHora, derivedValue(10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkDerivedValue(t, evalDerived(t, tt.texto, derivedTestReading(), tt.previa), tt.want)
+		})
+	}
+}
+
+func TestDerivedExprTemporal(t *testing.T) {
+	for texto, want := range map[string]bool{
+		"peso * 2":                    false,
+		"delta(peso)":                 true,
+		"round(rate(temperatura), 1)": true,
+		"max(peso, delta(peso))":      true,
+	} {
+		expr, err := ParseDerivedExpr(texto)
+		if err != nil {
+			t.Fatalf("ParseDerivedExpr(%q): %v", texto, err)
+		}
+		if expr.Temporal != want || expr.Texto != texto {
+			t.Errorf("ParseDerivedExpr(%q) = {Texto: %q, Temporal: %v}, se esperaba Temporal %v", texto, expr.Texto, expr.Temporal, want)
+		}
+	}
+}
+
+func TestValidDerivedName(t *testing.T) {
+	for nombre, want := range map[string]bool{
+		"merma":                       true,
+		"merma_diaria_2":              true,
+		"notas":                       true, // Solo se reservan las palabras completas
+		"nulls":                       true,
+		"x" + strings.Repeat("a", 49): true,
+		"x" + strings.Repeat("a", 50): false,
+		"":                            false,
+		"Merma":                       false,
+		"2merma":                      false,
+		"_merma":                      false,
+		"merma-diaria":                false,
+		"peso":                        false,
+		"llenado":                     false,
+		"and":                         false,
+		"or":                          false,
+		"not":                         false,
+		"in":                          false,
+		"is":                          false,
+		"null":                        false,
+		"between":                     false,
+	} {
+		if got := ValidDerivedName(nombre); got != want {
+			t.Errorf("ValidDerivedName(%q) = %v, se esperaba %v", nombre, got, want)
+		}
+	}
+}
+
+func TestParseDerivedExprErrors(t *testing.T) {
+	tests := []struct {
+		texto    string
+		posicion int
+		mensaje  string
+	}{
+		{"", 1, "la expresión está vacía"},
+		{"  ", 3, "la expresión está vacía"},
+		{"peso +", 7, "se esperaba un número, un campo o '('"},
+		{"peso * )", 8, "se esperaba un número, un campo o '('"},
+		{"foo + 1", 1, "campo desconocido 'foo'"},
+		{"llenado * 2", 1, "campo desconocido 'llenado'"},
+		{"temperatura * ñandú", 15, "campo desconocido 'ñandú'"}, // Posiciones en caracteres, no bytes
+		{"(peso + 1", 10, "se esperaba ')'"},
+		{"peso + 1)", 9, "se esperaba un operador o el final"},
+		{"peso 1", 6, "se esperaba un operador o el final"},
+		{"peso # 1", 6, "carácter inesperado '#'"},
+		{"peso > 1", 6, "carácter inesperado '>'"},
+		{"1e999", 1, "número inválido '1e999'"},
+		{"1.2.3 + peso", 1, "número inválido '1.2.3'"},
+		{"sin(peso)", 1, "función desconocida 'sin'"},
+		{"abs(peso, 1)", 1, "número de argumentos inválido para abs"},
+		{"2 * min(peso)", 5, "número de argumentos inválido para min"},
+		{"round(peso 2)", 12, "se esperaba ',' o ')'"},
+		{"abs()", 5, "se esperaba un número, un campo o '('"},
+		{"rate(delta(peso))", 6, "delta y rate no se pueden anidar"},
+		{"delta(peso + rate(peso))", 14, "delta y rate no se pueden anidar"},
+		// Límites
+		{strings.Repeat(" ", derivedExprMaxLength-3) + "peso", derivedExprMaxLength + 1, "la expresión supera los 500 caracteres"},
+		{"peso" + strings.Repeat("+peso", derivedExprMaxNodes/2), 4 + 5*(derivedExprMaxNodes/2-1) + 2, "la expresión es demasiado compleja"},
+	}
+	for _, tt := range tests {
+		name := tt.texto
+		if len(name) > 40 {
+			name = name[:40] + "..."
+		}
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseDerivedExpr(tt.texto)
+			parseErr, ok := err.(*MetricFilterError)
+			if !ok {
+				t.Fatalf("error = %v (%T), se esperaba *MetricFilterError", err, err)
+			}
+			if parseErr.Posicion != tt.posicion {
+				t.Errorf("Posicion = %d, se esperaba %d (%s)", parseErr.Posicion, tt.posicion, parseErr.Mensaje)
+			}
+			if !strings.HasPrefix(parseErr.Mensaje, tt.mensaje) {
+				t.Errorf("Mensaje = %q, se esperaba que empiece con %q", parseErr.Mensaje, tt.mensaje)
+			}
+		})
+	}
+}
+
+func TestParseDerivedExprLimitsAccepted(t *testing.T) {
+	for _, texto := range []string{
+		strings.Repeat(" ", derivedExprMaxLength-4) + "peso",
+		"peso" + strings.Repeat("+1", derivedExprMaxNodes/2-1) + "+1",
+	} {
+		if _, err := ParseDerivedExpr(texto); err != nil {
+			t.Errorf("ParseDerivedExpr(%.40q...) = %v, se esperaba aceptarla en el límite", texto, err)
+		}
+	}
+}
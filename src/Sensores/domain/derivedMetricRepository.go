@@ -0,0 +1,23 @@
+//File: derivedMetricRepository.go
+
+package domain
+
+import "API/src/Sensores/domain/entities"
+
+type DerivedMetricRepository interface {
+	// FindByMac lista las métricas derivadas del dispositivo por nombre
+	FindByMac(mac string) ([]entities.DerivedMetric, error)
+	// FindByID devuelve la métrica (nil si no existe)
+	FindByID(id int) (*entities.DerivedMetric, error)
+	// FindNames devuelve los nombres distintos de las métricas derivadas de los dispositivos dados
+	FindNames(macs []string) ([]string, error)
+	// Create devuelve "nombre_duplicado" si el dispositivo ya tiene una métrica con ese nombre
+	Create(metric *entities.DerivedMetric) error
+	// Update reemplaza nombre, expresión y unidad; con limpiarValores borra los valores ya calculados
+	Update(metric *entities.DerivedMetric, limpiarValores bool) error
+	// Delete borra la métrica y sus valores
+	Delete(id int) error
+	SaveValues(values []entities.DerivedValue) error
+	// FindValues devuelve los valores de las lecturas dadas, por ID de lectura y nombre de métrica
+	FindValues(datoIDs []int32) (map[int32]map[string]float64, error)
+}
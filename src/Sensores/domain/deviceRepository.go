@@ -0,0 +1,21 @@
+// File: src/Sensores/domain/deviceRepository.go
+
+package domain
+
+import "API/src/Sensores/domain/entities"
+
+type DeviceRepository interface {
+	// FindByMac devuelve los metadatos del dispositivo; si nunca se editaron
+	// devuelve un Device vacío con solo la MAC (nunca sql.ErrNoRows).
+	FindByMac(mac string) (*entities.Device, error)
+
+	// FindByMacs obtiene en bloque los metadatos de varias MACs (para enriquecer lecturas)
+	FindByMacs(macs []string) (map[string]*entities.Device, error)
+
+	// FindByUserID lista los dispositivos de las organizaciones del usuario, el que tenga asignado
+	// y los que le compartieron
+	FindByUserID(userID int) ([]entities.Device, error)
+
+	// Save crea o reemplaza nombre, descripción, ubicación y etiquetas
+	Save(device *entities.Device) error
+}
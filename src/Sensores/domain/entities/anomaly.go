@@ -0,0 +1,75 @@
+//File: anomaly.go
+
+package entities
+
+import "time"
+
+// AnomalyMetricas son las métricas numéricas que vigila el detector de anomalías
+var AnomalyMetricas = []string{"temperatura", "distancia", "peso"}
+
+// AnomalyHoraGlobal identifica el modelo de una métrica que aprende de todas las horas; se usa
+// mientras el modelo de la hora del día todavía no tiene suficientes muestras
+const AnomalyHoraGlobal = -1
+
+// Sentido de una anomalía respecto del valor esperado
+const (
+	AnomalyAlta = "alta"
+	AnomalyBaja = "baja"
+)
+
+// AnomalyNotifyDetectada es el tipo de notificación WebSocket de una anomalía
+const AnomalyNotifyDetectada = "anomalia"
+
+// AnomalySettings es la configuración del detector para un dispositivo. UmbralZ es la sensibilidad:
+// cuántas desviaciones típicas debe alejarse una lectura del valor esperado para ser anómala
+// (más bajo = más sensible).
+type AnomalySettings struct {
+	Mac         string    `json:"mac"`
+	UmbralZ     float64   `json:"umbral_z"`
+	Activa      bool      `json:"activa"` // false = el modelo sigue aprendiendo pero no marca lecturas
+	Actualizado time.Time `json:"actualizado"`
+}
+
+// AnomalyState es el modelo en línea (media y varianza con promedio móvil exponencial) de una
+// métrica de un dispositivo en una hora del día (0..23, hora local del servidor) o global
+type AnomalyState struct {
+	Mac         string
+	Metrica     string
+	Hora        int
+	Media       float64
+	Varianza    float64
+	Muestras    int
+	Actualizado time.Time
+}
+
+// AnomalyEvent es una lectura cuyo valor se alejó del esperado más que el umbral del dispositivo.
+// Score es la desviación en desviaciones típicas (con signo).
+type AnomalyEvent struct {
+	ID         int       `json:"id"`
+	DatoID     int64     `json:"dato_id,omitempty"`
+	Mac        string    `json:"mac"`
+	UserID     int       `json:"user_id,omitempty"`
+	OrgID      int       `json:"org_id,omitempty"`
+	Metrica    string    `json:"metrica"`
+	Tipo       string    `json:"tipo"` // alta | baja
+	Fecha      time.Time `json:"fecha"`
+	Valor      float64   `json:"valor"`
+	Esperado   float64   `json:"esperado"`
+	Desviacion float64   `json:"desviacion"` // Desviación típica del modelo usado
+	Score      float64   `json:"score"`
+	Estacional bool      `json:"estacional"` // true = comparado con el modelo de la hora del día
+}
+
+// AnomalyMark es la marca que llevan las lecturas anómalas al consultarlas
+type AnomalyMark struct {
+	Metrica  string  `json:"metrica"`
+	Score    float64 `json:"score"`
+	Esperado float64 `json:"esperado"`
+}
+
+// AnomalyNotification es el mensaje WebSocket que anuncia una anomalía
+type AnomalyNotification struct {
+	Tipo        string       `json:"tipo"` // AnomalyNotifyDetectada
+	Evento      AnomalyEvent `json:"evento"`
+	Dispositivo *Device      `json:"dispositivo,omitempty"`
+}
@@ -0,0 +1,72 @@
+//File: compliance.go
+
+package entities
+
+import "time"
+
+// Periodos de los reportes de cumplimiento (días y semanas en hora local del servidor; la semana
+// empieza el lunes)
+const (
+	ComplianceDiario  = "diario"
+	ComplianceSemanal = "semanal"
+)
+
+// Sentido de una excursión de temperatura
+const (
+	ExcursionAlta = "alta" // Por encima de TempMax
+	ExcursionBaja = "baja" // Por debajo de TempMin
+)
+
+// ComplianceProfile es el rango de temperatura que un dispositivo debe respetar. Las excursiones
+// que duran hasta ToleranciaSeg se informan pero no incumplen; los intervalos sin lecturas de
+// más de HuecoMaximoSeg cuentan como falta de datos.
+type ComplianceProfile struct {
+	Mac            string    `json:"mac"`
+	TempMin        float64   `json:"temp_min"`
+	TempMax        float64   `json:"temp_max"`
+	ToleranciaSeg  int       `json:"tolerancia_seg"`
+	HuecoMaximoSeg int       `json:"hueco_maximo_seg"`
+	Creado         time.Time `json:"creado"`
+	Actualizado    time.Time `json:"actualizado"`
+}
+
+// Excursion es un intervalo continuo con la temperatura fuera del rango, en un mismo sentido.
+// Pico es la temperatura más alejada del rango.
+type Excursion struct {
+	Tipo        string    `json:"tipo"` // alta | baja
+	Inicio      time.Time `json:"inicio"`
+	Fin         time.Time `json:"fin"`
+	DuracionSeg int64     `json:"duracion_seg"`
+	Pico        float64   `json:"pico"`
+	Tolerada    bool      `json:"tolerada"`
+}
+
+// ComplianceSummary son los datos de un reporte que se listan sin su detalle
+type ComplianceSummary struct {
+	ID                int       `json:"id"`
+	Mac               string    `json:"mac"`
+	Periodo           string    `json:"periodo"`
+	Desde             time.Time `json:"desde"`
+	Hasta             time.Time `json:"hasta"`
+	Cumple            bool      `json:"cumple"`
+	PorcentajeEnRango *float64  `json:"porcentaje_en_rango"` // nil si no hubo lecturas
+	Generado          time.Time `json:"generado"`
+	GeneradoPor       int       `json:"generado_por,omitempty"` // 0 = generado automáticamente
+}
+
+// ComplianceReport es el reporte completo; se guarda tal como se generó (con el perfil vigente en
+// ese momento) para auditoría. Cada lectura vale hasta la siguiente si están a menos del hueco
+// máximo; el porcentaje en rango es sobre el tiempo cubierto por lecturas.
+type ComplianceReport struct {
+	ComplianceSummary
+	Perfil              ComplianceProfile `json:"perfil"`
+	Lecturas            int               `json:"lecturas"`
+	TempMinima          *float64          `json:"temp_minima,omitempty"`
+	TempMaxima          *float64          `json:"temp_maxima,omitempty"`
+	SegundosCubiertos   int64             `json:"segundos_cubiertos"`
+	SegundosEnRango     int64             `json:"segundos_en_rango"`
+	SegundosFueraRango  int64             `json:"segundos_fuera_rango"`
+	PorcentajeCobertura float64           `json:"porcentaje_cobertura"`
+	Excursiones         []Excursion       `json:"excursiones"`
+	Huecos              []DataGap         `json:"huecos"`
+}
@@ -0,0 +1,79 @@
+//File: container.go
+
+package entities
+
+import (
+	"math"
+	"time"
+)
+
+// Formas de contenedor admitidas
+const (
+	ContainerCilindro           = "cilindro"            // Cilindro vertical (tanque)
+	ContainerRectangular        = "rectangular"         // Prisma rectangular (contenedor, tolva recta)
+	ContainerCilindroHorizontal = "cilindro_horizontal" // Cilindro acostado; la altura útil es el diámetro
+)
+
+// Estados de nivel respecto de los umbrales del contenedor
+const (
+	FillBajo   = "bajo"
+	FillNormal = "normal"
+	FillAlto   = "alto"
+)
+
+// ContainerConfig es la geometría del contenedor sobre el que está montado un sensor ultrasónico.
+// Las longitudes van en las mismas unidades que 'distancia' (cm); el volumen se informa en litros.
+type ContainerConfig struct {
+	Mac          string    `json:"mac"`
+	Forma        string    `json:"forma"`
+	AlturaSensor float64   `json:"altura_sensor"` // Del sensor al fondo: la distancia medida con el contenedor vacío
+	AlturaMaxima float64   `json:"altura_maxima"` // Altura del contenido con el contenedor lleno, desde el fondo
+	Diametro     *float64  `json:"diametro,omitempty"`
+	Largo        *float64  `json:"largo,omitempty"`
+	Ancho        *float64  `json:"ancho,omitempty"`
+	UmbralBajo   *float64  `json:"umbral_bajo,omitempty"` // Porcentaje de llenado por debajo del cual el nivel es 'bajo'
+	UmbralAlto   *float64  `json:"umbral_alto,omitempty"` // Porcentaje de llenado por encima del cual el nivel es 'alto'
+	Actualizado  time.Time `json:"actualizado"`
+}
+
+// FillLevel es el llenado del contenedor derivado de una lectura de distancia
+type FillLevel struct {
+	Nivel      float64  `json:"nivel"` // Altura del contenido desde el fondo
+	Porcentaje float64  `json:"porcentaje"`
+	Volumen    *float64 `json:"volumen_litros,omitempty"` // nil si faltan dimensiones
+	Estado     string   `json:"estado"`                   // bajo | normal | alto
+}
+
+// Fill calcula el llenado para una distancia medida por el sensor. El nivel se limita a
+// [0, AlturaMaxima]: lecturas dentro de la zona muerta del sensor cuentan como lleno.
+func (c *ContainerConfig) Fill(distancia float64) FillLevel {
+	nivel := math.Min(math.Max(c.AlturaSensor-distancia, 0), c.AlturaMaxima)
+	fill := FillLevel{Nivel: nivel, Estado: FillNormal}
+	if c.AlturaMaxima > 0 {
+		fill.Porcentaje = nivel / c.AlturaMaxima * 100
+	}
+	if c.UmbralBajo != nil && fill.Porcentaje < *c.UmbralBajo {
+		fill.Estado = FillBajo
+	} else if c.UmbralAlto != nil && fill.Porcentaje > *c.UmbralAlto {
+		fill.Estado = FillAlto
+	}
+
+	var cm3 float64
+	switch {
+	case c.Forma == ContainerCilindro && c.Diametro != nil:
+		r := *c.Diametro / 2
+		cm3 = math.Pi * r * r * nivel
+	case c.Forma == ContainerRectangular && c.Largo != nil && c.Ancho != nil:
+		cm3 = *c.Largo * *c.Ancho * nivel
+	case c.Forma == ContainerCilindroHorizontal && c.Diametro != nil && c.Largo != nil:
+		// Área del segmento circular de altura h por el largo del tanque
+		r := *c.Diametro / 2
+		h := math.Min(nivel, 2*r)
+		cm3 = (r*r*math.Acos((r-h)/r) - (r-h)*math.Sqrt(2*r*h-h*h)) * *c.Largo
+	default:
+		return fill
+	}
+	litros := cm3 / 1000
+	fill.Volumen = &litros
+	return fill
+}
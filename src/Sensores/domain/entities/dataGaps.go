@@ -0,0 +1,46 @@
+//File: dataGaps.go
+
+package entities
+
+import "time"
+
+// Origen del intervalo de reporte usado para detectar huecos
+const (
+	IntervaloConfigurado = "configurado" // Definido en los metadatos del dispositivo
+	IntervaloEstimado    = "estimado"    // Mediana de las separaciones entre sus lecturas del rango
+	IntervaloPorDefecto  = "defecto"     // Sin configuración ni lecturas suficientes para estimarlo
+)
+
+// DataGap es un intervalo sin lecturas más largo que el hueco tolerado (el máximo del perfil de
+// cumplimiento o un múltiplo del intervalo de reporte del dispositivo)
+type DataGap struct {
+	Inicio      time.Time `json:"inicio"`
+	Fin         time.Time `json:"fin"`
+	DuracionSeg int64     `json:"duracion_seg"`
+}
+
+// DayCompleteness es la completitud de un día (en la zona pedida) dentro del rango consultado
+type DayCompleteness struct {
+	Dia         time.Time `json:"dia"` // Inicio del día; el primero y el último pueden estar recortados por el rango
+	Lecturas    int64     `json:"lecturas"`
+	Esperadas   int64     `json:"esperadas"`
+	Porcentaje  float64   `json:"porcentaje"` // Lecturas / esperadas (0..100)
+	SinDatosSeg int64     `json:"sin_datos_seg"`
+}
+
+// DeviceGaps son los huecos y la completitud de las lecturas de un dispositivo en un rango
+type DeviceGaps struct {
+	Mac             string            `json:"mac"`
+	Desde           time.Time         `json:"desde"`
+	Hasta           time.Time         `json:"hasta"` // Recortado al momento de la consulta
+	Zona            string            `json:"zona"`
+	IntervaloSeg    int64             `json:"intervalo_seg"`
+	OrigenIntervalo string            `json:"origen_intervalo"` // configurado | estimado | defecto
+	UmbralSeg       int64             `json:"umbral_seg"`       // Separación a partir de la cual se cuenta un hueco
+	Lecturas        int64             `json:"lecturas"`
+	Esperadas       int64             `json:"esperadas"`
+	Porcentaje      float64           `json:"porcentaje"`
+	SinDatosSeg     int64             `json:"sin_datos_seg"`
+	Huecos          []DataGap         `json:"huecos"`
+	Dias            []DayCompleteness `json:"dias"`
+}
@@ -2,13 +2,30 @@
 
 package entities
 
+import "time"
+
+
 type Datos struct {
-	ID          int32   `json:"id"` 
-	Temperatura string  `json:"temperatura"` // Podría ser float64
-	Movimiento  string  `json:"movimiento"`  // Podría ser bool o string ("si", "no")
-	Distancia   string  `json:"distancia"`   // Podría ser float64
-	Peso        string  `json:"peso"`
-	Mac			string	 `json:"peso"`        // Podría ser float64
+	ID          int32              `json:"id"`
+	Temperatura string             `json:"temperatura"` // Podría ser float64
+	Movimiento  string             `json:"movimiento"`  // Podría ser bool o string ("si", "no")
+	Distancia   string             `json:"distancia"`   // Podría ser float64
+	Peso        string             `json:"peso"`        // Podría ser float64
+	Mac         string             `json:"mac"`
+	Fecha       time.Time          `json:"fecha"`                 // Momento en que se registró la lectura
+	UserID      int                `json:"user_id,omitempty"`     // Usuario al que se atribuyó la lectura al insertarla
+	OrgID       int                `json:"org_id,omitempty"`      // Organización dueña de la lectura (fijada al insertarla)
+	Dispositivo *Device            `json:"dispositivo,omitempty"` // Metadatos del dispositivo (nombre, ubicación, etiquetas)
+	Llenado     *FillLevel         `json:"llenado,omitempty"`     // Derivado de distancia si el dispositivo tiene contenedor configurado
+	Anomalias   []AnomalyMark      `json:"anomalias,omitempty"`   // Métricas de la lectura que el detector marcó como anómalas
+	Derivadas   map[string]float64 `json:"derivadas,omitempty"`   // Valores de las métricas derivadas del dispositivo, por nombre
+}
+
+// DatosPage es una página de lecturas; NextCursor se pasa como ?cursor= para pedir la siguiente
+type DatosPage struct {
+	Datos      []Datos `json:"datos"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	HasMore    bool    `json:"has_more"`
 }
 
 func NewDatos(temperatura string, movimiento string, distancia string, peso string, mac string) *Datos {
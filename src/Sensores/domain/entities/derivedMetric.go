@@ -0,0 +1,27 @@
+//File: derivedMetric.go
+
+package entities
+
+import "time"
+
+// DerivedMetric es una métrica que un usuario define para un dispositivo como expresión sobre las
+// métricas de cada lectura (ver domain.ParseDerivedExpr). Se evalúa al recibir cada lectura y su
+// valor se guarda junto a ella; las lecturas anteriores a la definición no tienen valor.
+type DerivedMetric struct {
+	ID          int       `json:"id"`
+	Mac         string    `json:"mac"`
+	Nombre      string    `json:"nombre"` // Se pide con ese nombre en ?metrics= de GET /datos/aggregate
+	Expresion   string    `json:"expresion"`
+	Unidad      string    `json:"unidad"`
+	Creado      time.Time `json:"creado"`
+	Actualizado time.Time `json:"actualizado"`
+}
+
+// DerivedValue es el valor de una métrica derivada en una lectura
+type DerivedValue struct {
+	MetricID int
+	DatoID   int64
+	Mac      string
+	Fecha    time.Time
+	Valor    float64
+}
@@ -0,0 +1,23 @@
+//File: device.go
+
+package entities
+
+// Device son los metadatos legibles de un ESP32, identificado por su MAC.
+type Device struct {
+	Mac          string   `json:"mac"`
+	Nombre       string   `json:"nombre"`
+	Descripcion  string   `json:"descripcion"`
+	Ubicacion    string   `json:"ubicacion"`
+	Etiquetas    []string `json:"etiquetas"`
+	AreaID       *int     `json:"area_id"`       // Área (sala) donde está instalado; nil = sin ubicar
+	SiteID       *int     `json:"site_id"`       // Sitio del área (derivado de AreaID)
+	OrgID        *int     `json:"org_id"`        // Organización dueña; sus lecturas nuevas se le atribuyen
+	IntervaloSeg *int     `json:"intervalo_seg"` // Cada cuántos segundos debería reportar; nil = estimarlo de sus lecturas
+}
+
+func NewDevice(mac string) *Device {
+	return &Device{
+		Mac:       mac,
+		Etiquetas: []string{},
+	}
+}
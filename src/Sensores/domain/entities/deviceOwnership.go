@@ -0,0 +1,28 @@
+//File: deviceOwnership.go
+
+package entities
+
+import "time"
+
+// Valores de DeviceOwnership.Historial
+const (
+	HistorialSinHistorial = "sin_historial" // No había dueño anterior
+	HistorialPendiente    = "pendiente"     // El nuevo dueño aún no decide
+	HistorialTransferido  = "transferido"   // El nuevo dueño ve las lecturas del dueño anterior
+	HistorialPrivado      = "privado"       // Las lecturas anteriores siguen siendo solo del dueño anterior
+)
+
+// DeviceOwnership es un periodo en el que una MAC perteneció a un usuario.
+type DeviceOwnership struct {
+	ID             int        `json:"id"`
+	Mac            string     `json:"mac"`
+	UserID         int        `json:"user_id"`
+	PreviousUserID *int       `json:"previous_user_id,omitempty"`
+	Desde          time.Time  `json:"desde"`
+	Hasta          *time.Time `json:"hasta"` // nil = dueño actual
+	AsignadoPor    *int       `json:"asignado_por,omitempty"`
+	Motivo         string     `json:"motivo"`
+	FinalizadoPor  *int       `json:"finalizado_por,omitempty"`
+	MotivoFin      string     `json:"motivo_fin,omitempty"`
+	Historial      string     `json:"historial"`
+}
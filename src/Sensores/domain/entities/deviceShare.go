@@ -0,0 +1,35 @@
+//File: deviceShare.go
+
+package entities
+
+import "time"
+
+// Permisos de un dispositivo compartido
+const (
+	PermisoLectura   = "lectura"   // Ve lecturas y el feed en vivo
+	PermisoEscritura = "escritura" // Además puede corregir o borrar lecturas
+)
+
+// DeviceShare concede a otro usuario acceso a las lecturas de un dispositivo. Solo expone las
+// lecturas de la organización que era dueña del dispositivo al compartirlo (OrgID).
+// Las invitaciones por email no tienen UserID hasta que alguien las acepta con su token.
+type DeviceShare struct {
+	ID          int        `json:"id"`
+	Mac         string     `json:"mac"`
+	OrgID       int        `json:"org_id"`
+	OtorgadoPor int        `json:"otorgado_por"`
+	UserID      *int       `json:"user_id"`
+	Username    string     `json:"username,omitempty"`
+	Email       string     `json:"email,omitempty"`
+	Permiso     string     `json:"permiso"`
+	Creado      time.Time  `json:"creado"`
+	Expira      *time.Time `json:"expira"`
+	Aceptado    *time.Time `json:"aceptado"`
+	Revocado    *time.Time `json:"revocado"`
+	Token       string     `json:"-"` // Solo viaja en el email de invitación
+}
+
+// Activo indica si el acceso está vigente en el instante dado
+func (s *DeviceShare) Activo(now time.Time) bool {
+	return s.UserID != nil && s.Revocado == nil && (s.Expira == nil || now.Before(*s.Expira))
+}
@@ -0,0 +1,26 @@
+// File: deviceStats.go
+
+package entities
+
+import "time"
+
+// DeviceStats resume un dispositivo para las vistas de flota: totales históricos visibles y
+// estadísticas de las lecturas dentro de una ventana reciente [Desde, Hasta).
+type DeviceStats struct {
+	Mac         string     `json:"mac"`
+	Dispositivo *Device    `json:"dispositivo,omitempty"`
+	Lecturas    int64      `json:"lecturas"` // Todas las lecturas visibles del dispositivo
+	Primera     *time.Time `json:"primera"`
+	Ultima      *time.Time `json:"ultima"`
+
+	Ventana         string      `json:"ventana"` // Duración pedida (24h, 7d, ...)
+	Desde           time.Time   `json:"desde"`
+	Hasta           time.Time   `json:"hasta"`
+	LecturasVentana int64       `json:"lecturas_ventana"`
+	LecturasPorHora float64     `json:"lecturas_por_hora"`   // Tasa de reporte en la ventana
+	IntervaloMedio  *float64    `json:"intervalo_medio_seg"` // Segundos entre lecturas de la ventana; nil con menos de 2
+	Temperatura     MetricStats `json:"temperatura"`
+	Distancia       MetricStats `json:"distancia"`
+	Peso            MetricStats `json:"peso"`
+	Movimiento      *float64    `json:"movimiento"` // Fracción de lecturas con movimiento (0..1)
+}
@@ -0,0 +1,44 @@
+//File: exportJob.go
+
+package entities
+
+import "time"
+
+// Estados de un trabajo de exportación
+const (
+	ExportPendiente  = "pendiente"
+	ExportProcesando = "procesando"
+	ExportCompletado = "completado"
+	ExportFallido    = "fallido"
+	ExportExpirado   = "expirado" // El archivo ya se borró
+)
+
+// ExportFilter son los filtros guardados con el trabajo (mismo significado que en GET /datos)
+type ExportFilter struct {
+	Mac       string     `json:"mac,omitempty"`
+	Etiquetas []string   `json:"etiquetas,omitempty"`
+	SiteID    int        `json:"site_id,omitempty"`
+	AreaID    int        `json:"area_id,omitempty"`
+	UserID    int        `json:"user_id,omitempty"` // Solo lecturas atribuidas a este usuario
+	Desde     *time.Time `json:"desde,omitempty"`
+	Hasta     *time.Time `json:"hasta,omitempty"`
+	Where     string     `json:"where,omitempty"` // Expresión sobre las métricas (domain.ParseMetricFilter)
+}
+
+// ExportJob es una exportación asíncrona de lecturas a Parquet
+type ExportJob struct {
+	ID          int          `json:"id"`
+	UserID      int          `json:"user_id"` // Quien la pidió
+	Todos       bool         `json:"todos"`   // true = sobre todas las lecturas (operador de la plataforma)
+	Formato     string       `json:"formato"` // parquet
+	Filtro      ExportFilter `json:"filtro"`
+	Estado      string       `json:"estado"`
+	Filas       int64        `json:"filas"`
+	Bytes       int64        `json:"bytes"`
+	Error       string       `json:"error,omitempty"`
+	Creado      time.Time    `json:"creado"`
+	Iniciado    *time.Time   `json:"iniciado,omitempty"`
+	Terminado   *time.Time   `json:"terminado,omitempty"`
+	Expira      *time.Time   `json:"expira,omitempty"` // Hasta cuándo se puede descargar
+	DownloadURL string       `json:"download_url,omitempty"`
+}
@@ -0,0 +1,61 @@
+//File: forecast.go
+
+package entities
+
+import "time"
+
+// Modelos de pronóstico
+const (
+	ForecastTendencia  = "tendencia"            // Recta ajustada a los promedios por hora
+	ForecastEstacional = "tendencia_estacional" // Recta más el patrón promedio de cada hora del día
+)
+
+// Origen de un umbral del pronóstico
+const (
+	UmbralPedido          = "pedido"           // Indicado en la consulta
+	UmbralProductoVacio   = "producto_vacio"   // Peso de la balanza sin producto (tara)
+	UmbralStockMinimo     = "stock_minimo"     // Peso con el umbral mínimo de unidades del producto
+	UmbralContenedorVacio = "contenedor_vacio" // Distancia con el contenedor vacío
+	UmbralContenedorBajo  = "contenedor_bajo"  // Distancia en el umbral bajo del contenedor
+	UmbralContenedorAlto  = "contenedor_alto"  // Distancia en el umbral alto del contenedor
+	UmbralCumplimientoMax = "cumplimiento_max" // Temperatura máxima del perfil de cumplimiento
+	UmbralCumplimientoMin = "cumplimiento_min" // Temperatura mínima del perfil de cumplimiento
+)
+
+// ForecastPoint es el valor proyectado de una hora con su banda de confianza
+type ForecastPoint struct {
+	Fecha    time.Time `json:"fecha"`
+	Valor    float64   `json:"valor"`
+	Inferior float64   `json:"inferior"`
+	Superior float64   `json:"superior"`
+}
+
+// ThresholdCrossing es el momento estimado en que la métrica alcanza un umbral, viniendo del lado
+// en que está ahora (Sentido). Temprano y Tardio salen de los bordes de la banda de confianza;
+// nil = no se alcanza dentro del horizonte.
+type ThresholdCrossing struct {
+	Umbral   float64    `json:"umbral"`
+	Origen   string     `json:"origen"`
+	Sentido  string     `json:"sentido"` // sube | baja
+	Estimado *time.Time `json:"estimado"`
+	EnHoras  *float64   `json:"en_horas"` // Horas desde la generación hasta Estimado
+	Temprano *time.Time `json:"temprano"`
+	Tardio   *time.Time `json:"tardio"`
+}
+
+// Forecast es la proyección de una métrica de un dispositivo a partir de su historia reciente
+type Forecast struct {
+	Mac              string              `json:"mac"`
+	Metrica          string              `json:"metrica"`
+	Modelo           string              `json:"modelo"`
+	Generado         time.Time           `json:"generado"`
+	HistoriaDesde    time.Time           `json:"historia_desde"`
+	HorasConDatos    int                 `json:"horas_con_datos"`
+	UltimaLectura    time.Time           `json:"ultima_lectura"`
+	UltimoValor      float64             `json:"ultimo_valor"`
+	PendientePorHora float64             `json:"pendiente_por_hora"`
+	Desviacion       float64             `json:"desviacion"` // Desviación típica de los residuos del ajuste
+	Confianza        int                 `json:"confianza"`  // Porcentaje de la banda
+	Puntos           []ForecastPoint     `json:"puntos"`
+	Cruces           []ThresholdCrossing `json:"cruces"`
+}
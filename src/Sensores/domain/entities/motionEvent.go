@@ -0,0 +1,40 @@
+//File: motionEvent.go
+
+package entities
+
+import "time"
+
+// Estados de un intervalo de movimiento
+const (
+	MotionCandidato = "candidato" // Aún no dura lo suficiente para reportarse (antirrebote)
+	MotionAbierto   = "abierto"   // En curso: todavía llegan muestras con movimiento
+	MotionCerrado   = "cerrado"
+)
+
+// Tipos de notificación WebSocket de los intervalos de movimiento
+const (
+	MotionNotifyInicio = "movimiento_inicio"
+	MotionNotifyFin    = "movimiento_fin"
+)
+
+// MotionEvent es un intervalo de movimiento continuo de un dispositivo, armado a partir de las
+// lecturas consecutivas con movimiento. Fin es la última muestra con movimiento (nil si sigue abierto).
+type MotionEvent struct {
+	ID               int        `json:"id"`
+	Mac              string     `json:"mac"`
+	UserID           int        `json:"user_id,omitempty"`
+	OrgID            int        `json:"org_id,omitempty"`
+	Estado           string     `json:"estado"`
+	Inicio           time.Time  `json:"inicio"`
+	Fin              *time.Time `json:"fin"`
+	UltimoMovimiento time.Time  `json:"ultimo_movimiento"`
+	Muestras         int        `json:"muestras"` // Lecturas con movimiento dentro del intervalo
+	DuracionSeg      float64    `json:"duracion_seg"`
+}
+
+// MotionNotification es el mensaje WebSocket que anuncia la apertura o el cierre de un intervalo
+type MotionNotification struct {
+	Tipo        string      `json:"tipo"` // MotionNotifyInicio | MotionNotifyFin
+	Evento      MotionEvent `json:"evento"`
+	Dispositivo *Device     `json:"dispositivo,omitempty"`
+}
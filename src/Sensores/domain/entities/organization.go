@@ -0,0 +1,36 @@
+//File: organization.go
+
+package entities
+
+// Roles de un usuario dentro de una organización
+const (
+	RolOrgOwner  = "owner"  // Todo, incluido borrar miembros owner
+	RolOrgAdmin  = "admin"  // Gestiona miembros (no owners) y dispositivos
+	RolOrgMember = "member" // Lee y edita datos y metadatos
+	RolOrgViewer = "viewer" // Solo lectura
+)
+
+// Organization es el tenant dueño de dispositivos y datos. Cada usuario tiene una
+// organización personal (Personal=true) creada al registrarse.
+type Organization struct {
+	ID       int                  `json:"id"`
+	Nombre   string               `json:"nombre"`
+	Personal bool                 `json:"personal"`
+	Rol      string               `json:"rol,omitempty"` // Rol del usuario que consulta
+	Miembros []OrganizationMember `json:"miembros,omitempty"`
+}
+
+type OrganizationMember struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Rol      string `json:"rol"`
+}
+
+// IsValidOrgRole indica si el rol es uno de los cuatro roles de organización
+func IsValidOrgRole(role string) bool {
+	switch role {
+	case RolOrgOwner, RolOrgAdmin, RolOrgMember, RolOrgViewer:
+		return true
+	}
+	return false
+}
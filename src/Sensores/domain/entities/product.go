@@ -0,0 +1,92 @@
+//File: product.go
+
+package entities
+
+import (
+	"math"
+	"time"
+)
+
+// Tipos de movimiento de inventario
+const (
+	InventoryReposicion = "reposicion" // Subió el peso: se repusieron unidades
+	InventoryConsumo    = "consumo"    // Bajó el peso: se retiraron unidades
+)
+
+// Estados de stock respecto del umbral mínimo del producto
+const (
+	StockBajo   = "bajo"
+	StockNormal = "normal"
+)
+
+// Product es el artículo que se guarda sobre la balanza de un dispositivo (uno por dispositivo).
+// Los pesos van en las mismas unidades que 'peso'; Tara es el peso de la balanza vacía (estante,
+// bandeja) que se descuenta antes de contar unidades.
+type Product struct {
+	ID           int         `json:"id"`
+	Mac          string      `json:"mac"`
+	Nombre       string      `json:"nombre"`
+	PesoUnitario float64     `json:"peso_unitario"`
+	Tara         float64     `json:"tara"`
+	UmbralMinimo *int        `json:"umbral_minimo,omitempty"` // Unidades a partir de las cuales el stock es 'bajo'
+	Creado       time.Time   `json:"creado"`
+	Actualizado  time.Time   `json:"actualizado"`
+	Stock        *StockLevel `json:"stock,omitempty"` // nil si la balanza todavía no reportó peso
+}
+
+// StockLevel es el stock del producto según el último peso estable de la balanza
+type StockLevel struct {
+	Unidades    int       `json:"unidades"`
+	Peso        float64   `json:"peso"`
+	Actualizado time.Time `json:"actualizado"`
+	Estado      string    `json:"estado"` // bajo | normal
+}
+
+// Units convierte un peso de la balanza en unidades del producto (redondeado, nunca negativo)
+func (p *Product) Units(peso float64) int {
+	if p.PesoUnitario <= 0 {
+		return 0
+	}
+	return int(math.Max(math.Round((peso-p.Tara)/p.PesoUnitario), 0))
+}
+
+// StockFor arma el stock del producto para un peso estable de la balanza
+func (p *Product) StockFor(peso float64, actualizado time.Time) *StockLevel {
+	stock := &StockLevel{Unidades: p.Units(peso), Peso: peso, Actualizado: actualizado, Estado: StockNormal}
+	if p.UmbralMinimo != nil && stock.Unidades <= *p.UmbralMinimo {
+		stock.Estado = StockBajo
+	}
+	return stock
+}
+
+// InventoryMovement es una reposición o un consumo del producto, derivado de un cambio de peso
+// confirmado (WeightEvent). Unidades es siempre positiva; Tipo indica el sentido.
+type InventoryMovement struct {
+	ID                  int       `json:"id"`
+	ProductID           int       `json:"product_id"`
+	WeightEventID       int       `json:"weight_event_id,omitempty"`
+	Tipo                string    `json:"tipo"`
+	Unidades            int       `json:"unidades"`
+	UnidadesResultantes int       `json:"unidades_resultantes"`
+	Fecha               time.Time `json:"fecha"`
+}
+
+// DailyConsumption resume los movimientos de un producto en un día (hora local del servidor)
+type DailyConsumption struct {
+	Dia         string `json:"dia"` // AAAA-MM-DD
+	Consumido   int    `json:"consumido"`
+	Repuesto    int    `json:"repuesto"`
+	Movimientos int    `json:"movimientos"`
+}
+
+// ConsumptionReport es el consumo diario de un producto en un rango de días
+type ConsumptionReport struct {
+	Producto       Product            `json:"producto"`
+	Desde          string             `json:"desde"` // AAAA-MM-DD, inclusive
+	Hasta          string             `json:"hasta"` // AAAA-MM-DD, inclusive
+	Dias           []DailyConsumption `json:"dias"`
+	TotalConsumido int                `json:"total_consumido"`
+	TotalRepuesto  int                `json:"total_repuesto"`
+	PromedioDiario float64            `json:"promedio_diario"`          // Unidades consumidas por día
+	DiasRestantes  *float64           `json:"dias_restantes,omitempty"` // Stock actual / promedio diario
+}
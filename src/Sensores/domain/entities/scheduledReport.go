@@ -0,0 +1,75 @@
+//File: scheduledReport.go
+
+package entities
+
+import "time"
+
+// Frecuencias de las suscripciones a reportes. Cada envío resume el periodo anterior completo
+// (ayer, la semana pasada de lunes a lunes o el mes pasado) en la zona de la suscripción.
+const (
+	ReportDiario  = "diario"
+	ReportSemanal = "semanal"
+	ReportMensual = "mensual"
+)
+
+// Tipos de alerta incluidos en los reportes
+const (
+	ReportAlertaCumplimiento = "incumplimiento_temperatura" // Reporte de cumplimiento del periodo que no cumple
+	ReportAlertaStock        = "stock_bajo"                 // Producto en o por debajo de su umbral mínimo
+	ReportAlertaNivel        = "nivel_contenedor"           // Última lectura con el contenedor en nivel bajo o alto
+)
+
+// ReportSubscription es el pedido de un usuario de recibir por email un resumen periódico de
+// sus dispositivos. ProximoEnvio es el instante (UTC) del siguiente envío: la hora Hora del día
+// en que empieza un periodo, en la zona Zona. No se envía nada hasta que alguien confirma el
+// email con el enlace que se le manda (Token).
+type ReportSubscription struct {
+	ID                  int        `json:"id"`
+	UserID              int        `json:"user_id"`
+	Frecuencia          string     `json:"frecuencia"` // diario | semanal | mensual
+	Zona                string     `json:"zona"`       // Nombre IANA
+	Hora                int        `json:"hora"`       // 0..23
+	Email               string     `json:"email"`
+	EmailConfirmado     bool       `json:"email_confirmado"`
+	Activa              bool       `json:"activa"`
+	ProximoEnvio        time.Time  `json:"proximo_envio"`
+	UltimoEnvio         *time.Time `json:"ultimo_envio,omitempty"`
+	Creado              time.Time  `json:"creado"`
+	Token               string     `json:"-"` // Token del enlace de confirmación pendiente
+	ConfirmacionEnviada *time.Time `json:"-"` // Último email de confirmación
+}
+
+// ScheduledReport es un reporte generado (y enviado, si se pudo). HTML es el cuerpo del correo.
+type ScheduledReport struct {
+	ID             int        `json:"id"`
+	SubscriptionID int        `json:"subscription_id,omitempty"` // 0 si la suscripción se borró
+	UserID         int        `json:"user_id"`
+	Frecuencia     string     `json:"frecuencia"`
+	Zona           string     `json:"zona"`
+	Desde          time.Time  `json:"desde"`
+	Hasta          time.Time  `json:"hasta"`
+	Email          string     `json:"email"`
+	Asunto         string     `json:"asunto"`
+	Dispositivos   int        `json:"dispositivos"`
+	Alertas        int        `json:"alertas"`
+	Huecos         int        `json:"huecos"`
+	Generado       time.Time  `json:"generado"`
+	Enviado        *time.Time `json:"enviado,omitempty"`
+	ErrorEnvio     string     `json:"error_envio,omitempty"`
+	HTML           string     `json:"-"`
+}
+
+// ReportAlert es una situación que requiere atención incluida en un reporte
+type ReportAlert struct {
+	Tipo    string     `json:"tipo"`
+	Mac     string     `json:"mac"`
+	Detalle string     `json:"detalle"`
+	Fecha   *time.Time `json:"fecha,omitempty"`
+}
+
+// ReportGap es un intervalo del periodo en que un dispositivo no envió lecturas
+type ReportGap struct {
+	Mac   string    `json:"mac"`
+	Desde time.Time `json:"desde"`
+	Hasta time.Time `json:"hasta"`
+}
@@ -0,0 +1,22 @@
+//File: site.go
+
+package entities
+
+// Site es un edificio o instalación; agrupa áreas (salas) y, a través de ellas, dispositivos.
+type Site struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"user_id"` // Usuario que creó el sitio
+	OrgID       int    `json:"org_id"`  // Organización dueña del sitio
+	Nombre      string `json:"nombre"`
+	Descripcion string `json:"descripcion"`
+	Areas       []Area `json:"areas"`
+}
+
+// Area es una sala o zona dentro de un sitio.
+type Area struct {
+	ID           int      `json:"id"`
+	SiteID       int      `json:"site_id"`
+	Nombre       string   `json:"nombre"`
+	Descripcion  string   `json:"descripcion"`
+	Dispositivos []Device `json:"dispositivos"`
+}
@@ -0,0 +1,25 @@
+//File: storage.go
+
+package entities
+
+import "time"
+
+// UserStorage es el uso de almacenamiento atribuido a un usuario
+type UserStorage struct {
+	UserID             int        `json:"user_id"`
+	Username           string     `json:"username"`
+	Lecturas           int64      `json:"lecturas"`
+	Dispositivos       int        `json:"dispositivos"`
+	Primera            *time.Time `json:"primera,omitempty"`
+	Ultima             *time.Time `json:"ultima,omitempty"`
+	BytesEstimados     int64      `json:"bytes_estimados"`     // Parte proporcional del tamaño de 'rutas'
+	ExportacionesBytes int64      `json:"exportaciones_bytes"` // Archivos de exportación aún descargables
+}
+
+// StorageUsage resume el almacenamiento de lecturas de toda la plataforma
+type StorageUsage struct {
+	Lecturas           int64         `json:"lecturas"`
+	LecturasSinUsuario int64         `json:"lecturas_sin_usuario"`
+	BytesTabla         int64         `json:"bytes_tabla"`
+	Usuarios           []UserStorage `json:"usuarios"` // Más lecturas primero
+}
@@ -0,0 +1,60 @@
+//File: summary.go
+
+package entities
+
+import "time"
+
+// MetricStats son estadísticas de una métrica numérica; nil si no hubo valores válidos.
+type MetricStats struct {
+	Promedio *float64 `json:"promedio"`
+	Min      *float64 `json:"min"`
+	Max      *float64 `json:"max"`
+}
+
+// DeviceSummary resume las lecturas de un dispositivo en un conjunto de datos.
+type DeviceSummary struct {
+	Mac         string      `json:"mac"`
+	Dispositivo *Device     `json:"dispositivo,omitempty"`
+	Lecturas    int64       `json:"lecturas"`
+	Primera     *time.Time  `json:"primera"`
+	Ultima      *time.Time  `json:"ultima"`
+	Temperatura MetricStats `json:"temperatura"`
+	Distancia   MetricStats `json:"distancia"`
+	Peso        MetricStats `json:"peso"`
+	Movimiento  *float64    `json:"movimiento"` // Fracción de lecturas con movimiento (0..1)
+}
+
+// MetricAggregate son las estadísticas de una métrica dentro de una cubeta de tiempo.
+// Lecturas cuenta solo los valores válidos; Percentiles usa claves "p50", "p95", ...
+type MetricAggregate struct {
+	Lecturas int64 `json:"lecturas"`
+	MetricStats
+	Percentiles map[string]*float64 `json:"percentiles,omitempty"`
+}
+
+// AggregateBucket es una cubeta de tiempo que empieza en Inicio (en la zona pedida)
+type AggregateBucket struct {
+	Inicio   time.Time                  `json:"inicio"`
+	Lecturas int64                      `json:"lecturas"`
+	Metricas map[string]MetricAggregate `json:"metricas"`
+}
+
+// DeviceSeries son las cubetas con lecturas de un dispositivo, en orden cronológico
+type DeviceSeries struct {
+	Mac         string            `json:"mac"`
+	Dispositivo *Device           `json:"dispositivo,omitempty"`
+	Buckets     []AggregateBucket `json:"buckets"`
+	Huecos      []DataGap         `json:"huecos,omitempty"` // Periodos sin reportar de al menos una cubeta
+}
+
+// DatosAggregate es la respuesta de una agregación por cubetas de tiempo
+type DatosAggregate struct {
+	Bucket      string         `json:"bucket"`
+	Zona        string         `json:"zona"`
+	Desde       time.Time      `json:"desde"`
+	Hasta       time.Time      `json:"hasta"`
+	Metricas    []string       `json:"metricas"`
+	Percentiles []int          `json:"percentiles,omitempty"`
+	Resolucion  string         `json:"resolucion"` // raw | hora | dia: de dónde salieron las cubetas
+	Series      []DeviceSeries `json:"series"`
+}
@@ -0,0 +1,56 @@
+//File: weightEvent.go
+
+package entities
+
+import "time"
+
+// Tipos de cambio de peso
+const (
+	WeightAgregado = "agregado" // Subió el peso: se añadió algo a la balanza
+	WeightRetirado = "retirado" // Bajó el peso: se retiró algo
+)
+
+// WeightNotifyCambio es el tipo de notificación WebSocket de un cambio de peso
+const WeightNotifyCambio = "peso_cambio"
+
+// WeightEvent es un cambio escalonado y estable del peso de un dispositivo. Inicio es la primera
+// lectura en el nuevo nivel y Confirmado la lectura con la que se cumplió el tiempo de asentamiento.
+type WeightEvent struct {
+	ID             int       `json:"id"`
+	Mac            string    `json:"mac"`
+	UserID         int       `json:"user_id,omitempty"`
+	OrgID          int       `json:"org_id,omitempty"`
+	Tipo           string    `json:"tipo"`
+	Inicio         time.Time `json:"inicio"`
+	Confirmado     time.Time `json:"confirmado"`
+	PesoAnterior   float64   `json:"peso_anterior"`
+	PesoResultante float64   `json:"peso_resultante"`
+	Delta          float64   `json:"delta"`
+	Muestras       int       `json:"muestras"` // Lecturas promediadas en el nuevo nivel
+}
+
+// WeightState es el seguimiento del peso de un dispositivo entre lecturas: el último nivel estable
+// y, si hay, el nivel candidato que todavía no cumplió el tiempo de asentamiento
+type WeightState struct {
+	Mac               string
+	PesoEstable       float64
+	UltimaMuestra     time.Time
+	CandidatoDesde    *time.Time // nil = sin candidato
+	CandidatoSuma     float64
+	CandidatoLecturas int
+}
+
+// CandidatoPromedio es el nivel del candidato (promedio de sus lecturas)
+func (s *WeightState) CandidatoPromedio() float64 {
+	if s.CandidatoLecturas == 0 {
+		return 0
+	}
+	return s.CandidatoSuma / float64(s.CandidatoLecturas)
+}
+
+// WeightNotification es el mensaje WebSocket que anuncia un cambio de peso
+type WeightNotification struct {
+	Tipo        string      `json:"tipo"` // WeightNotifyCambio
+	Evento      WeightEvent `json:"evento"`
+	Dispositivo *Device     `json:"dispositivo,omitempty"`
+}
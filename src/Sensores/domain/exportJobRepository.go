@@ -0,0 +1,48 @@
+// File: exportJobRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"io"
+	"time"
+)
+
+type ExportJobRepository interface {
+	Create(job *entities.ExportJob) error
+	// FindByID devuelve sql.ErrNoRows si no existe
+	FindByID(id int) (*entities.ExportJob, error)
+	// FindForUser lista los trabajos pedidos por el usuario, más recientes primero
+	FindForUser(userID int) ([]entities.ExportJob, error)
+	// CountActive cuenta los trabajos pendientes o en proceso del usuario
+	CountActive(userID int) (int, error)
+	MarkRunning(id int) error
+	Finish(id int, filas int64, bytes int64, expira time.Time) error
+	Fail(id int, motivo string) error
+	// FailInterrupted marca como fallidos los trabajos que quedaron a medias (p. ej. tras un reinicio)
+	FailInterrupted() (int, error)
+	// FindExpired devuelve los trabajos completados cuyo archivo venció
+	FindExpired(now time.Time) ([]entities.ExportJob, error)
+	MarkExpired(id int) error
+	// BytesByUser suma por usuario el tamaño de los archivos completados aún no expirados
+	BytesByUser() (map[int]int64, error)
+}
+
+// ArchiveWriter recibe las lecturas de un trabajo y genera su archivo
+type ArchiveWriter interface {
+	Write(d entities.Datos) error
+	// Close termina el archivo y devuelve su tamaño en bytes
+	Close() (int64, error)
+	// Abort descarta el archivo a medio escribir
+	Abort()
+}
+
+// ArchiveStore guarda los archivos de exportación, uno por trabajo
+type ArchiveStore interface {
+	Create(jobID int) (ArchiveWriter, error)
+	Open(jobID int) (io.ReadSeekCloser, error)
+	Remove(jobID int) error
+	// Extension y ContentType describen los archivos generados
+	Extension() string
+	ContentType() string
+}
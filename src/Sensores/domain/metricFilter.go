@@ -0,0 +1,412 @@
+// File: metricFilter.go
+
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lenguaje de filtros sobre las métricas de una lectura (?where= en las consultas de datos):
+//
+//	temperatura > 30 and movimiento = si
+//	(peso between 10 and 20 or distancia < 5) and not temperatura in (0, 99)
+//	peso is not null
+//
+// Campos: temperatura, distancia, peso, llenado, volumen (números), movimiento (si/no) y los nombres
+// de las métricas derivadas (números; ver ValidDerivedName). Operadores: = != <> < <= > >=,
+// [not] in (...), [not] between a and b, is [not] null, and/or/not (también && || !) y paréntesis.
+// Una comparación con una métrica vacía es falsa, también en los dispositivos que no definen la
+// métrica derivada.
+
+const (
+	metricFilterMaxLength = 1000
+	metricFilterMaxNodes  = 100
+	metricFilterMaxList   = 100
+)
+
+// MetricFilter es una expresión ya validada; Texto es la original (para guardarla y volver a leerla)
+type MetricFilter struct {
+	Texto string
+	Raiz  MetricExpr
+}
+
+// MetricExpr es un nodo de la expresión: MetricLogic, MetricNot o MetricCondition
+type MetricExpr interface {
+	metricExpr()
+}
+
+// MetricLogic combina dos expresiones con "AND" u "OR"
+type MetricLogic struct {
+	Op          string
+	Left, Right MetricExpr
+}
+
+type MetricNot struct {
+	Expr MetricExpr
+}
+
+// MetricCondition compara un campo. Op: = != < <= > >= IN BETWEEN "IS NULL" "IS NOT NULL".
+// En movimiento los valores son 1 (sí) o 0 (no).
+type MetricCondition struct {
+	Campo   string
+	Op      string
+	Valores []float64
+}
+
+func (MetricLogic) metricExpr()     {}
+func (MetricNot) metricExpr()       {}
+func (MetricCondition) metricExpr() {}
+
+// MetricFilterFields son los campos que admite el lenguaje y si son booleanos. llenado (%) y
+// volumen (litros) se derivan de distancia y son NULL en dispositivos sin contenedor configurado.
+var MetricFilterFields = map[string]bool{"temperatura": false, "distancia": false, "peso": false, "movimiento": true, "llenado": false, "volumen": false}
+
+// metricFilterKeywords son las palabras del lenguaje; no pueden ser nombres de campos
+var metricFilterKeywords = map[string]bool{"and": true, "or": true, "not": true, "in": true, "is": true, "null": true, "between": true}
+
+// MetricFilterError indica dónde falló la lectura de la expresión (posición en caracteres, desde 1)
+type MetricFilterError struct {
+	Posicion int
+	Mensaje  string
+}
+
+func (e *MetricFilterError) Error() string {
+	return fmt.Sprintf("posición %d: %s", e.Posicion, e.Mensaje)
+}
+
+type filterToken struct {
+	kind string // word | number | string | op | ( | ) | , | end
+	text string
+	pos  int // Byte dentro del texto
+}
+
+type metricFilterParser struct {
+	text   string
+	tokens []filterToken
+	next   int
+	nodes  int
+}
+
+// ParseMetricFilter valida y analiza una expresión
+func ParseMetricFilter(text string) (*MetricFilter, error) {
+	if utf8.RuneCountInString(text) > metricFilterMaxLength {
+		return nil, &MetricFilterError{Posicion: metricFilterMaxLength + 1, Mensaje: fmt.Sprintf("la expresión supera los %d caracteres", metricFilterMaxLength)}
+	}
+	p := &metricFilterParser{text: text}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	if p.peek().kind == "end" {
+		return nil, p.errorAt(p.peek(), "la expresión está vacía")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "end" {
+		return nil, p.errorAt(tok, fmt.Sprintf("se esperaba and, or o el final y se encontró '%s'", tok.text))
+	}
+	return &MetricFilter{Texto: text, Raiz: root}, nil
+}
+
+func (p *metricFilterParser) errorAt(tok filterToken, mensaje string) *MetricFilterError {
+	return &MetricFilterError{Posicion: utf8.RuneCountInString(p.text[:tok.pos]) + 1, Mensaje: mensaje}
+}
+
+func (p *metricFilterParser) tokenize() error {
+	text := p.text
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case r == '(' || r == ')' || r == ',':
+			p.tokens = append(p.tokens, filterToken{kind: string(r), text: string(r), pos: start})
+			i++
+			continue
+		case r == '\'' || r == '"':
+			end := strings.IndexRune(text[i+1:], r)
+			if end < 0 {
+				return p.errorAt(filterToken{pos: start}, "comillas sin cerrar")
+			}
+			p.tokens = append(p.tokens, filterToken{kind: "string", text: text[i+1 : i+1+end], pos: start})
+			i += end + 2
+			continue
+		case unicode.IsDigit(r) || ((r == '-' || r == '+' || r == '.') && i+1 < len(text) && (unicode.IsDigit(rune(text[i+1])) || text[i+1] == '.')):
+			i++
+			for i < len(text) && (unicode.IsDigit(rune(text[i])) || text[i] == '.' || text[i] == 'e' || text[i] == 'E' ||
+				((text[i] == '-' || text[i] == '+') && (text[i-1] == 'e' || text[i-1] == 'E'))) {
+				i++
+			}
+			p.tokens = append(p.tokens, filterToken{kind: "number", text: text[start:i], pos: start})
+			continue
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				i += size
+			}
+			p.tokens = append(p.tokens, filterToken{kind: "word", text: text[start:i], pos: start})
+			continue
+		}
+		// Operadores de uno o dos caracteres
+		op := ""
+		for _, candidate := range []string{"<=", ">=", "!=", "<>", "==", "&&", "||", "=", "<", ">", "!"} {
+			if strings.HasPrefix(text[i:], candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return p.errorAt(filterToken{pos: start}, fmt.Sprintf("carácter inesperado '%c'", r))
+		}
+		i += len(op)
+		switch op {
+		case "==":
+			op = "="
+		case "<>":
+			op = "!="
+		}
+		p.tokens = append(p.tokens, filterToken{kind: "op", text: op, pos: start})
+	}
+	p.tokens = append(p.tokens, filterToken{kind: "end", text: "final de la expresión", pos: len(text)})
+	return nil
+}
+
+func (p *metricFilterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *metricFilterParser) advance() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != "end" {
+		p.next++
+	}
+	return tok
+}
+
+// isKeyword reconoce palabras clave sin distinguir mayúsculas y sus equivalentes simbólicos
+func (p *metricFilterParser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	switch {
+	case tok.kind == "word":
+		return strings.EqualFold(tok.text, keyword)
+	case tok.kind == "op" && keyword == "and":
+		return tok.text == "&&"
+	case tok.kind == "op" && keyword == "or":
+		return tok.text == "||"
+	case tok.kind == "op" && keyword == "not":
+		return tok.text == "!"
+	}
+	return false
+}
+
+func (p *metricFilterParser) expect(kind string, description string) (filterToken, error) {
+	tok := p.peek()
+	if tok.kind != kind {
+		return tok, p.errorAt(tok, fmt.Sprintf("se esperaba %s y se encontró '%s'", description, tok.text))
+	}
+	return p.advance(), nil
+}
+
+func (p *metricFilterParser) countNode(tok filterToken) error {
+	p.nodes++
+	if p.nodes > metricFilterMaxNodes {
+		return p.errorAt(tok, fmt.Sprintf("la expresión es demasiado compleja (más de %d condiciones)", metricFilterMaxNodes))
+	}
+	return nil
+}
+
+func (p *metricFilterParser) parseOr() (MetricExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = MetricLogic{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *metricFilterParser) parseAnd() (MetricExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = MetricLogic{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *metricFilterParser) parseUnary() (MetricExpr, error) {
+	if p.isKeyword("not") {
+		if err := p.countNode(p.advance()); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return MetricNot{Expr: expr}, nil
+	}
+	if p.peek().kind == "(" {
+		p.advance()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")", "')'"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *metricFilterParser) parseCondition() (MetricExpr, error) {
+	fieldTok := p.peek()
+	if fieldTok.kind != "word" {
+		return nil, p.errorAt(fieldTok, fmt.Sprintf("se esperaba un campo (una métrica de la lectura o una métrica derivada) y se encontró '%s'", fieldTok.text))
+	}
+	campo := strings.ToLower(fieldTok.text)
+	booleano, ok := MetricFilterFields[campo]
+	if !ok && !ValidDerivedName(campo) {
+		return nil, p.errorAt(fieldTok, fmt.Sprintf("campo desconocido '%s': use temperatura, distancia, peso, movimiento, llenado, volumen o el nombre de una métrica derivada", fieldTok.text))
+	}
+	if err := p.countNode(fieldTok); err != nil {
+		return nil, err
+	}
+	p.advance()
+	cond := MetricCondition{Campo: campo}
+
+	negado := false
+	if p.isKeyword("is") {
+		p.advance()
+		cond.Op = "IS NULL"
+		if p.isKeyword("not") {
+			p.advance()
+			cond.Op = "IS NOT NULL"
+		}
+		if !p.isKeyword("null") {
+			return nil, p.errorAt(p.peek(), fmt.Sprintf("se esperaba null y se encontró '%s'", p.peek().text))
+		}
+		p.advance()
+		return cond, nil
+	}
+	if p.isKeyword("not") {
+		p.advance()
+		negado = true
+		if !p.isKeyword("in") && !p.isKeyword("between") {
+			return nil, p.errorAt(p.peek(), fmt.Sprintf("se esperaba in o between después de not y se encontró '%s'", p.peek().text))
+		}
+	}
+
+	switch {
+	case p.isKeyword("in"):
+		p.advance()
+		cond.Op = "IN"
+		if _, err := p.expect("(", "'(' con la lista de valores"); err != nil {
+			return nil, err
+		}
+		for {
+			v, err := p.parseValue(campo, booleano)
+			if err != nil {
+				return nil, err
+			}
+			cond.Valores = append(cond.Valores, v)
+			if len(cond.Valores) > metricFilterMaxList {
+				return nil, p.errorAt(p.peek(), fmt.Sprintf("la lista de in admite hasta %d valores", metricFilterMaxList))
+			}
+			if p.peek().kind != "," {
+				break
+			}
+			p.advance()
+		}
+		if _, err := p.expect(")", "',' o ')'"); err != nil {
+			return nil, err
+		}
+	case p.isKeyword("between"):
+		betweenTok := p.advance()
+		if booleano {
+			return nil, p.errorAt(betweenTok, "movimiento solo admite =, != e in")
+		}
+		cond.Op = "BETWEEN"
+		min, err := p.parseValue(campo, booleano)
+		if err != nil {
+			return nil, err
+		}
+		if !p.isKeyword("and") {
+			return nil, p.errorAt(p.peek(), fmt.Sprintf("se esperaba and en between y se encontró '%s'", p.peek().text))
+		}
+		p.advance()
+		max, err := p.parseValue(campo, booleano)
+		if err != nil {
+			return nil, err
+		}
+		cond.Valores = []float64{min, max}
+	case p.peek().kind == "op" && p.peek().text != "!" && p.peek().text != "&&" && p.peek().text != "||":
+		opTok := p.advance()
+		if booleano && opTok.text != "=" && opTok.text != "!=" {
+			return nil, p.errorAt(opTok, "movimiento solo admite =, != e in")
+		}
+		cond.Op = opTok.text
+		v, err := p.parseValue(campo, booleano)
+		if err != nil {
+			return nil, err
+		}
+		cond.Valores = []float64{v}
+	default:
+		return nil, p.errorAt(p.peek(), fmt.Sprintf("se esperaba un operador después de '%s' y se encontró '%s'", fieldTok.text, p.peek().text))
+	}
+	if negado {
+		return MetricNot{Expr: cond}, nil
+	}
+	return cond, nil
+}
+
+// parseValue lee un número o, para movimiento, si/no
+func (p *metricFilterParser) parseValue(campo string, booleano bool) (float64, error) {
+	tok := p.advance()
+	if booleano {
+		if tok.kind == "word" || tok.kind == "string" || tok.kind == "number" {
+			switch strings.ToLower(strings.TrimSpace(tok.text)) {
+			case "si", "sí", "true", "yes", "1":
+				return 1, nil
+			case "no", "false", "0":
+				return 0, nil
+			}
+		}
+		return 0, p.errorAt(tok, fmt.Sprintf("valor inválido '%s' para movimiento: use si o no", tok.text))
+	}
+	text := tok.text
+	if tok.kind == "string" {
+		text = strings.TrimSpace(text)
+	} else if tok.kind != "number" {
+		return 0, p.errorAt(tok, fmt.Sprintf("se esperaba un número para %s y se encontró '%s'", campo, tok.text))
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, p.errorAt(tok, fmt.Sprintf("número inválido '%s'", tok.text))
+	}
+	return v, nil
+}
@@ -0,0 +1,132 @@
+// File: metricFilter_test.go
+
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func cond(campo string, op string, valores ...float64) MetricCondition {
+	return MetricCondition{Campo: campo, Op: op, Valores: valores}
+}
+
+func TestParseMetricFilter(t *testing.T) {
+	tests := []struct {
+		texto string
+		want  MetricExpr
+	}{
+		{"temperatura > 30", cond("temperatura", ">", 30)},
+		{"TEMPERATURA >= -5.5", cond("temperatura", ">=", -5.5)},
+		{"distancia == 3", cond("distancia", "=", 3)},
+		{"peso <> 2", cond("peso", "!=", 2)},
+		{"peso = 1.5e2", cond("peso", "=", 150)},
+		{"movimiento = si", cond("movimiento", "=", 1)},
+		{"movimiento in (sí, no)", cond("movimiento", "IN", 1, 0)},
+		{"peso is null", cond("peso", "IS NULL")},
+		{"peso is not null", cond("peso", "IS NOT NULL")},
+		// Métricas derivadas: cualquier nombre válido, numérico
+		{"merma_diaria > 2", cond("merma_diaria", ">", 2)},
+		{"Fahrenheit between 32 and 50", cond("fahrenheit", "BETWEEN", 32, 50)},
+		{"peso > 1 and merma is null", MetricLogic{Op: "AND", Left: cond("peso", ">", 1), Right: cond("merma", "IS NULL")}},
+		{"llenado between 10 and 20", cond("llenado", "BETWEEN", 10, 20)},
+		{"peso not between 10 and 20", MetricNot{Expr: cond("peso", "BETWEEN", 10, 20)}},
+		{"not temperatura in (0, 99)", MetricNot{Expr: cond("temperatura", "IN", 0, 99)}},
+		{"temperatura > 30 and movimiento = si", MetricLogic{Op: "AND", Left: cond("temperatura", ">", 30), Right: cond("movimiento", "=", 1)}},
+		// and liga más que or
+		{"peso = 1 or peso = 2 and peso = 3", MetricLogic{Op: "OR", Left: cond("peso", "=", 1),
+			Right: MetricLogic{Op: "AND", Left: cond("peso", "=", 2), Right: cond("peso", "=", 3)}}},
+		{"(peso = 1 or peso = 2) and peso = 3", MetricLogic{Op: "AND",
+			Left: MetricLogic{Op: "OR", Left: cond("peso", "=", 1), Right: cond("peso", "=", 2)}, Right: cond("peso", "=", 3)}},
+		// and y or se asocian por la izquierda
+		{"peso = 1 or peso = 2 or peso = 3", MetricLogic{Op: "OR",
+			Left: MetricLogic{Op: "OR", Left: cond("peso", "=", 1), Right: cond("peso", "=", 2)}, Right: cond("peso", "=", 3)}},
+		{"temperatura < 0 && !(peso != 2) || volumen > 1", MetricLogic{Op: "OR",
+			Left:  MetricLogic{Op: "AND", Left: cond("temperatura", "<", 0), Right: MetricNot{Expr: cond("peso", "!=", 2)}},
+			Right: cond("volumen", ">", 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.texto, func(t *testing.T) {
+			filter, err := ParseMetricFilter(tt.texto)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if filter.Texto != tt.texto {
+				t.Errorf("Texto = %q, se esperaba %q", filter.Texto, tt.texto)
+			}
+			if !reflect.DeepEqual(filter.Raiz, tt.want) {
+				t.Errorf("Raiz = %#v\nse esperaba %#v", filter.Raiz, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMetricFilterErrors(t *testing.T) {
+	tests := []struct {
+		texto    string
+		posicion int
+		mensaje  string
+	}{
+		{"", 1, "la expresión está vacía"},
+		{"   ", 4, "la expresión está vacía"},
+		{"temperatura >", 14, "se esperaba un número para temperatura"},
+		{"_foo = 1", 1, "campo desconocido '_foo'"},
+		{strings.Repeat("x", 51) + " = 1", 1, "campo desconocido"},
+		{"merma = si", 9, "se esperaba un número para merma"},
+		{"temperatura = 1 and", 20, "se esperaba un campo"},
+		{"temperatura = 1 and ñandú > 2", 21, "campo desconocido 'ñandú'"}, // Posiciones en caracteres, no bytes
+		{"(peso > 1", 10, "se esperaba ')'"},
+		{"peso > 1)", 9, "se esperaba and, or o el final"},
+		{"peso = 1 peso", 10, "se esperaba and, or o el final"},
+		{"movimiento > 1", 12, "movimiento solo admite =, != e in"},
+		{"movimiento between si and no", 12, "movimiento solo admite =, != e in"},
+		{"movimiento = quizás", 14, "valor inválido 'quizás' para movimiento"},
+		{"peso = 'abc", 8, "comillas sin cerrar"},
+		{"peso # 1", 6, "carácter inesperado '#'"},
+		{"peso not like 1", 10, "se esperaba in o between después de not"},
+		{"peso between 1 or 2", 16, "se esperaba and en between"},
+		{"peso is nul", 9, "se esperaba null"},
+		{"peso = 1e999", 8, "número inválido '1e999'"},
+		{"peso = abc", 8, "se esperaba un número para peso"},
+		{"peso in 1", 9, "se esperaba '(' con la lista de valores"},
+		{"peso in (1 2)", 12, "se esperaba ',' o ')'"},
+		{"peso", 5, "se esperaba un operador después de 'peso'"},
+		// Límites
+		{strings.Repeat("a", metricFilterMaxLength+1), metricFilterMaxLength + 1, "la expresión supera los 1000 caracteres"},
+		{strings.Repeat("peso>1||", metricFilterMaxNodes) + "peso>1", 8*metricFilterMaxNodes + 1, "la expresión es demasiado compleja"},
+		{strings.Repeat("not ", metricFilterMaxNodes) + "peso>1", metricFilterMaxNodes*4 + 1, "la expresión es demasiado compleja"},
+		{"peso in (" + strings.TrimSuffix(strings.Repeat("1,", metricFilterMaxList+1), ",") + ")", 9 + 2*(metricFilterMaxList+1), "la lista de in admite hasta 100 valores"},
+	}
+	for _, tt := range tests {
+		name := tt.texto
+		if len(name) > 40 {
+			name = name[:40] + "..."
+		}
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseMetricFilter(tt.texto)
+			parseErr, ok := err.(*MetricFilterError)
+			if !ok {
+				t.Fatalf("error = %v (%T), se esperaba *MetricFilterError", err, err)
+			}
+			if parseErr.Posicion != tt.posicion {
+				t.Errorf("Posicion = %d, se esperaba %d (%s)", parseErr.Posicion, tt.posicion, parseErr.Mensaje)
+			}
+			if !strings.HasPrefix(parseErr.Mensaje, tt.mensaje) {
+				t.Errorf("Mensaje = %q, se esperaba que empiece con %q", parseErr.Mensaje, tt.mensaje)
+			}
+		})
+	}
+}
+
+func TestParseMetricFilterLimitsAccepted(t *testing.T) {
+	for _, texto := range []string{
+		strings.Repeat(" ", metricFilterMaxLength-len("peso>1")) + "peso>1",
+		strings.Repeat("peso>1||", metricFilterMaxNodes-1) + "peso>1",
+		"peso in (" + strings.TrimSuffix(strings.Repeat("1,", metricFilterMaxList), ",") + ")",
+	} {
+		if _, err := ParseMetricFilter(texto); err != nil {
+			t.Errorf("ParseMetricFilter(%.40q...) = %v, se esperaba aceptarla en el límite", texto, err)
+		}
+	}
+}
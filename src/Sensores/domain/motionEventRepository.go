@@ -0,0 +1,22 @@
+//File: motionEventRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"time"
+)
+
+type MotionEventRepository interface {
+	// FindOpen devuelve el intervalo candidato o abierto del dispositivo (nil si no hay)
+	FindOpen(mac string) (*entities.MotionEvent, error)
+	Create(event *entities.MotionEvent) error
+	// Update guarda estado, fin, último movimiento y muestras
+	Update(event *entities.MotionEvent) error
+	Delete(id int) error
+	// FindStale devuelve los intervalos candidatos o abiertos sin movimiento desde antes de 'antes'
+	FindStale(antes time.Time) ([]entities.MotionEvent, error)
+	// FindByMac lista, del más reciente al más antiguo, los intervalos abiertos o cerrados del
+	// dispositivo visibles para el usuario que se solapan con [desde, hasta)
+	FindByMac(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.MotionEvent, error)
+}
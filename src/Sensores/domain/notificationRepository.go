@@ -2,10 +2,54 @@
 
 package domain
 
-import "API/src/Sensores/domain/entities" // Importar entidad si se usa en la interfaz
+import (
+	"API/src/Sensores/domain/entities" // Importar entidad si se usa en la interfaz
+	"fmt"
+)
 
 type DatosNotifier interface {
 
 	NotifyNewData(data entities.Datos) error
+
+	// NotifyMotionEvent anuncia la apertura o el cierre de un intervalo de movimiento
+	NotifyMotionEvent(notification entities.MotionNotification) error
+
+	// NotifyWeightEvent anuncia un cambio de peso asentado
+	NotifyWeightEvent(notification entities.WeightNotification) error
+
+	// NotifyAnomaly anuncia una lectura anómala
+	NotifyAnomaly(notification entities.AnomalyNotification) error
 }
 
+// Temas de suscripción WebSocket. Cada notificación se publica en los temas de su
+// dueño, su organización, su dispositivo y (si está ubicado) su área y sitio.
+func TopicUser(userID int) string  { return fmt.Sprintf("usuario:%d", userID) }
+func TopicDevice(mac string) string { return "mac:" + mac }
+func TopicSite(siteID int) string  { return fmt.Sprintf("sitio:%d", siteID) }
+func TopicArea(areaID int) string  { return fmt.Sprintf("area:%d", areaID) }
+func TopicOrg(orgID int) string    { return fmt.Sprintf("org:%d", orgID) }
+
+// TopicsForData devuelve los temas en los que se publica una lectura
+func TopicsForData(data entities.Datos) []string {
+	return TopicsForDevice(data.Mac, data.UserID, data.OrgID, data.Dispositivo)
+}
+
+// TopicsForDevice devuelve los temas de un dispositivo con su dueño, organización y ubicación
+func TopicsForDevice(mac string, userID int, orgID int, device *entities.Device) []string {
+	topics := []string{TopicDevice(mac)}
+	if userID > 0 {
+		topics = append(topics, TopicUser(userID))
+	}
+	if orgID > 0 {
+		topics = append(topics, TopicOrg(orgID))
+	}
+	if device != nil {
+		if device.AreaID != nil {
+			topics = append(topics, TopicArea(*device.AreaID))
+		}
+		if device.SiteID != nil {
+			topics = append(topics, TopicSite(*device.SiteID))
+		}
+	}
+	return topics
+}
@@ -0,0 +1,32 @@
+// File: src/Sensores/domain/organizationRepository.go
+
+package domain
+
+import "API/src/Sensores/domain/entities"
+
+type OrganizationRepository interface {
+	// CreatePersonal crea (si no existe) la organización personal del usuario y lo hace owner
+	CreatePersonal(userID int, nombre string) (int, error)
+	// PersonalOrgID devuelve la organización personal del usuario (sql.ErrNoRows si no tiene)
+	PersonalOrgID(userID int) (int, error)
+
+	// Create crea una organización con ownerUserID como owner. Asigna org.ID.
+	Create(org *entities.Organization, ownerUserID int) error
+	Update(org *entities.Organization) error
+
+	// FindForUser lista las organizaciones del usuario con su rol en cada una
+	FindForUser(userID int) ([]entities.Organization, error)
+	// FindByID devuelve la organización con sus miembros (sql.ErrNoRows si no existe)
+	FindByID(orgID int) (*entities.Organization, error)
+
+	// MemberRole devuelve el rol del usuario en la organización ("" si no es miembro)
+	MemberRole(orgID int, userID int) (string, error)
+	SetMember(orgID int, userID int, role string) error // Inserta o cambia el rol
+	RemoveMember(orgID int, userID int) error
+	CountOwners(orgID int) (int, error)
+
+	// DeviceOrg devuelve la organización dueña del dispositivo (0 si no tiene)
+	DeviceOrg(mac string) (int, error)
+	// SetDeviceOrg mueve el dispositivo (y sus lecturas futuras) a otra organización
+	SetDeviceOrg(mac string, orgID int) error
+}
@@ -0,0 +1,30 @@
+// File: src/Sensores/domain/ownershipRepository.go
+
+package domain
+
+import "API/src/Sensores/domain/entities"
+
+// OwnershipChange describe una (re)asignación de MAC hecha por un admin.
+type OwnershipChange struct {
+	TargetUserID int    // Usuario que recibe la MAC
+	Mac          string // "" = desasignar la MAC actual del usuario
+	AssignedBy   int    // UserID del admin que hace el cambio
+	Motivo       string
+}
+
+type OwnershipRepository interface {
+	// Transfer mueve la MAC al usuario en una sola transacción: la quita al dueño anterior,
+	// cierra los periodos abiertos y abre uno nuevo. Devuelve el periodo nuevo (nil al desasignar)
+	// o sql.ErrNoRows si el usuario no existe.
+	Transfer(change OwnershipChange) (*entities.DeviceOwnership, error)
+
+	// OpenRegistration abre el primer periodo cuando un usuario se registra con MAC
+	OpenRegistration(userID int, mac string) error
+
+	// FindByMac devuelve todos los periodos de la MAC, del más reciente al más antiguo
+	FindByMac(mac string) ([]entities.DeviceOwnership, error)
+
+	// DecideHistory guarda la decisión del dueño actual sobre las lecturas del dueño anterior.
+	// Errores: "dispositivo_no_encontrado", "decision_no_pendiente".
+	DecideHistory(userID int, mac string, decision string) (*entities.DeviceOwnership, error)
+}
@@ -0,0 +1,26 @@
+//File: productRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"time"
+)
+
+type ProductRepository interface {
+	Create(product *entities.Product) error
+	Update(product *entities.Product) error
+	// Delete borra el producto y su historial de movimientos
+	Delete(id int) error
+	// FindByID devuelve el producto con su stock; sql.ErrNoRows si no existe
+	FindByID(id int) (*entities.Product, error)
+	// FindByMac devuelve el producto del dispositivo (nil si no tiene)
+	FindByMac(mac string) (*entities.Product, error)
+	// FindByMacs lista, con su stock, los productos de las MACs dadas
+	FindByMacs(macs []string) ([]entities.Product, error)
+	CreateMovement(movement *entities.InventoryMovement) error
+	// FindMovements lista, del más reciente al más antiguo, los movimientos en [desde, hasta)
+	FindMovements(productID int, desde time.Time, hasta time.Time, limit int) ([]entities.InventoryMovement, error)
+	// DailyConsumption suma los movimientos por día en [desde, hasta); solo devuelve días con movimientos
+	DailyConsumption(productID int, desde time.Time, hasta time.Time) ([]entities.DailyConsumption, error)
+}
@@ -0,0 +1,30 @@
+//File: reading.go
+
+package domain
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseMotion interpreta el campo movimiento de una lectura con las mismas reglas que las
+// consultas SQL: nil si vino vacío
+func ParseMotion(value string) *bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return nil
+	}
+	moving := value == "si" || value == "sí" || value == "1" || value == "true" || value == "yes"
+	return &moving
+}
+
+// ParseNumber interpreta un campo numérico de una lectura (temperatura, distancia, peso): nil si
+// vino vacío o no es un número finito
+func ParseNumber(value string) *float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,32 @@
+// File: rollupRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"time"
+)
+
+// Resoluciones de los resúmenes precalculados de lecturas
+const (
+	RollupHora = "hora"
+	RollupDia  = "dia" // Día local del servidor
+)
+
+// RollupRepository mantiene resúmenes por hora y por día de 'rutas' para consultar rangos largos
+type RollupRepository interface {
+	// MarkPending encola las horas de hasta limit lecturas nuevas (por id, así que incluye las
+	// cargadas con fechas pasadas) y devuelve cuántas lecturas revisó
+	MarkPending(limit int) (int, error)
+	// ProcessPending recalcula hasta limit horas encoladas y sus días; devuelve cuántas procesó
+	ProcessPending(limit int) (int, error)
+	// Coverage devuelve hasta cuándo los resúmenes incluyen todas las lecturas (cero = nunca se calcularon)
+	Coverage() (time.Time, error)
+	SetCoverage(hasta time.Time) error
+	// Aggregate es como DatosRepository.Aggregate pero sobre los resúmenes de la resolución pedida.
+	// filter.Desde y filter.Hasta deben estar alineados a esa resolución; no admite percentiles.
+	Aggregate(userID int, filter DatosFilter, query DatosAggregateQuery, resolucion string) ([]entities.DeviceSeries, error)
+	// StreamHourly entrega, ordenadas por dispositivo y hora, las horas con lecturas visibles del
+	// rango (alineado a horas) y cuántas lecturas tuvo cada una; si fn devuelve error se corta
+	StreamHourly(userID int, filter DatosFilter, fn func(mac string, hora time.Time, lecturas int64) error) error
+}
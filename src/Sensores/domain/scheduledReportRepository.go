@@ -0,0 +1,34 @@
+//File: scheduledReportRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"time"
+)
+
+type ScheduledReportRepository interface {
+	CreateSubscription(sub *entities.ReportSubscription) error
+	UpdateSubscription(sub *entities.ReportSubscription) error
+	// DeleteSubscription borra la suscripción; sus reportes generados se conservan
+	DeleteSubscription(id int) error
+	// FindSubscription devuelve sql.ErrNoRows si no existe
+	FindSubscription(id int) (*entities.ReportSubscription, error)
+	FindSubscriptions(userID int) ([]entities.ReportSubscription, error)
+	// FindDue devuelve las suscripciones activas y con email confirmado cuyo próximo envío ya llegó
+	FindDue(now time.Time) ([]entities.ReportSubscription, error)
+	// ConfirmEmail marca confirmado el email de la suscripción con ese token; false si no hay ninguna
+	ConfirmEmail(token string) (bool, error)
+	// MarkRun reprograma la suscripción tras un envío
+	MarkRun(id int, proximo time.Time, ultimo time.Time) error
+
+	CreateReport(report *entities.ScheduledReport) error
+	// MarkSent registra el resultado del envío por email (enviado nil = falló con errorEnvio)
+	MarkSent(id int, enviado *time.Time, errorEnvio string) error
+	// FindReport devuelve el reporte con su HTML; sql.ErrNoRows si no existe
+	FindReport(id int) (*entities.ScheduledReport, error)
+	// FindReports lista sin HTML los reportes del usuario, más recientes primero
+	FindReports(userID int, limit int) ([]entities.ScheduledReport, error)
+	// CountReportsSince cuenta los reportes generados para la suscripción desde since
+	CountReportsSince(subscriptionID int, since time.Time) (int, error)
+}
@@ -0,0 +1,47 @@
+// File: src/Sensores/domain/shareRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"time"
+)
+
+type ShareRepository interface {
+	// Create guarda el acceso (asigna ID) y revoca cualquier acceso vigente previo del mismo
+	// usuario o email al mismo dispositivo
+	Create(share *entities.DeviceShare) error
+
+	// FindByID y FindByToken devuelven sql.ErrNoRows si no existe
+	FindByID(id int) (*entities.DeviceShare, error)
+	FindByToken(token string) (*entities.DeviceShare, error)
+
+	// FindByMac lista los accesos no revocados del dispositivo (incluye invitaciones pendientes)
+	FindByMac(mac string) ([]entities.DeviceShare, error)
+	// FindForGrantee lista los accesos vigentes que recibió el usuario
+	FindForGrantee(userID int) ([]entities.DeviceShare, error)
+
+	Accept(id int, userID int) error
+	Revoke(id int) error
+
+	// ActivePermission devuelve el permiso vigente del usuario sobre la MAC ("" si no tiene)
+	// y su vencimiento (nil = sin vencimiento)
+	ActivePermission(userID int, mac string) (string, *time.Time, error)
+}
+
+// Mailer envía correos (invitaciones, reportes)
+type Mailer interface {
+	Send(to string, subject string, body string) error
+	// SendHTML envía un cuerpo HTML (reportes programados)
+	SendHTML(to string, subject string, body string) error
+}
+
+// SubscriptionRevoker retira un tema de las conexiones en vivo de un usuario (p. ej. al revocar
+// un dispositivo compartido) o se lo agrega al conceder el acceso, hasta su vencimiento (cero = no vence)
+type SubscriptionRevoker interface {
+	RevokeTopic(userID int, topic string)
+	GrantTopic(userID int, topic string, hasta time.Time)
+	// RecheckTopics vuelve a autorizar los temas de las conexiones del usuario y retira los que ya
+	// no le corresponden (p. ej. al bajarle el rol o sacarlo de una organización)
+	RecheckTopics(userID int)
+}
@@ -0,0 +1,27 @@
+// File: src/Sensores/domain/siteRepository.go
+
+package domain
+
+import "API/src/Sensores/domain/entities"
+
+type SiteRepository interface {
+	// FindByUserID lista los sitios de las organizaciones del usuario con sus áreas (sin dispositivos)
+	FindByUserID(userID int) ([]entities.Site, error)
+
+	// FindByID devuelve el sitio con sus áreas; cada área trae solo la MAC de sus dispositivos.
+	// sql.ErrNoRows si no existe.
+	FindByID(siteID int) (*entities.Site, error)
+
+	Create(site *entities.Site) error // Asigna site.ID
+	Update(site *entities.Site) error
+	Delete(siteID int) error // Borra sus áreas y deja sus dispositivos sin área
+
+	// FindArea devuelve el área (sin dispositivos) o sql.ErrNoRows
+	FindArea(areaID int) (*entities.Area, error)
+	CreateArea(area *entities.Area) error // Asigna area.ID
+	UpdateArea(area *entities.Area) error
+	DeleteArea(areaID int) error // Deja sus dispositivos sin área
+
+	// SetDeviceArea ubica un dispositivo en un área (nil = quitarlo de su área)
+	SetDeviceArea(mac string, areaID *int) error
+}
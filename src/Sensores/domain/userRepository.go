@@ -2,6 +2,18 @@ package domain
 
 import "database/sql"
 
+// Roles globales (columna users.role). Los roles dentro de una organización están en
+// entities.RolOrg*; el antiguo rol 'admin' global pasó a ser RolePlatformOperator.
+const (
+	RoleUser             = "user"
+	RolePlatformOperator = "operador_plataforma"
+)
+
+// IsPlatformOperator indica si el rol global permite operar la plataforma (rutas /admin)
+func IsPlatformOperator(role string) bool {
+	return role == RolePlatformOperator
+}
+
 // User representa la entidad de usuario
 type User struct {
 	ID            int
@@ -13,7 +25,14 @@ type User struct {
 
 // UserRepository define las operaciones de persistencia para usuarios
 type UserRepository interface {
-	FindUserIDByMAC(macAddress string) (int, error)
+	// FindUserIDByMAC devuelve el usuario asignado a la MAC solo si es miembro de la organización
+	// orgID; con orgID 0, solo si el dispositivo no tiene organización. sql.ErrNoRows si no hay.
+	FindUserIDByMAC(orgID int, macAddress string) (int, error)
+	// FindAssignedUserID devuelve el usuario asignado a la MAC sin restricción de organización
+	// (solo para la ingesta y los operadores de la plataforma)
+	FindAssignedUserID(macAddress string) (int, error)
+	// FindByUsername busca en todos los usuarios: los nombres son globales (inicio de sesión y
+	// altas en organizaciones o accesos compartidos por nombre)
 	FindByUsername(username string) (*User, error)
 	Create(user *User) error // Asumiendo que ya añadiste este
 
@@ -0,0 +1,19 @@
+//File: weightEventRepository.go
+
+package domain
+
+import (
+	"API/src/Sensores/domain/entities"
+	"time"
+)
+
+type WeightEventRepository interface {
+	// GetState devuelve el seguimiento del peso del dispositivo (nil si aún no hay)
+	GetState(mac string) (*entities.WeightState, error)
+	// SaveResult guarda el seguimiento y, si event no es nil, registra el cambio (le asigna ID) en
+	// una transacción: un cambio nunca queda sin el nivel que lo confirmó
+	SaveResult(state *entities.WeightState, event *entities.WeightEvent) error
+	// FindByMac lista, del más reciente al más antiguo, los cambios del dispositivo visibles para
+	// el usuario confirmados en [desde, hasta)
+	FindByMac(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.WeightEvent, error)
+}
@@ -4,14 +4,20 @@ package adapters
 
 import (
 	"API/src/core"
+	"API/src/Sensores/domain"
 	"API/src/Sensores/domain/entities"
 	"database/sql" // Necesario para sql.ErrNoRows
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 )
 
 type MySQLRutas struct {
 	conn *core.Conn_MySQL
+
+	versionMu   sync.Mutex
+	windowFuncs *bool // nil hasta consultar la versión del servidor (ver supportsWindowFunctions)
 }
 
 func NewMySQLRutas(conn *core.Conn_MySQL) *MySQLRutas { // Ahora recibe la conexión
@@ -22,143 +28,481 @@ func NewMySQLRutas(conn *core.Conn_MySQL) *MySQLRutas { // Ahora recibe la conex
 }
 
 
-// Save AHORA incluye user_id
-func (mysql *MySQLRutas) Save(userID int, temperatura string, movimiento string, distancia string, peso string, mac string) error {
-    query := "INSERT INTO rutas (user_id, temperatura, movimiento, distancia, peso, mac) VALUES (?, ?, ?, ?, ?, ?)"
-    result, err := mysql.conn.ExecutePreparedQuery(query, userID, temperatura, movimiento, distancia, peso, mac)
+// Save incluye user_id y la organización dueña (org_id NULL si orgID es 0)
+func (mysql *MySQLRutas) Save(userID int, orgID int, temperatura string, movimiento string, distancia string, peso string, mac string) (int64, error) {
+    query := "INSERT INTO rutas (user_id, org_id, temperatura, movimiento, distancia, peso, mac) VALUES (?, ?, ?, ?, ?, ?, ?)"
+    org := sql.NullInt64{Int64: int64(orgID), Valid: orgID > 0}
+    result, err := mysql.conn.ExecutePreparedQuery(query, userID, org, temperatura, movimiento, distancia, peso, mac)
     // ... manejo de errores y logs como antes ...
 			if err != nil {
 	log.Printf("ERROR: [MySQLAdapter] Error al ejecutar INSERT: %v", err)
-	return fmt.Errorf("error al guardar datos en MySQL: %w", err) // Envolver error
+	return 0, fmt.Errorf("error al guardar datos en MySQL: %w", err) // Envolver error
 }
 
 rowsAffected, _ := result.RowsAffected()
-lastInsertId, _ := result.LastInsertId() // Útil si necesitas el ID
+lastInsertId, _ := result.LastInsertId() // Los derivados de la lectura (anomalías) la referencian por ID
 
 if rowsAffected == 1 {
 	log.Printf("INFO: [MySQLAdapter] Datos insertados exitosamente para UserID %d. ID: %d, Filas afectadas: %d", userID, lastInsertId, rowsAffected)
 } else {
 	log.Printf("ADVERTENCIA: [MySQLAdapter] INSERT ejecutado pero filas afectadas no fue 1 (%d).", rowsAffected)
 }
-return nil
+return lastInsertId, nil
 }
 
-// GetAll (sin cambios si lo necesitas para admin)
-func (mysql *MySQLRutas) GetAll() ([]entities.Datos, error) {
-    // ... tu código existente ...
-			query := "SELECT id, user_id, temperatura, movimiento, distancia, peso, mac FROM rutas ORDER BY id DESC" // Añadir user_id al SELECT
-rows, err := mysql.conn.FetchRows(query)
-if err != nil {
-	log.Printf("ERROR: [MySQLAdapter] Error al ejecutar SELECT *: %v", err)
-	return nil, fmt.Errorf("error al obtener datos de MySQL: %w", err)
+// GetAll devuelve una página de todas las lecturas que cumplen el filtro, sin restricción de
+// visibilidad (solo para operadores de la plataforma)
+func (mysql *MySQLRutas) GetAll(filter domain.DatosFilter, page domain.DatosPageQuery) ([]entities.Datos, bool, error) {
+    datosList, hasMore, err := mysql.pageDatos("1 = 1", nil, filter, page)
+    if err != nil {
+        log.Printf("ERROR: [MySQLAdapter] Error al obtener página de todas las lecturas: %v", err)
+        return nil, false, err
+    }
+    log.Printf("INFO: [MySQLAdapter] Se recuperaron %d registros (GetAll, hay más: %t).", len(datosList), hasMore)
+    return datosList, hasMore, nil
 }
-defer rows.Close()
 
-var datosList []entities.Datos
+// buildDatosFilter traduce DatosFilter a condiciones SQL parametrizadas sobre 'rutas'.
+func buildDatosFilter(filter domain.DatosFilter) (string, []interface{}, error) {
+    clause, args := buildDeviceFilter("rutas", filter)
+    if !filter.Desde.IsZero() {
+        clause += " AND rutas.created_at >= ?"
+        args = append(args, filter.Desde)
+    }
+    if !filter.Hasta.IsZero() {
+        clause += " AND rutas.created_at < ?"
+        args = append(args, filter.Hasta)
+    }
+    if filter.Expresion != nil {
+        exprClause, exprArgs, err := compileMetricFilter(filter.Expresion.Raiz)
+        if err != nil {
+            return "", nil, fmt.Errorf("expresión de filtro inválida '%s': %w", filter.Expresion.Texto, err)
+        }
+        clause += " AND " + exprClause
+        args = append(args, exprArgs...)
+    }
+    return clause, args, nil
+}
 
-for rows.Next() {
-	var dato entities.Datos
-	// Añadir &dato.UserID al Scan en la posición correcta
-	var userId sql.NullInt32 // Usar NullInt32 por si user_id es NULL en la BD
-	if err := rows.Scan(&dato.ID, &userId, &dato.Temperatura, &dato.Movimiento, &dato.Distancia, &dato.Peso, &dato.Mac); err != nil {
-		log.Printf("ERROR: [MySQLAdapter] Error al escanear fila (GetAll): %v", err)
-		return nil, fmt.Errorf("error al procesar fila de datos MySQL: %w", err)
-	}
-	// Opcional: asignar UserID a tu struct si tiene el campo y no es NULL
-	if userId.Valid {
-		 // dato.UserID = userId.Int32 // Asumiendo que tienes un campo UserID en entities.Datos
-	}
-	datosList = append(datosList, dato)
+// buildDeviceFilter aplica los filtros de DatosFilter salvo el rango de fechas a una tabla con
+// columnas mac y user_id ('rutas' o 'datos_rollups')
+func buildDeviceFilter(alias string, filter domain.DatosFilter) (string, []interface{}) {
+    var clause strings.Builder
+    var args []interface{}
+    if filter.Mac != "" {
+        clause.WriteString(" AND " + alias + ".mac = ?")
+        args = append(args, filter.Mac)
+    }
+    if len(filter.Etiquetas) > 0 {
+        // El dispositivo debe tener todas las etiquetas pedidas
+        placeholders := strings.TrimSuffix(strings.Repeat("?,", len(filter.Etiquetas)), ",")
+        clause.WriteString(" AND " + alias + ".mac IN (SELECT mac FROM device_tags WHERE tag IN (" + placeholders + ") GROUP BY mac HAVING COUNT(DISTINCT tag) = ?)")
+        for _, tag := range filter.Etiquetas {
+            args = append(args, tag)
+        }
+        args = append(args, len(filter.Etiquetas))
+    }
+    if filter.AreaID > 0 {
+        clause.WriteString(" AND " + alias + ".mac IN (SELECT mac FROM devices WHERE area_id = ?)")
+        args = append(args, filter.AreaID)
+    }
+    if filter.SiteID > 0 {
+        clause.WriteString(" AND " + alias + ".mac IN (SELECT d.mac FROM devices d JOIN areas a ON a.id = d.area_id WHERE a.site_id = ?)")
+        args = append(args, filter.SiteID)
+    }
+    if filter.UserID > 0 {
+        clause.WriteString(" AND " + alias + ".user_id = ?")
+        args = append(args, filter.UserID)
+    }
+    return clause.String(), args
+}
+
+// visibleToUser es la condición de visibilidad de 'rutas' para un usuario: las lecturas de las
+// organizaciones de las que es miembro (cualquier rol), las suyas sin organización (anteriores
+// a los tenants), las de dispositivos que le compartieron (solo de la organización que compartió)
+// y las del dueño anterior de un dispositivo cuyo historial le fue transferido, solo de antes de
+// que empezara su periodo.
+func visibleToUser(userID int) (string, []interface{}) {
+    return visibleOn("rutas", "rutas.created_at < o.started_at", userID)
 }
 
-if err := rows.Err(); err != nil {
-	log.Printf("ERROR: [MySQLAdapter] Error durante la iteración de filas (GetAll): %v", err)
-	return nil, fmt.Errorf("error final al leer datos de MySQL: %w", err)
+// visibleOn aplica la condición de visibilidad a una tabla con columnas mac, org_id y user_id;
+// antesDe indica cuándo una fila es anterior al periodo del dueño actual (o.started_at).
+func visibleOn(alias string, antesDe string, userID int) (string, []interface{}) {
+    clause := `(` + alias + `.org_id IN (SELECT m.org_id FROM organization_members m WHERE m.user_id = ?)
+        OR (` + alias + `.org_id IS NULL AND ` + alias + `.user_id = ?)
+        OR EXISTS (SELECT 1 FROM device_shares s WHERE s.grantee_user_id = ? AND s.mac = ` + alias + `.mac
+        AND s.org_id = ` + alias + `.org_id AND ` + activeShare + `)
+        OR EXISTS (SELECT 1 FROM device_ownership o
+        WHERE o.user_id = ? AND o.mac = ` + alias + `.mac AND o.historial = 'transferido'
+        AND ` + alias + `.user_id = o.previous_user_id AND ` + antesDe + `))`
+    return clause, []interface{}{userID, userID, userID, userID}
 }
 
-log.Printf("INFO: [MySQLAdapter] Se recuperaron %d registros (GetAll).", len(datosList))
-return datosList, nil
+// editableByUser restringe 'rutas' a las lecturas que el usuario puede modificar o borrar:
+// las de organizaciones donde es owner, admin o member (los viewer solo leen) y las de
+// dispositivos compartidos con permiso de escritura.
+func editableByUser(userID int) (string, []interface{}) {
+    clause := `(rutas.org_id IN (SELECT m.org_id FROM organization_members m WHERE m.user_id = ? AND m.role IN ('owner', 'admin', 'member'))
+        OR (rutas.org_id IS NULL AND rutas.user_id = ?)
+        OR EXISTS (SELECT 1 FROM device_shares s WHERE s.grantee_user_id = ? AND s.mac = rutas.mac
+        AND s.org_id = rutas.org_id AND s.permiso = 'escritura' AND ` + activeShare + `))`
+    return clause, []interface{}{userID, userID, userID}
 }
 
-// NUEVO: GetByUserID
-func (mysql *MySQLRutas) GetByUserID(userID int) ([]entities.Datos, error) {
-    query := "SELECT id, user_id, temperatura, movimiento, distancia, peso, mac FROM rutas WHERE user_id = ? ORDER BY id DESC"
-    rows, err := mysql.conn.FetchRows(query, userID)
+// datosColumns son las columnas de 'rutas' que lee scanDatosRows, en ese orden
+const datosColumns = "rutas.id, rutas.user_id, rutas.org_id, rutas.temperatura, rutas.movimiento, rutas.distancia, rutas.peso, rutas.mac, rutas.created_at"
+
+// scanDatos lee una fila con datosColumns (user_id y org_id pueden ser NULL)
+func scanDatos(rows *sql.Rows) (entities.Datos, error) {
+    var dato entities.Datos
+    var dbUserId, dbOrgId sql.NullInt32
+    if err := rows.Scan(&dato.ID, &dbUserId, &dbOrgId, &dato.Temperatura, &dato.Movimiento, &dato.Distancia, &dato.Peso, &dato.Mac, &dato.Fecha); err != nil {
+        return dato, fmt.Errorf("error al procesar fila de datos MySQL: %w", err)
+    }
+    dato.UserID = int(dbUserId.Int32)
+    dato.OrgID = int(dbOrgId.Int32)
+    return dato, nil
+}
+
+// scanDatosRows lee todas las filas con datosColumns
+func scanDatosRows(rows *sql.Rows) ([]entities.Datos, error) {
+    datosList := []entities.Datos{}
+    for rows.Next() {
+        dato, err := scanDatos(rows)
+        if err != nil {
+            return nil, err
+        }
+        datosList = append(datosList, dato)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error final al leer datos de MySQL: %w", err)
+    }
+    return datosList, nil
+}
+
+// GetByUserID devuelve una página de las lecturas visibles para el usuario que cumplen el filtro.
+// Pagina por clave (created_at, id) para no recorrer las páginas anteriores.
+func (mysql *MySQLRutas) GetByUserID(userID int, filter domain.DatosFilter, page domain.DatosPageQuery) ([]entities.Datos, bool, error) {
+    scopeClause, args := visibleToUser(userID)
+    datosList, hasMore, err := mysql.pageDatos(scopeClause, args, filter, page)
     if err != nil {
         log.Printf("ERROR: [MySQLAdapter] Error al ejecutar SELECT por UserID %d: %v", userID, err)
-        return nil, fmt.Errorf("error al obtener datos por usuario de MySQL: %w", err)
+        return nil, false, err
+    }
+    log.Printf("INFO: [MySQLAdapter] Se recuperaron %d registros para UserID %d (hay más: %t).", len(datosList), userID, hasMore)
+    return datosList, hasMore, nil
+}
+
+// pageDatos pagina por (created_at, id) las lecturas dentro de scopeClause que cumplen el filtro
+func (mysql *MySQLRutas) pageDatos(scopeClause string, args []interface{}, filter domain.DatosFilter, page domain.DatosPageQuery) ([]entities.Datos, bool, error) {
+    filterClause, filterArgs, err := buildDatosFilter(filter)
+    if err != nil {
+        return nil, false, err
+    }
+    args = append(args, filterArgs...)
+    comparator, direction := "<", "DESC"
+    if page.Ascendente {
+        comparator, direction = ">", "ASC"
+    }
+    cursorClause := ""
+    if page.Despues != nil {
+        cursorClause = " AND (rutas.created_at " + comparator + " ? OR (rutas.created_at = ? AND rutas.id " + comparator + " ?))"
+        args = append(args, page.Despues.Fecha, page.Despues.Fecha, page.Despues.ID)
+    }
+    // Se pide una fila de más para saber si hay otra página
+    query := "SELECT " + datosColumns + " FROM rutas WHERE " + scopeClause + filterClause + cursorClause +
+        " ORDER BY rutas.created_at " + direction + ", rutas.id " + direction + " LIMIT ?"
+    rows, err := mysql.conn.FetchRows(query, append(args, page.Limit+1)...)
+    if err != nil {
+        return nil, false, fmt.Errorf("error al obtener datos de MySQL: %w", err)
     }
     defer rows.Close()
 
-    var datosList []entities.Datos
+    datosList, err := scanDatosRows(rows)
+    if err != nil {
+        return nil, false, err
+    }
+    hasMore := len(datosList) > page.Limit
+    if hasMore {
+        datosList = datosList[:page.Limit]
+    }
+    return datosList, hasMore, nil
+}
+
+// datosOrderBy traduce DatosOrden a la cláusula ORDER BY sobre 'rutas'
+func datosOrderBy(orden domain.DatosOrden) string {
+    switch orden {
+    case domain.OrdenFechaDesc:
+        return " ORDER BY rutas.created_at DESC, rutas.id DESC"
+    case domain.OrdenDispositivoFecha:
+        return " ORDER BY rutas.mac, rutas.created_at, rutas.id"
+    }
+    return " ORDER BY rutas.created_at, rutas.id"
+}
+
+// StreamByUserID lee fila a fila del cursor de la BD; la conexión queda ocupada hasta terminar
+func (mysql *MySQLRutas) StreamByUserID(userID int, filter domain.DatosFilter, orden domain.DatosOrden, fn func(entities.Datos) error) error {
+    scopeClause, args := visibleToUser(userID)
+    filterClause, filterArgs, err := buildDatosFilter(filter)
+    if err != nil {
+        return err
+    }
+    query := "SELECT " + datosColumns + " FROM rutas WHERE " + scopeClause + filterClause + datosOrderBy(orden)
+    count, err := mysql.streamDatos(query, append(args, filterArgs...), fn)
+    if err != nil {
+        log.Printf("ERROR: [MySQLAdapter] Recorrido de lecturas para UserID %d interrumpido tras %d filas: %v", userID, count, err)
+        return err
+    }
+    log.Printf("INFO: [MySQLAdapter] Recorridas %d lecturas para UserID %d.", count, userID)
+    return nil
+}
+
+// StreamAll recorre todas las lecturas que cumplen el filtro, sin restricción de visibilidad
+func (mysql *MySQLRutas) StreamAll(filter domain.DatosFilter, orden domain.DatosOrden, fn func(entities.Datos) error) error {
+    filterClause, args, err := buildDatosFilter(filter)
+    if err != nil {
+        return err
+    }
+    query := "SELECT " + datosColumns + " FROM rutas WHERE 1 = 1" + filterClause + datosOrderBy(orden)
+    count, err := mysql.streamDatos(query, args, fn)
+    if err != nil {
+        log.Printf("ERROR: [MySQLAdapter] Recorrido de todas las lecturas interrumpido tras %d filas: %v", count, err)
+        return err
+    }
+    log.Printf("INFO: [MySQLAdapter] Recorridas %d lecturas (todas).", count)
+    return nil
+}
+
+func (mysql *MySQLRutas) streamDatos(query string, args []interface{}, fn func(entities.Datos) error) (int, error) {
+    rows, err := mysql.conn.FetchRows(query, args...)
+    if err != nil {
+        return 0, fmt.Errorf("error al recorrer datos de MySQL: %w", err)
+    }
+    defer rows.Close()
+    count := 0
     for rows.Next() {
-        var dato entities.Datos
-					var dbUserId sql.NullInt32 // Necesario para Scan aunque ya filtramos
-        if err := rows.Scan(&dato.ID, &dbUserId, &dato.Temperatura, &dato.Movimiento, &dato.Distancia, &dato.Peso, &dato.Mac); err != nil {
-            log.Printf("ERROR: [MySQLAdapter] Error al escanear fila (GetByUserID: %d): %v", userID, err)
-            return nil, fmt.Errorf("error al procesar fila de datos MySQL por usuario: %w", err)
+        dato, err := scanDatos(rows)
+        if err != nil {
+            return count, err
         }
-					// if dbUserId.Valid {
-					// 	 dato.UserID = dbUserId.Int32 // Asumiendo que tienes UserID en tu struct
-					// }
-        datosList = append(datosList, dato)
+        if err := fn(dato); err != nil {
+            return count, err
+        }
+        count++
     }
-
     if err := rows.Err(); err != nil {
-        log.Printf("ERROR: [MySQLAdapter] Error durante la iteración de filas (GetByUserID: %d): %v", userID, err)
-        return nil, fmt.Errorf("error final al leer datos por usuario de MySQL: %w", err)
+        return count, fmt.Errorf("error final al recorrer datos de MySQL: %w", err)
     }
+    return count, nil
+}
 
-    log.Printf("INFO: [MySQLAdapter] Se recuperaron %d registros para UserID %d.", len(datosList), userID)
+// GetLatestByUserID devuelve la lectura más reciente de cada MAC entre las visibles para el
+// usuario que cumplen el filtro. La más reciente es la de mayor id (orden de inserción).
+func (mysql *MySQLRutas) GetLatestByUserID(userID int, filter domain.DatosFilter) ([]entities.Datos, error) {
+    scopeClause, args := visibleToUser(userID)
+    filterClause, filterArgs, err := buildDatosFilter(filter)
+    if err != nil {
+        return nil, err
+    }
+    query := "SELECT " + datosColumns + ` FROM rutas JOIN (
+        SELECT MAX(rutas.id) AS id FROM rutas WHERE ` + scopeClause + filterClause + ` GROUP BY rutas.mac
+        ) latest ON latest.id = rutas.id ORDER BY rutas.mac`
+    rows, err := mysql.conn.FetchRows(query, append(args, filterArgs...)...)
+    if err != nil {
+        log.Printf("ERROR: [MySQLAdapter] Error al consultar últimas lecturas para UserID %d: %v", userID, err)
+        return nil, fmt.Errorf("error al obtener últimas lecturas de MySQL: %w", err)
+    }
+    defer rows.Close()
+    datosList, err := scanDatosRows(rows)
+    if err != nil {
+        return nil, err
+    }
+    log.Printf("INFO: [MySQLAdapter] Últimas lecturas de %d dispositivos para UserID %d.", len(datosList), userID)
     return datosList, nil
 }
 
-// Update - Adaptar para recibir y potencialmente usar userID
+// GetByID lee una lectura solo si es visible para el usuario
+func (mysql *MySQLRutas) GetByID(id int, userID int) (*entities.Datos, error) {
+    scopeClause, args := visibleToUser(userID)
+    query := "SELECT " + datosColumns + " FROM rutas WHERE rutas.id = ? AND " + scopeClause
+    rows, err := mysql.conn.FetchRows(query, append([]interface{}{id}, args...)...)
+    if err != nil {
+        log.Printf("ERROR: [MySQLAdapter] Error al consultar la lectura %d para UserID %d: %v", id, userID, err)
+        return nil, fmt.Errorf("error al obtener dato de MySQL (ID: %d): %w", id, err)
+    }
+    defer rows.Close()
+    datosList, err := scanDatosRows(rows)
+    if err != nil {
+        return nil, err
+    }
+    if len(datosList) == 0 {
+        return nil, fmt.Errorf("dato_no_encontrado")
+    }
+    return &datosList[0], nil
+}
+
+// Update solo modifica la lectura si el usuario puede editarla en su organización. Los resúmenes
+// de la hora de la lectura (antes y después del cambio de MAC) se recalculan solo si se modificó.
 func (mysql *MySQLRutas) Update(id int, userID int, temperatura string, movimiento string, distancia string, peso string, mac string) error {
-    // Podrías añadir 'AND user_id = ?' al WHERE si solo el dueño puede actualizar
-    query := "UPDATE rutas SET temperatura = ?, movimiento = ?, distancia = ?, peso = ?, mac = ? WHERE id = ?" // AND user_id = ?
-    result, err := mysql.conn.ExecutePreparedQuery(query, temperatura, movimiento, distancia, peso, mac, id) // , userID
-     // ... manejo de errores y logs como antes ...
-			if err != nil {
-	log.Printf("ERROR: [MySQLAdapter] Error al ejecutar UPDATE (ID: %d): %v", id, err)
-	return fmt.Errorf("error al actualizar datos en MySQL (ID: %d): %w", id, err)
+    tx, err := mysql.conn.DB.Begin()
+    if err != nil {
+        return fmt.Errorf("error al iniciar transacción de actualización (ID: %d): %w", id, err)
+    }
+    defer tx.Rollback() // No-op si ya se hizo Commit
+
+    previa, err := lockEditableHour(tx, id, userID)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        err = mysql.checkEditable(id, userID)
+        if err == nil {
+            err = fmt.Errorf("dato_no_encontrado") // Otra petición la borró entre medio
+        }
+        log.Printf("ADVERTENCIA: [MySQLAdapter] UPDATE sin efecto (ID: %d, UserID: %d): %v", id, userID, err)
+        return err
+    }
+    if err != nil {
+        return fmt.Errorf("error al leer la lectura a actualizar (ID: %d): %w", id, err)
+    }
+    result, err := tx.Exec("UPDATE rutas SET temperatura = ?, movimiento = ?, distancia = ?, peso = ?, mac = ? WHERE id = ?", temperatura, movimiento, distancia, peso, mac, id)
+    if err != nil {
+        log.Printf("ERROR: [MySQLAdapter] Error al ejecutar UPDATE (ID: %d): %v", id, err)
+        return fmt.Errorf("error al actualizar datos en MySQL (ID: %d): %w", id, err)
+    }
+    rowsAffected, _ := result.RowsAffected()
+    if rowsAffected > 0 {
+        queueRollupHour(tx, id, previa)
+        queueRollupHour(tx, id, pendingHour{mac: mac, hora: previa.hora}) // created_at no cambia
+    }
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("error al confirmar actualización (ID: %d): %w", id, err)
+    }
+    if rowsAffected == 0 {
+        log.Printf("INFO: [MySQLAdapter] UPDATE sin cambios: los datos eran iguales (ID: %d).", id)
+        return nil
+    }
+    log.Printf("INFO: [MySQLAdapter] Datos actualizados exitosamente (ID: %d, UserID check: %d).", id, userID)
+    return nil
 }
 
-rowsAffected, _ := result.RowsAffected()
-if rowsAffected == 1 {
-	log.Printf("INFO: [MySQLAdapter] Datos actualizados exitosamente (ID: %d, UserID check: %d).", id, userID)
-} else if rowsAffected == 0 {
-	log.Printf("ADVERTENCIA: [MySQLAdapter] UPDATE ejecutado pero no se encontró el registro (ID: %d) o no pertenecía al usuario (UserID: %d) o los datos eran iguales.", id, userID)
-	// Podrías devolver un error específico aquí si no se encontró
-	// return fmt.Errorf("registro no encontrado o no perteneciente al usuario")
-} else {
-	log.Printf("ADVERTENCIA: [MySQLAdapter] UPDATE afectó un número inesperado de filas (%d) para ID %d.", rowsAffected, id)
+// lockEditableHour bloquea la lectura si el usuario puede editarla y devuelve su MAC y su hora
+// (sql.ErrNoRows si no existe o no es editable)
+func lockEditableHour(tx *sql.Tx, id int, userID int) (pendingHour, error) {
+    scopeClause, scopeArgs := editableByUser(userID)
+    var p pendingHour
+    err := tx.QueryRow("SELECT rutas.mac, CAST("+rollupHourExpr+" AS DATETIME) FROM rutas WHERE rutas.id = ? AND "+scopeClause+" FOR UPDATE",
+        append([]interface{}{id}, scopeArgs...)...).Scan(&p.mac, &p.hora)
+    return p, err
+}
+
+// checkEditable explica por qué una lectura no se pudo modificar: nil si el usuario puede
+// editarla, "permiso_insuficiente" si solo la puede ver y "dato_no_encontrado" en otro caso
+func (mysql *MySQLRutas) checkEditable(id int, userID int) error {
+    editableClause, editableArgs := editableByUser(userID)
+    visibleClause, visibleArgs := visibleToUser(userID)
+    query := "SELECT " + editableClause + " FROM rutas WHERE rutas.id = ? AND " + visibleClause
+    args := append(append(editableArgs, id), visibleArgs...)
+    var editable bool
+    err := mysql.conn.DB.QueryRow(query, args...).Scan(&editable)
+    if err == sql.ErrNoRows {
+        return fmt.Errorf("dato_no_encontrado")
+    }
+    if err != nil {
+        return fmt.Errorf("error al verificar permisos sobre el dato (ID: %d): %w", id, err)
+    }
+    if !editable {
+        return fmt.Errorf("permiso_insuficiente")
+    }
+    return nil
 }
 
-return nil
+// queueRollupHour pide recalcular los resúmenes de una hora y un dispositivo cuyas lecturas
+// cambiaron (ver MySQLRollupRepository); las lecturas nuevas se detectan solas por su id. Un fallo
+// no impide el cambio: los resúmenes de esa hora quedan desactualizados.
+func queueRollupHour(tx *sql.Tx, id int, p pendingHour) {
+    if _, err := tx.Exec("INSERT IGNORE INTO rollup_pendientes (mac, hora) VALUES (?, ?)", p.mac, p.hora); err != nil {
+        log.Printf("ADVERTENCIA: [MySQLAdapter] No se pudo marcar el resumen de la lectura %d para recalcular: %v", id, err)
+    }
 }
 
-// Delete - Adaptar para recibir y potencialmente usar userID
+// Delete solo borra la lectura si el usuario puede editarla en su organización
 func (mysql *MySQLRutas) Delete(id int, userID int) error {
-    // Podrías añadir 'AND user_id = ?' al WHERE si solo el dueño puede borrar
-    query := "DELETE FROM rutas WHERE id = ?" // AND user_id = ?
-    result, err := mysql.conn.ExecutePreparedQuery(query, id) // , userID
-     // ... manejo de errores y logs como antes ...
-			if err != nil {
-	log.Printf("ERROR: [MySQLAdapter] Error al ejecutar DELETE (ID: %d): %v", id, err)
-	return fmt.Errorf("error al eliminar datos en MySQL (ID: %d): %w", id, err)
+    tx, err := mysql.conn.DB.Begin()
+    if err != nil {
+        return fmt.Errorf("error al iniciar transacción de borrado (ID: %d): %w", id, err)
+    }
+    defer tx.Rollback() // No-op si ya se hizo Commit
+
+    previa, err := lockEditableHour(tx, id, userID)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        err = mysql.checkEditable(id, userID)
+        if err == nil {
+            // Editable pero no encontrada: otra petición la borró entre medio
+            err = fmt.Errorf("dato_no_encontrado")
+        }
+        log.Printf("ADVERTENCIA: [MySQLAdapter] DELETE sin efecto (ID: %d, UserID: %d): %v", id, userID, err)
+        return err
+    }
+    if err != nil {
+        return fmt.Errorf("error al leer la lectura a eliminar (ID: %d): %w", id, err)
+    }
+    result, err := tx.Exec("DELETE FROM rutas WHERE id = ?", id)
+    if err != nil {
+        log.Printf("ERROR: [MySQLAdapter] Error al ejecutar DELETE (ID: %d): %v", id, err)
+        return fmt.Errorf("error al eliminar datos en MySQL (ID: %d): %w", id, err)
+    }
+    if rowsAffected, _ := result.RowsAffected(); rowsAffected > 0 {
+        queueRollupHour(tx, id, previa)
+    }
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("error al confirmar borrado (ID: %d): %w", id, err)
+    }
+    log.Printf("INFO: [MySQLAdapter] Datos eliminados exitosamente (ID: %d, UserID check: %d).", id, userID)
+    return nil
 }
 
-rowsAffected, _ := result.RowsAffected()
-if rowsAffected == 1 {
-	log.Printf("INFO: [MySQLAdapter] Datos eliminados exitosamente (ID: %d, UserID check: %d).", id, userID)
-} else if rowsAffected == 0 {
-	log.Printf("ADVERTENCIA: [MySQLAdapter] DELETE ejecutado pero no se encontró el registro (ID: %d) o no pertenecía al usuario (UserID: %d).", id, userID)
-	 // Podrías devolver un error específico aquí si no se encontró
-	 // return fmt.Errorf("registro no encontrado o no perteneciente al usuario")
-} else {
-	log.Printf("ADVERTENCIA: [MySQLAdapter] DELETE afectó un número inesperado de filas (%d) para ID %d.", rowsAffected, id)
+// --- IMPLEMENTACIÓN MÉTODO StorageByUser ---
+func (mysql *MySQLRutas) StorageByUser() ([]entities.UserStorage, error) {
+    query := `SELECT u.id, u.username, COUNT(r.id), COUNT(DISTINCT r.mac), MIN(r.created_at), MAX(r.created_at)
+        FROM users u LEFT JOIN rutas r ON r.user_id = u.id
+        GROUP BY u.id, u.username ORDER BY COUNT(r.id) DESC, u.id`
+    rows, err := mysql.conn.FetchRows(query)
+    if err != nil {
+        log.Printf("ERROR: [MySQLAdapter] Error al calcular almacenamiento por usuario: %v", err)
+        return nil, fmt.Errorf("error al calcular almacenamiento por usuario: %w", err)
+    }
+    defer rows.Close()
+    usage := []entities.UserStorage{}
+    for rows.Next() {
+        var u entities.UserStorage
+        var primera, ultima sql.NullTime
+        if err := rows.Scan(&u.UserID, &u.Username, &u.Lecturas, &u.Dispositivos, &primera, &ultima); err != nil {
+            return nil, fmt.Errorf("error al procesar fila de almacenamiento: %w", err)
+        }
+        u.Primera = nullTimePtr(primera)
+        u.Ultima = nullTimePtr(ultima)
+        usage = append(usage, u)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error final al leer almacenamiento: %w", err)
+    }
+    return usage, nil
 }
 
-return nil
-}
\ No newline at end of file
+// --- IMPLEMENTACIÓN MÉTODO StorageTotals ---
+func (mysql *MySQLRutas) StorageTotals() (int64, int64, int64, error) {
+    var lecturas, sinUsuario int64
+    err := mysql.conn.DB.QueryRow("SELECT COUNT(*), COALESCE(SUM(user_id IS NULL), 0) FROM rutas").Scan(&lecturas, &sinUsuario)
+    if err != nil {
+        return 0, 0, 0, fmt.Errorf("error al contar lecturas: %w", err)
+    }
+    // Estimación del servidor (InnoDB la actualiza de forma periódica)
+    var bytes sql.NullInt64
+    err = mysql.conn.DB.QueryRow(`SELECT DATA_LENGTH + INDEX_LENGTH FROM information_schema.TABLES
+        WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'rutas'`).Scan(&bytes)
+    if err != nil && err != sql.ErrNoRows {
+        return 0, 0, 0, fmt.Errorf("error al consultar tamaño de la tabla: %w", err)
+    }
+    return lecturas, sinUsuario, bytes.Int64, nil
+}
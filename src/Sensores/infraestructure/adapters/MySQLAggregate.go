@@ -0,0 +1,268 @@
+// File: MySQLAggregate.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// aggregateMetricExpr devuelve la expresión SQL numérica de una métrica agregable; las métricas
+// derivadas de los usuarios se leen de los valores guardados de cada lectura
+func aggregateMetricExpr(name string) (string, bool) {
+	switch name {
+	case "temperatura", "distancia", "peso":
+		return numericMetric(name), true
+	case "movimiento":
+		return motionMetric, true
+	case "llenado":
+		return containerMetric("%[1]s / NULLIF(dc.altura_maxima, 0) * 100"), true
+	case "volumen":
+		return containerMetric(`CASE
+			WHEN dc.forma = 'cilindro' THEN PI() * POW(dc.diametro / 2, 2) * %[1]s
+			WHEN dc.forma = 'rectangular' THEN dc.largo * dc.ancho * %[1]s
+			WHEN dc.forma = 'cilindro_horizontal' THEN dc.largo * (POW(dc.diametro / 2, 2) * ACOS((dc.diametro / 2 - %[2]s) / (dc.diametro / 2))
+				- (dc.diametro / 2 - %[2]s) * SQRT(GREATEST(dc.diametro * %[2]s - POW(%[2]s, 2), 0)))
+			END / 1000`), true
+	}
+	if domain.ValidDerivedName(name) {
+		// El nombre ya está validado (minúsculas, dígitos y '_'), así que se puede incluir literal
+		return `(SELECT dv.valor FROM derived_values dv JOIN derived_metrics dm ON dm.id = dv.metric_id
+			WHERE dv.dato_id = rutas.id AND dm.nombre = '` + name + `')`, true
+	}
+	return "", false
+}
+
+// containerMetric calcula una métrica derivada de la distancia y la geometría del contenedor
+// del dispositivo (entities.ContainerConfig.Fill); NULL si no tiene contenedor. En la plantilla,
+// %[1]s es la altura del contenido y %[2]s la misma altura limitada al diámetro.
+func containerMetric(template string) string {
+	nivel := "LEAST(GREATEST(dc.altura_sensor - " + numericMetric("distancia") + ", 0), dc.altura_maxima)"
+	return "(SELECT " + fmt.Sprintf(template, nivel, "LEAST("+nivel+", dc.diametro)") + " FROM device_containers dc WHERE dc.mac = rutas.mac)"
+}
+
+// wallShift es la diferencia en segundos entre el reloj de la zona pedida y el reloj local con el
+// que se guarda rutas.created_at, vigente a partir de desde.
+type wallShift struct {
+	desde    time.Time
+	segundos int
+}
+
+func zoneShift(t time.Time, zona *time.Location) int {
+	_, target := t.In(zona).Zone()
+	_, local := t.In(time.Local).Zone()
+	return target - local
+}
+
+// wallShifts parte [desde, hasta) en tramos de diferencia constante. Los cambios de horario (de
+// cualquiera de las dos zonas) se buscan en pasos de 15 minutos y se afinan al segundo.
+func wallShifts(desde, hasta time.Time, zona *time.Location) []wallShift {
+	const step = 15 * 60
+	from, to := desde.Unix(), hasta.Unix()
+	shifts := []wallShift{{desde: desde, segundos: zoneShift(desde, zona)}}
+	for prev := from; prev < to; prev += step {
+		next := prev + step
+		if next > to {
+			next = to
+		}
+		current := shifts[len(shifts)-1].segundos
+		if zoneShift(time.Unix(next, 0), zona) == current {
+			continue
+		}
+		lo, hi := prev, next // En lo rige el tramo actual; en hi ya no
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if zoneShift(time.Unix(mid, 0), zona) == current {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		shifts = append(shifts, wallShift{desde: time.Unix(hi, 0), segundos: zoneShift(time.Unix(hi, 0), zona)})
+	}
+	return shifts
+}
+
+// bucketKeyExpr numera la cubeta de cada fila según column (hora local del servidor): segundos del
+// reloj de la zona pedida desde 1970-01-01 divididos por el ancho. Solo usa aritmética de DATETIME,
+// así que no requiere las tablas de zonas horarias de MySQL.
+func bucketKeyExpr(column string, shifts []wallShift, width int64) (string, []interface{}) {
+	shift := strconv.Itoa(shifts[0].segundos)
+	var args []interface{}
+	if len(shifts) > 1 {
+		var b strings.Builder
+		b.WriteString("(CASE")
+		for i := 1; i < len(shifts); i++ {
+			fmt.Fprintf(&b, " WHEN %s < ? THEN %d", column, shifts[i-1].segundos)
+			args = append(args, shifts[i].desde)
+		}
+		fmt.Fprintf(&b, " ELSE %d END)", shifts[len(shifts)-1].segundos)
+		shift = b.String()
+	}
+	return fmt.Sprintf("FLOOR((TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', %s) + %s) / %d)", column, shift, width), args
+}
+
+// bucketStart convierte el número de cubeta en su inicio en la zona pedida
+func bucketStart(key int64, width int64, zona *time.Location) time.Time {
+	wall := time.Unix(key*width, 0).UTC()
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, zona)
+}
+
+// supportsWindowFunctions indica si el servidor admite funciones de ventana (MySQL 8+, MariaDB 10.2+),
+// necesarias para los percentiles. Se consulta una vez por proceso.
+func (mysql *MySQLRutas) supportsWindowFunctions() bool {
+	mysql.versionMu.Lock()
+	defer mysql.versionMu.Unlock()
+	if mysql.windowFuncs != nil {
+		return *mysql.windowFuncs
+	}
+	var version string
+	if err := mysql.conn.DB.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		log.Printf("ADVERTENCIA: [MySQLAdapter] No se pudo consultar la versión del servidor: %v", err)
+		return false
+	}
+	var major, minor int
+	fmt.Sscanf(version, "%d.%d", &major, &minor)
+	supported := major >= 8
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		supported = major > 10 || (major == 10 && minor >= 2)
+	}
+	mysql.windowFuncs = &supported
+	log.Printf("INFO: [MySQLAdapter] Servidor %s; funciones de ventana: %t.", version, supported)
+	return supported
+}
+
+// Aggregate agrega en la BD por cubeta de tiempo y MAC las lecturas visibles que cumplen el filtro
+func (mysql *MySQLRutas) Aggregate(userID int, filter domain.DatosFilter, query domain.DatosAggregateQuery) ([]entities.DeviceSeries, error) {
+	width := int64(query.Bucket / time.Second)
+	if len(query.Percentiles) > 0 && !mysql.supportsWindowFunctions() {
+		return nil, fmt.Errorf("percentiles_no_soportados")
+	}
+	keyExpr, args := bucketKeyExpr("rutas.created_at", wallShifts(filter.Desde, filter.Hasta, query.Zona), width)
+	scopeClause, scopeArgs := visibleToUser(userID)
+	filterClause, filterArgs, err := buildDatosFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	args = append(append(args, scopeArgs...), filterArgs...)
+
+	exprs := make([]string, len(query.Metricas))
+	columns := make([]string, len(query.Metricas))
+	for i, name := range query.Metricas {
+		expr, ok := aggregateMetricExpr(name)
+		if !ok {
+			return nil, fmt.Errorf("metrica_invalida")
+		}
+		exprs[i] = expr
+		columns[i] = fmt.Sprintf("COUNT(%[1]s), AVG(%[1]s), MIN(%[1]s), MAX(%[1]s)", expr)
+	}
+	sqlQuery := "SELECT " + keyExpr + " AS k, rutas.mac, COUNT(*), " + strings.Join(columns, ", ") +
+		" FROM rutas WHERE " + scopeClause + filterClause + " GROUP BY k, rutas.mac ORDER BY rutas.mac, k"
+	rows, err := mysql.conn.FetchRows(sqlQuery, args...)
+	if err != nil {
+		log.Printf("ERROR: [MySQLAdapter] Error al agregar por cubetas para UserID %d: %v", userID, err)
+		return nil, fmt.Errorf("error al agregar datos de MySQL: %w", err)
+	}
+	defer rows.Close()
+
+	series := []entities.DeviceSeries{}
+	index := make(map[string]map[int64]int) // mac -> cubeta -> posición en Buckets
+	for rows.Next() {
+		var key int64
+		var mac string
+		var total int64
+		counts := make([]int64, len(exprs))
+		stats := make([][3]sql.NullFloat64, len(exprs))
+		dest := []interface{}{&key, &mac, &total}
+		for i := range exprs {
+			dest = append(dest, &counts[i], &stats[i][0], &stats[i][1], &stats[i][2])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de agregación: %w", err)
+		}
+		if len(series) == 0 || series[len(series)-1].Mac != mac {
+			series = append(series, entities.DeviceSeries{Mac: mac, Buckets: []entities.AggregateBucket{}})
+			index[mac] = make(map[int64]int)
+		}
+		current := &series[len(series)-1]
+		bucket := entities.AggregateBucket{Inicio: bucketStart(key, width, query.Zona), Lecturas: total, Metricas: make(map[string]entities.MetricAggregate)}
+		for i, name := range query.Metricas {
+			bucket.Metricas[name] = entities.MetricAggregate{
+				Lecturas:    counts[i],
+				MetricStats: entities.MetricStats{Promedio: nullFloatPtr(stats[i][0]), Min: nullFloatPtr(stats[i][1]), Max: nullFloatPtr(stats[i][2])},
+			}
+		}
+		index[mac][key] = len(current.Buckets)
+		current.Buckets = append(current.Buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer agregación: %w", err)
+	}
+
+	if len(query.Percentiles) > 0 {
+		positions := make(map[string]int, len(series))
+		for i := range series {
+			positions[series[i].Mac] = i
+		}
+		for i, name := range query.Metricas {
+			err := mysql.aggregatePercentiles(keyExpr, exprs[i], args, scopeClause+filterClause, query.Percentiles, func(mac string, key int64, values map[string]*float64) {
+				pos, ok := index[mac][key]
+				if !ok {
+					return
+				}
+				buckets := series[positions[mac]].Buckets
+				metric := buckets[pos].Metricas[name]
+				metric.Percentiles = values
+				buckets[pos].Metricas[name] = metric
+			})
+			if err != nil {
+				log.Printf("ERROR: [MySQLAdapter] Error al calcular percentiles de '%s' para UserID %d: %v", name, userID, err)
+				return nil, fmt.Errorf("error al calcular percentiles en MySQL: %w", err)
+			}
+		}
+	}
+	log.Printf("INFO: [MySQLAdapter] Agregación de %d dispositivos en cubetas de %ds para UserID %d.", len(series), width, userID)
+	return series, nil
+}
+
+// aggregatePercentiles calcula percentiles por rango más cercano: el valor en la posición
+// CEIL(n * p / 100) de la cubeta ordenada.
+func (mysql *MySQLRutas) aggregatePercentiles(keyExpr, metricExpr string, args []interface{}, where string, percentiles []int, apply func(mac string, key int64, values map[string]*float64)) error {
+	columns := make([]string, len(percentiles))
+	for i, p := range percentiles {
+		columns[i] = fmt.Sprintf("MIN(CASE WHEN rn >= CEIL(cnt * %d / 100) THEN v END)", p)
+	}
+	sqlQuery := "SELECT k, mac, " + strings.Join(columns, ", ") + ` FROM (
+		SELECT k, mac, v, ROW_NUMBER() OVER (PARTITION BY k, mac ORDER BY v) AS rn, COUNT(*) OVER (PARTITION BY k, mac) AS cnt
+		FROM (SELECT ` + keyExpr + " AS k, rutas.mac AS mac, " + metricExpr + " AS v FROM rutas WHERE " + where + `) base
+		WHERE v IS NOT NULL) ranked GROUP BY k, mac`
+	rows, err := mysql.conn.FetchRows(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key int64
+		var mac string
+		values := make([]sql.NullFloat64, len(percentiles))
+		dest := []interface{}{&key, &mac}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		result := make(map[string]*float64, len(percentiles))
+		for i, p := range percentiles {
+			result["p"+strconv.Itoa(p)] = nullFloatPtr(values[i])
+		}
+		apply(mac, key, result)
+	}
+	return rows.Err()
+}
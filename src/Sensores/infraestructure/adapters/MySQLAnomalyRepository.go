@@ -0,0 +1,200 @@
+// File: MySQLAnomalyRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+type MySQLAnomalyRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLAnomalyRepository(conn *core.Conn_MySQL) *MySQLAnomalyRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLAnomalyRepository recibió una conexión DB nula.")
+	}
+	return &MySQLAnomalyRepository{conn: conn}
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindSettings ---
+func (repo *MySQLAnomalyRepository) FindSettings(mac string) (*entities.AnomalySettings, error) {
+	settings := entities.AnomalySettings{Mac: mac}
+	err := repo.conn.DB.QueryRow("SELECT umbral_z, activa, updated_at FROM anomaly_settings WHERE mac = ?", mac).
+		Scan(&settings.UmbralZ, &settings.Activa, &settings.Actualizado)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer la configuración de anomalías de %s: %w", mac, err)
+	}
+	return &settings, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SaveSettings ---
+func (repo *MySQLAnomalyRepository) SaveSettings(settings *entities.AnomalySettings) error {
+	_, err := repo.conn.ExecutePreparedQuery("REPLACE INTO anomaly_settings (mac, umbral_z, activa) VALUES (?, ?, ?)", settings.Mac, settings.UmbralZ, settings.Activa)
+	if err != nil {
+		return fmt.Errorf("error al guardar la configuración de anomalías de %s: %w", settings.Mac, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO DeleteSettings ---
+func (repo *MySQLAnomalyRepository) DeleteSettings(mac string) error {
+	if _, err := repo.conn.ExecutePreparedQuery("DELETE FROM anomaly_settings WHERE mac = ?", mac); err != nil {
+		return fmt.Errorf("error al borrar la configuración de anomalías de %s: %w", mac, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO GetModel ---
+func (repo *MySQLAnomalyRepository) GetModel(mac string, hora int) (*entities.AnomalySettings, []entities.AnomalyState, error) {
+	// La fila fija garantiza un resultado aunque no haya configuración ni modelos
+	rows, err := repo.conn.FetchRows(`SELECT cfg.umbral_z, cfg.activa, cfg.updated_at, st.metrica, st.hora, st.media, st.varianza, st.muestras, st.actualizado
+		FROM (SELECT 1) base
+		LEFT JOIN anomaly_settings cfg ON cfg.mac = ?
+		LEFT JOIN anomaly_state st ON st.mac = ? AND st.hora IN (?, ?)`,
+		mac, mac, hora, entities.AnomalyHoraGlobal)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al leer los modelos de anomalías de %s: %w", mac, err)
+	}
+	defer rows.Close()
+	var settings *entities.AnomalySettings
+	states := []entities.AnomalyState{}
+	for rows.Next() {
+		var umbral sql.NullFloat64
+		var activa sql.NullBool
+		var configurado sql.NullTime
+		var metrica sql.NullString
+		var horaModelo, muestras sql.NullInt64
+		var media, varianza sql.NullFloat64
+		var actualizado sql.NullTime
+		if err := rows.Scan(&umbral, &activa, &configurado, &metrica, &horaModelo, &media, &varianza, &muestras, &actualizado); err != nil {
+			return nil, nil, fmt.Errorf("error al procesar modelo de anomalías: %w", err)
+		}
+		if settings == nil && umbral.Valid {
+			settings = &entities.AnomalySettings{Mac: mac, UmbralZ: umbral.Float64, Activa: activa.Bool, Actualizado: configurado.Time}
+		}
+		if metrica.Valid {
+			states = append(states, entities.AnomalyState{Mac: mac, Metrica: metrica.String, Hora: int(horaModelo.Int64), Media: media.Float64,
+				Varianza: varianza.Float64, Muestras: int(muestras.Int64), Actualizado: actualizado.Time})
+		}
+	}
+	return settings, states, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SaveResults ---
+func (repo *MySQLAnomalyRepository) SaveResults(states []entities.AnomalyState, events []entities.AnomalyEvent) error {
+	if len(states) == 0 && len(events) == 0 {
+		return nil
+	}
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de anomalías: %w", err)
+	}
+	defer tx.Rollback() // No-op si ya se hizo Commit
+
+	if len(states) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?),", len(states)), ",")
+		args := make([]interface{}, 0, len(states)*7)
+		for _, s := range states {
+			args = append(args, s.Mac, s.Metrica, s.Hora, s.Media, s.Varianza, s.Muestras, s.Actualizado)
+		}
+		if _, err := tx.Exec("REPLACE INTO anomaly_state (mac, metrica, hora, media, varianza, muestras, actualizado) VALUES "+placeholders, args...); err != nil {
+			return fmt.Errorf("error al guardar los modelos de anomalías de %s: %w", states[0].Mac, err)
+		}
+	}
+	for i := range events {
+		event := &events[i]
+		result, err := tx.Exec(`INSERT INTO anomaly_events (dato_id, mac, org_id, user_id, metrica, tipo, fecha, valor, esperado, desviacion, score, estacional)
+			VALUES (NULLIF(?, 0), ?, NULLIF(?, 0), NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?, ?)`,
+			event.DatoID, event.Mac, event.OrgID, event.UserID, event.Metrica, event.Tipo, event.Fecha, event.Valor, event.Esperado,
+			event.Desviacion, event.Score, event.Estacional)
+		if err != nil {
+			return fmt.Errorf("error al registrar anomalía: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error al leer el id de la anomalía: %w", err)
+		}
+		event.ID = int(id)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción de anomalías: %w", err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO DeleteStates ---
+func (repo *MySQLAnomalyRepository) DeleteStates(mac string) error {
+	if _, err := repo.conn.ExecutePreparedQuery("DELETE FROM anomaly_state WHERE mac = ?", mac); err != nil {
+		return fmt.Errorf("error al borrar los modelos de anomalías de %s: %w", mac, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLAnomalyRepository) FindByMac(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.AnomalyEvent, error) {
+	scopeClause, scopeArgs := visibleOn("anomaly_events", "anomaly_events.fecha < o.started_at", userID)
+	query := `SELECT anomaly_events.id, anomaly_events.dato_id, anomaly_events.mac, anomaly_events.user_id, anomaly_events.org_id, anomaly_events.metrica,
+		anomaly_events.tipo, anomaly_events.fecha, anomaly_events.valor, anomaly_events.esperado, anomaly_events.desviacion, anomaly_events.score,
+		anomaly_events.estacional
+		FROM anomaly_events WHERE anomaly_events.mac = ? AND anomaly_events.fecha >= ? AND anomaly_events.fecha < ? AND ` + scopeClause +
+		" ORDER BY anomaly_events.fecha DESC, anomaly_events.id DESC LIMIT ?"
+	args := append([]interface{}{mac, desde, hasta}, scopeArgs...)
+	rows, err := repo.conn.FetchRows(query, append(args, limit)...)
+	if err != nil {
+		log.Printf("ERROR: [AnomalyRepo] Error al listar anomalías de %s para UserID %d: %v", mac, userID, err)
+		return nil, fmt.Errorf("error al consultar anomalías: %w", err)
+	}
+	defer rows.Close()
+	events := []entities.AnomalyEvent{}
+	for rows.Next() {
+		var event entities.AnomalyEvent
+		var datoID sql.NullInt64
+		var eventUserID, orgID sql.NullInt32
+		if err := rows.Scan(&event.ID, &datoID, &event.Mac, &eventUserID, &orgID, &event.Metrica, &event.Tipo, &event.Fecha, &event.Valor,
+			&event.Esperado, &event.Desviacion, &event.Score, &event.Estacional); err != nil {
+			return nil, fmt.Errorf("error al procesar anomalía: %w", err)
+		}
+		event.DatoID = datoID.Int64
+		event.UserID = int(eventUserID.Int32)
+		event.OrgID = int(orgID.Int32)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindMarks ---
+func (repo *MySQLAnomalyRepository) FindMarks(datoIDs []int32) (map[int32][]entities.AnomalyMark, error) {
+	marks := make(map[int32][]entities.AnomalyMark)
+	if len(datoIDs) == 0 {
+		return marks, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(datoIDs)), ",")
+	args := make([]interface{}, len(datoIDs))
+	for i, id := range datoIDs {
+		args[i] = id
+	}
+	rows, err := repo.conn.FetchRows("SELECT dato_id, metrica, score, esperado FROM anomaly_events WHERE dato_id IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer marcas de anomalías: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var datoID int32
+		var mark entities.AnomalyMark
+		if err := rows.Scan(&datoID, &mark.Metrica, &mark.Score, &mark.Esperado); err != nil {
+			return nil, fmt.Errorf("error al procesar marca de anomalía: %w", err)
+		}
+		marks[datoID] = append(marks[datoID], mark)
+	}
+	return marks, rows.Err()
+}
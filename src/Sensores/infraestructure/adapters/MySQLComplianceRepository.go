@@ -0,0 +1,184 @@
+// File: MySQLComplianceRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLComplianceRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLComplianceRepository(conn *core.Conn_MySQL) *MySQLComplianceRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLComplianceRepository recibió una conexión DB nula.")
+	}
+	return &MySQLComplianceRepository{conn: conn}
+}
+
+const complianceProfileColumns = "mac, temp_min, temp_max, tolerancia_seg, hueco_maximo_seg, created_at, updated_at"
+
+func scanComplianceProfile(scanner interface{ Scan(...interface{}) error }) (*entities.ComplianceProfile, error) {
+	var profile entities.ComplianceProfile
+	if err := scanner.Scan(&profile.Mac, &profile.TempMin, &profile.TempMax, &profile.ToleranciaSeg, &profile.HuecoMaximoSeg,
+		&profile.Creado, &profile.Actualizado); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindProfile ---
+func (repo *MySQLComplianceRepository) FindProfile(mac string) (*entities.ComplianceProfile, error) {
+	profile, err := scanComplianceProfile(repo.conn.DB.QueryRow("SELECT "+complianceProfileColumns+" FROM compliance_profiles WHERE mac = ?", mac))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el perfil de cumplimiento de %s: %w", mac, err)
+	}
+	return profile, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindProfiles ---
+func (repo *MySQLComplianceRepository) FindProfiles() ([]entities.ComplianceProfile, error) {
+	rows, err := repo.conn.FetchRows("SELECT " + complianceProfileColumns + " FROM compliance_profiles ORDER BY mac")
+	if err != nil {
+		return nil, fmt.Errorf("error al listar perfiles de cumplimiento: %w", err)
+	}
+	defer rows.Close()
+	profiles := []entities.ComplianceProfile{}
+	for rows.Next() {
+		profile, err := scanComplianceProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar perfil de cumplimiento: %w", err)
+		}
+		profiles = append(profiles, *profile)
+	}
+	return profiles, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SaveProfile ---
+// Conserva created_at al reemplazar: los reportes automáticos solo cubren periodos posteriores
+func (repo *MySQLComplianceRepository) SaveProfile(profile *entities.ComplianceProfile) error {
+	_, err := repo.conn.ExecutePreparedQuery(`INSERT INTO compliance_profiles (mac, temp_min, temp_max, tolerancia_seg, hueco_maximo_seg)
+		VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE temp_min = VALUES(temp_min), temp_max = VALUES(temp_max),
+		tolerancia_seg = VALUES(tolerancia_seg), hueco_maximo_seg = VALUES(hueco_maximo_seg)`,
+		profile.Mac, profile.TempMin, profile.TempMax, profile.ToleranciaSeg, profile.HuecoMaximoSeg)
+	if err != nil {
+		return fmt.Errorf("error al guardar el perfil de cumplimiento de %s: %w", profile.Mac, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO DeleteProfile ---
+func (repo *MySQLComplianceRepository) DeleteProfile(mac string) error {
+	if _, err := repo.conn.ExecutePreparedQuery("DELETE FROM compliance_profiles WHERE mac = ?", mac); err != nil {
+		return fmt.Errorf("error al borrar el perfil de cumplimiento de %s: %w", mac, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CreateReport ---
+func (repo *MySQLComplianceRepository) CreateReport(report *entities.ComplianceReport) error {
+	contenido, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("error al serializar reporte de cumplimiento: %w", err)
+	}
+	result, err := repo.conn.ExecutePreparedQuery(`INSERT INTO compliance_reports (mac, periodo, desde, hasta, cumple, en_rango, contenido, generado_por, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?)`,
+		report.Mac, report.Periodo, report.Desde, report.Hasta, report.Cumple, report.PorcentajeEnRango, string(contenido), report.GeneradoPor, report.Generado)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return fmt.Errorf("reporte_duplicado")
+		}
+		return fmt.Errorf("error al guardar reporte de cumplimiento: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al leer el id del reporte de cumplimiento: %w", err)
+	}
+	report.ID = int(id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindReport ---
+func (repo *MySQLComplianceRepository) FindReport(id int) (*entities.ComplianceReport, error) {
+	var contenido string
+	if err := repo.conn.DB.QueryRow("SELECT contenido FROM compliance_reports WHERE id = ?", id).Scan(&contenido); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error al leer reporte de cumplimiento %d: %w", id, err)
+	}
+	var report entities.ComplianceReport
+	if err := json.Unmarshal([]byte(contenido), &report); err != nil {
+		return nil, fmt.Errorf("reporte de cumplimiento %d ilegible: %w", id, err)
+	}
+	report.ID = id
+	return &report, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindReports ---
+func (repo *MySQLComplianceRepository) FindReports(mac string, periodo string, desde time.Time, hasta time.Time, limit int) ([]entities.ComplianceSummary, error) {
+	query := `SELECT id, mac, periodo, desde, hasta, cumple, en_rango, generado_por, created_at FROM compliance_reports
+		WHERE mac = ? AND desde >= ? AND desde < ?`
+	args := []interface{}{mac, desde, hasta}
+	if periodo != "" {
+		query += " AND periodo = ?"
+		args = append(args, periodo)
+	}
+	rows, err := repo.conn.FetchRows(query+" ORDER BY desde DESC, id DESC LIMIT ?", append(args, limit)...)
+	if err != nil {
+		log.Printf("ERROR: [ComplianceRepo] Error al listar reportes de %s: %v", mac, err)
+		return nil, fmt.Errorf("error al consultar reportes de cumplimiento: %w", err)
+	}
+	defer rows.Close()
+	reports := []entities.ComplianceSummary{}
+	for rows.Next() {
+		var report entities.ComplianceSummary
+		var enRango sql.NullFloat64
+		var generadoPor sql.NullInt64
+		if err := rows.Scan(&report.ID, &report.Mac, &report.Periodo, &report.Desde, &report.Hasta, &report.Cumple, &enRango,
+			&generadoPor, &report.Generado); err != nil {
+			return nil, fmt.Errorf("error al procesar reporte de cumplimiento: %w", err)
+		}
+		report.PorcentajeEnRango = nullFloatPtr(enRango)
+		report.GeneradoPor = int(generadoPor.Int64)
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO ReportExists ---
+func (repo *MySQLComplianceRepository) ReportExists(mac string, periodo string, desde time.Time) (bool, error) {
+	var count int
+	err := repo.conn.DB.QueryRow("SELECT COUNT(*) FROM compliance_reports WHERE mac = ? AND periodo = ? AND desde = ?", mac, periodo, desde).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error al buscar reporte de cumplimiento de %s: %w", mac, err)
+	}
+	return count > 0, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindPeriodReport ---
+func (repo *MySQLComplianceRepository) FindPeriodReport(mac string, periodo string, desde time.Time) (*entities.ComplianceReport, error) {
+	var id int
+	err := repo.conn.DB.QueryRow("SELECT id FROM compliance_reports WHERE mac = ? AND periodo = ? AND desde = ?", mac, periodo, desde).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar reporte de cumplimiento de %s: %w", mac, err)
+	}
+	return repo.FindReport(id)
+}
@@ -0,0 +1,93 @@
+// File: MySQLContainerRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+type MySQLContainerRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLContainerRepository(conn *core.Conn_MySQL) *MySQLContainerRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLContainerRepository recibió una conexión DB nula.")
+	}
+	return &MySQLContainerRepository{conn: conn}
+}
+
+const containerColumns = "mac, forma, altura_sensor, altura_maxima, diametro, largo, ancho, umbral_bajo, umbral_alto, updated_at"
+
+func scanContainer(scanner interface{ Scan(...interface{}) error }) (*entities.ContainerConfig, error) {
+	var config entities.ContainerConfig
+	var diametro, largo, ancho, bajo, alto sql.NullFloat64
+	if err := scanner.Scan(&config.Mac, &config.Forma, &config.AlturaSensor, &config.AlturaMaxima, &diametro, &largo, &ancho, &bajo, &alto, &config.Actualizado); err != nil {
+		return nil, err
+	}
+	config.Diametro, config.Largo, config.Ancho = nullFloatPtr(diametro), nullFloatPtr(largo), nullFloatPtr(ancho)
+	config.UmbralBajo, config.UmbralAlto = nullFloatPtr(bajo), nullFloatPtr(alto)
+	return &config, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLContainerRepository) FindByMac(mac string) (*entities.ContainerConfig, error) {
+	config, err := scanContainer(repo.conn.DB.QueryRow("SELECT "+containerColumns+" FROM device_containers WHERE mac = ?", mac))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el contenedor de %s: %w", mac, err)
+	}
+	return config, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMacs ---
+func (repo *MySQLContainerRepository) FindByMacs(macs []string) (map[string]*entities.ContainerConfig, error) {
+	configs := make(map[string]*entities.ContainerConfig, len(macs))
+	if len(macs) == 0 {
+		return configs, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(macs)), ",")
+	args := make([]interface{}, len(macs))
+	for i, mac := range macs {
+		args[i] = mac
+	}
+	rows, err := repo.conn.FetchRows("SELECT "+containerColumns+" FROM device_containers WHERE mac IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer contenedores: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		config, err := scanContainer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar contenedor: %w", err)
+		}
+		configs[config.Mac] = config
+	}
+	return configs, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Save ---
+func (repo *MySQLContainerRepository) Save(config *entities.ContainerConfig) error {
+	_, err := repo.conn.ExecutePreparedQuery(`REPLACE INTO device_containers (mac, forma, altura_sensor, altura_maxima, diametro, largo, ancho, umbral_bajo, umbral_alto)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		config.Mac, config.Forma, config.AlturaSensor, config.AlturaMaxima, config.Diametro, config.Largo, config.Ancho, config.UmbralBajo, config.UmbralAlto)
+	if err != nil {
+		return fmt.Errorf("error al guardar el contenedor de %s: %w", config.Mac, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Delete ---
+func (repo *MySQLContainerRepository) Delete(mac string) error {
+	if _, err := repo.conn.ExecutePreparedQuery("DELETE FROM device_containers WHERE mac = ?", mac); err != nil {
+		return fmt.Errorf("error al borrar el contenedor de %s: %w", mac, err)
+	}
+	return nil
+}
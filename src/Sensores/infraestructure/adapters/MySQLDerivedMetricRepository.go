@@ -0,0 +1,207 @@
+// File: MySQLDerivedMetricRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLDerivedMetricRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLDerivedMetricRepository(conn *core.Conn_MySQL) *MySQLDerivedMetricRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLDerivedMetricRepository recibió una conexión DB nula.")
+	}
+	return &MySQLDerivedMetricRepository{conn: conn}
+}
+
+const derivedMetricSelect = "SELECT id, mac, nombre, expresion, unidad, created_at, updated_at FROM derived_metrics"
+
+func scanDerivedMetric(scanner interface{ Scan(...interface{}) error }) (*entities.DerivedMetric, error) {
+	var metric entities.DerivedMetric
+	if err := scanner.Scan(&metric.ID, &metric.Mac, &metric.Nombre, &metric.Expresion, &metric.Unidad, &metric.Creado, &metric.Actualizado); err != nil {
+		return nil, err
+	}
+	return &metric, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLDerivedMetricRepository) FindByMac(mac string) ([]entities.DerivedMetric, error) {
+	rows, err := repo.conn.FetchRows(derivedMetricSelect+" WHERE mac = ? ORDER BY nombre", mac)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar métricas derivadas de %s: %w", mac, err)
+	}
+	defer rows.Close()
+	metrics := []entities.DerivedMetric{}
+	for rows.Next() {
+		metric, err := scanDerivedMetric(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar métrica derivada: %w", err)
+		}
+		metrics = append(metrics, *metric)
+	}
+	return metrics, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByID ---
+func (repo *MySQLDerivedMetricRepository) FindByID(id int) (*entities.DerivedMetric, error) {
+	metric, err := scanDerivedMetric(repo.conn.DB.QueryRow(derivedMetricSelect+" WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer la métrica derivada %d: %w", id, err)
+	}
+	return metric, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindNames ---
+func (repo *MySQLDerivedMetricRepository) FindNames(macs []string) ([]string, error) {
+	names := []string{}
+	if len(macs) == 0 {
+		return names, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(macs)), ",")
+	args := make([]interface{}, len(macs))
+	for i, mac := range macs {
+		args[i] = mac
+	}
+	rows, err := repo.conn.FetchRows("SELECT DISTINCT nombre FROM derived_metrics WHERE mac IN ("+placeholders+") ORDER BY nombre", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar nombres de métricas derivadas: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error al procesar nombre de métrica derivada: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Create ---
+func (repo *MySQLDerivedMetricRepository) Create(metric *entities.DerivedMetric) error {
+	result, err := repo.conn.ExecutePreparedQuery("INSERT INTO derived_metrics (mac, nombre, expresion, unidad) VALUES (?, ?, ?, ?)",
+		metric.Mac, metric.Nombre, metric.Expresion, metric.Unidad)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return fmt.Errorf("nombre_duplicado")
+		}
+		return fmt.Errorf("error al crear métrica derivada: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al leer el id de la métrica derivada: %w", err)
+	}
+	metric.ID = int(id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Update ---
+func (repo *MySQLDerivedMetricRepository) Update(metric *entities.DerivedMetric, limpiarValores bool) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de métrica derivada: %w", err)
+	}
+	defer tx.Rollback() // No-op si ya se hizo Commit
+
+	_, err = tx.Exec("UPDATE derived_metrics SET nombre = ?, expresion = ?, unidad = ? WHERE id = ?", metric.Nombre, metric.Expresion, metric.Unidad, metric.ID)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return fmt.Errorf("nombre_duplicado")
+		}
+		return fmt.Errorf("error al actualizar métrica derivada %d: %w", metric.ID, err)
+	}
+	if limpiarValores {
+		if _, err = tx.Exec("DELETE FROM derived_values WHERE metric_id = ?", metric.ID); err != nil {
+			return fmt.Errorf("error al borrar valores de la métrica derivada %d: %w", metric.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción de métrica derivada: %w", err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Delete ---
+func (repo *MySQLDerivedMetricRepository) Delete(id int) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de métrica derivada: %w", err)
+	}
+	defer tx.Rollback() // No-op si ya se hizo Commit
+
+	if _, err = tx.Exec("DELETE FROM derived_values WHERE metric_id = ?", id); err != nil {
+		return fmt.Errorf("error al borrar valores de la métrica derivada %d: %w", id, err)
+	}
+	if _, err = tx.Exec("DELETE FROM derived_metrics WHERE id = ?", id); err != nil {
+		return fmt.Errorf("error al borrar la métrica derivada %d: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción de métrica derivada: %w", err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SaveValues ---
+func (repo *MySQLDerivedMetricRepository) SaveValues(values []entities.DerivedValue) error {
+	if len(values) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?),", len(values)), ",")
+	args := make([]interface{}, 0, len(values)*5)
+	for _, v := range values {
+		args = append(args, v.DatoID, v.MetricID, v.Mac, v.Fecha, v.Valor)
+	}
+	_, err := repo.conn.ExecutePreparedQuery("REPLACE INTO derived_values (dato_id, metric_id, mac, fecha, valor) VALUES "+placeholders, args...)
+	if err != nil {
+		return fmt.Errorf("error al guardar métricas derivadas de %s: %w", values[0].Mac, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindValues ---
+func (repo *MySQLDerivedMetricRepository) FindValues(datoIDs []int32) (map[int32]map[string]float64, error) {
+	values := make(map[int32]map[string]float64)
+	if len(datoIDs) == 0 {
+		return values, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(datoIDs)), ",")
+	args := make([]interface{}, len(datoIDs))
+	for i, id := range datoIDs {
+		args[i] = id
+	}
+	rows, err := repo.conn.FetchRows(`SELECT dv.dato_id, dm.nombre, dv.valor FROM derived_values dv
+		JOIN derived_metrics dm ON dm.id = dv.metric_id WHERE dv.dato_id IN (`+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer valores de métricas derivadas: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var datoID int32
+		var name string
+		var valor float64
+		if err := rows.Scan(&datoID, &name, &valor); err != nil {
+			return nil, fmt.Errorf("error al procesar valor de métrica derivada: %w", err)
+		}
+		if values[datoID] == nil {
+			values[datoID] = make(map[string]float64)
+		}
+		values[datoID][name] = valor
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,156 @@
+// File: MySQLDeviceRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+type MySQLDeviceRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLDeviceRepository(conn *core.Conn_MySQL) *MySQLDeviceRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLDeviceRepository recibió una conexión DB nula.")
+	}
+	return &MySQLDeviceRepository{conn: conn}
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLDeviceRepository) FindByMac(mac string) (*entities.Device, error) {
+	devices, err := repo.FindByMacs([]string{mac})
+	if err != nil {
+		return nil, err
+	}
+	return devices[mac], nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMacs ---
+func (repo *MySQLDeviceRepository) FindByMacs(macs []string) (map[string]*entities.Device, error) {
+	result := make(map[string]*entities.Device, len(macs))
+	if len(macs) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, 0, len(macs))
+	for _, mac := range macs {
+		result[mac] = entities.NewDevice(mac) // Por defecto, sin metadatos
+		args = append(args, mac)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(macs)), ",")
+
+	rows, err := repo.conn.FetchRows(`SELECT d.mac, d.nombre, d.descripcion, d.ubicacion, d.area_id, a.site_id, d.org_id, d.intervalo_seg
+		FROM devices d LEFT JOIN areas a ON a.id = d.area_id WHERE d.mac IN (`+placeholders+")", args...)
+	if err != nil {
+		log.Printf("ERROR: [DeviceRepo] Error al consultar metadatos de %d dispositivos: %v", len(macs), err)
+		return nil, fmt.Errorf("error al consultar dispositivos: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var mac string
+		var device entities.Device
+		var areaID, siteID, orgID, intervalo sql.NullInt64
+		if err := rows.Scan(&mac, &device.Nombre, &device.Descripcion, &device.Ubicacion, &areaID, &siteID, &orgID, &intervalo); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de dispositivo: %w", err)
+		}
+		if d, ok := result[mac]; ok {
+			d.Nombre, d.Descripcion, d.Ubicacion = device.Nombre, device.Descripcion, device.Ubicacion
+			d.AreaID, d.SiteID, d.OrgID = nullIntPtr(areaID), nullIntPtr(siteID), nullIntPtr(orgID)
+			d.IntervaloSeg = nullIntPtr(intervalo)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer dispositivos: %w", err)
+	}
+
+	tagRows, err := repo.conn.FetchRows("SELECT mac, tag FROM device_tags WHERE mac IN ("+placeholders+") ORDER BY tag", args...)
+	if err != nil {
+		log.Printf("ERROR: [DeviceRepo] Error al consultar etiquetas: %v", err)
+		return nil, fmt.Errorf("error al consultar etiquetas de dispositivos: %w", err)
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var mac, tag string
+		if err := tagRows.Scan(&mac, &tag); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de etiqueta: %w", err)
+		}
+		if d, ok := result[mac]; ok {
+			d.Etiquetas = append(d.Etiquetas, tag)
+		}
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer etiquetas: %w", err)
+	}
+	return result, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByUserID ---
+func (repo *MySQLDeviceRepository) FindByUserID(userID int) ([]entities.Device, error) {
+	rows, err := repo.conn.FetchRows(`SELECT d.mac FROM devices d JOIN organization_members m ON m.org_id = d.org_id WHERE m.user_id = ?
+		UNION SELECT mac_address FROM users WHERE id = ? AND mac_address IS NOT NULL AND mac_address <> ''
+		UNION SELECT s.mac FROM device_shares s WHERE s.grantee_user_id = ? AND `+activeShare+`
+		ORDER BY 1`, userID, userID, userID)
+	if err != nil {
+		log.Printf("ERROR: [DeviceRepo] Error al listar MACs del UserID %d: %v", userID, err)
+		return nil, fmt.Errorf("error al consultar dispositivos del usuario: %w", err)
+	}
+	defer rows.Close()
+	macs := []string{}
+	for rows.Next() {
+		var mac string
+		if err := rows.Scan(&mac); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de dispositivo: %w", err)
+		}
+		macs = append(macs, mac)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer dispositivos del usuario: %w", err)
+	}
+
+	byMac, err := repo.FindByMacs(macs)
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]entities.Device, 0, len(macs))
+	for _, mac := range macs {
+		devices = append(devices, *byMac[mac])
+	}
+	return devices, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Save ---
+// Reemplaza metadatos y etiquetas en una transacción para no dejar etiquetas a medias.
+func (repo *MySQLDeviceRepository) Save(device *entities.Device) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de dispositivo: %w", err)
+	}
+	defer tx.Rollback() // No-op si ya se hizo Commit
+
+	_, err = tx.Exec(`INSERT INTO devices (mac, nombre, descripcion, ubicacion, intervalo_seg) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE nombre = VALUES(nombre), descripcion = VALUES(descripcion), ubicacion = VALUES(ubicacion),
+		intervalo_seg = VALUES(intervalo_seg)`,
+		device.Mac, device.Nombre, device.Descripcion, device.Ubicacion, device.IntervaloSeg)
+	if err != nil {
+		log.Printf("ERROR: [DeviceRepo] Error al guardar metadatos de MAC %s: %v", device.Mac, err)
+		return fmt.Errorf("error al guardar dispositivo: %w", err)
+	}
+	if _, err = tx.Exec("DELETE FROM device_tags WHERE mac = ?", device.Mac); err != nil {
+		return fmt.Errorf("error al limpiar etiquetas: %w", err)
+	}
+	for _, tag := range device.Etiquetas {
+		if _, err = tx.Exec("INSERT INTO device_tags (mac, tag) VALUES (?, ?)", device.Mac, tag); err != nil {
+			return fmt.Errorf("error al guardar etiqueta '%s': %w", tag, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción de dispositivo: %w", err)
+	}
+	log.Printf("INFO: [DeviceRepo] Metadatos guardados para MAC %s (%d etiquetas).", device.Mac, len(device.Etiquetas))
+	return nil
+}
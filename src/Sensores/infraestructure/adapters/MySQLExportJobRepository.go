@@ -0,0 +1,179 @@
+// File: MySQLExportJobRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+)
+
+type MySQLExportJobRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLExportJobRepository(conn *core.Conn_MySQL) *MySQLExportJobRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLExportJobRepository recibió una conexión DB nula.")
+	}
+	return &MySQLExportJobRepository{conn: conn}
+}
+
+const exportJobColumns = "id, user_id, todos, formato, filtro, estado, filas, bytes, COALESCE(error, ''), created_at, started_at, finished_at, expires_at"
+
+func scanExportJob(scanner interface{ Scan(...interface{}) error }) (*entities.ExportJob, error) {
+	var job entities.ExportJob
+	var filtro string
+	var started, finished, expires sql.NullTime
+	err := scanner.Scan(&job.ID, &job.UserID, &job.Todos, &job.Formato, &filtro, &job.Estado, &job.Filas, &job.Bytes, &job.Error,
+		&job.Creado, &started, &finished, &expires)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(filtro), &job.Filtro); err != nil {
+		return nil, fmt.Errorf("filtro de exportación %d ilegible: %w", job.ID, err)
+	}
+	job.Iniciado = nullTimePtr(started)
+	job.Terminado = nullTimePtr(finished)
+	job.Expira = nullTimePtr(expires)
+	return &job, nil
+}
+
+func (repo *MySQLExportJobRepository) findMany(where string, args ...interface{}) ([]entities.ExportJob, error) {
+	rows, err := repo.conn.FetchRows("SELECT "+exportJobColumns+" FROM export_jobs WHERE "+where+" ORDER BY created_at DESC, id DESC", args...)
+	if err != nil {
+		log.Printf("ERROR: [ExportJobRepo] Error al consultar exportaciones: %v", err)
+		return nil, fmt.Errorf("error al consultar exportaciones: %w", err)
+	}
+	defer rows.Close()
+	jobs := []entities.ExportJob{}
+	for rows.Next() {
+		job, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar fila de exportación: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer exportaciones: %w", err)
+	}
+	return jobs, nil
+}
+
+func (repo *MySQLExportJobRepository) exec(tag string, id int, query string, args ...interface{}) error {
+	if _, err := repo.conn.ExecutePreparedQuery(query, args...); err != nil {
+		log.Printf("ERROR: [ExportJobRepo] Error al %s la exportación %d: %v", tag, id, err)
+		return fmt.Errorf("error al %s la exportación: %w", tag, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Create ---
+func (repo *MySQLExportJobRepository) Create(job *entities.ExportJob) error {
+	filtro, err := json.Marshal(job.Filtro)
+	if err != nil {
+		return fmt.Errorf("error al serializar filtro de exportación: %w", err)
+	}
+	result, err := repo.conn.ExecutePreparedQuery("INSERT INTO export_jobs (user_id, todos, formato, filtro, estado, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		job.UserID, job.Todos, job.Formato, string(filtro), job.Estado, job.Creado)
+	if err != nil {
+		log.Printf("ERROR: [ExportJobRepo] Error al crear exportación de UserID %d: %v", job.UserID, err)
+		return fmt.Errorf("error al guardar exportación: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	job.ID = int(id)
+	log.Printf("INFO: [ExportJobRepo] Exportación %d (%s) creada por UserID %d.", job.ID, job.Formato, job.UserID)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByID ---
+func (repo *MySQLExportJobRepository) FindByID(id int) (*entities.ExportJob, error) {
+	job, err := scanExportJob(repo.conn.DB.QueryRow("SELECT "+exportJobColumns+" FROM export_jobs WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("error al consultar exportación: %w", err)
+	}
+	return job, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindForUser ---
+func (repo *MySQLExportJobRepository) FindForUser(userID int) ([]entities.ExportJob, error) {
+	return repo.findMany("user_id = ?", userID)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CountActive ---
+func (repo *MySQLExportJobRepository) CountActive(userID int) (int, error) {
+	var count int
+	err := repo.conn.DB.QueryRow("SELECT COUNT(*) FROM export_jobs WHERE user_id = ? AND estado IN (?, ?)",
+		userID, entities.ExportPendiente, entities.ExportProcesando).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error al contar exportaciones activas: %w", err)
+	}
+	return count, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO MarkRunning ---
+func (repo *MySQLExportJobRepository) MarkRunning(id int) error {
+	return repo.exec("iniciar", id, "UPDATE export_jobs SET estado = ?, started_at = NOW() WHERE id = ?", entities.ExportProcesando, id)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Finish ---
+func (repo *MySQLExportJobRepository) Finish(id int, filas int64, bytes int64, expira time.Time) error {
+	return repo.exec("completar", id, "UPDATE export_jobs SET estado = ?, filas = ?, bytes = ?, finished_at = NOW(), expires_at = ? WHERE id = ?",
+		entities.ExportCompletado, filas, bytes, expira, id)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Fail ---
+func (repo *MySQLExportJobRepository) Fail(id int, motivo string) error {
+	if utf8.RuneCountInString(motivo) > 255 {
+		motivo = string([]rune(motivo)[:255])
+	}
+	return repo.exec("marcar como fallida", id, "UPDATE export_jobs SET estado = ?, error = ?, finished_at = NOW() WHERE id = ?", entities.ExportFallido, motivo, id)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FailInterrupted ---
+func (repo *MySQLExportJobRepository) FailInterrupted() (int, error) {
+	result, err := repo.conn.ExecutePreparedQuery("UPDATE export_jobs SET estado = ?, error = ?, finished_at = NOW() WHERE estado IN (?, ?)",
+		entities.ExportFallido, "interrumpida por reinicio del servidor", entities.ExportPendiente, entities.ExportProcesando)
+	if err != nil {
+		return 0, fmt.Errorf("error al cerrar exportaciones interrumpidas: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindExpired ---
+func (repo *MySQLExportJobRepository) FindExpired(now time.Time) ([]entities.ExportJob, error) {
+	return repo.findMany("estado = ? AND expires_at <= ?", entities.ExportCompletado, now)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO MarkExpired ---
+func (repo *MySQLExportJobRepository) MarkExpired(id int) error {
+	return repo.exec("expirar", id, "UPDATE export_jobs SET estado = ? WHERE id = ?", entities.ExportExpirado, id)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO BytesByUser ---
+func (repo *MySQLExportJobRepository) BytesByUser() (map[int]int64, error) {
+	rows, err := repo.conn.FetchRows("SELECT user_id, SUM(bytes) FROM export_jobs WHERE estado = ? GROUP BY user_id", entities.ExportCompletado)
+	if err != nil {
+		return nil, fmt.Errorf("error al sumar archivos de exportación: %w", err)
+	}
+	defer rows.Close()
+	bytes := make(map[int]int64)
+	for rows.Next() {
+		var userID int
+		var total int64
+		if err := rows.Scan(&userID, &total); err != nil {
+			return nil, fmt.Errorf("error al procesar suma de exportaciones: %w", err)
+		}
+		bytes[userID] = total
+	}
+	return bytes, rows.Err()
+}
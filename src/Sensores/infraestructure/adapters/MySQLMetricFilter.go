@@ -0,0 +1,66 @@
+// File: MySQLMetricFilter.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain"
+	"fmt"
+	"strings"
+)
+
+// compileMetricFilter traduce una expresión de domain.ParseMetricFilter a una condición SQL sobre
+// 'rutas'. Los campos y operadores salen de listas fijas y los valores van siempre como parámetros.
+func compileMetricFilter(expr domain.MetricExpr) (string, []interface{}, error) {
+	switch e := expr.(type) {
+	case domain.MetricLogic:
+		if e.Op != "AND" && e.Op != "OR" {
+			return "", nil, fmt.Errorf("operador lógico desconocido '%s'", e.Op)
+		}
+		left, leftArgs, err := compileMetricFilter(e.Left)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := compileMetricFilter(e.Right)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + left + " " + e.Op + " " + right + ")", append(leftArgs, rightArgs...), nil
+	case domain.MetricNot:
+		inner, args, err := compileMetricFilter(e.Expr)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(NOT " + inner + ")", args, nil
+	case domain.MetricCondition:
+		column, ok := aggregateMetricExpr(e.Campo)
+		if !ok {
+			return "", nil, fmt.Errorf("campo desconocido '%s'", e.Campo)
+		}
+		args := make([]interface{}, len(e.Valores))
+		for i, v := range e.Valores {
+			args[i] = v
+		}
+		// COALESCE: una comparación con la métrica vacía es falsa (y su NOT, verdadero)
+		switch e.Op {
+		case "=", "!=", "<", "<=", ">", ">=":
+			if len(args) != 1 {
+				break
+			}
+			return "COALESCE(" + column + " " + e.Op + " ?, FALSE)", args, nil
+		case "IN":
+			if len(args) == 0 {
+				break
+			}
+			return "COALESCE(" + column + " IN (" + strings.TrimSuffix(strings.Repeat("?,", len(args)), ",") + "), FALSE)", args, nil
+		case "BETWEEN":
+			if len(args) != 2 {
+				break
+			}
+			return "COALESCE(" + column + " BETWEEN ? AND ?, FALSE)", args, nil
+		case "IS NULL", "IS NOT NULL":
+			return "(" + column + " " + e.Op + ")", nil, nil
+		}
+		return "", nil, fmt.Errorf("condición inválida sobre '%s' (%s con %d valores)", e.Campo, e.Op, len(args))
+	}
+	return "", nil, fmt.Errorf("expresión de filtro desconocida %T", expr)
+}
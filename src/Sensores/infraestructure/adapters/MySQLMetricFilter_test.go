@@ -0,0 +1,125 @@
+// File: MySQLMetricFilter_test.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileMetricFilter(t *testing.T) {
+	temperatura, _ := aggregateMetricExpr("temperatura")
+	peso, _ := aggregateMetricExpr("peso")
+	movimiento, _ := aggregateMetricExpr("movimiento")
+	llenado, _ := aggregateMetricExpr("llenado")
+	merma, _ := aggregateMetricExpr("merma")
+
+	tests := []struct {
+		texto string
+		sql   string
+		args  []interface{}
+	}{
+		{"temperatura > 30", "COALESCE(" + temperatura + " > ?, FALSE)", []interface{}{30.0}},
+		{"peso <> 2.5", "COALESCE(" + peso + " != ?, FALSE)", []interface{}{2.5}},
+		{"movimiento = si", "COALESCE(" + movimiento + " = ?, FALSE)", []interface{}{1.0}},
+		{"peso in (1, 2, 3)", "COALESCE(" + peso + " IN (?,?,?), FALSE)", []interface{}{1.0, 2.0, 3.0}},
+		{"llenado between 10 and 90", "COALESCE(" + llenado + " BETWEEN ? AND ?, FALSE)", []interface{}{10.0, 90.0}},
+		{"peso is null", "(" + peso + " IS NULL)", nil},
+		{"peso is not null", "(" + peso + " IS NOT NULL)", nil},
+		// Las métricas derivadas usan el mismo valor guardado que las agregaciones
+		{"merma > 0.5", "COALESCE(" + merma + " > ?, FALSE)", []interface{}{0.5}},
+		{"merma is null", "(" + merma + " IS NULL)", nil},
+		{"peso not in (1)", "(NOT COALESCE(" + peso + " IN (?), FALSE))", []interface{}{1.0}},
+		// Los argumentos siguen el orden de los '?' en el SQL
+		{"temperatura < 0 or peso > 5 and not movimiento = no",
+			"(COALESCE(" + temperatura + " < ?, FALSE) OR (COALESCE(" + peso + " > ?, FALSE) AND (NOT COALESCE(" + movimiento + " = ?, FALSE))))",
+			[]interface{}{0.0, 5.0, 0.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.texto, func(t *testing.T) {
+			filter, err := domain.ParseMetricFilter(tt.texto)
+			if err != nil {
+				t.Fatalf("ParseMetricFilter: %v", err)
+			}
+			sql, args, err := compileMetricFilter(filter.Raiz)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if sql != tt.sql {
+				t.Errorf("SQL = %s\nse esperaba %s", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, se esperaba %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCompileMetricFilterErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  domain.MetricExpr
+		error string
+	}{
+		{"campo desconocido", domain.MetricCondition{Campo: "Peso", Op: "=", Valores: []float64{1}}, "campo desconocido 'Peso'"},
+		{"campo con comillas", domain.MetricCondition{Campo: "x' OR 1=1 --", Op: "=", Valores: []float64{1}}, "campo desconocido"},
+		{"operador desconocido", domain.MetricCondition{Campo: "peso", Op: "LIKE", Valores: []float64{1}}, "condición inválida sobre 'peso'"},
+		{"comparación sin valor", domain.MetricCondition{Campo: "peso", Op: "=", Valores: nil}, "condición inválida sobre 'peso' (= con 0 valores)"},
+		{"between con un valor", domain.MetricCondition{Campo: "peso", Op: "BETWEEN", Valores: []float64{1}}, "condición inválida sobre 'peso' (BETWEEN con 1 valores)"},
+		{"in vacío", domain.MetricCondition{Campo: "peso", Op: "IN"}, "condición inválida sobre 'peso' (IN con 0 valores)"},
+		{"lógica desconocida", domain.MetricLogic{Op: "XOR",
+			Left:  domain.MetricCondition{Campo: "peso", Op: "IS NULL"},
+			Right: domain.MetricCondition{Campo: "peso", Op: "IS NULL"}}, "operador lógico desconocido 'XOR'"},
+		{"error dentro de not", domain.MetricNot{Expr: domain.MetricCondition{Campo: "Peso", Op: "IS NULL"}}, "campo desconocido 'Peso'"},
+		{"error a la derecha", domain.MetricLogic{Op: "AND",
+			Left:  domain.MetricCondition{Campo: "peso", Op: "IS NULL"},
+			Right: domain.MetricCondition{Campo: "peso", Op: "<", Valores: []float64{1, 2}}}, "condición inválida sobre 'peso' (< con 2 valores)"},
+		{"nodo nulo", nil, "expresión de filtro desconocida"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := compileMetricFilter(tt.expr)
+			if err == nil {
+				t.Fatalf("se esperaba un error y se obtuvo %q %v", sql, args)
+			}
+			if !strings.HasPrefix(err.Error(), tt.error) {
+				t.Errorf("error = %q, se esperaba que empiece con %q", err.Error(), tt.error)
+			}
+		})
+	}
+}
+
+func TestBuildDatosFilter(t *testing.T) {
+	desde := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	hasta := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	filter, err := domain.ParseMetricFilter("peso between 1 and 2")
+	if err != nil {
+		t.Fatalf("ParseMetricFilter: %v", err)
+	}
+	peso, _ := aggregateMetricExpr("peso")
+
+	clause, args, err := buildDatosFilter(domain.DatosFilter{Mac: "AA:BB", Desde: desde, Hasta: hasta, Expresion: filter})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	wantClause := " AND rutas.mac = ? AND rutas.created_at >= ? AND rutas.created_at < ? AND COALESCE(" + peso + " BETWEEN ? AND ?, FALSE)"
+	if clause != wantClause {
+		t.Errorf("cláusula = %s\nse esperaba %s", clause, wantClause)
+	}
+	if wantArgs := []interface{}{"AA:BB", desde, hasta, 1.0, 2.0}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, se esperaba %v", args, wantArgs)
+	}
+
+	// Un árbol que no se puede compilar no debe convertirse en un filtro que no devuelve nada
+	invalid := &domain.MetricFilter{Texto: "Peso = 1", Raiz: domain.MetricCondition{Campo: "Peso", Op: "=", Valores: []float64{1}}}
+	clause, args, err = buildDatosFilter(domain.DatosFilter{Mac: "AA:BB", Expresion: invalid})
+	if err == nil {
+		t.Fatalf("se esperaba un error y se obtuvo %q %v", clause, args)
+	}
+	if !strings.Contains(err.Error(), "'Peso = 1'") || !strings.Contains(err.Error(), "campo desconocido 'Peso'") {
+		t.Errorf("error = %q, debe incluir la expresión y la causa", err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+// File: MySQLMotionEventRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type MySQLMotionEventRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLMotionEventRepository(conn *core.Conn_MySQL) *MySQLMotionEventRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLMotionEventRepository recibió una conexión DB nula.")
+	}
+	return &MySQLMotionEventRepository{conn: conn}
+}
+
+const motionEventColumns = "motion_events.id, motion_events.mac, motion_events.user_id, motion_events.org_id, motion_events.estado, motion_events.inicio, motion_events.fin, motion_events.ultimo_movimiento, motion_events.muestras"
+
+func scanMotionEvent(scanner interface{ Scan(...interface{}) error }) (*entities.MotionEvent, error) {
+	var event entities.MotionEvent
+	var userID, orgID sql.NullInt32
+	var fin sql.NullTime
+	if err := scanner.Scan(&event.ID, &event.Mac, &userID, &orgID, &event.Estado, &event.Inicio, &fin, &event.UltimoMovimiento, &event.Muestras); err != nil {
+		return nil, err
+	}
+	event.UserID = int(userID.Int32)
+	event.OrgID = int(orgID.Int32)
+	end := event.UltimoMovimiento
+	if fin.Valid {
+		event.Fin = &fin.Time
+		end = fin.Time
+	}
+	event.DuracionSeg = end.Sub(event.Inicio).Seconds()
+	return &event, nil
+}
+
+func (repo *MySQLMotionEventRepository) fetchEvents(query string, args ...interface{}) ([]entities.MotionEvent, error) {
+	rows, err := repo.conn.FetchRows(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar intervalos de movimiento: %w", err)
+	}
+	defer rows.Close()
+	events := []entities.MotionEvent{}
+	for rows.Next() {
+		event, err := scanMotionEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar intervalo de movimiento: %w", err)
+		}
+		events = append(events, *event)
+	}
+	return events, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindOpen ---
+func (repo *MySQLMotionEventRepository) FindOpen(mac string) (*entities.MotionEvent, error) {
+	row := repo.conn.DB.QueryRow("SELECT "+motionEventColumns+" FROM motion_events WHERE mac = ? AND estado <> ? ORDER BY id DESC LIMIT 1", mac, entities.MotionCerrado)
+	event, err := scanMotionEvent(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar intervalo abierto de %s: %w", mac, err)
+	}
+	return event, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Create ---
+func (repo *MySQLMotionEventRepository) Create(event *entities.MotionEvent) error {
+	result, err := repo.conn.ExecutePreparedQuery(
+		"INSERT INTO motion_events (mac, org_id, user_id, estado, inicio, fin, ultimo_movimiento, muestras) VALUES (?, NULLIF(?, 0), NULLIF(?, 0), ?, ?, ?, ?, ?)",
+		event.Mac, event.OrgID, event.UserID, event.Estado, event.Inicio, event.Fin, event.UltimoMovimiento, event.Muestras)
+	if err != nil {
+		return fmt.Errorf("error al crear intervalo de movimiento: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al leer el id del intervalo de movimiento: %w", err)
+	}
+	event.ID = int(id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Update ---
+func (repo *MySQLMotionEventRepository) Update(event *entities.MotionEvent) error {
+	_, err := repo.conn.ExecutePreparedQuery("UPDATE motion_events SET estado = ?, fin = ?, ultimo_movimiento = ?, muestras = ? WHERE id = ?",
+		event.Estado, event.Fin, event.UltimoMovimiento, event.Muestras, event.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar intervalo de movimiento %d: %w", event.ID, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Delete ---
+func (repo *MySQLMotionEventRepository) Delete(id int) error {
+	if _, err := repo.conn.ExecutePreparedQuery("DELETE FROM motion_events WHERE id = ?", id); err != nil {
+		return fmt.Errorf("error al borrar intervalo de movimiento %d: %w", id, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindStale ---
+func (repo *MySQLMotionEventRepository) FindStale(antes time.Time) ([]entities.MotionEvent, error) {
+	return repo.fetchEvents("SELECT "+motionEventColumns+" FROM motion_events WHERE estado IN (?, ?) AND ultimo_movimiento < ?",
+		entities.MotionCandidato, entities.MotionAbierto, antes)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLMotionEventRepository) FindByMac(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.MotionEvent, error) {
+	// Mismo criterio de visibilidad que las lecturas: los intervalos del dueño anterior solo si empezaron antes del cambio
+	scopeClause, scopeArgs := visibleOn("motion_events", "motion_events.inicio < o.started_at", userID)
+	query := "SELECT " + motionEventColumns + ` FROM motion_events
+		WHERE motion_events.mac = ? AND motion_events.estado <> ? AND motion_events.inicio < ?
+		AND COALESCE(motion_events.fin, motion_events.ultimo_movimiento) >= ? AND ` + scopeClause +
+		" ORDER BY motion_events.inicio DESC LIMIT ?"
+	args := append([]interface{}{mac, entities.MotionCandidato, hasta, desde}, scopeArgs...)
+	events, err := repo.fetchEvents(query, append(args, limit)...)
+	if err != nil {
+		log.Printf("ERROR: [MotionEventRepo] Error al listar intervalos de %s para UserID %d: %v", mac, userID, err)
+		return nil, err
+	}
+	return events, nil
+}
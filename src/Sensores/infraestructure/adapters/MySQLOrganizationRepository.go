@@ -0,0 +1,219 @@
+// File: MySQLOrganizationRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type MySQLOrganizationRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLOrganizationRepository(conn *core.Conn_MySQL) *MySQLOrganizationRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLOrganizationRepository recibió una conexión DB nula.")
+	}
+	return &MySQLOrganizationRepository{conn: conn}
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CreatePersonal ---
+func (repo *MySQLOrganizationRepository) CreatePersonal(userID int, nombre string) (int, error) {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error al iniciar transacción de organización: %w", err)
+	}
+	defer tx.Rollback()
+
+	// INSERT IGNORE: la clave única sobre personal_user_id evita duplicados
+	if _, err := tx.Exec("INSERT IGNORE INTO organizations (nombre, personal_user_id) VALUES (?, ?)", nombre, userID); err != nil {
+		log.Printf("ERROR: [OrgRepo] Error al crear organización personal de UserID %d: %v", userID, err)
+		return 0, fmt.Errorf("error al crear organización personal: %w", err)
+	}
+	var orgID int
+	if err := tx.QueryRow("SELECT id FROM organizations WHERE personal_user_id = ?", userID).Scan(&orgID); err != nil {
+		return 0, fmt.Errorf("error al leer organización personal: %w", err)
+	}
+	if _, err := tx.Exec("INSERT IGNORE INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)", orgID, userID, entities.RolOrgOwner); err != nil {
+		return 0, fmt.Errorf("error al registrar owner de la organización personal: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error al confirmar organización personal: %w", err)
+	}
+	log.Printf("INFO: [OrgRepo] Organización personal %d lista para UserID %d.", orgID, userID)
+	return orgID, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO PersonalOrgID ---
+func (repo *MySQLOrganizationRepository) PersonalOrgID(userID int) (int, error) {
+	var orgID int
+	err := repo.conn.DB.QueryRow("SELECT id FROM organizations WHERE personal_user_id = ?", userID).Scan(&orgID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, sql.ErrNoRows
+		}
+		return 0, fmt.Errorf("error al consultar organización personal: %w", err)
+	}
+	return orgID, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Create ---
+func (repo *MySQLOrganizationRepository) Create(org *entities.Organization, ownerUserID int) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de organización: %w", err)
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec("INSERT INTO organizations (nombre) VALUES (?)", org.Nombre)
+	if err != nil {
+		log.Printf("ERROR: [OrgRepo] Error al crear organización '%s': %v", org.Nombre, err)
+		return fmt.Errorf("error al guardar organización: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	if _, err := tx.Exec("INSERT INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)", id, ownerUserID, entities.RolOrgOwner); err != nil {
+		return fmt.Errorf("error al registrar owner de la organización: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar organización: %w", err)
+	}
+	org.ID = int(id)
+	log.Printf("INFO: [OrgRepo] Organización '%s' creada con ID %d (owner UserID %d).", org.Nombre, org.ID, ownerUserID)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Update ---
+func (repo *MySQLOrganizationRepository) Update(org *entities.Organization) error {
+	_, err := repo.conn.ExecutePreparedQuery("UPDATE organizations SET nombre = ? WHERE id = ?", org.Nombre, org.ID)
+	if err != nil {
+		log.Printf("ERROR: [OrgRepo] Error al actualizar organización %d: %v", org.ID, err)
+		return fmt.Errorf("error al actualizar organización: %w", err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindForUser ---
+func (repo *MySQLOrganizationRepository) FindForUser(userID int) ([]entities.Organization, error) {
+	rows, err := repo.conn.FetchRows(`SELECT o.id, o.nombre, o.personal_user_id IS NOT NULL, m.role
+		FROM organizations o JOIN organization_members m ON m.org_id = o.id
+		WHERE m.user_id = ? ORDER BY o.personal_user_id IS NULL, o.nombre, o.id`, userID)
+	if err != nil {
+		log.Printf("ERROR: [OrgRepo] Error al listar organizaciones de UserID %d: %v", userID, err)
+		return nil, fmt.Errorf("error al consultar organizaciones: %w", err)
+	}
+	defer rows.Close()
+	orgs := []entities.Organization{}
+	for rows.Next() {
+		var org entities.Organization
+		if err := rows.Scan(&org.ID, &org.Nombre, &org.Personal, &org.Rol); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de organización: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer organizaciones: %w", err)
+	}
+	return orgs, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByID ---
+func (repo *MySQLOrganizationRepository) FindByID(orgID int) (*entities.Organization, error) {
+	org := &entities.Organization{Miembros: []entities.OrganizationMember{}}
+	err := repo.conn.DB.QueryRow("SELECT id, nombre, personal_user_id IS NOT NULL FROM organizations WHERE id = ?", orgID).
+		Scan(&org.ID, &org.Nombre, &org.Personal)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("error al consultar organización: %w", err)
+	}
+	rows, err := repo.conn.FetchRows(`SELECT m.user_id, u.username, m.role
+		FROM organization_members m JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ? ORDER BY u.username`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar miembros de la organización: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var member entities.OrganizationMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Rol); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de miembro: %w", err)
+		}
+		org.Miembros = append(org.Miembros, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer miembros: %w", err)
+	}
+	return org, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO MemberRole ---
+func (repo *MySQLOrganizationRepository) MemberRole(orgID int, userID int) (string, error) {
+	var role string
+	err := repo.conn.DB.QueryRow("SELECT role FROM organization_members WHERE org_id = ? AND user_id = ?", orgID, userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("error al consultar rol en la organización: %w", err)
+	}
+	return role, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SetMember ---
+func (repo *MySQLOrganizationRepository) SetMember(orgID int, userID int, role string) error {
+	_, err := repo.conn.ExecutePreparedQuery(`INSERT INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`, orgID, userID, role)
+	if err != nil {
+		log.Printf("ERROR: [OrgRepo] Error al guardar miembro UserID %d en organización %d: %v", userID, orgID, err)
+		return fmt.Errorf("error al guardar miembro: %w", err)
+	}
+	log.Printf("INFO: [OrgRepo] UserID %d es '%s' en organización %d.", userID, role, orgID)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO RemoveMember ---
+func (repo *MySQLOrganizationRepository) RemoveMember(orgID int, userID int) error {
+	_, err := repo.conn.ExecutePreparedQuery("DELETE FROM organization_members WHERE org_id = ? AND user_id = ?", orgID, userID)
+	if err != nil {
+		log.Printf("ERROR: [OrgRepo] Error al quitar UserID %d de organización %d: %v", userID, orgID, err)
+		return fmt.Errorf("error al quitar miembro: %w", err)
+	}
+	log.Printf("INFO: [OrgRepo] UserID %d quitado de organización %d.", userID, orgID)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CountOwners ---
+func (repo *MySQLOrganizationRepository) CountOwners(orgID int) (int, error) {
+	var count int
+	err := repo.conn.DB.QueryRow("SELECT COUNT(*) FROM organization_members WHERE org_id = ? AND role = ?", orgID, entities.RolOrgOwner).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error al contar owners: %w", err)
+	}
+	return count, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO DeviceOrg ---
+func (repo *MySQLOrganizationRepository) DeviceOrg(mac string) (int, error) {
+	var orgID sql.NullInt64
+	err := repo.conn.DB.QueryRow("SELECT org_id FROM devices WHERE mac = ?", mac).Scan(&orgID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("error al consultar organización del dispositivo: %w", err)
+	}
+	return int(orgID.Int64), nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SetDeviceOrg ---
+func (repo *MySQLOrganizationRepository) SetDeviceOrg(mac string, orgID int) error {
+	_, err := repo.conn.ExecutePreparedQuery(`INSERT INTO devices (mac, org_id) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE org_id = VALUES(org_id)`, mac, orgID)
+	if err != nil {
+		log.Printf("ERROR: [OrgRepo] Error al mover MAC %s a organización %d: %v", mac, orgID, err)
+		return fmt.Errorf("error al mover dispositivo de organización: %w", err)
+	}
+	log.Printf("INFO: [OrgRepo] MAC %s pertenece ahora a la organización %d.", mac, orgID)
+	return nil
+}
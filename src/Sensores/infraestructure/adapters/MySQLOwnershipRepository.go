@@ -0,0 +1,247 @@
+// File: MySQLOwnershipRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type MySQLOwnershipRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLOwnershipRepository(conn *core.Conn_MySQL) *MySQLOwnershipRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLOwnershipRepository recibió una conexión DB nula.")
+	}
+	return &MySQLOwnershipRepository{conn: conn}
+}
+
+const ownershipColumns = "id, mac, user_id, previous_user_id, started_at, ended_at, assigned_by, motivo, ended_by, motivo_fin, historial"
+
+// scanOwnership lee una fila con las columnas de ownershipColumns
+func scanOwnership(scanner interface{ Scan(...interface{}) error }) (*entities.DeviceOwnership, error) {
+	var o entities.DeviceOwnership
+	var previousUserID, assignedBy, endedBy sql.NullInt64
+	var endedAt sql.NullTime
+	err := scanner.Scan(&o.ID, &o.Mac, &o.UserID, &previousUserID, &o.Desde, &endedAt, &assignedBy, &o.Motivo, &endedBy, &o.MotivoFin, &o.Historial)
+	if err != nil {
+		return nil, err
+	}
+	o.PreviousUserID = nullIntPtr(previousUserID)
+	o.AsignadoPor = nullIntPtr(assignedBy)
+	o.FinalizadoPor = nullIntPtr(endedBy)
+	if endedAt.Valid {
+		o.Hasta = &endedAt.Time
+	}
+	return &o, nil
+}
+
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+// closeOpen cierra el periodo abierto de la MAC (si lo hay) dentro de la transacción
+func closeOpen(tx *sql.Tx, mac string, endedBy int, motivo string) error {
+	_, err := tx.Exec("UPDATE device_ownership SET ended_at = NOW(), ended_by = ?, motivo_fin = ? WHERE mac = ? AND ended_at IS NULL",
+		sql.NullInt64{Int64: int64(endedBy), Valid: endedBy > 0}, motivo, mac)
+	if err != nil {
+		return fmt.Errorf("error al cerrar periodo de MAC %s: %w", mac, err)
+	}
+	return nil
+}
+
+// openPeriod abre un periodo nuevo. El dueño anterior es el último que tuvo la MAC
+// (aunque estuviera desasignada); si existe, la decisión sobre su historial queda pendiente.
+// El dispositivo pasa a la organización personal del nuevo dueño; los accesos compartidos de la
+// anterior los revoca el caso de uso, que además corta su feed en vivo.
+func openPeriod(tx *sql.Tx, mac string, userID int, assignedBy int, motivo string) (int64, error) {
+	var previous sql.NullInt64
+	err := tx.QueryRow("SELECT user_id FROM device_ownership WHERE mac = ? ORDER BY started_at DESC, id DESC LIMIT 1", mac).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("error al buscar dueño anterior de MAC %s: %w", mac, err)
+	}
+	historial := entities.HistorialSinHistorial
+	if previous.Valid && int(previous.Int64) != userID {
+		historial = entities.HistorialPendiente
+	} else {
+		previous = sql.NullInt64{}
+	}
+	result, err := tx.Exec(`INSERT INTO device_ownership (mac, user_id, previous_user_id, started_at, assigned_by, motivo, historial)
+		VALUES (?, ?, ?, NOW(), ?, ?, ?)`,
+		mac, userID, previous, sql.NullInt64{Int64: int64(assignedBy), Valid: assignedBy > 0}, motivo, historial)
+	if err != nil {
+		return 0, fmt.Errorf("error al abrir periodo de MAC %s: %w", mac, err)
+	}
+	// El dispositivo (y sus lecturas futuras) pasa a la organización personal del nuevo dueño
+	_, err = tx.Exec(`INSERT INTO devices (mac, org_id) SELECT ?, id FROM organizations WHERE personal_user_id = ?
+		ON DUPLICATE KEY UPDATE org_id = VALUES(org_id)`, mac, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error al mover MAC %s a la organización del nuevo dueño: %w", mac, err)
+	}
+	return result.LastInsertId()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Transfer ---
+func (repo *MySQLOwnershipRepository) Transfer(change domain.OwnershipChange) (*entities.DeviceOwnership, error) {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error al iniciar transacción de propiedad: %w", err)
+	}
+	defer tx.Rollback() // No-op si ya se hizo Commit
+
+	// 1. Bloquear al usuario destino y conocer su MAC actual
+	var currentMac sql.NullString
+	err = tx.QueryRow("SELECT mac_address FROM users WHERE id = ? FOR UPDATE", change.TargetUserID).Scan(&currentMac)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("error al consultar usuario destino: %w", err)
+	}
+	if currentMac.Valid && currentMac.String == change.Mac {
+		log.Printf("INFO: [OwnershipRepo] UserID %d ya es dueño de MAC %s; sin cambios.", change.TargetUserID, change.Mac)
+		return repo.findOpen(tx, change.Mac)
+	}
+
+	// 2. El usuario destino deja su MAC anterior
+	if currentMac.Valid && currentMac.String != "" {
+		if err := closeOpen(tx, currentMac.String, change.AssignedBy, change.Motivo); err != nil {
+			return nil, err
+		}
+	}
+
+	if change.Mac == "" {
+		if _, err := tx.Exec("UPDATE users SET mac_address = NULL WHERE id = ?", change.TargetUserID); err != nil {
+			return nil, fmt.Errorf("error al desasignar MAC: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("error al confirmar desasignación: %w", err)
+		}
+		log.Printf("INFO: [OwnershipRepo] MAC desasignada de UserID %d por UserID %d.", change.TargetUserID, change.AssignedBy)
+		return nil, nil
+	}
+
+	// 3. Quitar la MAC a su dueño actual (si lo tiene) y cerrar su periodo
+	var previousOwner sql.NullInt64
+	err = tx.QueryRow("SELECT id FROM users WHERE mac_address = ? FOR UPDATE", change.Mac).Scan(&previousOwner)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error al consultar dueño actual de MAC %s: %w", change.Mac, err)
+	}
+	if previousOwner.Valid {
+		if _, err := tx.Exec("UPDATE users SET mac_address = NULL WHERE id = ?", previousOwner.Int64); err != nil {
+			return nil, fmt.Errorf("error al quitar MAC al dueño anterior: %w", err)
+		}
+	}
+	if err := closeOpen(tx, change.Mac, change.AssignedBy, change.Motivo); err != nil {
+		return nil, err
+	}
+
+	// 4. Asignar al nuevo dueño y abrir su periodo
+	if _, err := tx.Exec("UPDATE users SET mac_address = ? WHERE id = ?", change.Mac, change.TargetUserID); err != nil {
+		return nil, fmt.Errorf("error al asignar MAC: %w", err)
+	}
+	if _, err := openPeriod(tx, change.Mac, change.TargetUserID, change.AssignedBy, change.Motivo); err != nil {
+		return nil, err
+	}
+	period, err := repo.findOpen(tx, change.Mac)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error al confirmar transferencia: %w", err)
+	}
+	log.Printf("INFO: [OwnershipRepo] MAC %s transferida a UserID %d por UserID %d (dueño anterior: %v). Motivo: %s",
+		change.Mac, change.TargetUserID, change.AssignedBy, previousOwner.Int64, change.Motivo)
+	return period, nil
+}
+
+func (repo *MySQLOwnershipRepository) findOpen(tx *sql.Tx, mac string) (*entities.DeviceOwnership, error) {
+	row := tx.QueryRow("SELECT "+ownershipColumns+" FROM device_ownership WHERE mac = ? AND ended_at IS NULL ORDER BY id DESC LIMIT 1", mac)
+	period, err := scanOwnership(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer periodo abierto de MAC %s: %w", mac, err)
+	}
+	return period, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO OpenRegistration ---
+func (repo *MySQLOwnershipRepository) OpenRegistration(userID int, mac string) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de propiedad: %w", err)
+	}
+	defer tx.Rollback()
+	if err := closeOpen(tx, mac, 0, "registro de otro usuario"); err != nil {
+		return err
+	}
+	if _, err := openPeriod(tx, mac, userID, 0, "registro"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLOwnershipRepository) FindByMac(mac string) ([]entities.DeviceOwnership, error) {
+	rows, err := repo.conn.FetchRows("SELECT "+ownershipColumns+" FROM device_ownership WHERE mac = ? ORDER BY started_at DESC, id DESC", mac)
+	if err != nil {
+		log.Printf("ERROR: [OwnershipRepo] Error al consultar historial de MAC %s: %v", mac, err)
+		return nil, fmt.Errorf("error al consultar historial de propiedad: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []entities.DeviceOwnership{}
+	for rows.Next() {
+		period, err := scanOwnership(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar fila de historial: %w", err)
+		}
+		periods = append(periods, *period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer historial: %w", err)
+	}
+	return periods, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO DecideHistory ---
+func (repo *MySQLOwnershipRepository) DecideHistory(userID int, mac string, decision string) (*entities.DeviceOwnership, error) {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error al iniciar transacción de propiedad: %w", err)
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow("SELECT "+ownershipColumns+" FROM device_ownership WHERE mac = ? AND ended_at IS NULL ORDER BY id DESC LIMIT 1 FOR UPDATE", mac)
+	period, err := scanOwnership(row)
+	if err == sql.ErrNoRows || (err == nil && period.UserID != userID) {
+		return nil, fmt.Errorf("dispositivo_no_encontrado")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer periodo abierto de MAC %s: %w", mac, err)
+	}
+	if period.Historial != entities.HistorialPendiente {
+		return nil, fmt.Errorf("decision_no_pendiente")
+	}
+	if _, err := tx.Exec("UPDATE device_ownership SET historial = ?, historial_decided_at = NOW() WHERE id = ?", decision, period.ID); err != nil {
+		return nil, fmt.Errorf("error al guardar decisión de historial: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error al confirmar decisión de historial: %w", err)
+	}
+	period.Historial = decision
+	log.Printf("INFO: [OwnershipRepo] UserID %d decidió '%s' sobre el historial de MAC %s.", userID, decision, mac)
+	return period, nil
+}
@@ -0,0 +1,205 @@
+// File: MySQLProductRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLProductRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLProductRepository(conn *core.Conn_MySQL) *MySQLProductRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLProductRepository recibió una conexión DB nula.")
+	}
+	return &MySQLProductRepository{conn: conn}
+}
+
+// productSelect lee los productos junto con el último peso estable de su balanza
+const productSelect = `SELECT p.id, p.mac, p.nombre, p.peso_unitario, p.tara, p.umbral_minimo, p.created_at, p.updated_at,
+	ws.peso_estable, ws.ultima_muestra
+	FROM products p LEFT JOIN weight_state ws ON ws.mac = p.mac`
+
+func scanProduct(scanner interface{ Scan(...interface{}) error }) (*entities.Product, error) {
+	var product entities.Product
+	var umbral sql.NullInt64
+	var peso sql.NullFloat64
+	var muestra sql.NullTime
+	if err := scanner.Scan(&product.ID, &product.Mac, &product.Nombre, &product.PesoUnitario, &product.Tara, &umbral,
+		&product.Creado, &product.Actualizado, &peso, &muestra); err != nil {
+		return nil, err
+	}
+	product.UmbralMinimo = nullIntPtr(umbral)
+	if peso.Valid {
+		product.Stock = product.StockFor(peso.Float64, muestra.Time)
+	}
+	return &product, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Create ---
+func (repo *MySQLProductRepository) Create(product *entities.Product) error {
+	result, err := repo.conn.ExecutePreparedQuery("INSERT INTO products (mac, nombre, peso_unitario, tara, umbral_minimo) VALUES (?, ?, ?, ?, ?)",
+		product.Mac, product.Nombre, product.PesoUnitario, product.Tara, product.UmbralMinimo)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return fmt.Errorf("producto_duplicado")
+		}
+		return fmt.Errorf("error al crear producto: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al leer el id del producto: %w", err)
+	}
+	product.ID = int(id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Update ---
+func (repo *MySQLProductRepository) Update(product *entities.Product) error {
+	_, err := repo.conn.ExecutePreparedQuery("UPDATE products SET nombre = ?, peso_unitario = ?, tara = ?, umbral_minimo = ? WHERE id = ?",
+		product.Nombre, product.PesoUnitario, product.Tara, product.UmbralMinimo, product.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar producto %d: %w", product.ID, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Delete ---
+func (repo *MySQLProductRepository) Delete(id int) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM inventory_movements WHERE product_id = ?", id); err != nil {
+		return fmt.Errorf("error al borrar movimientos del producto %d: %w", id, err)
+	}
+	if _, err := tx.Exec("DELETE FROM products WHERE id = ?", id); err != nil {
+		return fmt.Errorf("error al borrar producto %d: %w", id, err)
+	}
+	return tx.Commit()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByID ---
+func (repo *MySQLProductRepository) FindByID(id int) (*entities.Product, error) {
+	product, err := scanProduct(repo.conn.DB.QueryRow(productSelect+" WHERE p.id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error al leer producto %d: %w", id, err)
+	}
+	return product, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLProductRepository) FindByMac(mac string) (*entities.Product, error) {
+	product, err := scanProduct(repo.conn.DB.QueryRow(productSelect+" WHERE p.mac = ?", mac))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el producto de %s: %w", mac, err)
+	}
+	return product, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMacs ---
+func (repo *MySQLProductRepository) FindByMacs(macs []string) ([]entities.Product, error) {
+	products := []entities.Product{}
+	if len(macs) == 0 {
+		return products, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(macs)), ",")
+	args := make([]interface{}, len(macs))
+	for i, mac := range macs {
+		args[i] = mac
+	}
+	rows, err := repo.conn.FetchRows(productSelect+" WHERE p.mac IN ("+placeholders+") ORDER BY p.nombre, p.id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al listar productos: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar producto: %w", err)
+		}
+		products = append(products, *product)
+	}
+	return products, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CreateMovement ---
+func (repo *MySQLProductRepository) CreateMovement(movement *entities.InventoryMovement) error {
+	result, err := repo.conn.ExecutePreparedQuery(`INSERT INTO inventory_movements (product_id, weight_event_id, tipo, unidades, unidades_resultantes, fecha)
+		VALUES (?, NULLIF(?, 0), ?, ?, ?, ?)`,
+		movement.ProductID, movement.WeightEventID, movement.Tipo, movement.Unidades, movement.UnidadesResultantes, movement.Fecha)
+	if err != nil {
+		return fmt.Errorf("error al registrar movimiento de inventario: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al leer el id del movimiento: %w", err)
+	}
+	movement.ID = int(id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindMovements ---
+func (repo *MySQLProductRepository) FindMovements(productID int, desde time.Time, hasta time.Time, limit int) ([]entities.InventoryMovement, error) {
+	rows, err := repo.conn.FetchRows(`SELECT id, product_id, weight_event_id, tipo, unidades, unidades_resultantes, fecha
+		FROM inventory_movements WHERE product_id = ? AND fecha >= ? AND fecha < ? ORDER BY fecha DESC, id DESC LIMIT ?`,
+		productID, desde, hasta, limit)
+	if err != nil {
+		log.Printf("ERROR: [ProductRepo] Error al listar movimientos del producto %d: %v", productID, err)
+		return nil, fmt.Errorf("error al consultar movimientos de inventario: %w", err)
+	}
+	defer rows.Close()
+	movements := []entities.InventoryMovement{}
+	for rows.Next() {
+		var movement entities.InventoryMovement
+		var eventID sql.NullInt64
+		if err := rows.Scan(&movement.ID, &movement.ProductID, &eventID, &movement.Tipo, &movement.Unidades,
+			&movement.UnidadesResultantes, &movement.Fecha); err != nil {
+			return nil, fmt.Errorf("error al procesar movimiento de inventario: %w", err)
+		}
+		movement.WeightEventID = int(eventID.Int64)
+		movements = append(movements, movement)
+	}
+	return movements, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO DailyConsumption ---
+func (repo *MySQLProductRepository) DailyConsumption(productID int, desde time.Time, hasta time.Time) ([]entities.DailyConsumption, error) {
+	rows, err := repo.conn.FetchRows(`SELECT DATE_FORMAT(fecha, '%Y-%m-%d') AS dia,
+		COALESCE(SUM(CASE WHEN tipo = ? THEN unidades END), 0), COALESCE(SUM(CASE WHEN tipo = ? THEN unidades END), 0), COUNT(*)
+		FROM inventory_movements WHERE product_id = ? AND fecha >= ? AND fecha < ? GROUP BY dia ORDER BY dia`,
+		entities.InventoryConsumo, entities.InventoryReposicion, productID, desde, hasta)
+	if err != nil {
+		log.Printf("ERROR: [ProductRepo] Error al resumir el consumo del producto %d: %v", productID, err)
+		return nil, fmt.Errorf("error al resumir consumo: %w", err)
+	}
+	defer rows.Close()
+	days := []entities.DailyConsumption{}
+	for rows.Next() {
+		var day entities.DailyConsumption
+		if err := rows.Scan(&day.Dia, &day.Consumido, &day.Repuesto, &day.Movimientos); err != nil {
+			return nil, fmt.Errorf("error al procesar consumo diario: %w", err)
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
@@ -0,0 +1,262 @@
+// File: MySQLRollupRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rollupHourExpr es la hora (local del servidor) de una lectura de 'rutas'
+const rollupHourExpr = "DATE_FORMAT(created_at, '%Y-%m-%d %H:00:00')"
+
+type MySQLRollupRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLRollupRepository(conn *core.Conn_MySQL) *MySQLRollupRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLRollupRepository recibió una conexión DB nula.")
+	}
+	return &MySQLRollupRepository{conn: conn}
+}
+
+func (repo *MySQLRollupRepository) getEstado(clave string) (string, error) {
+	var valor string
+	err := repo.conn.DB.QueryRow("SELECT valor FROM rollup_estado WHERE clave = ?", clave).Scan(&valor)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return valor, err
+}
+
+func (repo *MySQLRollupRepository) setEstado(clave string, valor string) error {
+	_, err := repo.conn.ExecutePreparedQuery("REPLACE INTO rollup_estado (clave, valor) VALUES (?, ?)", clave, valor)
+	return err
+}
+
+// --- IMPLEMENTACIÓN MÉTODO MarkPending ---
+func (repo *MySQLRollupRepository) MarkPending(limit int) (int, error) {
+	raw, err := repo.getEstado("ultimo_id")
+	if err != nil {
+		return 0, fmt.Errorf("error al leer progreso de resúmenes: %w", err)
+	}
+	ultimo, _ := strconv.ParseInt(raw, 10, 64)
+	var hasta sql.NullInt64
+	var count int
+	err = repo.conn.DB.QueryRow("SELECT MAX(id), COUNT(*) FROM (SELECT id FROM rutas WHERE id > ? ORDER BY id LIMIT ?) nuevas", ultimo, limit).Scan(&hasta, &count)
+	if err != nil {
+		return 0, fmt.Errorf("error al buscar lecturas nuevas: %w", err)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	_, err = repo.conn.ExecutePreparedQuery("INSERT IGNORE INTO rollup_pendientes (mac, hora) SELECT DISTINCT mac, "+rollupHourExpr+" FROM rutas WHERE id > ? AND id <= ?", ultimo, hasta.Int64)
+	if err != nil {
+		return 0, fmt.Errorf("error al encolar horas para resumir: %w", err)
+	}
+	if err := repo.setEstado("ultimo_id", strconv.FormatInt(hasta.Int64, 10)); err != nil {
+		return 0, fmt.Errorf("error al guardar progreso de resúmenes: %w", err)
+	}
+	return count, nil
+}
+
+type pendingHour struct {
+	mac  string
+	hora time.Time
+}
+
+// --- IMPLEMENTACIÓN MÉTODO ProcessPending ---
+func (repo *MySQLRollupRepository) ProcessPending(limit int) (int, error) {
+	rows, err := repo.conn.FetchRows("SELECT mac, hora FROM rollup_pendientes ORDER BY hora LIMIT ?", limit)
+	if err != nil {
+		return 0, fmt.Errorf("error al leer horas pendientes: %w", err)
+	}
+	pending := []pendingHour{}
+	for rows.Next() {
+		var p pendingHour
+		if err := rows.Scan(&p.mac, &p.hora); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error al procesar hora pendiente: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	days := []pendingHour{}
+	seenDays := make(map[string]bool)
+	for _, p := range pending {
+		// Se desencola antes de recalcular: una lectura que llegue durante el cálculo vuelve a encolarla
+		if _, err := repo.conn.ExecutePreparedQuery("DELETE FROM rollup_pendientes WHERE mac = ? AND hora = ?", p.mac, p.hora); err != nil {
+			return 0, fmt.Errorf("error al desencolar hora: %w", err)
+		}
+		if err := repo.rebuild(domain.RollupHora, p.mac, p.hora); err != nil {
+			repo.conn.ExecutePreparedQuery("INSERT IGNORE INTO rollup_pendientes (mac, hora) VALUES (?, ?)", p.mac, p.hora)
+			return 0, err
+		}
+		day := pendingHour{mac: p.mac, hora: time.Date(p.hora.Year(), p.hora.Month(), p.hora.Day(), 0, 0, 0, 0, time.Local)}
+		if key := day.mac + day.hora.Format("2006-01-02"); !seenDays[key] {
+			seenDays[key] = true
+			days = append(days, day)
+		}
+	}
+	for _, d := range days {
+		if err := repo.rebuild(domain.RollupDia, d.mac, d.hora); err != nil {
+			return 0, err
+		}
+	}
+	return len(pending), nil
+}
+
+// rebuild reemplaza en una transacción los resúmenes de un dispositivo en una hora (desde 'rutas')
+// o en un día (desde los resúmenes por hora)
+func (repo *MySQLRollupRepository) rebuild(resolucion string, mac string, inicio time.Time) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de resúmenes: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM datos_rollups WHERE resolucion = ? AND mac = ? AND inicio = ?", resolucion, mac, inicio); err != nil {
+		return fmt.Errorf("error al borrar resúmenes de %s: %w", mac, err)
+	}
+	if resolucion == domain.RollupHora {
+		// Una fila por métrica aunque no traiga valores, para conservar el total de lecturas
+		for _, metrica := range []string{"temperatura", "distancia", "peso", "movimiento"} {
+			expr, _ := aggregateMetricExpr(metrica)
+			query := fmt.Sprintf(`INSERT INTO datos_rollups (resolucion, inicio, mac, org_id, user_id, metrica, total, lecturas, suma, minimo, maximo)
+				SELECT ?, ?, rutas.mac, rutas.org_id, rutas.user_id, ?, COUNT(*), COUNT(%[1]s), SUM(%[1]s), MIN(%[1]s), MAX(%[1]s)
+				FROM rutas WHERE rutas.mac = ? AND rutas.created_at >= ? AND rutas.created_at < DATE_ADD(?, INTERVAL 1 HOUR)
+				GROUP BY rutas.mac, rutas.org_id, rutas.user_id`, expr)
+			if _, err := tx.Exec(query, resolucion, inicio, metrica, mac, inicio, inicio); err != nil {
+				return fmt.Errorf("error al resumir %s de %s: %w", metrica, mac, err)
+			}
+		}
+	} else {
+		query := `INSERT INTO datos_rollups (resolucion, inicio, mac, org_id, user_id, metrica, total, lecturas, suma, minimo, maximo)
+			SELECT ?, ?, mac, org_id, user_id, metrica, SUM(total), SUM(lecturas), SUM(suma), MIN(minimo), MAX(maximo)
+			FROM datos_rollups WHERE resolucion = ? AND mac = ? AND inicio >= ? AND inicio < DATE_ADD(?, INTERVAL 1 DAY)
+			GROUP BY mac, org_id, user_id, metrica`
+		if _, err := tx.Exec(query, resolucion, inicio, domain.RollupHora, mac, inicio, inicio); err != nil {
+			return fmt.Errorf("error al resumir el día de %s: %w", mac, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Coverage ---
+func (repo *MySQLRollupRepository) Coverage() (time.Time, error) {
+	raw, err := repo.getEstado("cubierto_hasta")
+	if err != nil || raw == "" {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SetCoverage ---
+func (repo *MySQLRollupRepository) SetCoverage(hasta time.Time) error {
+	return repo.setEstado("cubierto_hasta", hasta.Format(time.RFC3339))
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Aggregate ---
+func (repo *MySQLRollupRepository) Aggregate(userID int, filter domain.DatosFilter, query domain.DatosAggregateQuery, resolucion string) ([]entities.DeviceSeries, error) {
+	width := int64(query.Bucket / time.Second)
+	keyExpr, args := bucketKeyExpr("datos_rollups.inicio", wallShifts(filter.Desde, filter.Hasta, query.Zona), width)
+	unit := "HOUR"
+	if resolucion == domain.RollupDia {
+		unit = "DAY"
+	}
+	// Las lecturas transferidas del dueño anterior solo cuentan en las horas (o días) que terminan
+	// antes del periodo del nuevo dueño
+	scopeClause, scopeArgs := visibleOn("datos_rollups", "DATE_ADD(datos_rollups.inicio, INTERVAL 1 "+unit+") <= o.started_at", userID)
+	filterClause, filterArgs := buildDeviceFilter("datos_rollups", filter)
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(query.Metricas)), ",")
+	args = append(args, resolucion, filter.Desde, filter.Hasta)
+	for _, m := range query.Metricas {
+		args = append(args, m)
+	}
+	args = append(append(args, scopeArgs...), filterArgs...)
+
+	sqlQuery := "SELECT " + keyExpr + ` AS k, datos_rollups.mac, datos_rollups.metrica, SUM(total), SUM(lecturas), SUM(suma), MIN(minimo), MAX(maximo)
+		FROM datos_rollups WHERE datos_rollups.resolucion = ? AND datos_rollups.inicio >= ? AND datos_rollups.inicio < ?
+		AND datos_rollups.metrica IN (` + placeholders + ") AND " + scopeClause + filterClause +
+		" GROUP BY k, datos_rollups.mac, datos_rollups.metrica ORDER BY datos_rollups.mac, k"
+	rows, err := repo.conn.FetchRows(sqlQuery, args...)
+	if err != nil {
+		log.Printf("ERROR: [RollupRepo] Error al agregar resúmenes por %s para UserID %d: %v", resolucion, userID, err)
+		return nil, fmt.Errorf("error al agregar resúmenes de MySQL: %w", err)
+	}
+	defer rows.Close()
+
+	series := []entities.DeviceSeries{}
+	var lastKey int64
+	for rows.Next() {
+		var key, total, lecturas int64
+		var mac, metrica string
+		var suma, minimo, maximo sql.NullFloat64
+		if err := rows.Scan(&key, &mac, &metrica, &total, &lecturas, &suma, &minimo, &maximo); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de resúmenes: %w", err)
+		}
+		if len(series) == 0 || series[len(series)-1].Mac != mac {
+			series = append(series, entities.DeviceSeries{Mac: mac, Buckets: []entities.AggregateBucket{}})
+		}
+		current := &series[len(series)-1]
+		if len(current.Buckets) == 0 || lastKey != key {
+			bucket := entities.AggregateBucket{Inicio: bucketStart(key, width, query.Zona), Lecturas: total, Metricas: make(map[string]entities.MetricAggregate)}
+			for _, m := range query.Metricas {
+				bucket.Metricas[m] = entities.MetricAggregate{}
+			}
+			current.Buckets = append(current.Buckets, bucket)
+			lastKey = key
+		}
+		metric := entities.MetricAggregate{Lecturas: lecturas, MetricStats: entities.MetricStats{Min: nullFloatPtr(minimo), Max: nullFloatPtr(maximo)}}
+		if lecturas > 0 && suma.Valid {
+			promedio := suma.Float64 / float64(lecturas)
+			metric.Promedio = &promedio
+		}
+		current.Buckets[len(current.Buckets)-1].Metricas[metrica] = metric
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer resúmenes: %w", err)
+	}
+	log.Printf("INFO: [RollupRepo] Agregación de %d dispositivos desde resúmenes por %s para UserID %d.", len(series), resolucion, userID)
+	return series, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO StreamHourly ---
+func (repo *MySQLRollupRepository) StreamHourly(userID int, filter domain.DatosFilter, fn func(mac string, hora time.Time, lecturas int64) error) error {
+	scopeClause, scopeArgs := visibleOn("datos_rollups", "DATE_ADD(datos_rollups.inicio, INTERVAL 1 HOUR) <= o.started_at", userID)
+	filterClause, filterArgs := buildDeviceFilter("datos_rollups", filter)
+	// Cada hora tiene una fila por métrica con el mismo total: basta con una de ellas
+	args := append([]interface{}{domain.RollupHora, filter.Desde, filter.Hasta}, scopeArgs...)
+	args = append(args, filterArgs...)
+	rows, err := repo.conn.FetchRows(`SELECT datos_rollups.mac, datos_rollups.inicio, SUM(datos_rollups.total) FROM datos_rollups
+		WHERE datos_rollups.resolucion = ? AND datos_rollups.inicio >= ? AND datos_rollups.inicio < ?
+		AND datos_rollups.metrica = 'temperatura' AND `+scopeClause+filterClause+`
+		GROUP BY datos_rollups.mac, datos_rollups.inicio ORDER BY datos_rollups.mac, datos_rollups.inicio`, args...)
+	if err != nil {
+		return fmt.Errorf("error al leer lecturas por hora de los resúmenes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var mac string
+		var hora time.Time
+		var lecturas int64
+		if err := rows.Scan(&mac, &hora, &lecturas); err != nil {
+			return fmt.Errorf("error al procesar hora de los resúmenes: %w", err)
+		}
+		if err := fn(mac, hora, lecturas); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,234 @@
+// File: MySQLScheduledReportRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type MySQLScheduledReportRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLScheduledReportRepository(conn *core.Conn_MySQL) *MySQLScheduledReportRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLScheduledReportRepository recibió una conexión DB nula.")
+	}
+	return &MySQLScheduledReportRepository{conn: conn}
+}
+
+const subscriptionColumns = `id, user_id, frecuencia, zona, hora, email, email_confirmado, activa, proximo_envio, ultimo_envio, created_at,
+	token_confirmacion, confirmacion_enviada`
+
+func scanSubscription(scanner interface{ Scan(...interface{}) error }) (*entities.ReportSubscription, error) {
+	var sub entities.ReportSubscription
+	var ultimo, confirmacion sql.NullTime
+	var token sql.NullString
+	if err := scanner.Scan(&sub.ID, &sub.UserID, &sub.Frecuencia, &sub.Zona, &sub.Hora, &sub.Email, &sub.EmailConfirmado, &sub.Activa,
+		&sub.ProximoEnvio, &ultimo, &sub.Creado, &token, &confirmacion); err != nil {
+		return nil, err
+	}
+	sub.UltimoEnvio = nullTimePtr(ultimo)
+	sub.Token = token.String
+	sub.ConfirmacionEnviada = nullTimePtr(confirmacion)
+	return &sub, nil
+}
+
+func (repo *MySQLScheduledReportRepository) findSubscriptions(where string, args ...interface{}) ([]entities.ReportSubscription, error) {
+	rows, err := repo.conn.FetchRows("SELECT "+subscriptionColumns+" FROM report_subscriptions WHERE "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar suscripciones a reportes: %w", err)
+	}
+	defer rows.Close()
+	subs := []entities.ReportSubscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar suscripción a reportes: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CreateSubscription ---
+func (repo *MySQLScheduledReportRepository) CreateSubscription(sub *entities.ReportSubscription) error {
+	result, err := repo.conn.ExecutePreparedQuery(`INSERT INTO report_subscriptions (user_id, frecuencia, zona, hora, email, email_confirmado, activa,
+		proximo_envio, token_confirmacion, confirmacion_enviada) VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)`,
+		sub.UserID, sub.Frecuencia, sub.Zona, sub.Hora, sub.Email, sub.EmailConfirmado, sub.Activa, sub.ProximoEnvio, sub.Token, sub.ConfirmacionEnviada)
+	if err != nil {
+		return fmt.Errorf("error al crear suscripción a reportes: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al leer el id de la suscripción: %w", err)
+	}
+	sub.ID = int(id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO UpdateSubscription ---
+func (repo *MySQLScheduledReportRepository) UpdateSubscription(sub *entities.ReportSubscription) error {
+	_, err := repo.conn.ExecutePreparedQuery(`UPDATE report_subscriptions SET frecuencia = ?, zona = ?, hora = ?, email = ?, email_confirmado = ?,
+		activa = ?, proximo_envio = ?, token_confirmacion = NULLIF(?, ''), confirmacion_enviada = ? WHERE id = ?`,
+		sub.Frecuencia, sub.Zona, sub.Hora, sub.Email, sub.EmailConfirmado, sub.Activa, sub.ProximoEnvio, sub.Token, sub.ConfirmacionEnviada, sub.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar suscripción %d: %w", sub.ID, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO DeleteSubscription ---
+func (repo *MySQLScheduledReportRepository) DeleteSubscription(id int) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("UPDATE scheduled_reports SET subscription_id = NULL WHERE subscription_id = ?", id); err != nil {
+		return fmt.Errorf("error al desvincular reportes de la suscripción %d: %w", id, err)
+	}
+	if _, err := tx.Exec("DELETE FROM report_subscriptions WHERE id = ?", id); err != nil {
+		return fmt.Errorf("error al borrar suscripción %d: %w", id, err)
+	}
+	return tx.Commit()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindSubscription ---
+func (repo *MySQLScheduledReportRepository) FindSubscription(id int) (*entities.ReportSubscription, error) {
+	sub, err := scanSubscription(repo.conn.DB.QueryRow("SELECT "+subscriptionColumns+" FROM report_subscriptions WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error al leer suscripción %d: %w", id, err)
+	}
+	return sub, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindSubscriptions ---
+func (repo *MySQLScheduledReportRepository) FindSubscriptions(userID int) ([]entities.ReportSubscription, error) {
+	return repo.findSubscriptions("user_id = ?", userID)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindDue ---
+func (repo *MySQLScheduledReportRepository) FindDue(now time.Time) ([]entities.ReportSubscription, error) {
+	return repo.findSubscriptions("activa = TRUE AND email_confirmado = TRUE AND proximo_envio <= ?", now)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO ConfirmEmail ---
+func (repo *MySQLScheduledReportRepository) ConfirmEmail(token string) (bool, error) {
+	result, err := repo.conn.ExecutePreparedQuery("UPDATE report_subscriptions SET email_confirmado = TRUE, token_confirmacion = NULL WHERE token_confirmacion = ?", token)
+	if err != nil {
+		return false, fmt.Errorf("error al confirmar email de suscripción: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error al confirmar email de suscripción: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO MarkRun ---
+func (repo *MySQLScheduledReportRepository) MarkRun(id int, proximo time.Time, ultimo time.Time) error {
+	if _, err := repo.conn.ExecutePreparedQuery("UPDATE report_subscriptions SET proximo_envio = ?, ultimo_envio = ? WHERE id = ?", proximo, ultimo, id); err != nil {
+		return fmt.Errorf("error al reprogramar suscripción %d: %w", id, err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CreateReport ---
+func (repo *MySQLScheduledReportRepository) CreateReport(report *entities.ScheduledReport) error {
+	result, err := repo.conn.ExecutePreparedQuery(`INSERT INTO scheduled_reports (subscription_id, user_id, frecuencia, zona, desde, hasta, email, asunto,
+		dispositivos, alertas, huecos, html, generado) VALUES (NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		report.SubscriptionID, report.UserID, report.Frecuencia, report.Zona, report.Desde, report.Hasta, report.Email, report.Asunto,
+		report.Dispositivos, report.Alertas, report.Huecos, report.HTML, report.Generado)
+	if err != nil {
+		return fmt.Errorf("error al guardar reporte programado: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error al leer el id del reporte: %w", err)
+	}
+	report.ID = int(id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO MarkSent ---
+func (repo *MySQLScheduledReportRepository) MarkSent(id int, enviado *time.Time, errorEnvio string) error {
+	if len(errorEnvio) > 255 {
+		errorEnvio = errorEnvio[:255]
+	}
+	_, err := repo.conn.ExecutePreparedQuery("UPDATE scheduled_reports SET enviado = ?, error_envio = NULLIF(?, '') WHERE id = ?", enviado, errorEnvio, id)
+	if err != nil {
+		return fmt.Errorf("error al registrar el envío del reporte %d: %w", id, err)
+	}
+	return nil
+}
+
+const scheduledReportColumns = `id, subscription_id, user_id, frecuencia, zona, desde, hasta, email, asunto, dispositivos, alertas, huecos,
+	generado, enviado, error_envio`
+
+func scanScheduledReport(scanner interface{ Scan(...interface{}) error }, extra ...interface{}) (*entities.ScheduledReport, error) {
+	var report entities.ScheduledReport
+	var subID sql.NullInt64
+	var enviado sql.NullTime
+	var errorEnvio sql.NullString
+	dest := []interface{}{&report.ID, &subID, &report.UserID, &report.Frecuencia, &report.Zona, &report.Desde, &report.Hasta, &report.Email,
+		&report.Asunto, &report.Dispositivos, &report.Alertas, &report.Huecos, &report.Generado, &enviado, &errorEnvio}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	report.SubscriptionID = int(subID.Int64)
+	report.Enviado = nullTimePtr(enviado)
+	report.ErrorEnvio = errorEnvio.String
+	return &report, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindReport ---
+func (repo *MySQLScheduledReportRepository) FindReport(id int) (*entities.ScheduledReport, error) {
+	var html string
+	report, err := scanScheduledReport(repo.conn.DB.QueryRow("SELECT "+scheduledReportColumns+", html FROM scheduled_reports WHERE id = ?", id), &html)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error al leer reporte programado %d: %w", id, err)
+	}
+	report.HTML = html
+	return report, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindReports ---
+func (repo *MySQLScheduledReportRepository) FindReports(userID int, limit int) ([]entities.ScheduledReport, error) {
+	rows, err := repo.conn.FetchRows("SELECT "+scheduledReportColumns+" FROM scheduled_reports WHERE user_id = ? ORDER BY generado DESC, id DESC LIMIT ?", userID, limit)
+	if err != nil {
+		log.Printf("ERROR: [ScheduledReportRepo] Error al listar reportes de UserID %d: %v", userID, err)
+		return nil, fmt.Errorf("error al consultar reportes programados: %w", err)
+	}
+	defer rows.Close()
+	reports := []entities.ScheduledReport{}
+	for rows.Next() {
+		report, err := scanScheduledReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar reporte programado: %w", err)
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CountReportsSince ---
+func (repo *MySQLScheduledReportRepository) CountReportsSince(subscriptionID int, since time.Time) (int, error) {
+	var count int
+	err := repo.conn.DB.QueryRow("SELECT COUNT(*) FROM scheduled_reports WHERE subscription_id = ? AND generado >= ?", subscriptionID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error al contar reportes de la suscripción %d: %w", subscriptionID, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,212 @@
+// File: MySQLShareRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type MySQLShareRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLShareRepository(conn *core.Conn_MySQL) *MySQLShareRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLShareRepository recibió una conexión DB nula.")
+	}
+	return &MySQLShareRepository{conn: conn}
+}
+
+// activeShare es la condición SQL de un acceso vigente sobre 'device_shares s'
+const activeShare = "s.revoked_at IS NULL AND (s.expires_at IS NULL OR s.expires_at > NOW())"
+
+const shareColumns = `s.id, s.mac, s.org_id, s.granted_by, s.grantee_user_id, COALESCE(u.username, ''), COALESCE(s.invite_email, ''),
+	COALESCE(s.invite_token, ''), s.permiso, s.created_at, s.expires_at, s.accepted_at, s.revoked_at`
+
+const shareFrom = " FROM device_shares s LEFT JOIN users u ON u.id = s.grantee_user_id"
+
+func scanShare(scanner interface{ Scan(...interface{}) error }) (*entities.DeviceShare, error) {
+	var share entities.DeviceShare
+	var grantee sql.NullInt64
+	var expires, accepted, revoked sql.NullTime
+	err := scanner.Scan(&share.ID, &share.Mac, &share.OrgID, &share.OtorgadoPor, &grantee, &share.Username, &share.Email,
+		&share.Token, &share.Permiso, &share.Creado, &expires, &accepted, &revoked)
+	if err != nil {
+		return nil, err
+	}
+	share.UserID = nullIntPtr(grantee)
+	share.Expira = nullTimePtr(expires)
+	share.Aceptado = nullTimePtr(accepted)
+	share.Revocado = nullTimePtr(revoked)
+	return &share, nil
+}
+
+func nullTimePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
+
+func (repo *MySQLShareRepository) findMany(where string, args ...interface{}) ([]entities.DeviceShare, error) {
+	rows, err := repo.conn.FetchRows("SELECT "+shareColumns+shareFrom+" WHERE "+where+" ORDER BY s.created_at DESC, s.id DESC", args...)
+	if err != nil {
+		log.Printf("ERROR: [ShareRepo] Error al consultar accesos compartidos: %v", err)
+		return nil, fmt.Errorf("error al consultar accesos compartidos: %w", err)
+	}
+	defer rows.Close()
+	shares := []entities.DeviceShare{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al procesar fila de acceso compartido: %w", err)
+		}
+		shares = append(shares, *share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer accesos compartidos: %w", err)
+	}
+	return shares, nil
+}
+
+func (repo *MySQLShareRepository) findOne(where string, args ...interface{}) (*entities.DeviceShare, error) {
+	share, err := scanShare(repo.conn.DB.QueryRow("SELECT "+shareColumns+shareFrom+" WHERE "+where, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("error al consultar acceso compartido: %w", err)
+	}
+	return share, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Create ---
+func (repo *MySQLShareRepository) Create(share *entities.DeviceShare) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de acceso compartido: %w", err)
+	}
+	defer tx.Rollback()
+
+	var email, token sql.NullString
+	var granteeID sql.NullInt64
+	if share.UserID != nil {
+		granteeID = sql.NullInt64{Int64: int64(*share.UserID), Valid: true}
+		// Un nuevo acceso reemplaza al vigente del mismo usuario
+		_, err = tx.Exec("UPDATE device_shares s SET s.revoked_at = NOW() WHERE s.mac = ? AND s.grantee_user_id = ? AND s.revoked_at IS NULL",
+			share.Mac, *share.UserID)
+	} else {
+		email = sql.NullString{String: share.Email, Valid: true}
+		_, err = tx.Exec("UPDATE device_shares s SET s.revoked_at = NOW() WHERE s.mac = ? AND s.invite_email = ? AND s.grantee_user_id IS NULL AND s.revoked_at IS NULL",
+			share.Mac, share.Email)
+	}
+	if err != nil {
+		return fmt.Errorf("error al reemplazar acceso previo: %w", err)
+	}
+	if share.Token != "" {
+		token = sql.NullString{String: share.Token, Valid: true}
+	}
+	var expires sql.NullTime
+	if share.Expira != nil {
+		expires = sql.NullTime{Time: *share.Expira, Valid: true}
+	}
+	var accepted sql.NullTime
+	if share.UserID != nil {
+		accepted = sql.NullTime{Time: share.Creado, Valid: true}
+	}
+	result, err := tx.Exec(`INSERT INTO device_shares (mac, org_id, granted_by, grantee_user_id, invite_email, invite_token, permiso, created_at, expires_at, accepted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		share.Mac, share.OrgID, share.OtorgadoPor, granteeID, email, token, share.Permiso, share.Creado, expires, accepted)
+	if err != nil {
+		log.Printf("ERROR: [ShareRepo] Error al compartir MAC %s: %v", share.Mac, err)
+		return fmt.Errorf("error al guardar acceso compartido: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar acceso compartido: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	share.ID = int(id)
+	share.Aceptado = nullTimePtr(accepted)
+	log.Printf("INFO: [ShareRepo] MAC %s compartida (acceso %d, permiso '%s') por UserID %d.", share.Mac, share.ID, share.Permiso, share.OtorgadoPor)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByID ---
+func (repo *MySQLShareRepository) FindByID(id int) (*entities.DeviceShare, error) {
+	return repo.findOne("s.id = ?", id)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByToken ---
+func (repo *MySQLShareRepository) FindByToken(token string) (*entities.DeviceShare, error) {
+	return repo.findOne("s.invite_token = ?", token)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLShareRepository) FindByMac(mac string) ([]entities.DeviceShare, error) {
+	return repo.findMany("s.mac = ? AND "+activeShare, mac)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindForGrantee ---
+func (repo *MySQLShareRepository) FindForGrantee(userID int) ([]entities.DeviceShare, error) {
+	return repo.findMany("s.grantee_user_id = ? AND "+activeShare, userID)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Accept ---
+func (repo *MySQLShareRepository) Accept(id int, userID int) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de invitación: %w", err)
+	}
+	defer tx.Rollback()
+	var mac string
+	if err := tx.QueryRow("SELECT mac FROM device_shares WHERE id = ? FOR UPDATE", id).Scan(&mac); err != nil {
+		return fmt.Errorf("error al leer invitación: %w", err)
+	}
+	// Aceptar reemplaza cualquier acceso vigente que el usuario ya tuviera al dispositivo
+	if _, err := tx.Exec("UPDATE device_shares SET revoked_at = NOW() WHERE mac = ? AND grantee_user_id = ? AND revoked_at IS NULL", mac, userID); err != nil {
+		return fmt.Errorf("error al reemplazar acceso previo: %w", err)
+	}
+	// El token es de un solo uso
+	if _, err := tx.Exec("UPDATE device_shares SET grantee_user_id = ?, accepted_at = NOW(), invite_token = NULL WHERE id = ?", userID, id); err != nil {
+		return fmt.Errorf("error al aceptar invitación: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar invitación: %w", err)
+	}
+	log.Printf("INFO: [ShareRepo] UserID %d aceptó la invitación %d (MAC %s).", userID, id, mac)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Revoke ---
+func (repo *MySQLShareRepository) Revoke(id int) error {
+	_, err := repo.conn.ExecutePreparedQuery("UPDATE device_shares SET revoked_at = NOW(), invite_token = NULL WHERE id = ? AND revoked_at IS NULL", id)
+	if err != nil {
+		log.Printf("ERROR: [ShareRepo] Error al revocar acceso %d: %v", id, err)
+		return fmt.Errorf("error al revocar acceso compartido: %w", err)
+	}
+	log.Printf("INFO: [ShareRepo] Acceso compartido %d revocado.", id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO ActivePermission ---
+func (repo *MySQLShareRepository) ActivePermission(userID int, mac string) (string, *time.Time, error) {
+	// Si hubiera varios vigentes, prevalece escritura
+	var permiso string
+	var expires sql.NullTime
+	err := repo.conn.DB.QueryRow(`SELECT s.permiso, s.expires_at FROM device_shares s
+		WHERE s.grantee_user_id = ? AND s.mac = ? AND `+activeShare+`
+		ORDER BY s.permiso = 'escritura' DESC, s.expires_at IS NULL DESC, s.expires_at DESC LIMIT 1`, userID, mac).Scan(&permiso, &expires)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("error al consultar acceso compartido: %w", err)
+	}
+	return permiso, nullTimePtr(expires), nil
+}
@@ -0,0 +1,244 @@
+// File: MySQLSiteRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+type MySQLSiteRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLSiteRepository(conn *core.Conn_MySQL) *MySQLSiteRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLSiteRepository recibió una conexión DB nula.")
+	}
+	return &MySQLSiteRepository{conn: conn}
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByUserID ---
+func (repo *MySQLSiteRepository) FindByUserID(userID int) ([]entities.Site, error) {
+	rows, err := repo.conn.FetchRows(`SELECT s.id, s.user_id, s.org_id, s.nombre, s.descripcion
+		FROM sites s JOIN organization_members m ON m.org_id = s.org_id
+		WHERE m.user_id = ? ORDER BY s.nombre, s.id`, userID)
+	if err != nil {
+		log.Printf("ERROR: [SiteRepo] Error al listar sitios de UserID %d: %v", userID, err)
+		return nil, fmt.Errorf("error al consultar sitios: %w", err)
+	}
+	defer rows.Close()
+
+	sites := []entities.Site{}
+	for rows.Next() {
+		site := entities.Site{Areas: []entities.Area{}}
+		if err := rows.Scan(&site.ID, &site.UserID, &site.OrgID, &site.Nombre, &site.Descripcion); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de sitio: %w", err)
+		}
+		sites = append(sites, site)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer sitios: %w", err)
+	}
+
+	for i := range sites {
+		areas, err := repo.findAreas(sites[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		sites[i].Areas = areas
+	}
+	return sites, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByID ---
+func (repo *MySQLSiteRepository) FindByID(siteID int) (*entities.Site, error) {
+	site := &entities.Site{}
+	var orgID sql.NullInt64
+	err := repo.conn.DB.QueryRow("SELECT id, user_id, org_id, nombre, descripcion FROM sites WHERE id = ?", siteID).
+		Scan(&site.ID, &site.UserID, &orgID, &site.Nombre, &site.Descripcion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		log.Printf("ERROR: [SiteRepo] Error al buscar sitio %d: %v", siteID, err)
+		return nil, fmt.Errorf("error al consultar sitio: %w", err)
+	}
+	site.OrgID = int(orgID.Int64)
+	areas, err := repo.findAreas(siteID)
+	if err != nil {
+		return nil, err
+	}
+	site.Areas = areas
+	if len(areas) == 0 {
+		return site, nil
+	}
+
+	// MACs ubicadas en cada área del sitio
+	byArea := make(map[int]*entities.Area, len(areas))
+	for i := range site.Areas {
+		byArea[site.Areas[i].ID] = &site.Areas[i]
+	}
+	rows, err := repo.conn.FetchRows("SELECT d.mac, d.area_id FROM devices d JOIN areas a ON a.id = d.area_id WHERE a.site_id = ? ORDER BY d.mac", siteID)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar dispositivos del sitio: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var mac string
+		var areaID int
+		if err := rows.Scan(&mac, &areaID); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de dispositivo del sitio: %w", err)
+		}
+		if area, ok := byArea[areaID]; ok {
+			device := entities.NewDevice(mac)
+			device.AreaID, device.SiteID = &area.ID, &site.ID
+			area.Dispositivos = append(area.Dispositivos, *device)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer dispositivos del sitio: %w", err)
+	}
+	return site, nil
+}
+
+func (repo *MySQLSiteRepository) findAreas(siteID int) ([]entities.Area, error) {
+	rows, err := repo.conn.FetchRows("SELECT id, site_id, nombre, descripcion FROM areas WHERE site_id = ? ORDER BY nombre, id", siteID)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar áreas del sitio %d: %w", siteID, err)
+	}
+	defer rows.Close()
+	areas := []entities.Area{}
+	for rows.Next() {
+		area := entities.Area{Dispositivos: []entities.Device{}}
+		if err := rows.Scan(&area.ID, &area.SiteID, &area.Nombre, &area.Descripcion); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de área: %w", err)
+		}
+		areas = append(areas, area)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer áreas: %w", err)
+	}
+	return areas, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Create ---
+func (repo *MySQLSiteRepository) Create(site *entities.Site) error {
+	result, err := repo.conn.ExecutePreparedQuery("INSERT INTO sites (user_id, org_id, nombre, descripcion) VALUES (?, ?, ?, ?)",
+		site.UserID, site.OrgID, site.Nombre, site.Descripcion)
+	if err != nil {
+		log.Printf("ERROR: [SiteRepo] Error al crear sitio para UserID %d: %v", site.UserID, err)
+		return fmt.Errorf("error al guardar sitio: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	site.ID = int(id)
+	log.Printf("INFO: [SiteRepo] Sitio '%s' creado con ID %d en organización %d por UserID %d.", site.Nombre, site.ID, site.OrgID, site.UserID)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Update ---
+func (repo *MySQLSiteRepository) Update(site *entities.Site) error {
+	_, err := repo.conn.ExecutePreparedQuery("UPDATE sites SET nombre = ?, descripcion = ? WHERE id = ?", site.Nombre, site.Descripcion, site.ID)
+	if err != nil {
+		log.Printf("ERROR: [SiteRepo] Error al actualizar sitio %d: %v", site.ID, err)
+		return fmt.Errorf("error al actualizar sitio: %w", err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Delete ---
+func (repo *MySQLSiteRepository) Delete(siteID int) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de sitio: %w", err)
+	}
+	defer tx.Rollback()
+	statements := []string{
+		"UPDATE devices SET area_id = NULL WHERE area_id IN (SELECT id FROM areas WHERE site_id = ?)",
+		"DELETE FROM areas WHERE site_id = ?",
+		"DELETE FROM sites WHERE id = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, siteID); err != nil {
+			log.Printf("ERROR: [SiteRepo] Error al eliminar sitio %d: %v", siteID, err)
+			return fmt.Errorf("error al eliminar sitio: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar eliminación de sitio: %w", err)
+	}
+	log.Printf("INFO: [SiteRepo] Sitio %d eliminado.", siteID)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindArea ---
+func (repo *MySQLSiteRepository) FindArea(areaID int) (*entities.Area, error) {
+	area := &entities.Area{Dispositivos: []entities.Device{}}
+	err := repo.conn.DB.QueryRow("SELECT id, site_id, nombre, descripcion FROM areas WHERE id = ?", areaID).
+		Scan(&area.ID, &area.SiteID, &area.Nombre, &area.Descripcion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("error al consultar área: %w", err)
+	}
+	return area, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO CreateArea ---
+func (repo *MySQLSiteRepository) CreateArea(area *entities.Area) error {
+	result, err := repo.conn.ExecutePreparedQuery("INSERT INTO areas (site_id, nombre, descripcion) VALUES (?, ?, ?)",
+		area.SiteID, area.Nombre, area.Descripcion)
+	if err != nil {
+		log.Printf("ERROR: [SiteRepo] Error al crear área en sitio %d: %v", area.SiteID, err)
+		return fmt.Errorf("error al guardar área: %w", err)
+	}
+	id, _ := result.LastInsertId()
+	area.ID = int(id)
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO UpdateArea ---
+func (repo *MySQLSiteRepository) UpdateArea(area *entities.Area) error {
+	_, err := repo.conn.ExecutePreparedQuery("UPDATE areas SET nombre = ?, descripcion = ? WHERE id = ?", area.Nombre, area.Descripcion, area.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar área: %w", err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO DeleteArea ---
+func (repo *MySQLSiteRepository) DeleteArea(areaID int) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de área: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("UPDATE devices SET area_id = NULL WHERE area_id = ?", areaID); err != nil {
+		return fmt.Errorf("error al liberar dispositivos del área: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM areas WHERE id = ?", areaID); err != nil {
+		return fmt.Errorf("error al eliminar área: %w", err)
+	}
+	return tx.Commit()
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SetDeviceArea ---
+func (repo *MySQLSiteRepository) SetDeviceArea(mac string, areaID *int) error {
+	var area sql.NullInt64
+	if areaID != nil {
+		area = sql.NullInt64{Int64: int64(*areaID), Valid: true}
+	}
+	// El dispositivo puede no tener fila en 'devices' si nunca se editaron sus metadatos
+	_, err := repo.conn.ExecutePreparedQuery(`INSERT INTO devices (mac, area_id) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE area_id = VALUES(area_id)`, mac, area)
+	if err != nil {
+		log.Printf("ERROR: [SiteRepo] Error al ubicar MAC %s en área %v: %v", mac, areaID, err)
+		return fmt.Errorf("error al asignar área al dispositivo: %w", err)
+	}
+	log.Printf("INFO: [SiteRepo] MAC %s ubicada en área %d (0 = sin área).", mac, area.Int64)
+	return nil
+}
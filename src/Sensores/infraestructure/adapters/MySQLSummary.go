@@ -0,0 +1,146 @@
+// File: MySQLSummary.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// numericMetric convierte una columna de texto de 'rutas' a número; ” y NULL cuentan como ausentes.
+func numericMetric(column string) string {
+	return "CAST(NULLIF(TRIM(rutas." + column + "), '') AS DECIMAL(14,4))"
+}
+
+// motionMetric vale 1 si la lectura indica movimiento, 0 si no, NULL si vino vacía.
+const motionMetric = "(CASE WHEN TRIM(rutas.movimiento) = '' THEN NULL WHEN LOWER(TRIM(rutas.movimiento)) IN ('si', 'sí', '1', 'true', 'yes') THEN 1 ELSE 0 END)"
+
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+// Summarize agrega en la BD por MAC todas las lecturas visibles que cumplen el filtro
+func (mysql *MySQLRutas) Summarize(userID int, filter domain.DatosFilter) ([]entities.DeviceSummary, error) {
+	scopeClause, args := visibleToUser(userID)
+	filterClause, filterArgs, err := buildDatosFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	temperatura, distancia, peso := numericMetric("temperatura"), numericMetric("distancia"), numericMetric("peso")
+	query := fmt.Sprintf(`SELECT rutas.mac, COUNT(*), MIN(rutas.created_at), MAX(rutas.created_at),
+		AVG(%[1]s), MIN(%[1]s), MAX(%[1]s),
+		AVG(%[2]s), MIN(%[2]s), MAX(%[2]s),
+		AVG(%[3]s), MIN(%[3]s), MAX(%[3]s),
+		AVG(%[4]s)
+		FROM rutas WHERE %[5]s%[6]s GROUP BY rutas.mac ORDER BY rutas.mac`,
+		temperatura, distancia, peso, motionMetric, scopeClause, filterClause)
+
+	rows, err := mysql.conn.FetchRows(query, append(args, filterArgs...)...)
+	if err != nil {
+		log.Printf("ERROR: [MySQLAdapter] Error al agregar lecturas para UserID %d: %v", userID, err)
+		return nil, fmt.Errorf("error al resumir datos de MySQL: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []entities.DeviceSummary{}
+	for rows.Next() {
+		var s entities.DeviceSummary
+		var first, last sql.NullTime
+		var stats [10]sql.NullFloat64
+		if err := rows.Scan(&s.Mac, &s.Lecturas, &first, &last,
+			&stats[0], &stats[1], &stats[2], &stats[3], &stats[4], &stats[5], &stats[6], &stats[7], &stats[8], &stats[9]); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de resumen: %w", err)
+		}
+		if first.Valid {
+			s.Primera = &first.Time
+		}
+		if last.Valid {
+			s.Ultima = &last.Time
+		}
+		s.Temperatura = entities.MetricStats{Promedio: nullFloatPtr(stats[0]), Min: nullFloatPtr(stats[1]), Max: nullFloatPtr(stats[2])}
+		s.Distancia = entities.MetricStats{Promedio: nullFloatPtr(stats[3]), Min: nullFloatPtr(stats[4]), Max: nullFloatPtr(stats[5])}
+		s.Peso = entities.MetricStats{Promedio: nullFloatPtr(stats[6]), Min: nullFloatPtr(stats[7]), Max: nullFloatPtr(stats[8])}
+		s.Movimiento = nullFloatPtr(stats[9])
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer resumen: %w", err)
+	}
+	log.Printf("INFO: [MySQLAdapter] Resumen de %d dispositivos para UserID %d.", len(summaries), userID)
+	return summaries, nil
+}
+
+// DeviceStats agrega por MAC en una sola pasada: totales sobre todas las lecturas visibles y
+// estadísticas solo de las que caen en la ventana (desde, ahora]
+func (mysql *MySQLRutas) DeviceStats(userID int, filter domain.DatosFilter, desde time.Time) ([]entities.DeviceStats, error) {
+	filter.Desde, filter.Hasta = time.Time{}, time.Time{}
+	hasta := time.Now()
+	scopeClause, args := visibleToUser(userID)
+	filterClause, filterArgs, err := buildDatosFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	inWindow := func(expr string) string {
+		return "CASE WHEN rutas.created_at >= v.desde THEN " + expr + " END"
+	}
+	temperatura, distancia, peso := inWindow(numericMetric("temperatura")), inWindow(numericMetric("distancia")), inWindow(numericMetric("peso"))
+	query := fmt.Sprintf(`SELECT rutas.mac, COUNT(*), MIN(rutas.created_at), MAX(rutas.created_at),
+		COUNT(%[1]s), MIN(%[1]s), MAX(%[1]s),
+		AVG(%[2]s), MIN(%[2]s), MAX(%[2]s),
+		AVG(%[3]s), MIN(%[3]s), MAX(%[3]s),
+		AVG(%[4]s), MIN(%[4]s), MAX(%[4]s),
+		AVG(%[5]s)
+		FROM rutas JOIN (SELECT ? AS desde) v WHERE %[6]s%[7]s GROUP BY rutas.mac ORDER BY rutas.mac`,
+		inWindow("rutas.created_at"), temperatura, distancia, peso, inWindow(motionMetric), scopeClause, filterClause)
+
+	args = append(append([]interface{}{desde}, args...), filterArgs...)
+	rows, err := mysql.conn.FetchRows(query, args...)
+	if err != nil {
+		log.Printf("ERROR: [MySQLAdapter] Error al calcular estadísticas de dispositivos para UserID %d: %v", userID, err)
+		return nil, fmt.Errorf("error al calcular estadísticas de MySQL: %w", err)
+	}
+	defer rows.Close()
+
+	horas := hasta.Sub(desde).Hours()
+	stats := []entities.DeviceStats{}
+	for rows.Next() {
+		s := entities.DeviceStats{Desde: desde, Hasta: hasta}
+		var first, last, firstWindow, lastWindow sql.NullTime
+		var values [10]sql.NullFloat64
+		if err := rows.Scan(&s.Mac, &s.Lecturas, &first, &last, &s.LecturasVentana, &firstWindow, &lastWindow,
+			&values[0], &values[1], &values[2], &values[3], &values[4], &values[5], &values[6], &values[7], &values[8], &values[9]); err != nil {
+			return nil, fmt.Errorf("error al procesar fila de estadísticas: %w", err)
+		}
+		if first.Valid {
+			s.Primera = &first.Time
+		}
+		if last.Valid {
+			s.Ultima = &last.Time
+		}
+		if horas > 0 {
+			s.LecturasPorHora = float64(s.LecturasVentana) / horas
+		}
+		if s.LecturasVentana > 1 && firstWindow.Valid && lastWindow.Valid {
+			intervalo := lastWindow.Time.Sub(firstWindow.Time).Seconds() / float64(s.LecturasVentana-1)
+			s.IntervaloMedio = &intervalo
+		}
+		s.Temperatura = entities.MetricStats{Promedio: nullFloatPtr(values[0]), Min: nullFloatPtr(values[1]), Max: nullFloatPtr(values[2])}
+		s.Distancia = entities.MetricStats{Promedio: nullFloatPtr(values[3]), Min: nullFloatPtr(values[4]), Max: nullFloatPtr(values[5])}
+		s.Peso = entities.MetricStats{Promedio: nullFloatPtr(values[6]), Min: nullFloatPtr(values[7]), Max: nullFloatPtr(values[8])}
+		s.Movimiento = nullFloatPtr(values[9])
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error final al leer estadísticas: %w", err)
+	}
+	log.Printf("INFO: [MySQLAdapter] Estadísticas de %d dispositivos para UserID %d.", len(stats), userID)
+	return stats, nil
+}
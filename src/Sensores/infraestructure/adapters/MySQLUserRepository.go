@@ -58,7 +58,29 @@ func (repo *MySQLUserRepository) Create(user *domain.User) error {
 }
 
 // --- IMPLEMENTACIÓN MÉTODO FindUserIDByMAC ---
-func (repo *MySQLUserRepository) FindUserIDByMAC(macAddress string) (int, error) {
+func (repo *MySQLUserRepository) FindUserIDByMAC(orgID int, macAddress string) (int, error) {
+	var userID int
+	query := "SELECT u.id FROM users u JOIN organization_members m ON m.user_id = u.id AND m.org_id = ? WHERE u.mac_address = ? LIMIT 1"
+	args := []interface{}{orgID, macAddress}
+	if orgID == 0 {
+		// Dispositivos anteriores a las organizaciones: solo los que todavía no tienen una
+		query = "SELECT u.id FROM users u WHERE u.mac_address = ? AND NOT EXISTS (SELECT 1 FROM devices d WHERE d.mac = u.mac_address AND d.org_id IS NOT NULL) LIMIT 1"
+		args = args[1:]
+	}
+	err := repo.conn.DB.QueryRow(query, args...).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("INFO: [UserRepo] No se encontró usuario de la organización %d para MAC: %s", orgID, macAddress)
+			return 0, sql.ErrNoRows
+		}
+		log.Printf("ERROR: [UserRepo] Error al buscar usuario por MAC %s en la organización %d: %v", macAddress, orgID, err)
+		return 0, fmt.Errorf("error al consultar usuario por MAC: %w", err)
+	}
+	return userID, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindAssignedUserID ---
+func (repo *MySQLUserRepository) FindAssignedUserID(macAddress string) (int, error) {
 	var userID int
 	query := "SELECT id FROM users WHERE mac_address = ? LIMIT 1"
 	err := repo.conn.DB.QueryRow(query, macAddress).Scan(&userID)
@@ -0,0 +1,102 @@
+// File: MySQLWeightEventRepository.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain/entities"
+	"API/src/core"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type MySQLWeightEventRepository struct {
+	conn *core.Conn_MySQL
+}
+
+func NewMySQLWeightEventRepository(conn *core.Conn_MySQL) *MySQLWeightEventRepository {
+	if conn == nil || conn.DB == nil {
+		log.Fatal("CRÍTICO: MySQLWeightEventRepository recibió una conexión DB nula.")
+	}
+	return &MySQLWeightEventRepository{conn: conn}
+}
+
+// --- IMPLEMENTACIÓN MÉTODO GetState ---
+func (repo *MySQLWeightEventRepository) GetState(mac string) (*entities.WeightState, error) {
+	state := entities.WeightState{Mac: mac}
+	var desde sql.NullTime
+	err := repo.conn.DB.QueryRow("SELECT peso_estable, ultima_muestra, candidato_desde, candidato_suma, candidato_lecturas FROM weight_state WHERE mac = ?", mac).
+		Scan(&state.PesoEstable, &state.UltimaMuestra, &desde, &state.CandidatoSuma, &state.CandidatoLecturas)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el seguimiento de peso de %s: %w", mac, err)
+	}
+	if desde.Valid {
+		state.CandidatoDesde = &desde.Time
+	}
+	return &state, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SaveResult ---
+func (repo *MySQLWeightEventRepository) SaveResult(state *entities.WeightState, event *entities.WeightEvent) error {
+	tx, err := repo.conn.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error al iniciar transacción de peso: %w", err)
+	}
+	defer tx.Rollback() // No-op si ya se hizo Commit
+
+	if event != nil {
+		result, err := tx.Exec(`INSERT INTO weight_events (mac, org_id, user_id, tipo, inicio, confirmado, peso_anterior, peso_resultante, delta, muestras)
+			VALUES (?, NULLIF(?, 0), NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?)`,
+			event.Mac, event.OrgID, event.UserID, event.Tipo, event.Inicio, event.Confirmado, event.PesoAnterior, event.PesoResultante, event.Delta, event.Muestras)
+		if err != nil {
+			return fmt.Errorf("error al registrar cambio de peso: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error al leer el id del cambio de peso: %w", err)
+		}
+		event.ID = int(id)
+	}
+	_, err = tx.Exec(`REPLACE INTO weight_state (mac, peso_estable, ultima_muestra, candidato_desde, candidato_suma, candidato_lecturas)
+		VALUES (?, ?, ?, ?, ?, ?)`, state.Mac, state.PesoEstable, state.UltimaMuestra, state.CandidatoDesde, state.CandidatoSuma, state.CandidatoLecturas)
+	if err != nil {
+		return fmt.Errorf("error al guardar el seguimiento de peso de %s: %w", state.Mac, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar transacción de peso: %w", err)
+	}
+	return nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO FindByMac ---
+func (repo *MySQLWeightEventRepository) FindByMac(userID int, mac string, desde time.Time, hasta time.Time, limit int) ([]entities.WeightEvent, error) {
+	scopeClause, scopeArgs := visibleOn("weight_events", "weight_events.confirmado < o.started_at", userID)
+	query := `SELECT weight_events.id, weight_events.mac, weight_events.user_id, weight_events.org_id, weight_events.tipo, weight_events.inicio,
+		weight_events.confirmado, weight_events.peso_anterior, weight_events.peso_resultante, weight_events.delta, weight_events.muestras
+		FROM weight_events WHERE weight_events.mac = ? AND weight_events.confirmado >= ? AND weight_events.confirmado < ? AND ` + scopeClause +
+		" ORDER BY weight_events.confirmado DESC LIMIT ?"
+	args := append([]interface{}{mac, desde, hasta}, scopeArgs...)
+	rows, err := repo.conn.FetchRows(query, append(args, limit)...)
+	if err != nil {
+		log.Printf("ERROR: [WeightEventRepo] Error al listar cambios de peso de %s para UserID %d: %v", mac, userID, err)
+		return nil, fmt.Errorf("error al consultar cambios de peso: %w", err)
+	}
+	defer rows.Close()
+	events := []entities.WeightEvent{}
+	for rows.Next() {
+		var event entities.WeightEvent
+		var eventUserID, orgID sql.NullInt32
+		if err := rows.Scan(&event.ID, &event.Mac, &eventUserID, &orgID, &event.Tipo, &event.Inicio, &event.Confirmado,
+			&event.PesoAnterior, &event.PesoResultante, &event.Delta, &event.Muestras); err != nil {
+			return nil, fmt.Errorf("error al procesar cambio de peso: %w", err)
+		}
+		event.UserID = int(eventUserID.Int32)
+		event.OrgID = int(orgID.Int32)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,208 @@
+// File: parquet_archive.go
+
+package adapters
+
+import (
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRow es el esquema de los archivos exportados: columnas tipadas en lugar del texto de 'rutas'.
+// Las métricas que no se pueden interpretar quedan en null.
+type parquetRow struct {
+	ID          int64     `parquet:"id"`
+	Fecha       time.Time `parquet:"fecha,timestamp(millisecond:utc)"`
+	Mac         string    `parquet:"mac,dict"`
+	Temperatura *float64  `parquet:"temperatura,optional"`
+	Movimiento  *bool     `parquet:"movimiento,optional"`
+	Distancia   *float64  `parquet:"distancia,optional"`
+	Peso        *float64  `parquet:"peso,optional"`
+	UserID      *int64    `parquet:"user_id,optional"`
+	OrgID       *int64    `parquet:"org_id,optional"`
+}
+
+func optionalID(id int) *int64 {
+	if id <= 0 {
+		return nil
+	}
+	v := int64(id)
+	return &v
+}
+
+func toParquetRow(d entities.Datos) parquetRow {
+	return parquetRow{
+		ID:          int64(d.ID),
+		Fecha:       d.Fecha.UTC(),
+		Mac:         d.Mac,
+		Temperatura: domain.ParseNumber(d.Temperatura),
+		Movimiento:  domain.ParseMotion(d.Movimiento),
+		Distancia:   domain.ParseNumber(d.Distancia),
+		Peso:        domain.ParseNumber(d.Peso),
+		UserID:      optionalID(d.UserID),
+		OrgID:       optionalID(d.OrgID),
+	}
+}
+
+// ParquetArchiveStore guarda cada exportación como un ZIP con un Parquet por dispositivo y día (UTC),
+// en rutas al estilo Hive (mac=AA-BB-.../fecha=2024-01-31/datos.parquet) que DuckDB, pandas o
+// Spark leen como particiones.
+type ParquetArchiveStore struct {
+	dir string
+}
+
+// NewParquetArchiveStoreFromEnv usa EXPORT_DIR (por defecto un subdirectorio del temporal del sistema)
+func NewParquetArchiveStoreFromEnv() *ParquetArchiveStore {
+	dir := os.Getenv("EXPORT_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "api-exports")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		log.Fatalf("CRÍTICO: No se pudo crear el directorio de exportaciones '%s': %v", dir, err)
+	}
+	log.Printf("INFO: [ParquetArchive] Exportaciones en '%s'.", dir)
+	return &ParquetArchiveStore{dir: dir}
+}
+
+func (store *ParquetArchiveStore) path(jobID int) string {
+	return filepath.Join(store.dir, fmt.Sprintf("export-%d.zip", jobID))
+}
+
+func (store *ParquetArchiveStore) Extension() string   { return "zip" }
+func (store *ParquetArchiveStore) ContentType() string { return "application/zip" }
+
+// --- IMPLEMENTACIÓN MÉTODO Create ---
+func (store *ParquetArchiveStore) Create(jobID int) (domain.ArchiveWriter, error) {
+	final := store.path(jobID)
+	file, err := os.Create(final + ".partial")
+	if err != nil {
+		return nil, fmt.Errorf("error al crear archivo de exportación: %w", err)
+	}
+	return &parquetArchiveWriter{file: file, final: final, zip: zip.NewWriter(file), parts: make(map[string]int)}, nil
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Open ---
+func (store *ParquetArchiveStore) Open(jobID int) (io.ReadSeekCloser, error) {
+	return os.Open(store.path(jobID))
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Remove ---
+func (store *ParquetArchiveStore) Remove(jobID int) error {
+	if err := os.Remove(store.path(jobID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// parquetArchiveWriter escribe una partición a la vez directamente en su entrada del ZIP, por lo
+// que espera las lecturas ordenadas por MAC y fecha (domain.OrdenDispositivoFecha).
+type parquetArchiveWriter struct {
+	file    *os.File
+	final   string
+	zip     *zip.Writer
+	current string // Partición abierta ("" = ninguna)
+	writer  *parquet.GenericWriter[parquetRow]
+	batch   []parquetRow
+	parts   map[string]int // Entradas ya escritas por partición (por si una reaparece)
+}
+
+const parquetBatchSize = 1024
+
+func partitionName(mac string, fecha time.Time) string {
+	safeMac := strings.Map(func(r rune) rune {
+		if r == ':' || r == '/' || r == '\\' || r == '=' {
+			return '-'
+		}
+		return r
+	}, mac)
+	return "mac=" + safeMac + "/fecha=" + fecha.UTC().Format("2006-01-02")
+}
+
+func (w *parquetArchiveWriter) Write(d entities.Datos) error {
+	partition := partitionName(d.Mac, d.Fecha)
+	if partition != w.current {
+		if err := w.closePartition(); err != nil {
+			return err
+		}
+		name := partition + "/datos.parquet"
+		if n := w.parts[partition]; n > 0 {
+			name = fmt.Sprintf("%s/datos-%d.parquet", partition, n)
+		}
+		entry, err := w.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()}) // Parquet ya va comprimido
+		if err != nil {
+			return fmt.Errorf("error al crear partición %s: %w", partition, err)
+		}
+		w.parts[partition]++
+		w.current = partition
+		w.writer = parquet.NewGenericWriter[parquetRow](entry, parquet.Compression(&parquet.Snappy))
+	}
+	w.batch = append(w.batch, toParquetRow(d))
+	if len(w.batch) >= parquetBatchSize {
+		return w.flushBatch()
+	}
+	return nil
+}
+
+func (w *parquetArchiveWriter) flushBatch() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	if _, err := w.writer.Write(w.batch); err != nil {
+		return fmt.Errorf("error al escribir filas Parquet: %w", err)
+	}
+	w.batch = w.batch[:0]
+	return nil
+}
+
+func (w *parquetArchiveWriter) closePartition() error {
+	if w.writer == nil {
+		return nil
+	}
+	if err := w.flushBatch(); err != nil {
+		return err
+	}
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("error al cerrar partición %s: %w", w.current, err)
+	}
+	w.writer, w.current = nil, ""
+	return nil
+}
+
+func (w *parquetArchiveWriter) Close() (int64, error) {
+	if err := w.closePartition(); err != nil {
+		w.Abort()
+		return 0, err
+	}
+	if err := w.zip.Close(); err != nil {
+		w.Abort()
+		return 0, fmt.Errorf("error al cerrar ZIP de exportación: %w", err)
+	}
+	info, err := w.file.Stat()
+	if err != nil {
+		w.Abort()
+		return 0, err
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.file.Name())
+		return 0, err
+	}
+	if err := os.Rename(w.file.Name(), w.final); err != nil {
+		os.Remove(w.file.Name())
+		return 0, fmt.Errorf("error al publicar archivo de exportación: %w", err)
+	}
+	return info.Size(), nil
+}
+
+func (w *parquetArchiveWriter) Abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
@@ -0,0 +1,80 @@
+// File: smtp_mailer.go
+
+package adapters
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// SMTPMailer implementa domain.Mailer con net/smtp. Se configura con SMTP_HOST, SMTP_PORT
+// (587 por defecto), SMTP_USER, SMTP_PASS y SMTP_FROM; sin SMTP_HOST solo registra el
+// correo en el log (útil en desarrollo). Para probar envíos reales sin un servidor externo
+// sirve un SMTP local de pruebas (p. ej. MailHog: SMTP_HOST=localhost, SMTP_PORT=1025, sin
+// SMTP_USER para que no se intente autenticar).
+type SMTPMailer struct {
+	host string
+	port string
+	user string
+	pass string
+	from string
+}
+
+func NewSMTPMailerFromEnv() *SMTPMailer {
+	m := &SMTPMailer{
+		host: os.Getenv("SMTP_HOST"),
+		port: os.Getenv("SMTP_PORT"),
+		user: os.Getenv("SMTP_USER"),
+		pass: os.Getenv("SMTP_PASS"),
+		from: os.Getenv("SMTP_FROM"),
+	}
+	if m.port == "" {
+		m.port = "587"
+	}
+	if m.from == "" {
+		m.from = m.user
+	}
+	if m.host == "" {
+		log.Println("ADVERTENCIA: SMTP_HOST no configurado; los correos solo se registrarán en el log.")
+	}
+	return m
+}
+
+// --- IMPLEMENTACIÓN MÉTODO Send ---
+func (m *SMTPMailer) Send(to string, subject string, body string) error {
+	return m.send(to, subject, "text/plain", body)
+}
+
+// --- IMPLEMENTACIÓN MÉTODO SendHTML ---
+func (m *SMTPMailer) SendHTML(to string, subject string, body string) error {
+	return m.send(to, subject, "text/html", body)
+}
+
+func (m *SMTPMailer) send(to string, subject string, contentType string, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("destinatario o asunto inválido")
+	}
+	if m.host == "" {
+		log.Printf("INFO: [Mailer] (sin SMTP) Para: %s | Asunto: %s | %s\n%s", to, subject, contentType, body)
+		return nil
+	}
+	msg := "From: " + m.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: " + contentType + "; charset=UTF-8\r\n\r\n" + body
+	var auth smtp.Auth
+	if m.user != "" {
+		auth = smtp.PlainAuth("", m.user, m.pass, m.host)
+	}
+	if err := smtp.SendMail(m.host+":"+m.port, auth, m.from, []string{to}, []byte(msg)); err != nil {
+		log.Printf("ERROR: [Mailer] Error al enviar correo a %s: %v", to, err)
+		return fmt.Errorf("error al enviar correo: %w", err)
+	}
+	log.Printf("INFO: [Mailer] Correo '%s' enviado a %s.", subject, to)
+	return nil
+}
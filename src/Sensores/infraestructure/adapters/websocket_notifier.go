@@ -3,6 +3,7 @@
 package adapters
 
 import (
+	"API/src/Sensores/domain"
 	"API/src/Sensores/domain/entities"           // Importar la entidad
 	infraWS "API/src/Sensores/infraestructure/websocket" // Importar el manager desde su ubicación
 	"encoding/json"
@@ -35,10 +36,44 @@ func (n *WebSocketNotifier) NotifyNewData(data entities.Datos) error {
 		return fmt.Errorf("error al codificar datos para websocket: %w", err)
 	}
 
-	// Usar el manager para transmitir el mensaje JSON a todos los clientes conectados
+	// Usar el manager para publicar el mensaje JSON a los suscriptores del dueño, dispositivo, área y sitio
 	log.Printf("INFO: [WebSocketNotifier] Transmitiendo datos vía WebSocket: %s", string(jsonData))
-	n.wsManager.BroadcastMessage(jsonData) // El manager se encarga del envío
+	n.wsManager.PublishMessage(domain.TopicsForData(data), jsonData) // El manager se encarga del envío
 
 	return nil
 }
 
+
+// NotifyMotionEvent publica la apertura o el cierre de un intervalo de movimiento en los mismos
+// temas que las lecturas del dispositivo; el campo "tipo" lo distingue de una lectura
+func (n *WebSocketNotifier) NotifyMotionEvent(notification entities.MotionNotification) error {
+	jsonData, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("error al codificar evento de movimiento para websocket: %w", err)
+	}
+	event := notification.Evento
+	n.wsManager.PublishMessage(domain.TopicsForDevice(event.Mac, event.UserID, event.OrgID, notification.Dispositivo), jsonData)
+	return nil
+}
+
+// NotifyWeightEvent publica un cambio de peso en los temas del dispositivo
+func (n *WebSocketNotifier) NotifyWeightEvent(notification entities.WeightNotification) error {
+	jsonData, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("error al codificar cambio de peso para websocket: %w", err)
+	}
+	event := notification.Evento
+	n.wsManager.PublishMessage(domain.TopicsForDevice(event.Mac, event.UserID, event.OrgID, notification.Dispositivo), jsonData)
+	return nil
+}
+
+// NotifyAnomaly publica una lectura anómala en los temas del dispositivo
+func (n *WebSocketNotifier) NotifyAnomaly(notification entities.AnomalyNotification) error {
+	jsonData, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("error al codificar anomalía para websocket: %w", err)
+	}
+	event := notification.Evento
+	n.wsManager.PublishMessage(domain.TopicsForDevice(event.Mac, event.UserID, event.OrgID, notification.Dispositivo), jsonData)
+	return nil
+}
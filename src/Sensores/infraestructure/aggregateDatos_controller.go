@@ -0,0 +1,85 @@
+// File: aggregateDatos_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AggregateDatosController struct {
+	useCase application.AggregateDatos
+}
+
+func NewAggregateDatosController(useCase application.AggregateDatos) *AggregateDatosController {
+	return &AggregateDatosController{useCase: useCase}
+}
+
+// splitQueryList admite tanto ?x=a,b como ?x=a&x=b
+func splitQueryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		values = append(values, strings.Split(raw, ",")...)
+	}
+	return values
+}
+
+// Execute maneja GET /datos/aggregate?bucket=1h&metrics=temperatura,peso&percentiles=50,95&from=&to=&tz=
+// (acepta además los filtros de GET /datos: mac, etiquetas, site_id, area_id, where). Los rangos largos se
+// leen de los resúmenes por hora o día salvo con ?resolution=raw. Cada serie trae sus huecos de al menos
+// una cubeta para que el gráfico los corte en vez de unir los puntos (?gaps=false los omite): con
+// resúmenes se calculan por horas; sin ellos, solo en rangos de hasta 93 días.
+func (ctrl *AggregateDatosController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "AggregateCtrl")
+	if !ok {
+		return
+	}
+	filter, ok := parseDatosFilter(c)
+	if !ok {
+		return
+	}
+	huecos, err := strconv.ParseBool(c.DefaultQuery("gaps", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'gaps' inválido: use true o false"})
+		return
+	}
+	input := application.AggregateInput{
+		Bucket:      c.DefaultQuery("bucket", "1h"),
+		Metricas:    splitQueryList(c, "metrics"),
+		Percentiles: splitQueryList(c, "percentiles"),
+		Zona:        c.Query("tz"),
+		Resolucion:  c.Query("resolution"),
+		SinHuecos:   !huecos,
+	}
+	result, err := ctrl.useCase.Execute(userID, filter, input)
+	if err != nil {
+		switch err.Error() {
+		case "bucket_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'bucket' inválido: use 1m, 5m, 15m, 1h, 6h o 1d"})
+		case "metrica_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Métrica inválida: use temperatura, distancia, peso, movimiento, llenado, volumen o el nombre de una métrica derivada de sus dispositivos"})
+		case "percentil_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Percentil inválido: use enteros entre 1 y 99"})
+		case "zona_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Zona horaria 'tz' desconocida (use un nombre IANA, p. ej. America/Mexico_City)"})
+		case "rango_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'from' debe ser anterior a 'to'"})
+		case "demasiados_buckets":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El rango pedido genera demasiadas cubetas; use un bucket mayor o un rango menor"})
+		case "resolucion_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'resolution' inválido: use auto o raw"})
+		case "percentiles_no_soportados":
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "La base de datos no admite percentiles (requiere MySQL 8 o MariaDB 10.2)"})
+		default:
+			log.Printf("ERROR: [AggregateCtrl] Falló la agregación para UserID %d: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al agregar los datos del sensor"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,127 @@
+// File: anomalyDetection_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnomalyDetectionController struct {
+	useCase application.AnomalyDetection
+}
+
+func NewAnomalyDetectionController(useCase application.AnomalyDetection) *AnomalyDetectionController {
+	return &AnomalyDetectionController{useCase: useCase}
+}
+
+type anomalySettingsRequest struct {
+	UmbralZ *float64 `json:"umbral_z"` // 1..10; más bajo = más sensible (por defecto 3.5)
+	Activa  *bool    `json:"activa"`
+}
+
+// respondAnomalyError traduce los errores de AnomalyDetection a respuestas HTTP
+func respondAnomalyError(c *gin.Context, err error, tag string) {
+	switch err.Error() {
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	case "permiso_insuficiente":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite configurar este dispositivo"})
+	case "umbral_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'umbral_z' debe estar entre 1 y 10"})
+	case "rango_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' debe ser anterior a 'to'"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar las anomalías"})
+	}
+}
+
+// List maneja GET /devices/:mac/anomalies?from=&to=&limit=: lecturas anómalas en el rango (por
+// defecto, las últimas 24 horas), de la más reciente a la más antigua
+func (ctrl *AnomalyDetectionController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "AnomalyCtrl")
+	if !ok {
+		return
+	}
+	desde, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	hasta, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+	limit, ok := parseIDQuery(c, "limit")
+	if !ok {
+		return
+	}
+	events, err := ctrl.useCase.List(userID, c.Param("mac"), desde, hasta, limit)
+	if err != nil {
+		respondAnomalyError(c, err, "AnomalyCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// GetSettings maneja GET /devices/:mac/anomaly-settings
+func (ctrl *AnomalyDetectionController) GetSettings(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "AnomalyCtrl")
+	if !ok {
+		return
+	}
+	settings, err := ctrl.useCase.GetSettings(userID, c.Param("mac"))
+	if err != nil {
+		respondAnomalyError(c, err, "AnomalyCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// SaveSettings maneja PUT /devices/:mac/anomaly-settings: sensibilidad y activación del detector
+func (ctrl *AnomalyDetectionController) SaveSettings(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "AnomalyCtrl")
+	if !ok {
+		return
+	}
+	var req anomalySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cuerpo inválido", "detail": err.Error()})
+		return
+	}
+	settings, err := ctrl.useCase.SaveSettings(userID, c.Param("mac"), application.AnomalySettingsInput{UmbralZ: req.UmbralZ, Activa: req.Activa})
+	if err != nil {
+		respondAnomalyError(c, err, "AnomalyCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// DeleteSettings maneja DELETE /devices/:mac/anomaly-settings (vuelve a la sensibilidad predeterminada)
+func (ctrl *AnomalyDetectionController) DeleteSettings(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "AnomalyCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.DeleteSettings(userID, c.Param("mac")); err != nil {
+		respondAnomalyError(c, err, "AnomalyCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Configuración de anomalías restablecida"})
+}
+
+// ResetModel maneja DELETE /devices/:mac/anomaly-model: el detector vuelve a aprender desde cero
+func (ctrl *AnomalyDetectionController) ResetModel(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "AnomalyCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.ResetModel(userID, c.Param("mac")); err != nil {
+		respondAnomalyError(c, err, "AnomalyCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Modelo de anomalías reiniciado"})
+}
@@ -26,18 +26,12 @@ func NewAssignMacController(uc application.AssignMacToUserUseCase) *AssignMacCon
 type assignMacRequest struct {
 	// Permitir cadena vacía para desasignar, `binding:"required"` fallaría
 	MacAddress string `json:"mac_address"`
+	Motivo     string `json:"motivo"` // Obligatorio si la MAC ya pertenece a otro usuario
 }
 
 // Execute es el manejador Gin para la ruta PUT /admin/users/:userId/assign-mac
 func (ctrl *AssignMacController) Execute(c *gin.Context) {
-	// 1. Verificar si el usuario es Administrador
-	userRoleValue, _ := c.Get("userRole") // Obtiene rol del middleware JWT
-	userRole, _ := userRoleValue.(string)
-	if userRole != "admin" { // <-- CHEQUEO DE ROL
-		log.Printf("WARN: [AssignMacCtrl] Intento de acceso no autorizado por rol: '%s'", userRole)
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Acceso denegado: requiere rol de administrador"})
-		return
-	}
+	// 1. El rol de operador de la plataforma lo exige RequirePlatformOperator en el grupo /admin
 
 	// 2. Obtener el ID del usuario objetivo de la URL
 	userIdParam := c.Param("userId") // El :userId de la ruta
@@ -60,11 +54,15 @@ func (ctrl *AssignMacController) Execute(c *gin.Context) {
     // Nota: req.MacAddress puede ser "" si el JSON es `{"mac_address": ""}` o si se omite el campo y no hay `binding:"required"`
 
 	// 4. Preparar y ejecutar el caso de uso
+	adminUserID, _ := c.Get("userID") // Queda registrado en el historial de propiedad
+	adminID, _ := adminUserID.(int)
 	input := application.AssignMacInput{
 		TargetUserID: targetUserID,
 		MacAddress:   req.MacAddress, // Pasamos el valor recibido (puede ser vacío)
+		AdminUserID:  adminID,
+		Motivo:       req.Motivo,
 	}
-	err = ctrl.useCase.Execute(input)
+	period, err := ctrl.useCase.Execute(input)
 
 	// 5. Manejar la respuesta basada en el error del caso de uso
 	if err != nil {
@@ -75,6 +73,8 @@ func (ctrl *AssignMacController) Execute(c *gin.Context) {
 			c.JSON(http.StatusConflict, gin.H{"error": "La dirección MAC ya está asignada a otro usuario"})
 		} else if err.Error() == "formato_mac_invalido" { // Error específico del use case
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de dirección MAC inválido"})
+		} else if err.Error() == "motivo_requerido" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "La MAC pertenece a otro usuario: indique el 'motivo' de la reasignación"})
 		} else {
 			// Otro error (probablemente DB o interno)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al asignar la MAC"})
@@ -84,5 +84,5 @@ func (ctrl *AssignMacController) Execute(c *gin.Context) {
 
 	// Éxito
 	log.Printf("INFO: [AssignMacCtrl] MAC '%s' asignada/actualizada para UserID %d por admin.", req.MacAddress, targetUserID)
-	c.JSON(http.StatusOK, gin.H{"message": "Dirección MAC asignada/actualizada exitosamente"})
+	c.JSON(http.StatusOK, gin.H{"message": "Dirección MAC asignada/actualizada exitosamente", "periodo": period})
 }
\ No newline at end of file
@@ -0,0 +1,28 @@
+// File: auth_context.go
+
+package infraestructure
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getAuthUserID obtiene el userID puesto por JWTMiddleware. Si falta o es inválido
+// responde al cliente y devuelve ok=false, así cada controlador solo hace `return`.
+func getAuthUserID(c *gin.Context, tag string) (int, bool) {
+	userIDValue, exists := c.Get("userID")
+	if !exists {
+		log.Printf("ERROR: [%s] No se encontró userID en el contexto. Middleware de Auth ausente o falló?", tag)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado (contexto inválido)"})
+		return 0, false
+	}
+	userID, ok := userIDValue.(int)
+	if !ok || userID <= 0 {
+		log.Printf("ERROR: [%s] userID en contexto tiene tipo inválido (%T) o valor no positivo.", tag, userIDValue)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno de autorización"})
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,218 @@
+// File: complianceReports_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"API/src/Sensores/domain/entities"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ComplianceReportsController struct {
+	useCase application.ComplianceReports
+}
+
+func NewComplianceReportsController(useCase application.ComplianceReports) *ComplianceReportsController {
+	return &ComplianceReportsController{useCase: useCase}
+}
+
+type complianceProfileRequest struct {
+	TempMin        *float64 `json:"temp_min" binding:"required"`
+	TempMax        *float64 `json:"temp_max" binding:"required"`
+	ToleranciaSeg  int      `json:"tolerancia_seg"`
+	HuecoMaximoSeg int      `json:"hueco_maximo_seg"` // 0 = 15 minutos
+}
+
+type complianceGenerateRequest struct {
+	Periodo string `json:"periodo" binding:"required"` // diario | semanal
+	Fecha   string `json:"fecha"`                      // AAAA-MM-DD dentro del periodo; vacío = el periodo anterior
+}
+
+// respondComplianceError traduce los errores de ComplianceReports a respuestas HTTP
+func respondComplianceError(c *gin.Context, err error, tag string) {
+	switch err.Error() {
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	case "reporte_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reporte no encontrado"})
+	case "perfil_no_configurado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "El dispositivo no tiene perfil de cumplimiento"})
+	case "permiso_insuficiente":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite configurar este dispositivo"})
+	case "rango_temperatura_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'temp_min' debe ser menor que 'temp_max'"})
+	case "duracion_invalida":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'tolerancia_seg' y 'hueco_maximo_seg' no pueden ser negativos"})
+	case "periodo_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Periodo inválido: use diario o semanal"})
+	case "periodo_incompleto":
+		c.JSON(http.StatusConflict, gin.H{"error": "El periodo todavía no terminó"})
+	case "rango_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' debe ser anterior a 'to'"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar el cumplimiento de temperatura"})
+	}
+}
+
+// GetProfile maneja GET /devices/:mac/compliance
+func (ctrl *ComplianceReportsController) GetProfile(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ComplianceCtrl")
+	if !ok {
+		return
+	}
+	profile, err := ctrl.useCase.GetProfile(userID, c.Param("mac"))
+	if err != nil {
+		respondComplianceError(c, err, "ComplianceCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// SaveProfile maneja PUT /devices/:mac/compliance: rango permitido, excursión tolerada y hueco máximo
+func (ctrl *ComplianceReportsController) SaveProfile(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ComplianceCtrl")
+	if !ok {
+		return
+	}
+	var req complianceProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'temp_min' y 'temp_max'", "detail": err.Error()})
+		return
+	}
+	profile, err := ctrl.useCase.SaveProfile(userID, c.Param("mac"), application.ComplianceProfileInput{
+		TempMin: *req.TempMin, TempMax: *req.TempMax, ToleranciaSeg: req.ToleranciaSeg, HuecoMaximoSeg: req.HuecoMaximoSeg,
+	})
+	if err != nil {
+		respondComplianceError(c, err, "ComplianceCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteProfile maneja DELETE /devices/:mac/compliance (los reportes generados se conservan)
+func (ctrl *ComplianceReportsController) DeleteProfile(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ComplianceCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.DeleteProfile(userID, c.Param("mac")); err != nil {
+		respondComplianceError(c, err, "ComplianceCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Perfil de cumplimiento eliminado"})
+}
+
+// Generate maneja POST /devices/:mac/compliance/reports: 201 con el reporte nuevo o 200 con el que
+// ya tenía el periodo
+func (ctrl *ComplianceReportsController) Generate(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ComplianceCtrl")
+	if !ok {
+		return
+	}
+	var req complianceGenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'periodo'", "detail": err.Error()})
+		return
+	}
+	var fecha time.Time
+	if req.Fecha != "" {
+		var err error
+		if fecha, err = time.ParseInLocation("2006-01-02", req.Fecha, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'fecha' debe tener formato AAAA-MM-DD"})
+			return
+		}
+	}
+	report, nuevo, err := ctrl.useCase.Generate(userID, c.Param("mac"), req.Periodo, fecha)
+	if err != nil {
+		respondComplianceError(c, err, "ComplianceCtrl")
+		return
+	}
+	if !nuevo {
+		// El periodo ya tenía reporte: se devuelve el guardado
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// List maneja GET /devices/:mac/compliance/reports?periodo=&from=&to=&limit=: reportes que empiezan
+// en el rango (por defecto, los últimos 90 días), del más reciente al más antiguo
+func (ctrl *ComplianceReportsController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ComplianceCtrl")
+	if !ok {
+		return
+	}
+	desde, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	hasta, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+	limit, ok := parseIDQuery(c, "limit")
+	if !ok {
+		return
+	}
+	reports, err := ctrl.useCase.List(userID, c.Param("mac"), c.Query("periodo"), desde, hasta, limit)
+	if err != nil {
+		respondComplianceError(c, err, "ComplianceCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// Get maneja GET /compliance/reports/:id?format=json|html|csv
+func (ctrl *ComplianceReportsController) Get(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ComplianceCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "ComplianceCtrl")
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	var write func(*entities.ComplianceReport, io.Writer) error
+	var contentType string
+	switch format {
+	case "json":
+	case "html":
+		write = application.WriteComplianceHTML
+		contentType = "text/html; charset=utf-8"
+	case "csv":
+		write = application.WriteComplianceCSV
+		contentType = "text/csv; charset=utf-8"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'format' inválido: use json, html o csv"})
+		return
+	}
+	report, err := ctrl.useCase.Get(userID, id)
+	if err != nil {
+		respondComplianceError(c, err, "ComplianceCtrl")
+		return
+	}
+	if write == nil {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	// Los reportes son chicos: se arman completos para poder responder 500 si algo falla
+	var body bytes.Buffer
+	if err := write(report, &body); err != nil {
+		log.Printf("ERROR: [ComplianceCtrl] No se pudo generar el reporte %d en %s: %v", id, format, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al generar el archivo"})
+		return
+	}
+	filename := fmt.Sprintf("cumplimiento-%d-%s-%s.%s", report.ID, report.Periodo, report.Desde.Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
@@ -0,0 +1,101 @@
+// File: container_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContainerController struct {
+	useCase application.DeviceContainer
+}
+
+func NewContainerController(useCase application.DeviceContainer) *ContainerController {
+	return &ContainerController{useCase: useCase}
+}
+
+type containerRequest struct {
+	Forma        string   `json:"forma" binding:"required"` // cilindro | rectangular | cilindro_horizontal
+	AlturaSensor float64  `json:"altura_sensor" binding:"required"`
+	AlturaMaxima float64  `json:"altura_maxima"` // Opcional: por defecto, altura_sensor (o el diámetro)
+	Diametro     *float64 `json:"diametro"`
+	Largo        *float64 `json:"largo"`
+	Ancho        *float64 `json:"ancho"`
+	UmbralBajo   *float64 `json:"umbral_bajo"`
+	UmbralAlto   *float64 `json:"umbral_alto"`
+}
+
+// respondContainerError traduce los errores de DeviceContainer a respuestas HTTP
+func respondContainerError(c *gin.Context, err error, tag string) {
+	switch err.Error() {
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	case "contenedor_no_configurado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "El dispositivo no tiene contenedor configurado"})
+	case "permiso_insuficiente":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite configurar este dispositivo"})
+	case "forma_invalida":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Forma inválida: use cilindro, rectangular o cilindro_horizontal"})
+	case "dimensiones_invalidas":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dimensiones inválidas: deben ser positivas, altura_maxima no puede superar altura_sensor y cilindro_horizontal requiere diametro y largo"})
+	case "umbrales_invalidos":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Umbrales inválidos: deben estar entre 0 y 100 y umbral_bajo debe ser menor que umbral_alto"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar el contenedor"})
+	}
+}
+
+// Get maneja GET /devices/:mac/container
+func (ctrl *ContainerController) Get(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ContainerCtrl")
+	if !ok {
+		return
+	}
+	config, err := ctrl.useCase.Get(userID, c.Param("mac"))
+	if err != nil {
+		respondContainerError(c, err, "ContainerCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+// Save maneja PUT /devices/:mac/container: crea o reemplaza la geometría del contenedor
+func (ctrl *ContainerController) Save(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ContainerCtrl")
+	if !ok {
+		return
+	}
+	var req containerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'forma' y 'altura_sensor'", "detail": err.Error()})
+		return
+	}
+	input := application.ContainerInput{
+		Forma: req.Forma, AlturaSensor: req.AlturaSensor, AlturaMaxima: req.AlturaMaxima,
+		Diametro: req.Diametro, Largo: req.Largo, Ancho: req.Ancho, UmbralBajo: req.UmbralBajo, UmbralAlto: req.UmbralAlto,
+	}
+	config, err := ctrl.useCase.Save(userID, c.Param("mac"), input)
+	if err != nil {
+		respondContainerError(c, err, "ContainerCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+// Delete maneja DELETE /devices/:mac/container
+func (ctrl *ContainerController) Delete(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ContainerCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.Delete(userID, c.Param("mac")); err != nil {
+		respondContainerError(c, err, "ContainerCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Contenedor eliminado"})
+}
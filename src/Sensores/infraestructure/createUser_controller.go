@@ -25,7 +25,7 @@ type createUserRequest struct {
 	Username   string `json:"username" binding:"required"`
 	Password   string `json:"password" binding:"required"`
 	MacAddress string `json:"mac_address"` // MAC es opcional en el request
-	Role       string `json:"role"`        // Rol es opcional, el use case pondrá 'user' por defecto
+	Role       string `json:"role"`        // Opcional; solo se acepta 'user' (valor por defecto)
 }
 
 // Execute es el manejador Gin para la ruta POST /auth/register
@@ -53,6 +53,8 @@ func (ctrl *CreateUserController) Execute(c *gin.Context) {
 		// Mapear errores del caso de uso a respuestas HTTP
 		if strings.HasPrefix(err.Error(), "la contraseña debe") {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err.Error() == "rol_no_permitido" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Solo se puede registrar con el rol 'user'"})
 		} else if strings.HasPrefix(err.Error(), "conflicto:") {
 			// Extraer el campo duplicado si es posible (ej: "username_duplicado")
 			fieldName := "recurso"
@@ -0,0 +1,55 @@
+// File: dataGaps_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataGapsController struct {
+	useCase application.DataGaps
+}
+
+func NewDataGapsController(useCase application.DataGaps) *DataGapsController {
+	return &DataGapsController{useCase: useCase}
+}
+
+// Execute maneja GET /devices/:mac/gaps?from=&to=&tz=: huecos en las lecturas según el intervalo de
+// reporte del dispositivo y porcentaje de lecturas recibidas por día (por defecto, los últimos 7 días)
+func (ctrl *DataGapsController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "DataGapsCtrl")
+	if !ok {
+		return
+	}
+	desde, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	hasta, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+	mac := c.Param("mac")
+	result, err := ctrl.useCase.Execute(userID, mac, desde, hasta, c.Query("tz"))
+	if err != nil {
+		switch err.Error() {
+		case "dispositivo_no_encontrado":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+		case "zona_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Zona horaria 'tz' desconocida (use un nombre IANA, p. ej. America/Mexico_City)"})
+		case "rango_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'from' debe ser anterior a 'to' y al momento actual"})
+		case "rango_demasiado_largo":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El rango no puede superar 93 días"})
+		default:
+			log.Printf("ERROR: [DataGapsCtrl] Falló el cálculo de huecos de MAC %s para UserID %d: %v", mac, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al calcular los huecos de datos"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -43,12 +43,17 @@ func (dsc *DeleteDatosController) Execute(c *gin.Context) {
 	}
 
 	// Ejecutar el caso de uso, pasando userID para validación
-	// Asume que DeleteUseCase.Execute ahora toma userID como parámetro
-	err = dsc.useCase.Execute(id /*, userID */) // Pasa userID si el caso de uso/repo lo usa
+	err = dsc.useCase.Execute(id, userID)
 	if err != nil {
+		switch err.Error() {
+		case "dato_no_encontrado":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dato no encontrado"})
+		case "permiso_insuficiente":
+			c.JSON(http.StatusForbidden, gin.H{"error": "No tiene permiso para eliminar este dato"})
+		default:
 			log.Printf("ERROR: [DeleteCtrl] Falló la ejecución del caso de uso DeleteDatos (ID: %d, UserID: %d): %v", id, userID, err)
-			// Podrías devolver 404 Not Found o 403 Forbidden si el error indica que no existe o no pertenece al usuario
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al eliminar los datos"})
+		}
 		return
 	}
 
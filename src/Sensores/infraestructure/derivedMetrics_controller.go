@@ -0,0 +1,130 @@
+// File: derivedMetrics_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"API/src/Sensores/domain"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DerivedMetricsController struct {
+	useCase application.DerivedMetrics
+}
+
+func NewDerivedMetricsController(useCase application.DerivedMetrics) *DerivedMetricsController {
+	return &DerivedMetricsController{useCase: useCase}
+}
+
+type derivedMetricRequest struct {
+	Nombre    string `json:"nombre" binding:"required"`
+	Expresion string `json:"expresion" binding:"required"` // p. ej. "rate(peso)" o "(temperatura * 9 / 5) + 32"
+	Unidad    string `json:"unidad"`
+}
+
+func (req derivedMetricRequest) input() application.DerivedMetricInput {
+	return application.DerivedMetricInput{Nombre: req.Nombre, Expresion: req.Expresion, Unidad: req.Unidad}
+}
+
+// respondDerivedMetricError traduce los errores de DerivedMetrics a respuestas HTTP
+func respondDerivedMetricError(c *gin.Context, err error, tag string) {
+	if parseErr, ok := err.(*domain.MetricFilterError); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expresión inválida", "posicion": parseErr.Posicion, "detalle": parseErr.Mensaje})
+		return
+	}
+	switch err.Error() {
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	case "metrica_no_encontrada":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Métrica derivada no encontrada"})
+	case "permiso_insuficiente":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite modificar las métricas de este dispositivo"})
+	case "nombre_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'nombre' debe empezar con una letra y tener solo minúsculas, dígitos o '_' (máximo 50), sin repetir una métrica de las lecturas ni una palabra de los filtros (and, or, not, in, is, null, between)"})
+	case "unidad_invalida":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'unidad' admite como máximo 20 caracteres"})
+	case "nombre_duplicado":
+		c.JSON(http.StatusConflict, gin.H{"error": "El dispositivo ya tiene una métrica derivada con ese nombre"})
+	case "demasiadas_metricas":
+		c.JSON(http.StatusConflict, gin.H{"error": "El dispositivo ya tiene el máximo de 20 métricas derivadas"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar las métricas derivadas"})
+	}
+}
+
+// List maneja GET /devices/:mac/derived-metrics
+func (ctrl *DerivedMetricsController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "DerivedMetricsCtrl")
+	if !ok {
+		return
+	}
+	metrics, err := ctrl.useCase.List(userID, c.Param("mac"))
+	if err != nil {
+		respondDerivedMetricError(c, err, "DerivedMetricsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, metrics)
+}
+
+// Create maneja POST /devices/:mac/derived-metrics; solo las lecturas nuevas tendrán valor
+func (ctrl *DerivedMetricsController) Create(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "DerivedMetricsCtrl")
+	if !ok {
+		return
+	}
+	var req derivedMetricRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre' y 'expresion'", "detail": err.Error()})
+		return
+	}
+	metric, err := ctrl.useCase.Create(userID, c.Param("mac"), req.input())
+	if err != nil {
+		respondDerivedMetricError(c, err, "DerivedMetricsCtrl")
+		return
+	}
+	c.JSON(http.StatusCreated, metric)
+}
+
+// Update maneja PUT /devices/:mac/derived-metrics/:id (cambiar la expresión borra los valores ya calculados)
+func (ctrl *DerivedMetricsController) Update(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "DerivedMetricsCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "DerivedMetricsCtrl")
+	if !ok {
+		return
+	}
+	var req derivedMetricRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre' y 'expresion'", "detail": err.Error()})
+		return
+	}
+	metric, err := ctrl.useCase.Update(userID, c.Param("mac"), id, req.input())
+	if err != nil {
+		respondDerivedMetricError(c, err, "DerivedMetricsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, metric)
+}
+
+// Delete maneja DELETE /devices/:mac/derived-metrics/:id (también borra sus valores)
+func (ctrl *DerivedMetricsController) Delete(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "DerivedMetricsCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "DerivedMetricsCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.Delete(userID, c.Param("mac"), id); err != nil {
+		respondDerivedMetricError(c, err, "DerivedMetricsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Métrica derivada eliminada"})
+}
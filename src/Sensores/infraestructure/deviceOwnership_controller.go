@@ -0,0 +1,79 @@
+// File: deviceOwnership_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceOwnershipController struct {
+	useCase application.DeviceOwnership
+}
+
+func NewDeviceOwnershipController(useCase application.DeviceOwnership) *DeviceOwnershipController {
+	return &DeviceOwnershipController{useCase: useCase}
+}
+
+type decideHistoryRequest struct {
+	Transferir *bool `json:"transferir" binding:"required"` // Puntero para distinguir false de ausente
+}
+
+// Execute maneja GET /devices/:mac/ownership (periodos del propio usuario)
+func (ctrl *DeviceOwnershipController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OwnershipCtrl")
+	if !ok {
+		return
+	}
+	periods, err := ctrl.useCase.ExecuteForOwner(userID, c.Param("mac"))
+	if err != nil {
+		if err.Error() == "dispositivo_no_encontrado" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+			return
+		}
+		log.Printf("ERROR: [OwnershipCtrl] Falló al obtener historial para UserID %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener el historial del dispositivo"})
+		return
+	}
+	c.JSON(http.StatusOK, periods)
+}
+
+// DecideHistory maneja PUT /devices/:mac/ownership/historial
+func (ctrl *DeviceOwnershipController) DecideHistory(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OwnershipCtrl")
+	if !ok {
+		return
+	}
+	var req decideHistoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'transferir' (true o false)"})
+		return
+	}
+	period, err := ctrl.useCase.DecideHistory(userID, c.Param("mac"), *req.Transferir)
+	if err != nil {
+		switch err.Error() {
+		case "dispositivo_no_encontrado":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+		case "decision_no_pendiente":
+			c.JSON(http.StatusConflict, gin.H{"error": "No hay una decisión de historial pendiente para este dispositivo"})
+		default:
+			log.Printf("ERROR: [OwnershipCtrl] Falló al decidir historial para UserID %d: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al guardar la decisión"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, period)
+}
+
+// ExecuteAdmin maneja GET /admin/devices/:mac/ownership (historial completo; el grupo /admin exige el rol)
+func (ctrl *DeviceOwnershipController) ExecuteAdmin(c *gin.Context) {
+	periods, err := ctrl.useCase.ExecuteAdmin(c.Param("mac"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener el historial del dispositivo"})
+		return
+	}
+	c.JSON(http.StatusOK, periods)
+}
@@ -0,0 +1,69 @@
+// File: deviceStats_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"API/src/Sensores/domain/entities"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceStatsController struct {
+	useCase application.DeviceStats
+}
+
+func NewDeviceStatsController(useCase application.DeviceStats) *DeviceStatsController {
+	return &DeviceStatsController{useCase: useCase}
+}
+
+func respondDeviceStatsError(c *gin.Context, context string, err error) {
+	switch err.Error() {
+	case "ventana_invalida":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'window' inválido: use un entero seguido de m, h o d (ej. 24h, 7d), hasta 90d"})
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	default:
+		log.Printf("ERROR: [DeviceStatsCtrl] Falló %s: %v", context, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al calcular las estadísticas"})
+	}
+}
+
+// Execute maneja GET /devices/stats?window=24h: estadísticas de todos los dispositivos con
+// lecturas visibles (acepta además los filtros de GET /datos: mac, etiquetas, site_id, area_id, where)
+func (ctrl *DeviceStatsController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "DeviceStatsCtrl")
+	if !ok {
+		return
+	}
+	filter, ok := parseDatosFilter(c)
+	if !ok {
+		return
+	}
+	stats, err := ctrl.useCase.Execute(userID, filter, c.Query("window"))
+	if err != nil {
+		respondDeviceStatsError(c, "DeviceStats.Execute", err)
+		return
+	}
+	if stats == nil {
+		stats = []entities.DeviceStats{}
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// ExecuteOne maneja GET /devices/:mac/stats?window=24h
+func (ctrl *DeviceStatsController) ExecuteOne(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "DeviceStatsCtrl")
+	if !ok {
+		return
+	}
+	mac := c.Param("mac")
+	stats, err := ctrl.useCase.ExecuteOne(userID, mac, c.Query("window"))
+	if err != nil {
+		respondDeviceStatsError(c, "DeviceStats.ExecuteOne para MAC "+mac, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,79 @@
+// File: exportDatos_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportDatosController struct {
+	useCase application.ExportDatos
+}
+
+func NewExportDatosController(useCase application.ExportDatos) *ExportDatosController {
+	return &ExportDatosController{useCase: useCase}
+}
+
+// flushWriter envía al cliente cada bloque escrito; sin Content-Length la respuesta va en
+// chunked encoding y no se acumula en memoria
+type flushWriter struct {
+	w gin.ResponseWriter
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.w.Flush()
+	return n, err
+}
+
+// Execute maneja GET /datos/export?format=csv|ndjson&columns=fecha,mac,temperatura&tz=&sort=asc|desc
+// (acepta además los filtros de GET /datos: mac, etiquetas, site_id, area_id, from, to, where)
+func (ctrl *ExportDatosController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ExportCtrl")
+	if !ok {
+		return
+	}
+	filter, ok := parseDatosFilter(c)
+	if !ok {
+		return
+	}
+	spec, err := ctrl.useCase.Prepare(application.ExportInput{
+		Formato:  c.Query("format"),
+		Columnas: splitQueryList(c, "columns"),
+		Zona:     c.Query("tz"),
+		Orden:    c.Query("sort"),
+	})
+	if err != nil {
+		switch err.Error() {
+		case "formato_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'format' inválido: use csv o ndjson"})
+		case "columna_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Columna inválida: use id, fecha, mac, temperatura, movimiento, distancia, peso, user_id u org_id"})
+		case "zona_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Zona horaria 'tz' desconocida (use un nombre IANA, p. ej. America/Mexico_City)"})
+		case "orden_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'sort' inválido: use asc o desc"})
+		default:
+			log.Printf("ERROR: [ExportCtrl] %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al preparar la exportación"})
+		}
+		return
+	}
+
+	filename := fmt.Sprintf("datos-%s.%s", time.Now().In(spec.Zona).Format("20060102-150405"), spec.Extension())
+	c.Header("Content-Type", spec.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	// Los errores a mitad de la descarga solo pueden registrarse: el estado ya se envió
+	if _, err := ctrl.useCase.Stream(userID, filter, spec, flushWriter{w: c.Writer}); err != nil {
+		log.Printf("ERROR: [ExportCtrl] Exportación de UserID %d interrumpida: %v", userID, err)
+	}
+}
@@ -0,0 +1,168 @@
+// File: exportJobs_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"API/src/Sensores/domain"
+	"API/src/Sensores/domain/entities"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportJobsController struct {
+	useCase application.ExportJobs
+}
+
+func NewExportJobsController(useCase application.ExportJobs) *ExportJobsController {
+	return &ExportJobsController{useCase: useCase}
+}
+
+// withDownloadURL completa la URL de descarga de los trabajos terminados
+func withDownloadURL(job entities.ExportJob) entities.ExportJob {
+	if job.Estado == entities.ExportCompletado {
+		job.DownloadURL = "/exports/" + strconv.Itoa(job.ID) + "/download"
+	}
+	return job
+}
+
+func toExportFilter(filter domain.DatosFilter) entities.ExportFilter {
+	f := entities.ExportFilter{Mac: filter.Mac, Etiquetas: filter.Etiquetas, SiteID: filter.SiteID, AreaID: filter.AreaID, UserID: filter.UserID}
+	if !filter.Desde.IsZero() {
+		f.Desde = &filter.Desde
+	}
+	if !filter.Hasta.IsZero() {
+		f.Hasta = &filter.Hasta
+	}
+	if filter.Expresion != nil {
+		f.Where = filter.Expresion.Texto
+	}
+	return f
+}
+
+func respondExportJobError(c *gin.Context, userID int, err error) {
+	switch err.Error() {
+	case "rango_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rango inválido: 'from' debe ser anterior a 'to'"})
+	case "demasiados_trabajos":
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Ya tiene exportaciones en curso; espere a que terminen"})
+	case "cola_llena":
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "El servidor está procesando demasiadas exportaciones; intente más tarde"})
+	case "exportacion_no_encontrada":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exportación no encontrada"})
+	case "exportacion_no_lista":
+		c.JSON(http.StatusConflict, gin.H{"error": "La exportación aún no está disponible para descarga"})
+	case "exportacion_expirada":
+		c.JSON(http.StatusGone, gin.H{"error": "El archivo de la exportación ya expiró"})
+	default:
+		log.Printf("ERROR: [ExportJobsCtrl] Falló para UserID %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar la exportación"})
+	}
+}
+
+func (ctrl *ExportJobsController) create(c *gin.Context, userID int, filter domain.DatosFilter, todos bool) {
+	job, err := ctrl.useCase.Create(userID, toExportFilter(filter), todos)
+	if err != nil {
+		respondExportJobError(c, userID, err)
+		return
+	}
+	c.Header("Location", "/exports/"+strconv.Itoa(job.ID))
+	c.JSON(http.StatusAccepted, withDownloadURL(*job))
+}
+
+// Create maneja POST /datos/export/parquet con los filtros de GET /datos en el query string
+func (ctrl *ExportJobsController) Create(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ExportJobsCtrl")
+	if !ok {
+		return
+	}
+	filter, ok := parseDatosFilter(c)
+	if !ok {
+		return
+	}
+	ctrl.create(c, userID, filter, false)
+}
+
+// CreateAdmin maneja POST /admin/datos/export/parquet: todas las lecturas, con los filtros de
+// GET /datos más ?user_id=N
+func (ctrl *ExportJobsController) CreateAdmin(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ExportJobsCtrl")
+	if !ok {
+		return
+	}
+	filter, ok := parseDatosFilter(c)
+	if !ok {
+		return
+	}
+	if filter.UserID, ok = parseIDQuery(c, "user_id"); !ok {
+		return
+	}
+	ctrl.create(c, userID, filter, true)
+}
+
+// List maneja GET /exports
+func (ctrl *ExportJobsController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ExportJobsCtrl")
+	if !ok {
+		return
+	}
+	jobs, err := ctrl.useCase.List(userID)
+	if err != nil {
+		respondExportJobError(c, userID, err)
+		return
+	}
+	for i := range jobs {
+		jobs[i] = withDownloadURL(jobs[i])
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// Get maneja GET /exports/:id (estado del trabajo)
+func (ctrl *ExportJobsController) Get(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ExportJobsCtrl")
+	if !ok {
+		return
+	}
+	jobID, ok := parseIDParam(c, "id", "ExportJobsCtrl")
+	if !ok {
+		return
+	}
+	job, err := ctrl.useCase.Get(userID, jobID)
+	if err != nil {
+		respondExportJobError(c, userID, err)
+		return
+	}
+	c.JSON(http.StatusOK, withDownloadURL(*job))
+}
+
+// Download maneja GET /exports/:id/download (admite Range para reanudar descargas)
+func (ctrl *ExportJobsController) Download(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ExportJobsCtrl")
+	if !ok {
+		return
+	}
+	jobID, ok := parseIDParam(c, "id", "ExportJobsCtrl")
+	if !ok {
+		return
+	}
+	job, file, err := ctrl.useCase.Download(userID, jobID)
+	if err != nil {
+		respondExportJobError(c, userID, err)
+		return
+	}
+	defer file.Close()
+	c.Header("Content-Type", ctrl.useCase.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ctrl.useCase.FileName(job)))
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	var modified time.Time
+	if job.Terminado != nil {
+		modified = *job.Terminado
+	}
+	http.ServeContent(c.Writer, c.Request, "", modified, file)
+}
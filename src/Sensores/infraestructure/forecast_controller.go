@@ -0,0 +1,83 @@
+// File: forecast_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ForecastController struct {
+	useCase application.Forecasting
+}
+
+func NewForecastController(useCase application.Forecasting) *ForecastController {
+	return &ForecastController{useCase: useCase}
+}
+
+// Execute maneja GET /devices/:mac/forecast?metrica=peso|distancia|temperatura&horizon=&history=&umbral=&confianza=
+// horizon e history van en horas (por defecto 72 y 168); umbral admite varios valores separados por comas
+func (ctrl *ForecastController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ForecastCtrl")
+	if !ok {
+		return
+	}
+	horizonte, ok := parseIDQuery(c, "horizon")
+	if !ok {
+		return
+	}
+	historia, ok := parseIDQuery(c, "history")
+	if !ok {
+		return
+	}
+	confianza, ok := parseIDQuery(c, "confianza")
+	if !ok {
+		return
+	}
+	umbrales := []float64{}
+	for _, raw := range c.QueryArray("umbral") {
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			u, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'umbral' inválido"})
+				return
+			}
+			umbrales = append(umbrales, u)
+		}
+	}
+	mac := c.Param("mac")
+	forecast, err := ctrl.useCase.Execute(userID, mac, application.ForecastInput{
+		Metrica: c.Query("metrica"), Horizonte: horizonte, Historia: historia, Umbrales: umbrales, Confianza: confianza,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "dispositivo_no_encontrado":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+		case "metrica_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'metrica' inválido: use peso, distancia o temperatura"})
+		case "horizonte_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'horizon' debe estar entre 1 y 720 horas"})
+		case "historia_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'history' debe estar entre 6 y 1440 horas"})
+		case "confianza_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'confianza' debe ser 80, 90, 95 o 99"})
+		case "umbral_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Se admiten hasta 5 umbrales numéricos"})
+		case "historia_insuficiente":
+			c.JSON(http.StatusConflict, gin.H{"error": "No hay suficientes lecturas recientes de la métrica para pronosticar (al menos 6 horas con datos)"})
+		default:
+			log.Printf("ERROR: [ForecastCtrl] Falló el pronóstico de MAC %s para UserID %d: %v", mac, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al calcular el pronóstico"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, forecast)
+}
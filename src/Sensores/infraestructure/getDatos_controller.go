@@ -4,9 +4,13 @@ package infraestructure
 
 import (
 	"API/src/Sensores/application"
+	"API/src/Sensores/domain"
 	"API/src/Sensores/domain/entities"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,6 +23,46 @@ func NewGetDatosController(useCase application.GetDatos) *GetDatosController {
 	return &GetDatosController{useCase: useCase}
 }
 
+// parseDatosFilter lee los filtros comunes de las consultas de lecturas. Si alguno es inválido
+// responde 400 y devuelve ok=false.
+func parseDatosFilter(c *gin.Context) (domain.DatosFilter, bool) {
+	// Filtros opcionales: ?mac=AA:BB:...&etiqueta=freezer&etiqueta=cocina (o ?etiquetas=freezer,cocina)
+	filter := domain.DatosFilter{Mac: c.Query("mac"), Etiquetas: c.QueryArray("etiqueta")}
+	if etiquetas := c.Query("etiquetas"); etiquetas != "" {
+		filter.Etiquetas = append(filter.Etiquetas, strings.Split(etiquetas, ",")...)
+	}
+	var ok bool
+	// ?site_id=N / ?area_id=N: lecturas de todos los dispositivos bajo ese sitio o área
+	if filter.SiteID, ok = parseIDQuery(c, "site_id"); !ok {
+		return filter, false
+	}
+	if filter.AreaID, ok = parseIDQuery(c, "area_id"); !ok {
+		return filter, false
+	}
+	// ?from=&to=: rango [from, to) sobre la fecha de la lectura
+	if filter.Desde, ok = parseTimeQuery(c, "from"); !ok {
+		return filter, false
+	}
+	if filter.Hasta, ok = parseTimeQuery(c, "to"); !ok {
+		return filter, false
+	}
+	// ?where=temperatura > 30 and movimiento = si (ver domain.ParseMetricFilter)
+	if raw := c.Query("where"); raw != "" {
+		expr, err := domain.ParseMetricFilter(raw)
+		if err != nil {
+			detail := gin.H{"error": "Expresión 'where' inválida: " + err.Error()}
+			if parseErr, ok := err.(*domain.MetricFilterError); ok {
+				detail["posicion"] = parseErr.Posicion
+				detail["detalle"] = parseErr.Mensaje
+			}
+			c.JSON(http.StatusBadRequest, detail)
+			return filter, false
+		}
+		filter.Expresion = expr
+	}
+	return filter, true
+}
+
 func (gdc *GetDatosController) Execute(c *gin.Context) {
 	// --- OBTENER USER ID DEL CONTEXTO (Puesto por JWTMiddleware) ---
 	userIDValue, exists := c.Get("userID") // Usa la clave "userID"
@@ -37,45 +81,128 @@ func (gdc *GetDatosController) Execute(c *gin.Context) {
 	}
 	// --- FIN OBTENER USER ID ---
 
+	filter, ok := parseDatosFilter(c)
+	if !ok {
+		return
+	}
+
+	page, ok := parseDatosPageInput(c)
+	if !ok {
+		return
+	}
+
 	// Pasar el userID al caso de uso para filtrar
-	datos, err := gdc.useCase.Execute(userID) // Llama al caso de uso con el ID
+	result, err := gdc.useCase.Execute(userID, filter, page) // Llama al caso de uso con el ID
 	if err != nil {
-		log.Printf("ERROR: [GetCtrl] Falló la ejecución del caso de uso GetDatos para UserID %d: %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener los datos del sensor"})
+		respondDatosPageError(c, fmt.Sprintf("GetDatos para UserID %d", userID), err)
 		return
 	}
 
 	// Si no hay datos, devolver un array vacío en lugar de null
-	if datos == nil {
-		log.Println("INFO: [GetCtrl] No se encontraron datos para UserID %d. Devolviendo array vacío.", userID)
-		datos = []entities.Datos{}
+	if result.Datos == nil {
+		result.Datos = []entities.Datos{}
+	}
+
+	log.Printf("INFO: [GetCtrl] Devolviendo %d registros para UserID %d.", len(result.Datos), userID)
+	c.JSON(http.StatusOK, result) // {datos, next_cursor, has_more}
+}
+
+// ExecuteLatest maneja GET /datos/latest: la lectura más reciente de cada dispositivo accesible
+// (acepta los mismos filtros que GET /datos; ?to= da el estado a esa fecha)
+func (gdc *GetDatosController) ExecuteLatest(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "GetCtrl")
+	if !ok {
+		return
+	}
+	filter, ok := parseDatosFilter(c)
+	if !ok {
+		return
+	}
+	datos, err := gdc.useCase.ExecuteLatest(userID, filter)
+	if err != nil {
+		log.Printf("ERROR: [GetCtrl] Falló GetDatos.ExecuteLatest para UserID %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener las últimas lecturas"})
+		return
 	}
+	c.JSON(http.StatusOK, datos)
+}
 
-	log.Printf("INFO: [GetCtrl] Devolviendo %d registros para UserID %d.", len(datos), userID)
-	c.JSON(http.StatusOK, datos) // Devuelve los datos filtrados
+// ExecuteOne maneja GET /datos/:id. Las lecturas de dispositivos ajenos responden 404, igual
+// que las inexistentes.
+func (gdc *GetDatosController) ExecuteOne(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "GetCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "GetCtrl")
+	if !ok {
+		return
+	}
+	dato, err := gdc.useCase.ExecuteOne(userID, id)
+	if err != nil {
+		if err.Error() == "dato_no_encontrado" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dato no encontrado"})
+			return
+		}
+		log.Printf("ERROR: [GetCtrl] Falló GetDatos.ExecuteOne (ID: %d, UserID: %d): %v", id, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener el dato"})
+		return
+	}
+	c.JSON(http.StatusOK, dato)
 }
 
-// Opcional: Endpoint para Admin (si lo implementaste en el use case)
-/*
+// ExecuteAll maneja GET /admin/datos: todas las lecturas de la plataforma, con los filtros y la
+// paginación de GET /datos más ?user_id=N (lecturas atribuidas a ese usuario)
 func (gdc *GetDatosController) ExecuteAll(c *gin.Context) {
-    // Aquí verificarías el rol del usuario obtenido del contexto:
-    // userRoleValue, _ := c.Get("userRole")
-    // userRole, _ := userRoleValue.(string)
-    // if userRole != "admin" {
-    //     c.JSON(http.StatusForbidden, gin.H{"error": "Acceso denegado: requiere rol de administrador"})
-    //     return
-    // }
-
-    datos, err := gdc.useCase.ExecuteAll() // Llama al método sin filtro
-    if err != nil {
-        log.Printf("ERROR: [GetCtrl] Falló la ejecución del caso de uso ExecuteAll (admin): %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener todos los datos"})
-        return
-    }
-     if datos == nil {
-        datos = []entities.Datos{}
-    }
-    log.Printf("INFO: [GetCtrl] Devolviendo %d registros en total (admin).", len(datos))
-    c.JSON(http.StatusOK, datos)
+	filter, ok := parseDatosFilter(c)
+	if !ok {
+		return
+	}
+	if filter.UserID, ok = parseIDQuery(c, "user_id"); !ok {
+		return
+	}
+	page, ok := parseDatosPageInput(c)
+	if !ok {
+		return
+	}
+	result, err := gdc.useCase.ExecuteAll(filter, page)
+	if err != nil {
+		respondDatosPageError(c, "GetDatos.ExecuteAll", err)
+		return
+	}
+	if result.Datos == nil {
+		result.Datos = []entities.Datos{}
+	}
+	log.Printf("INFO: [GetCtrl] Devolviendo %d registros en total (admin).", len(result.Datos))
+	c.JSON(http.StatusOK, result)
+}
+
+// parseDatosPageInput lee la paginación: ?limit=N&cursor=<next_cursor>&sort=desc|asc
+func parseDatosPageInput(c *gin.Context) (application.DatosPageInput, bool) {
+	page := application.DatosPageInput{Cursor: c.Query("cursor"), Orden: c.Query("sort")}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'limit' inválido"})
+			return page, false
+		}
+		page.Limit = limit
+	}
+	return page, true
+}
+
+func respondDatosPageError(c *gin.Context, useCase string, err error) {
+	switch err.Error() {
+	case "limite_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("'limit' debe estar entre 1 y %d", application.DatosPageMax)})
+	case "orden_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'sort' inválido: use asc o desc"})
+	case "cursor_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor inválido o de otra consulta"})
+	case "rango_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' debe ser anterior a 'to'"})
+	default:
+		log.Printf("ERROR: [GetCtrl] Falló la ejecución del caso de uso %s: %v", useCase, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener los datos del sensor"})
+	}
 }
-*/
\ No newline at end of file
@@ -0,0 +1,53 @@
+// File: getDevices_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetDevicesController struct {
+	useCase application.GetDevices
+}
+
+func NewGetDevicesController(useCase application.GetDevices) *GetDevicesController {
+	return &GetDevicesController{useCase: useCase}
+}
+
+// Execute maneja GET /devices
+func (ctrl *GetDevicesController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "GetDevicesCtrl")
+	if !ok {
+		return
+	}
+	devices, err := ctrl.useCase.Execute(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener los dispositivos"})
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+// ExecuteOne maneja GET /devices/:mac
+func (ctrl *GetDevicesController) ExecuteOne(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "GetDevicesCtrl")
+	if !ok {
+		return
+	}
+	mac := c.Param("mac")
+	device, err := ctrl.useCase.ExecuteOne(userID, mac)
+	if err != nil {
+		if err.Error() == "dispositivo_no_encontrado" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+			return
+		}
+		log.Printf("ERROR: [GetDevicesCtrl] Falló al obtener MAC %s para UserID %d: %v", mac, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener el dispositivo"})
+		return
+	}
+	c.JSON(http.StatusOK, device)
+}
@@ -0,0 +1,40 @@
+// File: getSiteSummary_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetSiteSummaryController struct {
+	useCase application.GetSiteSummary
+}
+
+func NewGetSiteSummaryController(useCase application.GetSiteSummary) *GetSiteSummaryController {
+	return &GetSiteSummaryController{useCase: useCase}
+}
+
+// Execute maneja GET /sites/:id/resumen[?area_id=N]
+func (ctrl *GetSiteSummaryController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SiteSummaryCtrl")
+	if !ok {
+		return
+	}
+	siteID, ok := parseIDParam(c, "id", "SiteSummaryCtrl")
+	if !ok {
+		return
+	}
+	areaID, ok := parseIDQuery(c, "area_id")
+	if !ok {
+		return
+	}
+	summary, err := ctrl.useCase.Execute(userID, siteID, areaID)
+	if err != nil {
+		respondSiteError(c, err, "SiteSummaryCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,211 @@
+// File: inventory_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"API/src/Sensores/domain/entities"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InventoryController struct {
+	useCase application.Inventory
+}
+
+func NewInventoryController(useCase application.Inventory) *InventoryController {
+	return &InventoryController{useCase: useCase}
+}
+
+type productRequest struct {
+	Mac          string  `json:"mac"` // Solo al crear: dispositivo cuya balanza guarda el producto
+	Nombre       string  `json:"nombre" binding:"required"`
+	PesoUnitario float64 `json:"peso_unitario" binding:"required"`
+	Tara         float64 `json:"tara"`
+	UmbralMinimo *int    `json:"umbral_minimo"` // Unidades; null = sin aviso de stock bajo
+}
+
+func (req productRequest) input() application.ProductInput {
+	return application.ProductInput{Nombre: req.Nombre, PesoUnitario: req.PesoUnitario, Tara: req.Tara, UmbralMinimo: req.UmbralMinimo}
+}
+
+// respondInventoryError traduce los errores de Inventory a respuestas HTTP
+func respondInventoryError(c *gin.Context, err error, tag string) {
+	switch err.Error() {
+	case "producto_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Producto no encontrado"})
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	case "permiso_insuficiente":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite modificar el inventario de este dispositivo"})
+	case "producto_duplicado":
+		c.JSON(http.StatusConflict, gin.H{"error": "El dispositivo ya tiene un producto configurado"})
+	case "nombre_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre es requerido (máximo 100 caracteres)"})
+	case "peso_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'peso_unitario' debe ser positivo y 'tara' no puede ser negativa"})
+	case "umbral_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'umbral_minimo' no puede ser negativo"})
+	case "rango_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' debe ser anterior a 'to' (máximo 366 días en el reporte de consumo)"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar el inventario"})
+	}
+}
+
+// List maneja GET /products?estado=bajo: productos de los dispositivos accesibles con su stock
+func (ctrl *InventoryController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "InventoryCtrl")
+	if !ok {
+		return
+	}
+	estado := c.Query("estado")
+	if estado != "" && estado != entities.StockBajo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'estado' inválido: use bajo"})
+		return
+	}
+	products, err := ctrl.useCase.List(userID, estado == entities.StockBajo)
+	if err != nil {
+		respondInventoryError(c, err, "InventoryCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, products)
+}
+
+// Get maneja GET /products/:id
+func (ctrl *InventoryController) Get(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "InventoryCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "InventoryCtrl")
+	if !ok {
+		return
+	}
+	product, err := ctrl.useCase.Get(userID, id)
+	if err != nil {
+		respondInventoryError(c, err, "InventoryCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
+
+// Create maneja POST /products
+func (ctrl *InventoryController) Create(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "InventoryCtrl")
+	if !ok {
+		return
+	}
+	var req productRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Mac == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'mac', 'nombre' y 'peso_unitario'"})
+		return
+	}
+	product, err := ctrl.useCase.Create(userID, req.Mac, req.input())
+	if err != nil {
+		respondInventoryError(c, err, "InventoryCtrl")
+		return
+	}
+	c.JSON(http.StatusCreated, product)
+}
+
+// Update maneja PUT /products/:id (el dispositivo no se cambia)
+func (ctrl *InventoryController) Update(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "InventoryCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "InventoryCtrl")
+	if !ok {
+		return
+	}
+	var req productRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre' y 'peso_unitario'", "detail": err.Error()})
+		return
+	}
+	product, err := ctrl.useCase.Update(userID, id, req.input())
+	if err != nil {
+		respondInventoryError(c, err, "InventoryCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
+
+// Delete maneja DELETE /products/:id (también borra su historial de movimientos)
+func (ctrl *InventoryController) Delete(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "InventoryCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "InventoryCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.Delete(userID, id); err != nil {
+		respondInventoryError(c, err, "InventoryCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Producto eliminado"})
+}
+
+// Movements maneja GET /products/:id/movements?from=&to=&limit=: reposiciones y consumos en el
+// rango (por defecto, las últimas 24 horas), del más reciente al más antiguo
+func (ctrl *InventoryController) Movements(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "InventoryCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "InventoryCtrl")
+	if !ok {
+		return
+	}
+	desde, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	hasta, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+	limit, ok := parseIDQuery(c, "limit")
+	if !ok {
+		return
+	}
+	movements, err := ctrl.useCase.Movements(userID, id, desde, hasta, limit)
+	if err != nil {
+		respondInventoryError(c, err, "InventoryCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, movements)
+}
+
+// Consumption maneja GET /products/:id/consumption?from=&to=: consumo por día entre dos fechas
+// (AAAA-MM-DD, ambas incluidas; por defecto, los últimos 30 días)
+func (ctrl *InventoryController) Consumption(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "InventoryCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "InventoryCtrl")
+	if !ok {
+		return
+	}
+	desde, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	hasta, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+	report, err := ctrl.useCase.Consumption(userID, id, desde, hasta)
+	if err != nil {
+		respondInventoryError(c, err, "InventoryCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
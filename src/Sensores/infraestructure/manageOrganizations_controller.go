@@ -0,0 +1,196 @@
+// File: manageOrganizations_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ManageOrganizationsController struct {
+	useCase application.ManageOrganizations
+}
+
+func NewManageOrganizationsController(useCase application.ManageOrganizations) *ManageOrganizationsController {
+	return &ManageOrganizationsController{useCase: useCase}
+}
+
+type organizationRequest struct {
+	Nombre string `json:"nombre" binding:"required"`
+}
+
+type organizationMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Rol      string `json:"rol" binding:"required"` // owner | admin | member | viewer
+}
+
+type deviceOrganizationRequest struct {
+	OrgID int `json:"org_id" binding:"required"`
+}
+
+// respondOrgError traduce los errores de ManageOrganizations a respuestas HTTP
+func respondOrgError(c *gin.Context, err error, tag string) {
+	switch err.Error() {
+	case "organizacion_no_encontrada":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organización no encontrada"})
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	case "usuario_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+	case "miembro_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "El usuario no es miembro de la organización"})
+	case "permiso_insuficiente":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite esta operación"})
+	case "ultimo_owner":
+		c.JSON(http.StatusConflict, gin.H{"error": "La organización debe conservar al menos un owner"})
+	case "rol_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rol inválido: use owner, admin, member o viewer"})
+	case "nombre_requerido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre es requerido"})
+	case "metadatos_demasiado_largos":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre excede la longitud máxima (100)"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar organizaciones"})
+	}
+}
+
+// List maneja GET /organizations
+func (ctrl *ManageOrganizationsController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OrgsCtrl")
+	if !ok {
+		return
+	}
+	orgs, err := ctrl.useCase.List(userID)
+	if err != nil {
+		respondOrgError(c, err, "OrgsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// Get maneja GET /organizations/:id (con miembros)
+func (ctrl *ManageOrganizationsController) Get(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OrgsCtrl")
+	if !ok {
+		return
+	}
+	orgID, ok := parseIDParam(c, "id", "OrgsCtrl")
+	if !ok {
+		return
+	}
+	org, err := ctrl.useCase.Get(userID, orgID)
+	if err != nil {
+		respondOrgError(c, err, "OrgsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// Create maneja POST /organizations (el usuario queda como owner)
+func (ctrl *ManageOrganizationsController) Create(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OrgsCtrl")
+	if !ok {
+		return
+	}
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre'", "detail": err.Error()})
+		return
+	}
+	org, err := ctrl.useCase.Create(userID, req.Nombre)
+	if err != nil {
+		respondOrgError(c, err, "OrgsCtrl")
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// Update maneja PUT /organizations/:id
+func (ctrl *ManageOrganizationsController) Update(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OrgsCtrl")
+	if !ok {
+		return
+	}
+	orgID, ok := parseIDParam(c, "id", "OrgsCtrl")
+	if !ok {
+		return
+	}
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre'", "detail": err.Error()})
+		return
+	}
+	org, err := ctrl.useCase.Update(userID, orgID, req.Nombre)
+	if err != nil {
+		respondOrgError(c, err, "OrgsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// SetMember maneja PUT /organizations/:id/members (añade o cambia el rol)
+func (ctrl *ManageOrganizationsController) SetMember(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OrgsCtrl")
+	if !ok {
+		return
+	}
+	orgID, ok := parseIDParam(c, "id", "OrgsCtrl")
+	if !ok {
+		return
+	}
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requieren 'username' y 'rol'", "detail": err.Error()})
+		return
+	}
+	org, err := ctrl.useCase.SetMember(userID, orgID, req.Username, req.Rol)
+	if err != nil {
+		respondOrgError(c, err, "OrgsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// RemoveMember maneja DELETE /organizations/:id/members/:userId
+func (ctrl *ManageOrganizationsController) RemoveMember(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OrgsCtrl")
+	if !ok {
+		return
+	}
+	orgID, ok := parseIDParam(c, "id", "OrgsCtrl")
+	if !ok {
+		return
+	}
+	targetUserID, ok := parseIDParam(c, "userId", "OrgsCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.RemoveMember(userID, orgID, targetUserID); err != nil {
+		respondOrgError(c, err, "OrgsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Miembro quitado de la organización"})
+}
+
+// MoveDevice maneja PUT /devices/:mac/organizacion
+func (ctrl *ManageOrganizationsController) MoveDevice(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "OrgsCtrl")
+	if !ok {
+		return
+	}
+	var req deviceOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cuerpo inválido: se espera {\"org_id\": <id>}"})
+		return
+	}
+	mac := c.Param("mac")
+	if err := ctrl.useCase.MoveDevice(userID, mac, req.OrgID); err != nil {
+		respondOrgError(c, err, "OrgsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dispositivo movido de organización", "mac": mac, "org_id": req.OrgID})
+}
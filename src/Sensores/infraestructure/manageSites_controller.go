@@ -0,0 +1,228 @@
+// File: manageSites_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ManageSitesController struct {
+	useCase application.ManageSites
+}
+
+func NewManageSitesController(useCase application.ManageSites) *ManageSitesController {
+	return &ManageSitesController{useCase: useCase}
+}
+
+// siteRequest sirve para sitios y áreas
+type siteRequest struct {
+	Nombre      string `json:"nombre" binding:"required"`
+	Descripcion string `json:"descripcion"`
+	OrgID       int    `json:"org_id"` // Solo al crear un sitio; 0 = organización personal
+}
+
+type deviceAreaRequest struct {
+	AreaID *int `json:"area_id"` // null = quitar el dispositivo de su área
+}
+
+// respondSiteError traduce los errores de ManageSites a respuestas HTTP
+func respondSiteError(c *gin.Context, err error, tag string) {
+	switch err.Error() {
+	case "sitio_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sitio no encontrado"})
+	case "area_no_encontrada":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Área no encontrada"})
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	case "organizacion_no_encontrada":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organización no encontrada"})
+	case "permiso_insuficiente":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite esta operación"})
+	case "organizacion_distinta":
+		c.JSON(http.StatusConflict, gin.H{"error": "El dispositivo y el área pertenecen a organizaciones distintas"})
+	case "nombre_requerido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre es requerido"})
+	case "metadatos_demasiado_largos":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nombre (100) o descripción (500) exceden la longitud máxima"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar sitios"})
+	}
+}
+
+// List maneja GET /sites
+func (ctrl *ManageSitesController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	sites, err := ctrl.useCase.List(userID)
+	if err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, sites)
+}
+
+// Get maneja GET /sites/:id (árbol sitio > áreas > dispositivos)
+func (ctrl *ManageSitesController) Get(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	siteID, ok := parseIDParam(c, "id", "SitesCtrl")
+	if !ok {
+		return
+	}
+	site, err := ctrl.useCase.Get(userID, siteID)
+	if err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, site)
+}
+
+// Create maneja POST /sites (opcionalmente con "org_id")
+func (ctrl *ManageSitesController) Create(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	var req siteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre'", "detail": err.Error()})
+		return
+	}
+	site, err := ctrl.useCase.Create(userID, req.OrgID, application.SiteInput{Nombre: req.Nombre, Descripcion: req.Descripcion})
+	if err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusCreated, site)
+}
+
+// Update maneja PUT /sites/:id
+func (ctrl *ManageSitesController) Update(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	siteID, ok := parseIDParam(c, "id", "SitesCtrl")
+	if !ok {
+		return
+	}
+	var req siteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre'", "detail": err.Error()})
+		return
+	}
+	site, err := ctrl.useCase.Update(userID, siteID, application.SiteInput{Nombre: req.Nombre, Descripcion: req.Descripcion})
+	if err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, site)
+}
+
+// Delete maneja DELETE /sites/:id (sus dispositivos quedan sin área)
+func (ctrl *ManageSitesController) Delete(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	siteID, ok := parseIDParam(c, "id", "SitesCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.Delete(userID, siteID); err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Sitio eliminado exitosamente"})
+}
+
+// CreateArea maneja POST /sites/:id/areas
+func (ctrl *ManageSitesController) CreateArea(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	siteID, ok := parseIDParam(c, "id", "SitesCtrl")
+	if !ok {
+		return
+	}
+	var req siteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre'", "detail": err.Error()})
+		return
+	}
+	area, err := ctrl.useCase.CreateArea(userID, siteID, application.SiteInput{Nombre: req.Nombre, Descripcion: req.Descripcion})
+	if err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusCreated, area)
+}
+
+// UpdateArea maneja PUT /areas/:id
+func (ctrl *ManageSitesController) UpdateArea(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	areaID, ok := parseIDParam(c, "id", "SitesCtrl")
+	if !ok {
+		return
+	}
+	var req siteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'nombre'", "detail": err.Error()})
+		return
+	}
+	area, err := ctrl.useCase.UpdateArea(userID, areaID, application.SiteInput{Nombre: req.Nombre, Descripcion: req.Descripcion})
+	if err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, area)
+}
+
+// DeleteArea maneja DELETE /areas/:id
+func (ctrl *ManageSitesController) DeleteArea(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	areaID, ok := parseIDParam(c, "id", "SitesCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.DeleteArea(userID, areaID); err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Área eliminada exitosamente"})
+}
+
+// AssignDevice maneja PUT /devices/:mac/area
+func (ctrl *ManageSitesController) AssignDevice(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "SitesCtrl")
+	if !ok {
+		return
+	}
+	var req deviceAreaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cuerpo inválido: se espera {\"area_id\": <id> | null}"})
+		return
+	}
+	device, err := ctrl.useCase.AssignDevice(userID, c.Param("mac"), req.AreaID)
+	if err != nil {
+		respondSiteError(c, err, "SitesCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, device)
+}
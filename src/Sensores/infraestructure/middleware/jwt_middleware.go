@@ -34,65 +34,71 @@ func JWTMiddleware() gin.HandlerFunc {
 		}
 		tokenString := parts[1]
 
-		// 3. Obtener clave secreta del entorno
-		secretKey := os.Getenv("JWT_SECRET_KEY")
-		if secretKey == "" {
-			log.Println("ERROR: [AuthMW] JWT_SECRET_KEY no configurada en el servidor")
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error de configuración del servidor"})
-			return
-		}
-
-		// 4. Parsear y validar el token JWT
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Valida que el alg sea el esperado (HS256 en este caso)
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
-			}
-			// Devuelve la clave secreta como []byte
-			return []byte(secretKey), nil
-		})
-
-		// 5. Manejar errores de parseo/validación
+		// 3-6. Validar el token y extraer los claims
+		userID, username, role, err := ParseToken(tokenString)
 		if err != nil {
 			log.Printf("WARN: [AuthMW] Error al parsear/validar token: %v", err)
-			errMsg := "Token inválido"
-			// Ser más específico si el token expiró
+			status, errMsg := http.StatusUnauthorized, "Token inválido"
+			// Ser más específico si el token expiró o si falta configuración
 			if errors.Is(err, jwt.ErrTokenExpired) {
 				errMsg = "Token expirado"
+			} else if errors.Is(err, errMissingSecret) {
+				status, errMsg = http.StatusInternalServerError, "Error de configuración del servidor"
 			}
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errMsg})
+			c.AbortWithStatusJSON(status, gin.H{"error": errMsg})
 			return
 		}
 
-		// 6. Extraer claims si el token es válido
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Extraer user_id (viene como float64 de JSON)
-			userIDFloat, okUserID := claims["user_id"].(float64)
-			if !okUserID {
-				log.Println("ERROR: [AuthMW] user_id no encontrado o tipo inválido en claims JWT")
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token inválido (claim user_id)"})
-				return
-			}
-			userID := int(userIDFloat) // Convertir a int
+		// 7. Guardar información en el contexto de Gin
+		c.Set("userID", userID)     // Clave "userID"
+		c.Set("username", username) // Clave "username"
+		c.Set("userRole", role)     // Clave "userRole"
 
-			// Extraer otros claims opcionales (username, role)
-			username, _ := claims["username"].(string) // Ignorar error si no está
-			role, _ := claims["role"].(string)         // Ignorar error si no está
+		log.Printf("INFO: [AuthMW] Token válido. Usuario autenticado: ID=%d, Username=%s, Role=%s", userID, username, role)
 
-			// 7. Guardar información en el contexto de Gin
-			c.Set("userID", userID)     // Clave "userID"
-			c.Set("username", username) // Clave "username"
-			c.Set("userRole", role)     // Clave "userRole"
+		// 8. Continuar con el siguiente manejador en la cadena
+		c.Next()
+	}
+}
 
-			log.Printf("INFO: [AuthMW] Token válido. Usuario autenticado: ID=%d, Username=%s, Role=%s", userID, username, role)
+var errMissingSecret = errors.New("JWT_SECRET_KEY no configurada en el servidor")
 
-			// 8. Continuar con el siguiente manejador en la cadena
-			c.Next()
-		} else {
-			// Caso raro donde el token no es válido después de parsear sin error
-			log.Println("WARN: [AuthMW] Token JWT inválido (claims no ok o token no válido)")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token inválido"})
-			return
+// ParseToken valida un JWT firmado con JWT_SECRET_KEY y devuelve sus claims.
+// Lo usan JWTMiddleware y la conexión WebSocket (?token=), que no puede enviar cabeceras.
+func ParseToken(tokenString string) (int, string, string, error) {
+	// Obtener clave secreta del entorno
+	secretKey := os.Getenv("JWT_SECRET_KEY")
+	if secretKey == "" {
+		log.Println("ERROR: [AuthMW] JWT_SECRET_KEY no configurada en el servidor")
+		return 0, "", "", errMissingSecret
+	}
+
+	// Parsear y validar el token JWT
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Valida que el alg sea el esperado (HS256 en este caso)
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
 		}
+		// Devuelve la clave secreta como []byte
+		return []byte(secretKey), nil
+	})
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	// Extraer claims si el token es válido
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		// Caso raro donde el token no es válido después de parsear sin error
+		return 0, "", "", errors.New("token JWT inválido (claims no ok o token no válido)")
+	}
+	// Extraer user_id (viene como float64 de JSON)
+	userIDFloat, okUserID := claims["user_id"].(float64)
+	if !okUserID {
+		return 0, "", "", errors.New("user_id no encontrado o tipo inválido en claims JWT")
 	}
-}
\ No newline at end of file
+	// Extraer otros claims opcionales (username, role)
+	username, _ := claims["username"].(string) // Ignorar error si no está
+	role, _ := claims["role"].(string)         // Ignorar error si no está
+	return int(userIDFloat), username, role, nil
+}
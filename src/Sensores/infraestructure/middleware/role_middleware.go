@@ -0,0 +1,26 @@
+// File: role_middleware.go
+
+package middleware
+
+import (
+	"API/src/Sensores/domain"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePlatformOperator deja pasar solo a los operadores de la plataforma (domain.IsPlatformOperator).
+// Usa el rol puesto por JWTMiddleware, que debe ir antes en la cadena; protege todo el grupo /admin.
+func RequirePlatformOperator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRoleValue, _ := c.Get("userRole")
+		userRole, _ := userRoleValue.(string)
+		if !domain.IsPlatformOperator(userRole) {
+			log.Printf("WARN: [RoleMW] Acceso denegado a %s %s para el rol '%s'", c.Request.Method, c.FullPath(), userRole)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Acceso denegado: requiere rol de operador de la plataforma"})
+			return
+		}
+		c.Next()
+	}
+}
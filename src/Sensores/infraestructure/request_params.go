@@ -0,0 +1,55 @@
+// File: request_params.go
+
+package infraestructure
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseIDParam lee un ID positivo de la ruta (p.ej. :id). Si es inválido responde 400 y devuelve ok=false.
+func parseIDParam(c *gin.Context, name string, tag string) (int, bool) {
+	raw := c.Param(name)
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		log.Printf("ERROR: [%s] %s inválido en la URL: '%s'", tag, name, raw)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido proporcionado"})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseIDQuery lee un ID opcional del query string (0 si no viene). Si es inválido responde 400.
+func parseIDQuery(c *gin.Context, name string) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro '" + name + "' inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseTimeQuery lee un instante opcional del query string (cero si no viene): RFC3339 o
+// AAAA-MM-DD (medianoche hora local del servidor). Si es inválido responde 400.
+func parseTimeQuery(c *gin.Context, name string) (time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+		return t, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro '" + name + "' inválido: use RFC3339 o AAAA-MM-DD"})
+	return time.Time{}, false
+}
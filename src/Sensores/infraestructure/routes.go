@@ -6,11 +6,13 @@ import (
 	"API/src/core"                                          // Importar core para la conexión
 	sensorApp "API/src/Sensores/application"                // Alias para claridad
 	sensorAdapters "API/src/Sensores/infraestructure/adapters" // Alias
+	authMW "API/src/Sensores/infraestructure/middleware"
 	infraWS "API/src/Sensores/infraestructure/websocket"
 	userDomain "API/src/Sensores/domain" // Importar el paquete que define UserRepository
 	// La dependencia de userAdapters puede ser necesaria aquí si se instancia aquí
 	// o si se pasa el repo ya creado desde main.go
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin" // Necesario para gin.HandlerFunc
 )
@@ -34,6 +36,23 @@ func SetupRoutesDatos(r *gin.Engine, wsManager *infraWS.Manager, dbConn *core.Co
 	// --- 1. Crear Adaptadores ---
 	dbSensorAdapter := sensorAdapters.NewMySQLRutas(dbConn)
 	log.Println("INFO: Adaptador MySQL para Sensores creado.")
+	deviceRepo := sensorAdapters.NewMySQLDeviceRepository(dbConn)
+	ownershipRepo := sensorAdapters.NewMySQLOwnershipRepository(dbConn)
+	siteRepo := sensorAdapters.NewMySQLSiteRepository(dbConn)
+	orgRepo := sensorAdapters.NewMySQLOrganizationRepository(dbConn)
+	shareRepo := sensorAdapters.NewMySQLShareRepository(dbConn)
+	mailer := sensorAdapters.NewSMTPMailerFromEnv()
+	exportJobRepo := sensorAdapters.NewMySQLExportJobRepository(dbConn)
+	rollupRepo := sensorAdapters.NewMySQLRollupRepository(dbConn)
+	archiveStore := sensorAdapters.NewParquetArchiveStoreFromEnv()
+	motionEventRepo := sensorAdapters.NewMySQLMotionEventRepository(dbConn)
+	weightEventRepo := sensorAdapters.NewMySQLWeightEventRepository(dbConn)
+	containerRepo := sensorAdapters.NewMySQLContainerRepository(dbConn)
+	productRepo := sensorAdapters.NewMySQLProductRepository(dbConn)
+	complianceRepo := sensorAdapters.NewMySQLComplianceRepository(dbConn)
+	scheduledReportRepo := sensorAdapters.NewMySQLScheduledReportRepository(dbConn)
+	anomalyRepo := sensorAdapters.NewMySQLAnomalyRepository(dbConn)
+	derivedMetricRepo := sensorAdapters.NewMySQLDerivedMetricRepository(dbConn)
 
 	// userRepo ya viene inyectado desde main.go
 
@@ -41,18 +60,70 @@ func SetupRoutesDatos(r *gin.Engine, wsManager *infraWS.Manager, dbConn *core.Co
 	log.Println("INFO: Adaptador WebSocketNotifier creado.")
 
 	// --- 2. Crear Casos de Uso ---
-	// CreateDatos necesita el userRepo (que ya recibimos)
-	createDatosUseCase := sensorApp.NewCreateDatos(dbSensorAdapter, userRepo, wsNotifierAdapter)
-	getDatosUseCase := sensorApp.NewGetDatos(dbSensorAdapter)
-	updateDatosUseCase := sensorApp.NewUpdateDatos(dbSensorAdapter) // Podría necesitar userRepo si valida pertenencia
-	deleteDatosUseCase := sensorApp.NewDeleteDatos(dbSensorAdapter) // Podría necesitar userRepo si valida pertenencia
+	motionEventsUseCase := sensorApp.NewMotionEvents(motionEventRepo, deviceRepo, userRepo, orgRepo, shareRepo, wsNotifierAdapter)
+	go motionEventsUseCase.Run() // Cierra los intervalos de movimiento de dispositivos que dejaron de reportar
+	inventoryUseCase := sensorApp.NewInventory(productRepo, deviceRepo, userRepo, orgRepo, shareRepo)
+	anomalyDetectionUseCase := sensorApp.NewAnomalyDetection(anomalyRepo, userRepo, orgRepo, shareRepo, wsNotifierAdapter)
+	weightEventsUseCase := sensorApp.NewWeightEvents(weightEventRepo, userRepo, orgRepo, shareRepo, wsNotifierAdapter, inventoryUseCase) // Cada cambio de peso mueve el inventario
+	derivedMetricsUseCase := sensorApp.NewDerivedMetrics(derivedMetricRepo, dbSensorAdapter, userRepo, orgRepo, shareRepo)
+	// CreateDatos necesita el userRepo (que ya recibimos); cada lectura alimenta los intervalos de
+	// movimiento, la detección de cambios de peso, la de anomalías y las métricas derivadas
+	createDatosUseCase := sensorApp.NewCreateDatos(dbSensorAdapter, userRepo, deviceRepo, orgRepo, wsNotifierAdapter, containerRepo, motionEventsUseCase, weightEventsUseCase, anomalyDetectionUseCase, derivedMetricsUseCase)
+	getDatosUseCase := sensorApp.NewGetDatos(dbSensorAdapter, deviceRepo, containerRepo, anomalyRepo, derivedMetricRepo)
+	aggregateDatosUseCase := sensorApp.NewAggregateDatos(dbSensorAdapter, rollupRepo, deviceRepo, derivedMetricRepo)
+	rollupDatosUseCase := sensorApp.NewRollupDatos(rollupRepo)
+	go rollupDatosUseCase.Run() // Mantiene los resúmenes por hora y día que usa GET /datos/aggregate
+	exportDatosUseCase := sensorApp.NewExportDatos(dbSensorAdapter)
+	exportJobsUseCase := sensorApp.NewExportJobs(exportJobRepo, dbSensorAdapter, archiveStore)
+	storageUsageUseCase := sensorApp.NewStorageUsage(dbSensorAdapter, exportJobRepo)
+	go exportJobsUseCase.Run() // Procesa las exportaciones Parquet en segundo plano
+	updateDatosUseCase := sensorApp.NewUpdateDatos(dbSensorAdapter, orgRepo, userRepo) // El repo valida el rol del usuario en la organización
+	deleteDatosUseCase := sensorApp.NewDeleteDatos(dbSensorAdapter) // El repo valida el rol del usuario en la organización
+	getDevicesUseCase := sensorApp.NewGetDevices(deviceRepo, userRepo, orgRepo, shareRepo)
+	deviceStatsUseCase := sensorApp.NewDeviceStats(dbSensorAdapter, deviceRepo, userRepo, orgRepo, shareRepo)
+	dataGapsUseCase := sensorApp.NewDataGaps(dbSensorAdapter, deviceRepo, userRepo, orgRepo, shareRepo)
+	forecastingUseCase := sensorApp.NewForecasting(dbSensorAdapter, productRepo, containerRepo, complianceRepo, userRepo, orgRepo, shareRepo)
+	deviceContainerUseCase := sensorApp.NewDeviceContainer(containerRepo, userRepo, orgRepo, shareRepo)
+	complianceReportsUseCase := sensorApp.NewComplianceReports(complianceRepo, dbSensorAdapter, userRepo, orgRepo, shareRepo)
+	go complianceReportsUseCase.Run() // Genera los reportes de cumplimiento diarios y semanales
+	scheduledReportsUseCase := sensorApp.NewScheduledReports(scheduledReportRepo, dbSensorAdapter, deviceRepo, productRepo, containerRepo, complianceRepo, mailer)
+	go scheduledReportsUseCase.Run() // Envía por email los reportes de las suscripciones
+	updateDeviceUseCase := sensorApp.NewUpdateDevice(deviceRepo, userRepo, orgRepo)
+	deviceOwnershipUseCase := sensorApp.NewDeviceOwnership(ownershipRepo, userRepo)
+	manageSitesUseCase := sensorApp.NewManageSites(siteRepo, deviceRepo, userRepo, orgRepo)
+	getSiteSummaryUseCase := sensorApp.NewGetSiteSummary(dbSensorAdapter, siteRepo, deviceRepo, orgRepo)
+	subscriptionAuthorizer := sensorApp.NewSubscriptionAuthorizer(siteRepo, userRepo, orgRepo, shareRepo)
+	manageOrganizationsUseCase := sensorApp.NewManageOrganizations(orgRepo, userRepo, siteRepo, shareRepo, wsManager)
+	shareDevicesUseCase := sensorApp.NewShareDevices(shareRepo, orgRepo, userRepo, mailer, wsManager) // wsManager corta el feed al revocar
 	log.Println("INFO: Casos de uso de Sensores creados e inyectados.")
 
 	// --- 3. Crear Controladores ---
 	createDatosController := NewCreateDatosController(*createDatosUseCase)
 	getDatosController := NewGetDatosController(*getDatosUseCase)
+	aggregateDatosController := NewAggregateDatosController(*aggregateDatosUseCase)
+	exportDatosController := NewExportDatosController(*exportDatosUseCase)
+	exportJobsController := NewExportJobsController(*exportJobsUseCase)
+	storageUsageController := NewStorageUsageController(*storageUsageUseCase)
 	updateDatosController := NewUpdateDatosController(*updateDatosUseCase)
 	deleteDatosController := NewDeleteDatosController(*deleteDatosUseCase)
+	getDevicesController := NewGetDevicesController(*getDevicesUseCase)
+	deviceStatsController := NewDeviceStatsController(*deviceStatsUseCase)
+	motionEventsController := NewMotionEventsController(*motionEventsUseCase)
+	weightEventsController := NewWeightEventsController(*weightEventsUseCase)
+	anomalyDetectionController := NewAnomalyDetectionController(*anomalyDetectionUseCase)
+	dataGapsController := NewDataGapsController(*dataGapsUseCase)
+	derivedMetricsController := NewDerivedMetricsController(*derivedMetricsUseCase)
+	forecastController := NewForecastController(*forecastingUseCase)
+	containerController := NewContainerController(*deviceContainerUseCase)
+	inventoryController := NewInventoryController(*inventoryUseCase)
+	complianceReportsController := NewComplianceReportsController(*complianceReportsUseCase)
+	scheduledReportsController := NewScheduledReportsController(*scheduledReportsUseCase)
+	updateDeviceController := NewUpdateDeviceController(*updateDeviceUseCase)
+	deviceOwnershipController := NewDeviceOwnershipController(*deviceOwnershipUseCase)
+	manageSitesController := NewManageSitesController(*manageSitesUseCase)
+	getSiteSummaryController := NewGetSiteSummaryController(*getSiteSummaryUseCase)
+	manageOrganizationsController := NewManageOrganizationsController(*manageOrganizationsUseCase)
+	shareDevicesController := NewShareDevicesController(*shareDevicesUseCase)
 	log.Println("INFO: Controladores HTTP de Sensores creados.")
 
 	// --- 4. Definir Rutas HTTP ---
@@ -66,11 +137,169 @@ func SetupRoutesDatos(r *gin.Engine, wsManager *infraWS.Manager, dbConn *core.Co
 	datosGroup.Use(authMiddleware) // <--- APLICAR MIDDLEWARE JWT A ESTE GRUPO
 	{
 		datosGroup.GET("", getDatosController.Execute)          // Protegido
+		datosGroup.GET("/aggregate", aggregateDatosController.Execute) // Estadísticas por cubetas de tiempo
+		datosGroup.GET("/latest", getDatosController.ExecuteLatest)      // Última lectura de cada dispositivo
+		datosGroup.GET("/export", exportDatosController.Execute)         // Descarga CSV / NDJSON en streaming
+		datosGroup.POST("/export/parquet", exportJobsController.Create)  // Exportación Parquet asíncrona
+		datosGroup.GET("/:id", getDatosController.ExecuteOne)            // Una lectura (404 si es ajena)
 		datosGroup.PUT("/:id", updateDatosController.Execute)   // Protegido
 		datosGroup.DELETE("/:id", deleteDatosController.Execute) // Protegido
-
-		// Opcional: Ruta admin (si la implementas)
-		// datosGroup.GET("/all", getDatosController.ExecuteAll) // Necesitaría check de rol adicional
 	}
 	log.Println("INFO: Rutas HTTP para /datos (frontend) configuradas y protegidas por JWT.")
+
+	// Metadatos de dispositivos (nombre, descripción, ubicación, etiquetas), según el rol en su organización
+	devicesGroup := r.Group("/devices")
+	devicesGroup.Use(authMiddleware)
+	{
+		devicesGroup.GET("", getDevicesController.Execute)
+		devicesGroup.GET("/stats", deviceStatsController.Execute) // Estadísticas de todos los dispositivos (caché breve)
+		devicesGroup.GET("/:mac", getDevicesController.ExecuteOne)
+		devicesGroup.GET("/:mac/stats", deviceStatsController.ExecuteOne)
+		devicesGroup.GET("/:mac/motion-events", motionEventsController.List)
+		devicesGroup.GET("/:mac/weight-events", weightEventsController.List)
+		devicesGroup.GET("/:mac/anomalies", anomalyDetectionController.List)
+		devicesGroup.GET("/:mac/gaps", dataGapsController.Execute) // Huecos y completitud diaria según el intervalo de reporte
+		devicesGroup.GET("/:mac/forecast", forecastController.Execute) // Proyección con bandas y cruces de umbrales
+		devicesGroup.GET("/:mac/derived-metrics", derivedMetricsController.List) // Métricas calculadas con cada lectura
+		devicesGroup.POST("/:mac/derived-metrics", derivedMetricsController.Create)
+		devicesGroup.PUT("/:mac/derived-metrics/:id", derivedMetricsController.Update)
+		devicesGroup.DELETE("/:mac/derived-metrics/:id", derivedMetricsController.Delete)
+		devicesGroup.GET("/:mac/anomaly-settings", anomalyDetectionController.GetSettings) // Sensibilidad del detector
+		devicesGroup.PUT("/:mac/anomaly-settings", anomalyDetectionController.SaveSettings)
+		devicesGroup.DELETE("/:mac/anomaly-settings", anomalyDetectionController.DeleteSettings)
+		devicesGroup.DELETE("/:mac/anomaly-model", anomalyDetectionController.ResetModel) // Vuelve a aprender desde cero
+		devicesGroup.GET("/:mac/container", containerController.Get) // Geometría para derivar el llenado desde 'distancia'
+		devicesGroup.PUT("/:mac/container", containerController.Save)
+		devicesGroup.DELETE("/:mac/container", containerController.Delete)
+		devicesGroup.GET("/:mac/compliance", complianceReportsController.GetProfile) // Rango de temperatura exigido
+		devicesGroup.PUT("/:mac/compliance", complianceReportsController.SaveProfile)
+		devicesGroup.DELETE("/:mac/compliance", complianceReportsController.DeleteProfile)
+		devicesGroup.GET("/:mac/compliance/reports", complianceReportsController.List)
+		devicesGroup.POST("/:mac/compliance/reports", complianceReportsController.Generate)
+		devicesGroup.PUT("/:mac", updateDeviceController.Execute)
+		devicesGroup.GET("/:mac/ownership", deviceOwnershipController.Execute)
+		devicesGroup.PUT("/:mac/ownership/historial", deviceOwnershipController.DecideHistory)
+		devicesGroup.PUT("/:mac/area", manageSitesController.AssignDevice)
+		devicesGroup.PUT("/:mac/organizacion", manageOrganizationsController.MoveDevice)
+		devicesGroup.POST("/:mac/shares", shareDevicesController.Share)
+		devicesGroup.GET("/:mac/shares", shareDevicesController.List)
+	}
+	log.Println("INFO: Rutas HTTP para /devices configuradas y protegidas por JWT.")
+
+	// Jerarquía sitio > área > dispositivo
+	sitesGroup := r.Group("/sites")
+	sitesGroup.Use(authMiddleware)
+	{
+		sitesGroup.GET("", manageSitesController.List)
+		sitesGroup.POST("", manageSitesController.Create)
+		sitesGroup.GET("/:id", manageSitesController.Get)
+		sitesGroup.PUT("/:id", manageSitesController.Update)
+		sitesGroup.DELETE("/:id", manageSitesController.Delete)
+		sitesGroup.POST("/:id/areas", manageSitesController.CreateArea)
+		sitesGroup.GET("/:id/resumen", getSiteSummaryController.Execute)
+	}
+	areasGroup := r.Group("/areas")
+	areasGroup.Use(authMiddleware)
+	{
+		areasGroup.PUT("/:id", manageSitesController.UpdateArea)
+		areasGroup.DELETE("/:id", manageSitesController.DeleteArea)
+	}
+	log.Println("INFO: Rutas HTTP para /sites y /areas configuradas y protegidas por JWT.")
+
+	// Inventario: un producto por balanza, con stock, movimientos y consumo diario
+	productsGroup := r.Group("/products")
+	productsGroup.Use(authMiddleware)
+	{
+		productsGroup.GET("", inventoryController.List)
+		productsGroup.POST("", inventoryController.Create)
+		productsGroup.GET("/:id", inventoryController.Get)
+		productsGroup.PUT("/:id", inventoryController.Update)
+		productsGroup.DELETE("/:id", inventoryController.Delete)
+		productsGroup.GET("/:id/movements", inventoryController.Movements)
+		productsGroup.GET("/:id/consumption", inventoryController.Consumption)
+	}
+	log.Println("INFO: Rutas HTTP para /products configuradas y protegidas por JWT.")
+
+	// Reportes de cumplimiento de temperatura (JSON, HTML o CSV); se conservan para auditoría
+	complianceGroup := r.Group("/compliance")
+	complianceGroup.Use(authMiddleware)
+	{
+		complianceGroup.GET("/reports/:id", complianceReportsController.Get)
+	}
+	log.Println("INFO: Rutas HTTP para /compliance configuradas y protegidas por JWT.")
+
+	// Suscripciones a reportes periódicos por email y reportes generados (JSON o el HTML enviado)
+	reportSubscriptionsGroup := r.Group("/report-subscriptions")
+	reportSubscriptionsGroup.Use(authMiddleware)
+	{
+		reportSubscriptionsGroup.GET("", scheduledReportsController.ListSubscriptions)
+		reportSubscriptionsGroup.POST("", scheduledReportsController.CreateSubscription)
+		reportSubscriptionsGroup.PUT("/:id", scheduledReportsController.UpdateSubscription)
+		reportSubscriptionsGroup.DELETE("/:id", scheduledReportsController.DeleteSubscription)
+		reportSubscriptionsGroup.POST("/:id/send", scheduledReportsController.SendNow) // Reporte del último periodo, en el momento
+		reportSubscriptionsGroup.POST("/:id/confirmation", scheduledReportsController.ResendConfirmation)
+	}
+	// Enlace de confirmación del email de una suscripción (sin auth JWT: lo abre el destinatario)
+	r.GET("/report-confirmations/:token", scheduledReportsController.ConfirmEmail)
+	reportsGroup := r.Group("/reports")
+	reportsGroup.Use(authMiddleware)
+	{
+		reportsGroup.GET("", scheduledReportsController.ListReports)
+		reportsGroup.GET("/:id", scheduledReportsController.GetReport)
+	}
+	log.Println("INFO: Rutas HTTP para /report-subscriptions y /reports configuradas y protegidas por JWT.")
+
+	// Organizaciones (tenants) y sus miembros con rol owner | admin | member | viewer
+	orgsGroup := r.Group("/organizations")
+	orgsGroup.Use(authMiddleware)
+	{
+		orgsGroup.GET("", manageOrganizationsController.List)
+		orgsGroup.POST("", manageOrganizationsController.Create)
+		orgsGroup.GET("/:id", manageOrganizationsController.Get)
+		orgsGroup.PUT("/:id", manageOrganizationsController.Update)
+		orgsGroup.PUT("/:id/members", manageOrganizationsController.SetMember)
+		orgsGroup.DELETE("/:id/members/:userId", manageOrganizationsController.RemoveMember)
+	}
+	log.Println("INFO: Rutas HTTP para /organizations configuradas y protegidas por JWT.")
+
+	// Dispositivos compartidos con el usuario e invitaciones por email
+	sharesGroup := r.Group("/shares")
+	sharesGroup.Use(authMiddleware)
+	{
+		sharesGroup.GET("", shareDevicesController.Received)
+		sharesGroup.POST("/invitaciones/:token/aceptar", shareDevicesController.Accept)
+		sharesGroup.DELETE("/:id", shareDevicesController.Revoke)
+	}
+	log.Println("INFO: Rutas HTTP para /shares configuradas y protegidas por JWT.")
+
+	// Trabajos de exportación asíncrona: estado y descarga del archivo
+	exportsGroup := r.Group("/exports")
+	exportsGroup.Use(authMiddleware)
+	{
+		exportsGroup.GET("", exportJobsController.List)
+		exportsGroup.GET("/:id", exportJobsController.Get)
+		exportsGroup.GET("/:id/download", exportJobsController.Download)
+	}
+	log.Println("INFO: Rutas HTTP para /exports configuradas y protegidas por JWT.")
+
+	// Vista de operadores de la plataforma sobre todas las lecturas
+	adminGroup := r.Group("/admin")
+	adminGroup.Use(authMiddleware, authMW.RequirePlatformOperator())
+	{
+		adminGroup.GET("/datos", getDatosController.ExecuteAll)
+		adminGroup.POST("/datos/export/parquet", exportJobsController.CreateAdmin)
+		adminGroup.GET("/storage", storageUsageController.Execute)
+	}
+	log.Println("INFO: Rutas HTTP para /admin/datos y /admin/storage configuradas (JWT + operador de la plataforma).")
+
+	// WebSocket: ?token=<JWT> identifica al usuario; por defecto recibe su propio tema y los de
+	// sus organizaciones y de los dispositivos compartidos con él (hasta que venzan), y puede
+	// suscribirse a sitios, áreas o dispositivos a los que tenga acceso
+	wsManager.SetAuth(func(token string) (int, map[string]time.Time, error) {
+		userID, _, _, err := authMW.ParseToken(token)
+		if err != nil {
+			return 0, nil, err
+		}
+		return userID, subscriptionAuthorizer.DefaultTopics(userID), nil
+	}, subscriptionAuthorizer.Authorize)
 }
\ No newline at end of file
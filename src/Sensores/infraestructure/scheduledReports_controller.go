@@ -0,0 +1,232 @@
+// File: scheduledReports_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduledReportsController struct {
+	useCase application.ScheduledReports
+}
+
+func NewScheduledReportsController(useCase application.ScheduledReports) *ScheduledReportsController {
+	return &ScheduledReportsController{useCase: useCase}
+}
+
+type reportSubscriptionRequest struct {
+	Frecuencia string `json:"frecuencia" binding:"required"` // diario | semanal | mensual
+	Zona       string `json:"zona"`                          // Nombre IANA; vacío = UTC
+	Hora       *int   `json:"hora"`                          // Hora de envío en la zona (0..23); por defecto 7
+	Email      string `json:"email" binding:"required"`
+	Activa     *bool  `json:"activa"`
+}
+
+func (r reportSubscriptionRequest) input() application.ReportSubscriptionInput {
+	return application.ReportSubscriptionInput{Frecuencia: r.Frecuencia, Zona: r.Zona, Hora: r.Hora, Email: r.Email, Activa: r.Activa}
+}
+
+// respondScheduledReportError traduce los errores de ScheduledReports a respuestas HTTP
+func respondScheduledReportError(c *gin.Context, err error, tag string) {
+	switch err.Error() {
+	case "suscripcion_no_encontrada":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suscripción no encontrada"})
+	case "reporte_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reporte no encontrado"})
+	case "frecuencia_invalida":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Frecuencia inválida: use diario, semanal o mensual"})
+	case "zona_invalida":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Zona horaria inválida (use un nombre IANA, p. ej. America/Mexico_City)"})
+	case "hora_invalida":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'hora' debe estar entre 0 y 23"})
+	case "email_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email inválido"})
+	case "demasiadas_suscripciones":
+		c.JSON(http.StatusConflict, gin.H{"error": "Se alcanzó el máximo de suscripciones a reportes"})
+	case "email_no_confirmado":
+		c.JSON(http.StatusConflict, gin.H{"error": "El email de la suscripción aún no se confirmó con el enlace enviado"})
+	case "email_ya_confirmado":
+		c.JSON(http.StatusConflict, gin.H{"error": "El email de la suscripción ya está confirmado"})
+	case "confirmacion_no_encontrada":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Enlace de confirmación inválido o ya usado"})
+	case "demasiados_envios":
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Demasiados envíos para esta suscripción; inténtelo más tarde"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar los reportes programados"})
+	}
+}
+
+// ListSubscriptions maneja GET /report-subscriptions
+func (ctrl *ScheduledReportsController) ListSubscriptions(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	subs, err := ctrl.useCase.ListSubscriptions(userID)
+	if err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, subs)
+}
+
+// CreateSubscription maneja POST /report-subscriptions
+func (ctrl *ScheduledReportsController) CreateSubscription(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	var req reportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'frecuencia' y 'email'", "detail": err.Error()})
+		return
+	}
+	sub, err := ctrl.useCase.CreateSubscription(userID, req.input())
+	if err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	c.JSON(http.StatusCreated, sub)
+}
+
+// UpdateSubscription maneja PUT /report-subscriptions/:id (reemplaza la configuración)
+func (ctrl *ScheduledReportsController) UpdateSubscription(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	var req reportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'frecuencia' y 'email'", "detail": err.Error()})
+		return
+	}
+	sub, err := ctrl.useCase.UpdateSubscription(userID, id, req.input())
+	if err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteSubscription maneja DELETE /report-subscriptions/:id (los reportes generados se conservan)
+func (ctrl *ScheduledReportsController) DeleteSubscription(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.DeleteSubscription(userID, id); err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Suscripción eliminada"})
+}
+
+// ResendConfirmation maneja POST /report-subscriptions/:id/confirmation: reenvía el enlace de
+// confirmación del email (como mucho uno cada 10 minutos)
+func (ctrl *ScheduledReportsController) ResendConfirmation(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.ResendConfirmation(userID, id); err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Enlace de confirmación enviado"})
+}
+
+// ConfirmEmail maneja GET /report-confirmations/:token, el enlace del email de confirmación (sin
+// sesión: quien lo abre puede no tener cuenta)
+func (ctrl *ScheduledReportsController) ConfirmEmail(c *gin.Context) {
+	if err := ctrl.useCase.ConfirmEmail(c.Param("token")); err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email confirmado: recibirá los reportes de la suscripción"})
+}
+
+// SendNow maneja POST /report-subscriptions/:id/send: genera y envía el reporte del último
+// periodo terminado. El reporte se crea aunque falle el envío (ver 'error_envio'). Requiere el
+// email confirmado y admite pocos envíos por hora.
+func (ctrl *ScheduledReportsController) SendNow(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	report, err := ctrl.useCase.SendNow(userID, id)
+	if err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// ListReports maneja GET /reports?limit=: reportes generados, del más reciente al más antiguo
+func (ctrl *ScheduledReportsController) ListReports(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	limit, ok := parseIDQuery(c, "limit")
+	if !ok {
+		return
+	}
+	reports, err := ctrl.useCase.ListReports(userID, limit)
+	if err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetReport maneja GET /reports/:id?format=json|html. Con html devuelve el mismo cuerpo que se
+// envió por email, para verlo en el navegador.
+func (ctrl *ScheduledReportsController) GetReport(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "id", "ScheduledReportsCtrl")
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro 'format' inválido: use json o html"})
+		return
+	}
+	report, err := ctrl.useCase.GetReport(userID, id)
+	if err != nil {
+		respondScheduledReportError(c, err, "ScheduledReportsCtrl")
+		return
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	// El HTML solo trae estilos en línea: no necesita scripts ni recursos externos
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(report.HTML))
+}
@@ -0,0 +1,143 @@
+// File: shareDevices_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShareDevicesController struct {
+	useCase application.ShareDevices
+}
+
+func NewShareDevicesController(useCase application.ShareDevices) *ShareDevicesController {
+	return &ShareDevicesController{useCase: useCase}
+}
+
+type shareRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Permiso  string `json:"permiso" binding:"required"` // lectura | escritura
+	ExpiraEn string `json:"expira_en"`                  // RFC3339, opcional
+}
+
+// respondShareError traduce los errores de ShareDevices a respuestas HTTP
+func respondShareError(c *gin.Context, err error, tag string) {
+	switch err.Error() {
+	case "dispositivo_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+	case "usuario_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+	case "invitacion_no_encontrada":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitación no encontrada, ya usada, revocada o vencida"})
+	case "acceso_no_encontrado":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Acceso compartido no encontrado"})
+	case "permiso_insuficiente":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite compartir este dispositivo"})
+	case "permiso_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Permiso inválido: use lectura o escritura"})
+	case "destinatario_requerido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Indique 'username' o 'email' (solo uno)"})
+	case "destinatario_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No puede compartir un dispositivo consigo mismo"})
+	case "email_invalido":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email inválido"})
+	case "expiracion_invalida":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La fecha de vencimiento debe ser futura"})
+	default:
+		log.Printf("ERROR: [%s] %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al procesar accesos compartidos"})
+	}
+}
+
+// Share maneja POST /devices/:mac/shares
+func (ctrl *ShareDevicesController) Share(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ShareCtrl")
+	if !ok {
+		return
+	}
+	var req shareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere 'permiso' y 'username' o 'email'", "detail": err.Error()})
+		return
+	}
+	input := application.ShareInput{Username: req.Username, Email: req.Email, Permiso: req.Permiso}
+	if req.ExpiraEn != "" {
+		expira, err := time.Parse(time.RFC3339, req.ExpiraEn)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'expira_en' debe tener formato RFC3339"})
+			return
+		}
+		input.Expira = &expira
+	}
+	share, err := ctrl.useCase.Share(userID, c.Param("mac"), input)
+	if err != nil {
+		respondShareError(c, err, "ShareCtrl")
+		return
+	}
+	c.JSON(http.StatusCreated, share)
+}
+
+// List maneja GET /devices/:mac/shares
+func (ctrl *ShareDevicesController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ShareCtrl")
+	if !ok {
+		return
+	}
+	shares, err := ctrl.useCase.List(userID, c.Param("mac"))
+	if err != nil {
+		respondShareError(c, err, "ShareCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+// Received maneja GET /shares (dispositivos compartidos con el usuario)
+func (ctrl *ShareDevicesController) Received(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ShareCtrl")
+	if !ok {
+		return
+	}
+	shares, err := ctrl.useCase.Received(userID)
+	if err != nil {
+		respondShareError(c, err, "ShareCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+// Accept maneja POST /shares/invitaciones/:token/aceptar
+func (ctrl *ShareDevicesController) Accept(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ShareCtrl")
+	if !ok {
+		return
+	}
+	share, err := ctrl.useCase.Accept(userID, c.Param("token"))
+	if err != nil {
+		respondShareError(c, err, "ShareCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, share)
+}
+
+// Revoke maneja DELETE /shares/:id
+func (ctrl *ShareDevicesController) Revoke(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "ShareCtrl")
+	if !ok {
+		return
+	}
+	shareID, ok := parseIDParam(c, "id", "ShareCtrl")
+	if !ok {
+		return
+	}
+	if err := ctrl.useCase.Revoke(userID, shareID); err != nil {
+		respondShareError(c, err, "ShareCtrl")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Acceso compartido revocado"})
+}
@@ -0,0 +1,28 @@
+// File: storageUsage_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StorageUsageController struct {
+	useCase application.StorageUsage
+}
+
+func NewStorageUsageController(useCase application.StorageUsage) *StorageUsageController {
+	return &StorageUsageController{useCase: useCase}
+}
+
+// Execute maneja GET /admin/storage: lecturas y bytes estimados por usuario
+func (ctrl *StorageUsageController) Execute(c *gin.Context) {
+	usage, err := ctrl.useCase.Execute()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al calcular el uso de almacenamiento"})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
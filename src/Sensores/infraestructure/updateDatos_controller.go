@@ -62,10 +62,9 @@ func (udc *UpdateDatosController) Execute(c *gin.Context) {
 	}
 
 	// Ejecutar el caso de uso de actualización, pasando el userID para validación
-	// Asume que UpdateUseCase.Execute ahora toma userID como parámetro
 	err = udc.useCase.Execute(
 		id,
-		// userID, // <--- Pasa el userID si tu caso de uso/repo lo necesita para validar pertenencia
+		userID, // Solo se actualizan lecturas editables por el usuario en su organización
 		requestBody.Temperatura,
 		requestBody.Movimiento,
 		requestBody.Distancia,
@@ -73,10 +72,19 @@ func (udc *UpdateDatosController) Execute(c *gin.Context) {
 		requestBody.Mac,
 	)
 	if err != nil {
-		// Aquí podrías diferenciar errores, ej: si el repo devuelve "no encontrado" o "no pertenece al usuario"
-		log.Printf("ERROR: [UpdateCtrl] Falló la ejecución del caso de uso UpdateDatos (ID: %d, UserID: %d): %v", id, userID, err)
-		// Podrías devolver 404 Not Found o 403 Forbidden si el error indica que el registro no existe o no pertenece al usuario
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al actualizar los datos del sensor"})
+		switch err.Error() {
+		case "dato_no_encontrado":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dato no encontrado"})
+		case "permiso_insuficiente":
+			c.JSON(http.StatusForbidden, gin.H{"error": "No tiene permiso para modificar este dato o el dispositivo destino"})
+		case "mac_destino_no_encontrada":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo destino ('mac') no encontrado"})
+		case "organizacion_distinta":
+			c.JSON(http.StatusConflict, gin.H{"error": "La lectura solo puede pasar a un dispositivo de su misma organización"})
+		default:
+			log.Printf("ERROR: [UpdateCtrl] Falló la ejecución del caso de uso UpdateDatos (ID: %d, UserID: %d): %v", id, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al actualizar los datos del sensor"})
+		}
 		return
 	}
 
@@ -0,0 +1,73 @@
+// File: updateDevice_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateDeviceController struct {
+	useCase application.UpdateDevice
+}
+
+func NewUpdateDeviceController(useCase application.UpdateDevice) *UpdateDeviceController {
+	return &UpdateDeviceController{useCase: useCase}
+}
+
+type UpdateDeviceRequest struct {
+	Nombre       string   `json:"nombre"`
+	Descripcion  string   `json:"descripcion"`
+	Ubicacion    string   `json:"ubicacion"`
+	Etiquetas    []string `json:"etiquetas"`
+	IntervaloSeg *int     `json:"intervalo_seg"` // Segundos entre lecturas esperados; null = estimarlo
+}
+
+// Execute maneja PUT /devices/:mac (solo el dueño del dispositivo)
+func (ctrl *UpdateDeviceController) Execute(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "UpdateDeviceCtrl")
+	if !ok {
+		return
+	}
+	mac := c.Param("mac")
+
+	var requestBody UpdateDeviceRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cuerpo de la petición inválido", "detail": err.Error()})
+		return
+	}
+
+	device, err := ctrl.useCase.Execute(application.UpdateDeviceInput{
+		UserID:       userID,
+		Mac:          mac,
+		Nombre:       requestBody.Nombre,
+		Descripcion:  requestBody.Descripcion,
+		Ubicacion:    requestBody.Ubicacion,
+		Etiquetas:    requestBody.Etiquetas,
+		IntervaloSeg: requestBody.IntervaloSeg,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "dispositivo_no_encontrado":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+		case "permiso_insuficiente":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Su rol en la organización no permite editar este dispositivo"})
+		case "metadatos_demasiado_largos":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nombre (100), descripción (500) o ubicación (200) exceden la longitud máxima"})
+		case "demasiadas_etiquetas":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Se permiten como máximo 20 etiquetas por dispositivo"})
+		case "etiqueta_invalida":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Las etiquetas no pueden superar 50 caracteres"})
+		case "intervalo_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'intervalo_seg' debe estar entre 1 y 86400 segundos"})
+		default:
+			log.Printf("ERROR: [UpdateDeviceCtrl] Falló al actualizar MAC %s para UserID %d: %v", mac, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al actualizar el dispositivo"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, device)
+}
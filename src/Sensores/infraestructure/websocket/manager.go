@@ -3,43 +3,168 @@
 package websocket // El paquete es 'websocket' dentro de infraestructure
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
-	"github.com/gorilla/websocket" 
+	"github.com/gorilla/websocket"
 )
 
+// client es una conexión WebSocket con su identidad y sus suscripciones.
+type client struct {
+	conn    *websocket.Conn
+	userID  int             // Usuario autenticado con ?token= (0 solo si no hay autenticación configurada)
+	topics  map[string]time.Time // Temas suscritos y su vencimiento (cero = no vence; protegidos por Manager.mutex)
+	writeMu sync.Mutex      // gorilla/websocket no admite escrituras concurrentes en la misma conexión
+}
+
+func (c *client) write(msg []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+// publication es un mensaje dirigido a uno o más temas (nil = a todos).
+type publication struct {
+	topics  []string
+	message []byte
+}
+
+// Authenticator valida el token de la conexión y devuelve el userID y sus temas por defecto
+// con su vencimiento (time.Time cero = no vence, p. ej. accesos compartidos con expiración).
+type Authenticator func(token string) (userID int, defaultTopics map[string]time.Time, err error)
+
+// Authorizer decide si el usuario puede suscribirse a un tema y hasta cuándo (cero = no vence).
+type Authorizer func(userID int, topic string) (hasta time.Time, ok bool)
+
 // Manager maneja las conexiones WebSocket activas y el broadcasting.
 type Manager struct {
-	clients    map[*websocket.Conn]bool 
-	broadcast  chan []byte             
-	register   chan *websocket.Conn    
-	unregister chan *websocket.Conn    
-	mutex      sync.Mutex              
+	clients      map[*websocket.Conn]*client
+	broadcast    chan publication
+	register     chan *client
+	unregister   chan *websocket.Conn
+	mutex        sync.Mutex
+	authenticate Authenticator
+	authorize    Authorizer
 }
 
 // NewManager crea e inicializa un nuevo WebSocket manager.
 func NewManager() *Manager {
 	return &Manager{
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *websocket.Conn),
+		clients:    make(map[*websocket.Conn]*client),
+		broadcast:  make(chan publication),
+		register:   make(chan *client),
 		unregister: make(chan *websocket.Conn),
 	}
 }
 
+// SetAuth configura cómo se autentican las conexiones (?token=<JWT>) y se autorizan las suscripciones.
+// Una vez configurada, las conexiones sin token se rechazan: cada cliente solo recibe los
+// temas de sus organizaciones. Sin configurar, todas las conexiones son anónimas y reciben todo.
+func (m *Manager) SetAuth(authenticate Authenticator, authorize Authorizer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.authenticate = authenticate
+	m.authorize = authorize
+}
+
+// wants indica si el cliente debe recibir una publicación
+func (c *client) wants(p publication) bool {
+	if p.topics == nil || (c.userID == 0 && len(c.topics) == 0) {
+		return true
+	}
+	now := time.Now()
+	for _, topic := range p.topics {
+		if hasta, ok := c.topics[topic]; ok && (hasta.IsZero() || now.Before(hasta)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RevokeTopic quita un tema de todas las conexiones del usuario (implementa domain.SubscriptionRevoker)
+func (m *Manager) RevokeTopic(userID int, topic string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, c := range m.clients {
+		if c.userID == userID {
+			delete(c.topics, topic)
+		}
+	}
+	log.Printf("INFO: Tema '%s' retirado de las conexiones WebSocket de UserID %d", topic, userID)
+}
+
+// GrantTopic suscribe las conexiones abiertas del usuario a un tema (implementa domain.SubscriptionRevoker),
+// sin acortar una suscripción que ya dure más
+func (m *Manager) GrantTopic(userID int, topic string, hasta time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, c := range m.clients {
+		if c.userID != userID {
+			continue
+		}
+		if actual, ok := c.topics[topic]; ok && (actual.IsZero() || (!hasta.IsZero() && actual.After(hasta))) {
+			continue
+		}
+		c.topics[topic] = hasta
+	}
+	log.Printf("INFO: Tema '%s' agregado a las conexiones WebSocket de UserID %d", topic, userID)
+}
+
+// RecheckTopics vuelve a autorizar los temas de las conexiones del usuario y retira los que ya no
+// le corresponden (implementa domain.SubscriptionRevoker)
+func (m *Manager) RecheckTopics(userID int) {
+	m.mutex.Lock()
+	authorize := m.authorize
+	held := make(map[string]bool)
+	for _, c := range m.clients {
+		if c.userID != userID {
+			continue
+		}
+		for topic := range c.topics {
+			held[topic] = true
+		}
+	}
+	m.mutex.Unlock()
+	if authorize == nil || len(held) == 0 {
+		return
+	}
+
+	// La autorización consulta la base de datos: se hace sin bloquear el broadcast
+	revoked := []string{}
+	for topic := range held {
+		if _, ok := authorize(userID, topic); !ok {
+			revoked = append(revoked, topic)
+		}
+	}
+	if len(revoked) == 0 {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, c := range m.clients {
+		if c.userID != userID {
+			continue
+		}
+		for _, topic := range revoked {
+			delete(c.topics, topic)
+		}
+	}
+	log.Printf("INFO: Temas %v retirados de las conexiones WebSocket de UserID %d", revoked, userID)
+}
 
 func (m *Manager) Run() {
 	log.Println("INFO: WebSocket Manager iniciado y escuchando eventos...")
 	for {
 		select {
-		case conn := <-m.register:
+		case c := <-m.register:
 			// Registrar nuevo cliente
 			m.mutex.Lock()
-			m.clients[conn] = true
+			m.clients[c.conn] = c
 			m.mutex.Unlock()
-			log.Printf("INFO: Cliente WebSocket conectado: %s. Clientes totales: %d", conn.RemoteAddr(), len(m.clients))
+			log.Printf("INFO: Cliente WebSocket conectado: %s (UserID %d). Clientes totales: %d", c.conn.RemoteAddr(), c.userID, len(m.clients))
 
 		case conn := <-m.unregister:
 			// Desregistrar cliente
@@ -52,32 +177,42 @@ func (m *Manager) Run() {
 			}
 			m.mutex.Unlock()
 
-		case message := <-m.broadcast:
-			// Enviar mensaje a todos los clientes conectados
+		case pub := <-m.broadcast:
+			// Enviar mensaje a los clientes interesados
 			m.mutex.Lock() // Bloquear mientras iteramos sobre los clientes
-			if len(m.clients) > 0 {
-				log.Printf("INFO: Transmitiendo mensaje a %d cliente(s) WebSocket...", len(m.clients))
-			}
-			for conn := range m.clients {
+			sent := 0
+			for _, c := range m.clients {
+				if !c.wants(pub) {
+					continue
+				}
+				sent++
 				// Enviar en una goroutine para no bloquear el broadcast si un cliente es lento
-				go func(c *websocket.Conn, msg []byte) {
-					
-					if err := c.WriteMessage(websocket.TextMessage, msg); err != nil {
-						log.Printf("ERROR: Error al escribir en WebSocket para %s: %v. Desregistrando cliente.", c.RemoteAddr(), err)
-						m.unregister <- c
+				go func(c *client, msg []byte) {
+					if err := c.write(msg); err != nil {
+						log.Printf("ERROR: Error al escribir en WebSocket para %s: %v. Desregistrando cliente.", c.conn.RemoteAddr(), err)
+						m.unregister <- c.conn
 					}
-				}(conn, message)
+				}(c, pub.message)
+			}
+			if sent > 0 {
+				log.Printf("INFO: Transmitiendo mensaje a %d cliente(s) WebSocket...", sent)
 			}
 			m.mutex.Unlock() // Desbloquear después de lanzar las goroutines de envío
 		}
 	}
 }
 
-// BroadcastMessage envía un mensaje al canal de broadcast para ser distribuido.
+// BroadcastMessage envía un mensaje al canal de broadcast para ser distribuido a todos.
 func (m *Manager) BroadcastMessage(message []byte) {
+	m.PublishMessage(nil, message)
+}
+
+// PublishMessage envía un mensaje solo a los clientes suscritos a alguno de los temas
+// (y a las conexiones anónimas cuando no hay autenticación configurada).
+func (m *Manager) PublishMessage(topics []string, message []byte) {
 	// Simplemente envía el mensaje al canal. El bucle Run se encargará del resto.
 	if len(message) > 0 {
-		m.broadcast <- message
+		m.broadcast <- publication{topics: topics, message: message}
 	} else {
 		log.Println("ADVERTENCIA: Intento de transmitir mensaje WebSocket vacío.")
 	}
@@ -87,57 +222,142 @@ func (m *Manager) BroadcastMessage(message []byte) {
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024, // Tamaño del buffer de lectura
 	WriteBufferSize: 1024, // Tamaño del buffer de escritura
-	
+
 	CheckOrigin: func(r *http.Request) bool {
 		// Ejemplo permisivo para desarrollo:
 		log.Printf("DEBUG: Verificando origen de WebSocket: %s", r.Header.Get("Origin"))
-		return true 
+		return true
 
 	},
 }
 
 
 func (m *Manager) HandleConnections(w http.ResponseWriter, r *http.Request) {
-	
+	c := &client{topics: make(map[string]time.Time)}
+
+	// Autenticación por query (?token=<JWT>): los navegadores no envían cabeceras en WebSocket
+	m.mutex.Lock()
+	authenticate := m.authenticate
+	m.mutex.Unlock()
+	if authenticate != nil {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			log.Printf("WARN: Conexión WebSocket rechazada sin token desde %s", r.RemoteAddr)
+			http.Error(w, "Se requiere ?token=<JWT>", http.StatusUnauthorized)
+			return
+		}
+		userID, defaultTopics, err := authenticate(token)
+		if err != nil {
+			log.Printf("WARN: Conexión WebSocket rechazada por token inválido: %v", err)
+			http.Error(w, "Token inválido", http.StatusUnauthorized)
+			return
+		}
+		c.userID = userID
+		for topic, hasta := range defaultTopics {
+			c.topics[topic] = hasta
+		}
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil) // w, r, y cabeceras adicionales (nil aquí)
 	if err != nil {
-		
+
 		log.Printf("ERROR: Falló la actualización a WebSocket: %v", err)
-		
+
 		return
 	}
+	c.conn = conn
 
 	// Registrar la nueva conexión exitosa.
-	m.register <- conn
+	m.register <- c
 
-	
-	go m.readLoop(conn)
+
+	go m.readLoop(c)
+}
+
+// subscriptionRequest es el mensaje que envía el cliente: {"accion": "suscribir", "tema": "sitio:3"}
+type subscriptionRequest struct {
+	Accion string `json:"accion"` // "suscribir" | "desuscribir"
+	Tema   string `json:"tema"`   // usuario:<id> | org:<id> | mac:<mac> | sitio:<id> | area:<id>
 }
 
-// readLoop lee mensajes del cliente WebSocket. Necesario para detectar cierres.
-func (m *Manager) readLoop(conn *websocket.Conn) {
-	
+type subscriptionResponse struct {
+	Tipo  string `json:"tipo"` // Siempre "suscripcion"
+	Tema  string `json:"tema"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleSubscription procesa un mensaje de (des)suscripción y devuelve la respuesta para el cliente
+func (m *Manager) handleSubscription(c *client, raw []byte) subscriptionResponse {
+	var req subscriptionRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.Tema == "" {
+		return subscriptionResponse{Tipo: "suscripcion", Error: "mensaje inválido: se espera {\"accion\": \"suscribir\", \"tema\": \"sitio:<id>\"}"}
+	}
+	resp := subscriptionResponse{Tipo: "suscripcion", Tema: req.Tema}
+	if c.userID == 0 {
+		resp.Error = "se requiere conectar con ?token=<JWT> para suscribirse"
+		return resp
+	}
+
+	switch req.Accion {
+	case "suscribir":
+		m.mutex.Lock()
+		authorize := m.authorize
+		m.mutex.Unlock()
+		var hasta time.Time
+		ok := false
+		if authorize != nil {
+			hasta, ok = authorize(c.userID, req.Tema)
+		}
+		if !ok {
+			resp.Error = "tema no encontrado o sin acceso"
+			return resp
+		}
+		m.mutex.Lock()
+		c.topics[req.Tema] = hasta
+		m.mutex.Unlock()
+	case "desuscribir":
+		m.mutex.Lock()
+		delete(c.topics, req.Tema)
+		m.mutex.Unlock()
+	default:
+		resp.Error = "accion desconocida (use 'suscribir' o 'desuscribir')"
+		return resp
+	}
+	resp.OK = true
+	log.Printf("INFO: WebSocket UserID %d: %s '%s'", c.userID, req.Accion, req.Tema)
+	return resp
+}
+
+// readLoop lee mensajes del cliente WebSocket: suscripciones y detección de cierres.
+func (m *Manager) readLoop(c *client) {
+
 	defer func() {
-		m.unregister <- conn
+		m.unregister <- c.conn
 	}()
 
-	
+
 
 	for {
-		
-		_, _, err := conn.ReadMessage()
+
+		_, raw, err := c.conn.ReadMessage()
 		if err != nil {
 			// Verificar si el error es un cierre esperado de la conexión.
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure, websocket.CloseNormalClosure) {
 				// Error inesperado
-				log.Printf("ERROR: Error inesperado de lectura WebSocket para %s: %v", conn.RemoteAddr(), err)
+				log.Printf("ERROR: Error inesperado de lectura WebSocket para %s: %v", c.conn.RemoteAddr(), err)
 			} else {
-				
-				log.Printf("INFO: Conexión WebSocket cerrada por el cliente %s: %v", conn.RemoteAddr(), err)
+
+				log.Printf("INFO: Conexión WebSocket cerrada por el cliente %s: %v", c.conn.RemoteAddr(), err)
 			}
-			
+
+			break
+		}
+
+		resp, _ := json.Marshal(m.handleSubscription(c, raw))
+		if err := c.write(resp); err != nil {
+			log.Printf("ERROR: Error al responder suscripción WebSocket para %s: %v", c.conn.RemoteAddr(), err)
 			break
 		}
-		
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,55 @@
+// File: weightEvents_controller.go
+
+package infraestructure
+
+import (
+	"API/src/Sensores/application"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WeightEventsController struct {
+	useCase application.WeightEvents
+}
+
+func NewWeightEventsController(useCase application.WeightEvents) *WeightEventsController {
+	return &WeightEventsController{useCase: useCase}
+}
+
+// List maneja GET /devices/:mac/weight-events?from=&to=&limit=: cambios de peso confirmados en el
+// rango (por defecto, las últimas 24 horas), del más reciente al más antiguo
+func (ctrl *WeightEventsController) List(c *gin.Context) {
+	userID, ok := getAuthUserID(c, "WeightEventsCtrl")
+	if !ok {
+		return
+	}
+	desde, ok := parseTimeQuery(c, "from")
+	if !ok {
+		return
+	}
+	hasta, ok := parseTimeQuery(c, "to")
+	if !ok {
+		return
+	}
+	limit, ok := parseIDQuery(c, "limit")
+	if !ok {
+		return
+	}
+	mac := c.Param("mac")
+	events, err := ctrl.useCase.List(userID, mac, desde, hasta, limit)
+	if err != nil {
+		switch err.Error() {
+		case "dispositivo_no_encontrado":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dispositivo no encontrado"})
+		case "rango_invalido":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'from' debe ser anterior a 'to'"})
+		default:
+			log.Printf("ERROR: [WeightEventsCtrl] Falló al listar cambios de peso de MAC %s para UserID %d: %v", mac, userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error interno al obtener los cambios de peso"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,460 @@
+//File: core/schema_mysql.go
+
+package core
+
+import (
+	"fmt"
+	"log"
+)
+
+// schemaStatements contiene las tablas que la API necesita además de 'users' y 'rutas'.
+// Todas usan IF NOT EXISTS para que EnsureSchema pueda ejecutarse en cada arranque.
+var schemaStatements = []string{
+	// Metadatos editables por el dueño de cada dispositivo (identificado por MAC)
+	`CREATE TABLE IF NOT EXISTS devices (
+		mac         VARCHAR(64)  NOT NULL PRIMARY KEY,
+		nombre      VARCHAR(100) NOT NULL DEFAULT '',
+		descripcion VARCHAR(500) NOT NULL DEFAULT '',
+		ubicacion   VARCHAR(200) NOT NULL DEFAULT '',
+		updated_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS device_tags (
+		mac VARCHAR(64) NOT NULL,
+		tag VARCHAR(50) NOT NULL,
+		PRIMARY KEY (mac, tag),
+		INDEX idx_device_tags_tag (tag)
+	)`,
+	// Historial de dueños: un registro por periodo [started_at, ended_at); ended_at NULL = dueño actual.
+	// historial: 'sin_historial' | 'pendiente' | 'transferido' | 'privado' (decisión del nuevo dueño
+	// sobre las lecturas del dueño anterior).
+	`CREATE TABLE IF NOT EXISTS device_ownership (
+		id                   INT AUTO_INCREMENT PRIMARY KEY,
+		mac                  VARCHAR(64)  NOT NULL,
+		user_id              INT          NOT NULL,
+		previous_user_id     INT          NULL,
+		started_at           DATETIME     NOT NULL,
+		ended_at             DATETIME     NULL,
+		assigned_by          INT          NULL,
+		motivo               VARCHAR(500) NOT NULL DEFAULT '',
+		ended_by             INT          NULL,
+		motivo_fin           VARCHAR(500) NOT NULL DEFAULT '',
+		historial            VARCHAR(20)  NOT NULL DEFAULT 'sin_historial',
+		historial_decided_at DATETIME     NULL,
+		INDEX idx_device_ownership_mac (mac, ended_at),
+		INDEX idx_device_ownership_user (user_id)
+	)`,
+	// Jerarquía de ubicación: sitio (edificio) > área (sala) > dispositivo (devices.area_id)
+	`CREATE TABLE IF NOT EXISTS sites (
+		id          INT AUTO_INCREMENT PRIMARY KEY,
+		user_id     INT          NOT NULL,
+		nombre      VARCHAR(100) NOT NULL,
+		descripcion VARCHAR(500) NOT NULL DEFAULT '',
+		created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_sites_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS areas (
+		id          INT AUTO_INCREMENT PRIMARY KEY,
+		site_id     INT          NOT NULL,
+		nombre      VARCHAR(100) NOT NULL,
+		descripcion VARCHAR(500) NOT NULL DEFAULT '',
+		created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_areas_site (site_id)
+	)`,
+	// Organizaciones (tenants): dueñas de dispositivos (devices.org_id), sitios (sites.org_id)
+	// y lecturas (rutas.org_id, fijado al insertar). personal_user_id marca la organización
+	// personal que cada usuario recibe al registrarse.
+	`CREATE TABLE IF NOT EXISTS organizations (
+		id               INT AUTO_INCREMENT PRIMARY KEY,
+		nombre           VARCHAR(100) NOT NULL,
+		personal_user_id INT          NULL,
+		created_at       TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_organizations_personal (personal_user_id)
+	)`,
+	// role: 'owner' | 'admin' | 'member' | 'viewer'
+	`CREATE TABLE IF NOT EXISTS organization_members (
+		org_id     INT         NOT NULL,
+		user_id    INT         NOT NULL,
+		role       VARCHAR(10) NOT NULL,
+		created_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (org_id, user_id),
+		INDEX idx_organization_members_user (user_id)
+	)`,
+	// Dispositivos compartidos con otros usuarios. grantee_user_id NULL = invitación por email
+	// pendiente de aceptar con invite_token. permiso: 'lectura' | 'escritura'.
+	`CREATE TABLE IF NOT EXISTS device_shares (
+		id              INT AUTO_INCREMENT PRIMARY KEY,
+		mac             VARCHAR(64)  NOT NULL,
+		org_id          INT          NOT NULL,
+		granted_by      INT          NOT NULL,
+		grantee_user_id INT          NULL,
+		invite_email    VARCHAR(254) NULL,
+		invite_token    VARCHAR(64)  NULL,
+		permiso         VARCHAR(10)  NOT NULL,
+		created_at      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at      DATETIME     NULL,
+		accepted_at     DATETIME     NULL,
+		revoked_at      DATETIME     NULL,
+		UNIQUE KEY uq_device_shares_token (invite_token),
+		INDEX idx_device_shares_grantee (grantee_user_id, mac),
+		INDEX idx_device_shares_mac (mac)
+	)`,
+	// Exportaciones asíncronas (Parquet). filtro guarda entities.ExportFilter en JSON; el archivo
+	// vive en EXPORT_DIR hasta expires_at.
+	`CREATE TABLE IF NOT EXISTS export_jobs (
+		id          INT AUTO_INCREMENT PRIMARY KEY,
+		user_id     INT          NOT NULL,
+		todos       BOOLEAN      NOT NULL DEFAULT FALSE,
+		formato     VARCHAR(16)  NOT NULL,
+		filtro      TEXT         NOT NULL,
+		estado      VARCHAR(16)  NOT NULL,
+		filas       BIGINT       NOT NULL DEFAULT 0,
+		bytes       BIGINT       NOT NULL DEFAULT 0,
+		error       VARCHAR(255) NULL,
+		created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		started_at  DATETIME     NULL,
+		finished_at DATETIME     NULL,
+		expires_at  DATETIME     NULL,
+		INDEX idx_export_jobs_user (user_id, created_at),
+		INDEX idx_export_jobs_estado (estado, expires_at)
+	)`,
+	// Resúmenes por hora y por día (hora local del servidor, como rutas.created_at) de cada
+	// dispositivo y métrica. Se agrupan también por org_id y user_id para aplicar la misma
+	// visibilidad que a 'rutas'. total cuenta las lecturas; lecturas, las que traen la métrica.
+	`CREATE TABLE IF NOT EXISTS datos_rollups (
+		id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+		resolucion VARCHAR(8)  NOT NULL,
+		inicio     DATETIME    NOT NULL,
+		mac        VARCHAR(64) NOT NULL,
+		org_id     INT         NULL,
+		user_id    INT         NULL,
+		metrica    VARCHAR(16) NOT NULL,
+		total      BIGINT      NOT NULL,
+		lecturas   BIGINT      NOT NULL,
+		suma       DOUBLE      NULL,
+		minimo     DOUBLE      NULL,
+		maximo     DOUBLE      NULL,
+		INDEX idx_datos_rollups_mac (resolucion, mac, inicio),
+		INDEX idx_datos_rollups_inicio (resolucion, inicio)
+	)`,
+	// Horas de un dispositivo cuyos resúmenes hay que recalcular
+	`CREATE TABLE IF NOT EXISTS rollup_pendientes (
+		mac  VARCHAR(64) NOT NULL,
+		hora DATETIME    NOT NULL,
+		PRIMARY KEY (mac, hora)
+	)`,
+	// Progreso del cálculo de resúmenes: 'ultimo_id' (última lectura de 'rutas' encolada) y
+	// 'cubierto_hasta' (los resúmenes incluyen todas las lecturas anteriores)
+	`CREATE TABLE IF NOT EXISTS rollup_estado (
+		clave VARCHAR(32) NOT NULL PRIMARY KEY,
+		valor VARCHAR(64) NOT NULL
+	)`,
+	// Intervalos de movimiento armados a partir de lecturas consecutivas con movimiento.
+	// estado: 'candidato' | 'abierto' | 'cerrado'; fin es la última muestra con movimiento.
+	`CREATE TABLE IF NOT EXISTS motion_events (
+		id                INT AUTO_INCREMENT PRIMARY KEY,
+		mac               VARCHAR(64) NOT NULL,
+		org_id            INT         NULL,
+		user_id           INT         NULL,
+		estado            VARCHAR(16) NOT NULL,
+		inicio            DATETIME    NOT NULL,
+		fin               DATETIME    NULL,
+		ultimo_movimiento DATETIME    NOT NULL,
+		muestras          INT         NOT NULL DEFAULT 1,
+		INDEX idx_motion_events_mac (mac, inicio),
+		INDEX idx_motion_events_estado (estado, ultimo_movimiento)
+	)`,
+	// Cambios escalonados de peso ('agregado' | 'retirado') ya asentados
+	`CREATE TABLE IF NOT EXISTS weight_events (
+		id              INT AUTO_INCREMENT PRIMARY KEY,
+		mac             VARCHAR(64) NOT NULL,
+		org_id          INT         NULL,
+		user_id         INT         NULL,
+		tipo            VARCHAR(16) NOT NULL,
+		inicio          DATETIME    NOT NULL,
+		confirmado      DATETIME    NOT NULL,
+		peso_anterior   DOUBLE      NOT NULL,
+		peso_resultante DOUBLE      NOT NULL,
+		delta           DOUBLE      NOT NULL,
+		muestras        INT         NOT NULL,
+		INDEX idx_weight_events_mac (mac, confirmado)
+	)`,
+	// Geometría del contenedor bajo un sensor ultrasónico (ver entities.ContainerConfig)
+	`CREATE TABLE IF NOT EXISTS device_containers (
+		mac           VARCHAR(64) NOT NULL PRIMARY KEY,
+		forma         VARCHAR(32) NOT NULL,
+		altura_sensor DOUBLE      NOT NULL,
+		altura_maxima DOUBLE      NOT NULL,
+		diametro      DOUBLE      NULL,
+		largo         DOUBLE      NULL,
+		ancho         DOUBLE      NULL,
+		umbral_bajo   DOUBLE      NULL,
+		umbral_alto   DOUBLE      NULL,
+		updated_at    TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	// Producto guardado sobre la balanza de cada dispositivo (ver entities.Product)
+	`CREATE TABLE IF NOT EXISTS products (
+		id            INT AUTO_INCREMENT PRIMARY KEY,
+		mac           VARCHAR(64)  NOT NULL,
+		nombre        VARCHAR(100) NOT NULL,
+		peso_unitario DOUBLE       NOT NULL,
+		tara          DOUBLE       NOT NULL DEFAULT 0,
+		umbral_minimo INT          NULL,
+		created_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_products_mac (mac)
+	)`,
+	// Reposiciones y consumos derivados de los cambios de peso. tipo: 'reposicion' | 'consumo'.
+	`CREATE TABLE IF NOT EXISTS inventory_movements (
+		id                   INT AUTO_INCREMENT PRIMARY KEY,
+		product_id           INT         NOT NULL,
+		weight_event_id      INT         NULL,
+		tipo                 VARCHAR(16) NOT NULL,
+		unidades             INT         NOT NULL,
+		unidades_resultantes INT         NOT NULL,
+		fecha                DATETIME    NOT NULL,
+		INDEX idx_inventory_movements_product (product_id, fecha)
+	)`,
+	// Rango de temperatura exigido a cada dispositivo (ver entities.ComplianceProfile)
+	`CREATE TABLE IF NOT EXISTS compliance_profiles (
+		mac              VARCHAR(64) NOT NULL PRIMARY KEY,
+		temp_min         DOUBLE      NOT NULL,
+		temp_max         DOUBLE      NOT NULL,
+		tolerancia_seg   INT         NOT NULL DEFAULT 0,
+		hueco_maximo_seg INT         NOT NULL,
+		created_at       TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at       TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	// Reportes de cumplimiento generados; contenido guarda entities.ComplianceReport en JSON.
+	// Se conservan para auditoría: la API no los modifica ni los borra. generado_por NULL = automático.
+	`CREATE TABLE IF NOT EXISTS compliance_reports (
+		id           INT AUTO_INCREMENT PRIMARY KEY,
+		mac          VARCHAR(64) NOT NULL,
+		periodo      VARCHAR(10) NOT NULL,
+		desde        DATETIME    NOT NULL,
+		hasta        DATETIME    NOT NULL,
+		cumple       BOOLEAN     NOT NULL,
+		en_rango     DOUBLE      NULL,
+		contenido    MEDIUMTEXT  NOT NULL,
+		generado_por INT         NULL,
+		created_at   DATETIME    NOT NULL,
+		UNIQUE KEY uq_compliance_reports_periodo (mac, periodo, desde)
+	)`,
+	// Suscripciones a reportes periódicos por email (ver entities.ReportSubscription)
+	`CREATE TABLE IF NOT EXISTS report_subscriptions (
+		id            INT AUTO_INCREMENT PRIMARY KEY,
+		user_id       INT          NOT NULL,
+		frecuencia    VARCHAR(10)  NOT NULL,
+		zona          VARCHAR(64)  NOT NULL,
+		hora          TINYINT      NOT NULL,
+		email         VARCHAR(254) NOT NULL,
+		activa        BOOLEAN      NOT NULL DEFAULT TRUE,
+		proximo_envio DATETIME     NOT NULL,
+		ultimo_envio  DATETIME     NULL,
+		created_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_report_subscriptions_user (user_id),
+		INDEX idx_report_subscriptions_due (activa, proximo_envio)
+	)`,
+	// Reportes generados por las suscripciones; html es el cuerpo enviado por email
+	`CREATE TABLE IF NOT EXISTS scheduled_reports (
+		id              INT AUTO_INCREMENT PRIMARY KEY,
+		subscription_id INT          NULL,
+		user_id         INT          NOT NULL,
+		frecuencia      VARCHAR(10)  NOT NULL,
+		zona            VARCHAR(64)  NOT NULL,
+		desde           DATETIME     NOT NULL,
+		hasta           DATETIME     NOT NULL,
+		email           VARCHAR(254) NOT NULL,
+		asunto          VARCHAR(200) NOT NULL,
+		dispositivos    INT          NOT NULL,
+		alertas         INT          NOT NULL,
+		huecos          INT          NOT NULL,
+		html            MEDIUMTEXT   NOT NULL,
+		generado        DATETIME     NOT NULL,
+		enviado         DATETIME     NULL,
+		error_envio     VARCHAR(255) NULL,
+		INDEX idx_scheduled_reports_user (user_id, generado)
+	)`,
+	// Sensibilidad del detector de anomalías por dispositivo (ver entities.AnomalySettings)
+	`CREATE TABLE IF NOT EXISTS anomaly_settings (
+		mac        VARCHAR(64) NOT NULL PRIMARY KEY,
+		umbral_z   DOUBLE      NOT NULL,
+		activa     BOOLEAN     NOT NULL DEFAULT TRUE,
+		updated_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	// Modelos en línea del detector por dispositivo, métrica y hora del día (-1 = global)
+	`CREATE TABLE IF NOT EXISTS anomaly_state (
+		mac         VARCHAR(64) NOT NULL,
+		metrica     VARCHAR(32) NOT NULL,
+		hora        TINYINT     NOT NULL,
+		media       DOUBLE      NOT NULL,
+		varianza    DOUBLE      NOT NULL,
+		muestras    INT         NOT NULL,
+		actualizado DATETIME    NOT NULL,
+		PRIMARY KEY (mac, metrica, hora)
+	)`,
+	// Lecturas anómalas detectadas (ver entities.AnomalyEvent)
+	`CREATE TABLE IF NOT EXISTS anomaly_events (
+		id          INT AUTO_INCREMENT PRIMARY KEY,
+		dato_id     BIGINT      NULL,
+		mac         VARCHAR(64) NOT NULL,
+		org_id      INT         NULL,
+		user_id     INT         NULL,
+		metrica     VARCHAR(32) NOT NULL,
+		tipo        VARCHAR(8)  NOT NULL,
+		fecha       DATETIME    NOT NULL,
+		valor       DOUBLE      NOT NULL,
+		esperado    DOUBLE      NOT NULL,
+		desviacion  DOUBLE      NOT NULL,
+		score       DOUBLE      NOT NULL,
+		estacional  BOOLEAN     NOT NULL,
+		INDEX idx_anomaly_events_mac (mac, fecha),
+		INDEX idx_anomaly_events_dato (dato_id)
+	)`,
+	// Métricas derivadas definidas por los usuarios para cada dispositivo (ver entities.DerivedMetric)
+	`CREATE TABLE IF NOT EXISTS derived_metrics (
+		id         INT AUTO_INCREMENT PRIMARY KEY,
+		mac        VARCHAR(64)  NOT NULL,
+		nombre     VARCHAR(50)  NOT NULL,
+		expresion  VARCHAR(500) NOT NULL,
+		unidad     VARCHAR(20)  NOT NULL DEFAULT '',
+		created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_derived_metrics_mac_nombre (mac, nombre)
+	)`,
+	// Valores de las métricas derivadas, calculados al recibir cada lectura
+	`CREATE TABLE IF NOT EXISTS derived_values (
+		dato_id   BIGINT      NOT NULL,
+		metric_id INT         NOT NULL,
+		mac       VARCHAR(64) NOT NULL,
+		fecha     DATETIME    NOT NULL,
+		valor     DOUBLE      NOT NULL,
+		PRIMARY KEY (dato_id, metric_id),
+		INDEX idx_derived_values_metric (metric_id, fecha)
+	)`,
+	// Seguimiento del peso por dispositivo entre lecturas (nivel estable y candidato)
+	`CREATE TABLE IF NOT EXISTS weight_state (
+		mac                VARCHAR(64) NOT NULL PRIMARY KEY,
+		peso_estable       DOUBLE      NOT NULL,
+		ultima_muestra     DATETIME    NOT NULL,
+		candidato_desde    DATETIME    NULL,
+		candidato_suma     DOUBLE      NOT NULL DEFAULT 0,
+		candidato_lecturas INT         NOT NULL DEFAULT 0
+	)`,
+}
+
+// schemaColumn describe una columna que se añade a una tabla existente si falta.
+type schemaColumn struct {
+	table      string
+	column     string
+	definition string
+}
+
+// schemaColumns son columnas nuevas en tablas que ya existían antes de EnsureSchema.
+var schemaColumns = []schemaColumn{
+	// Momento de la lectura; necesario para atribuir lecturas a periodos de dueño
+	{table: "rutas", column: "created_at", definition: "DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP"},
+	{table: "devices", column: "area_id", definition: "INT NULL"},
+	{table: "devices", column: "org_id", definition: "INT NULL"},
+	{table: "devices", column: "intervalo_seg", definition: "INT NULL"},
+	{table: "sites", column: "org_id", definition: "INT NULL"},
+	{table: "rutas", column: "org_id", definition: "INT NULL"},
+	// Confirmación del email de las suscripciones a reportes (las previas quedan sin confirmar)
+	{table: "report_subscriptions", column: "email_confirmado", definition: "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{table: "report_subscriptions", column: "token_confirmacion", definition: "VARCHAR(64) NULL"},
+	{table: "report_subscriptions", column: "confirmacion_enviada", definition: "DATETIME NULL"},
+}
+
+// schemaIndex describe un índice sobre una tabla existente que se crea si falta.
+type schemaIndex struct {
+	table     string
+	name      string
+	statement string
+}
+
+var schemaIndexes = []schemaIndex{
+	{table: "rutas", name: "idx_rutas_mac_created", statement: "CREATE INDEX idx_rutas_mac_created ON rutas (mac, created_at)"},
+	{table: "devices", name: "idx_devices_area", statement: "CREATE INDEX idx_devices_area ON devices (area_id)"},
+	{table: "devices", name: "idx_devices_org", statement: "CREATE INDEX idx_devices_org ON devices (org_id)"},
+	{table: "sites", name: "idx_sites_org", statement: "CREATE INDEX idx_sites_org ON sites (org_id)"},
+	{table: "rutas", name: "idx_rutas_org_created", statement: "CREATE INDEX idx_rutas_org_created ON rutas (org_id, created_at)"},
+	{table: "rutas", name: "idx_rutas_created_id", statement: "CREATE INDEX idx_rutas_created_id ON rutas (created_at, id)"},
+	{table: "rutas", name: "idx_rutas_user_created", statement: "CREATE INDEX idx_rutas_user_created ON rutas (user_id, created_at)"},
+	{table: "report_subscriptions", name: "idx_report_subscriptions_token", statement: "CREATE UNIQUE INDEX idx_report_subscriptions_token ON report_subscriptions (token_confirmacion)"},
+	{table: "scheduled_reports", name: "idx_scheduled_reports_subscription", statement: "CREATE INDEX idx_scheduled_reports_subscription ON scheduled_reports (subscription_id, generado)"},
+}
+
+// schemaBackfills se ejecutan al final y deben ser idempotentes.
+var schemaBackfills = []string{
+	// Abre un periodo de propiedad para las MACs asignadas antes de existir device_ownership
+	`INSERT INTO device_ownership (mac, user_id, started_at, motivo)
+		SELECT u.mac_address, u.id,
+			COALESCE((SELECT MIN(r.created_at) FROM rutas r WHERE r.mac = u.mac_address AND r.user_id = u.id), NOW()),
+			'asignación previa al historial'
+		FROM users u
+		WHERE u.mac_address IS NOT NULL AND u.mac_address <> ''
+			AND NOT EXISTS (SELECT 1 FROM device_ownership o WHERE o.mac = u.mac_address AND o.ended_at IS NULL)`,
+	// Organización personal (y membresía owner) para los usuarios previos a las organizaciones
+	`INSERT INTO organizations (nombre, personal_user_id)
+		SELECT u.username, u.id FROM users u
+		WHERE NOT EXISTS (SELECT 1 FROM organizations o WHERE o.personal_user_id = u.id)`,
+	`INSERT INTO organization_members (org_id, user_id, role)
+		SELECT o.id, o.personal_user_id, 'owner' FROM organizations o
+		WHERE o.personal_user_id IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM organization_members m WHERE m.org_id = o.id AND m.user_id = o.personal_user_id)`,
+	// Dispositivos, sitios y lecturas sin organización pasan a la organización personal de su usuario
+	`INSERT INTO devices (mac, org_id)
+		SELECT u.mac_address, o.id FROM users u JOIN organizations o ON o.personal_user_id = u.id
+		WHERE u.mac_address IS NOT NULL AND u.mac_address <> ''
+		ON DUPLICATE KEY UPDATE org_id = COALESCE(devices.org_id, VALUES(org_id))`,
+	`UPDATE sites s JOIN organizations o ON o.personal_user_id = s.user_id SET s.org_id = o.id WHERE s.org_id IS NULL`,
+	`UPDATE rutas r JOIN organizations o ON o.personal_user_id = r.user_id SET r.org_id = o.id WHERE r.org_id IS NULL`,
+	// El antiguo rol global 'admin' es ahora el rol de operador de la plataforma
+	`UPDATE users SET role = 'operador_plataforma' WHERE role = 'admin'`,
+}
+
+// EnsureSchema crea las tablas, columnas e índices auxiliares si todavía no existen.
+func EnsureSchema(conn *Conn_MySQL) error {
+	if conn == nil || conn.DB == nil {
+		return fmt.Errorf("la instancia de base de datos es nula")
+	}
+	for _, stmt := range schemaStatements {
+		if _, err := conn.DB.Exec(stmt); err != nil {
+			return fmt.Errorf("error al aplicar esquema: %w", err)
+		}
+	}
+	for _, col := range schemaColumns {
+		var count int
+		err := conn.DB.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+			col.table, col.column).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("error al inspeccionar columna %s.%s: %w", col.table, col.column, err)
+		}
+		if count == 0 {
+			if _, err := conn.DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.table, col.column, col.definition)); err != nil {
+				return fmt.Errorf("error al añadir columna %s.%s: %w", col.table, col.column, err)
+			}
+			log.Printf("INFO: [Schema] Columna %s.%s añadida.", col.table, col.column)
+		}
+	}
+	for _, idx := range schemaIndexes {
+		var count int
+		err := conn.DB.QueryRow("SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?",
+			idx.table, idx.name).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("error al inspeccionar índice %s: %w", idx.name, err)
+		}
+		if count == 0 {
+			if _, err := conn.DB.Exec(idx.statement); err != nil {
+				return fmt.Errorf("error al crear índice %s: %w", idx.name, err)
+			}
+			log.Printf("INFO: [Schema] Índice %s creado.", idx.name)
+		}
+	}
+	for _, stmt := range schemaBackfills {
+		if _, err := conn.DB.Exec(stmt); err != nil {
+			return fmt.Errorf("error al completar datos del esquema: %w", err)
+		}
+	}
+	log.Printf("INFO: [Schema] Esquema verificado (%d tablas, %d columnas, %d índices).", len(schemaStatements), len(schemaColumns), len(schemaIndexes))
+	return nil
+}